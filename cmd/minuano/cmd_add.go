@@ -1,15 +1,11 @@
 package main
 
 import (
-	"crypto/rand"
-	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
-	"unicode"
 
-	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+	"github.com/otavio/minuano/internal/service"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +13,8 @@ var (
 	addAfter            []string
 	addPriority         int
 	addTestCmd          string
+	addGates            []string
+	addProfile          string
 	addProject          string
 	addBody             string
 	addStatus           string
@@ -28,72 +26,36 @@ var addCmd = &cobra.Command{
 	Short: "Create a task",
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := connectDB(); err != nil {
+		gates, err := parseGates(addGates)
+		if err != nil {
 			return err
 		}
-
-		// Validate --status flag.
-		if addStatus != "ready" && addStatus != "draft" {
-			return fmt.Errorf("invalid --status %q: must be 'ready' or 'draft'", addStatus)
+		if err := connectDB(); err != nil {
+			return err
 		}
 
-		title := strings.Join(args, " ")
-		id := generateID(title)
-
 		projectID := addProject
 		if projectID == "" {
-			projectID = os.Getenv("MINUANO_PROJECT")
-		}
-		var projPtr *string
-		if projectID != "" {
-			projPtr = &projectID
-		}
-
-		var metadata json.RawMessage
-		if addTestCmd != "" {
-			m := map[string]string{"test_cmd": addTestCmd}
-			metadata, _ = json.Marshal(m)
+			projectID = defaultProject()
 		}
 
-		if err := db.CreateTask(pool, id, title, addBody, addPriority, projPtr, metadata, addRequiresApproval); err != nil {
+		task, err := newService().CreateTask(service.CreateTaskInput{
+			Title:            strings.Join(args, " "),
+			Body:             addBody,
+			Priority:         &addPriority,
+			TestCmd:          addTestCmd,
+			Gates:            gates,
+			Profile:          addProfile,
+			ProjectID:        projectID,
+			After:            addAfter,
+			Status:           addStatus,
+			RequiresApproval: addRequiresApproval,
+		})
+		if err != nil {
 			return err
 		}
 
-		// Add dependencies.
-		for _, dep := range addAfter {
-			resolvedDep, err := db.ResolvePartialID(pool, dep)
-			if err != nil {
-				return fmt.Errorf("resolving dependency %q: %w", dep, err)
-			}
-			if err := db.AddDependency(pool, id, resolvedDep); err != nil {
-				return err
-			}
-		}
-
-		// Set status based on --status flag and deps.
-		if addStatus == "draft" {
-			// Draft tasks stay draft regardless of deps.
-			if err := db.SetTaskStatus(pool, id, "draft"); err != nil {
-				return err
-			}
-		} else if len(addAfter) > 0 {
-			hasUnmet, err := db.HasUnmetDeps(pool, id)
-			if err != nil {
-				return err
-			}
-			if !hasUnmet {
-				if err := db.SetTaskStatus(pool, id, "ready"); err != nil {
-					return err
-				}
-			}
-			// Otherwise stays 'pending' (default).
-		} else {
-			if err := db.SetTaskStatus(pool, id, "ready"); err != nil {
-				return err
-			}
-		}
-
-		fmt.Printf("Created: %s  %q\n", id, title)
+		fmt.Printf("Created: %s  %q\n", task.ID, task.Title)
 		return nil
 	},
 }
@@ -102,6 +64,8 @@ func init() {
 	addCmd.Flags().StringSliceVar(&addAfter, "after", nil, "dependency task ID (partial ok, repeatable)")
 	addCmd.Flags().IntVar(&addPriority, "priority", 5, "priority 0-10")
 	addCmd.Flags().StringVar(&addTestCmd, "test-cmd", "", "test command override")
+	addCmd.Flags().StringArrayVar(&addGates, "gate", nil, "completion gate name[:timeout][:advisory]=command, run in order instead of the test command (repeatable)")
+	addCmd.Flags().StringVar(&addProfile, "profile", "", "only let agents running this profile claim the task")
 	addCmd.Flags().StringVar(&addProject, "project", "", "project ID (or MINUANO_PROJECT env)")
 	addCmd.Flags().StringVar(&addBody, "body", "", "task body/specification")
 	addCmd.Flags().StringVar(&addStatus, "status", "ready", "initial task status: ready, draft")
@@ -109,34 +73,15 @@ func init() {
 	rootCmd.AddCommand(addCmd)
 }
 
-// generateID creates a slug from the title plus a random suffix.
-func generateID(title string) string {
-	slug := slugify(title)
-	if len(slug) > 15 {
-		slug = slug[:15]
-	}
-	suffix := randomHex(3)
-	return slug + "-" + suffix
-}
-
-func slugify(s string) string {
-	s = strings.ToLower(s)
-	var b strings.Builder
-	prevDash := false
-	for _, r := range s {
-		if unicode.IsLetter(r) || unicode.IsDigit(r) {
-			b.WriteRune(r)
-			prevDash = false
-		} else if !prevDash && b.Len() > 0 {
-			b.WriteByte('-')
-			prevDash = true
+// parseGates parses --gate flags, in order.
+func parseGates(specs []string) ([]gate.Def, error) {
+	var defs []gate.Def
+	for _, spec := range specs {
+		d, err := gate.ParseDef(spec)
+		if err != nil {
+			return nil, err
 		}
+		defs = append(defs, d)
 	}
-	return strings.TrimRight(b.String(), "-")
-}
-
-func randomHex(n int) string {
-	b := make([]byte, n)
-	rand.Read(b)
-	return hex.EncodeToString(b)[:n+2]
+	return defs, gate.ValidateDefs(defs)
 }
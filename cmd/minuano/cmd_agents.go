@@ -6,7 +6,7 @@ import (
 	"text/tabwriter"
 	"time"
 
-	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/runtime"
 	"github.com/otavio/minuano/internal/tui"
 	"github.com/spf13/cobra"
 )
@@ -35,7 +35,7 @@ func init() {
 }
 
 func printAgents() error {
-	agents, err := db.ListAgents(pool)
+	agents, err := newService().ListAgents()
 	if err != nil {
 		return err
 	}
@@ -46,12 +46,16 @@ func printAgents() error {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(w, "  \tAGENT\tSTATUS\tTASK\tBRANCH\tLAST SEEN\n")
+	fmt.Fprintf(w, "  \tAGENT\tSTATUS\tTASK\tBRANCH\tPROJECT\tRUNTIME\tPROFILE\tLAST SEEN\n")
 	for _, a := range agents {
 		sym := "○"
 		if a.Status == "working" {
 			sym = "●"
 		}
+		status := a.Status
+		if rt, err := runtime.For(pool, a); err == nil && !rt.Alive(a) {
+			sym, status = "✗", "dead"
+		}
 		taskID := "—"
 		if a.TaskID != nil {
 			taskID = *a.TaskID
@@ -60,11 +64,15 @@ func printAgents() error {
 		if a.Branch != nil {
 			branch = *a.Branch
 		}
+		project := "—"
+		if a.ProjectID != nil {
+			project = *a.ProjectID
+		}
 		lastSeen := "—"
 		if a.LastSeen != nil {
 			lastSeen = relativeTime(*a.LastSeen)
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", sym, a.ID, a.Status, taskID, branch, lastSeen)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", sym, a.ID, status, taskID, branch, project, a.Runtime, a.Profile, lastSeen)
 	}
 	w.Flush()
 	return nil
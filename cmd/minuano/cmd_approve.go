@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/otavio/minuano/internal/db"
 	"github.com/spf13/cobra"
 )
 
@@ -19,11 +18,6 @@ var approveCmd = &cobra.Command{
 			return err
 		}
 
-		resolvedID, err := db.ResolvePartialID(pool, args[0])
-		if err != nil {
-			return err
-		}
-
 		actor := approveBy
 		if actor == "" {
 			actor = os.Getenv("APPROVER_ID")
@@ -32,10 +26,11 @@ var approveCmd = &cobra.Command{
 			actor = "cli"
 		}
 
-		if err := db.ApproveTask(pool, resolvedID, actor); err != nil {
+		task, err := newService().Approve(args[0], actor)
+		if err != nil {
 			return err
 		}
-		fmt.Printf("Approved: %s (by %s)\n", resolvedID, actor)
+		fmt.Printf("Approved: %s (by %s)\n", task.ID, actor)
 		return nil
 	},
 }
@@ -51,16 +46,12 @@ var rejectCmd = &cobra.Command{
 			return err
 		}
 
-		resolvedID, err := db.ResolvePartialID(pool, args[0])
+		task, err := newService().Reject(args[0], rejectReason)
 		if err != nil {
 			return err
 		}
 
-		if err := db.RejectTask(pool, resolvedID, rejectReason); err != nil {
-			return err
-		}
-
-		msg := fmt.Sprintf("Rejected: %s", resolvedID)
+		msg := fmt.Sprintf("Rejected: %s", task.ID)
 		if rejectReason != "" {
 			msg += fmt.Sprintf(" (%s)", rejectReason)
 		}
@@ -86,13 +77,13 @@ var draftReleaseCmd = &cobra.Command{
 		if draftReleaseAll {
 			proj := draftReleaseProject
 			if proj == "" {
-				proj = os.Getenv("MINUANO_PROJECT")
+				proj = defaultProject()
 			}
 			if proj == "" {
 				return fmt.Errorf("--project is required with --all")
 			}
 
-			n, err := db.DraftReleaseAll(pool, proj)
+			n, err := newService().DraftReleaseAll(proj)
 			if err != nil {
 				return err
 			}
@@ -104,15 +95,11 @@ var draftReleaseCmd = &cobra.Command{
 			return fmt.Errorf("specify a task ID or use --all --project <id>")
 		}
 
-		resolvedID, err := db.ResolvePartialID(pool, args[0])
+		task, err := newService().DraftRelease(args[0])
 		if err != nil {
 			return err
 		}
-
-		if err := db.DraftRelease(pool, resolvedID); err != nil {
-			return err
-		}
-		fmt.Printf("Released: %s\n", resolvedID)
+		fmt.Printf("Released: %s (%s)\n", task.ID, task.Status)
 		return nil
 	},
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/runtime"
 	"github.com/otavio/minuano/internal/tmux"
 	"github.com/spf13/cobra"
 )
@@ -31,7 +32,7 @@ var attachCmd = &cobra.Command{
 			return err
 		}
 		if agent != nil {
-			return tmux.AttachOrSwitch(session, agent.TmuxWindow)
+			return attachAgent(agent)
 		}
 
 		// Try as task ID (partial match) — find the agent working on it.
@@ -48,10 +49,19 @@ var attachCmd = &cobra.Command{
 			return fmt.Errorf("no agent is currently working on task %s", resolvedID)
 		}
 
-		return tmux.AttachOrSwitch(session, taskAgent.TmuxWindow)
+		return attachAgent(taskAgent)
 	},
 }
 
+// attachAgent attaches through the runtime that owns the agent.
+func attachAgent(a *db.Agent) error {
+	rt, err := runtime.For(pool, a)
+	if err != nil {
+		return err
+	}
+	return rt.Attach(a)
+}
+
 func init() {
 	rootCmd.AddCommand(attachCmd)
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/otavio/minuano/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configShowJSON bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect Minuano's configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the resolved settings and where each came from",
+	Long: `Print every setting with its resolved value and source. Settings come from
+the first of: a command-line flag, the environment, the config file
+(.minuano.yaml at the repository root, or $MINUANO_CONFIG), and the built-in
+default.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c := cfg()
+		if sessionName != "" {
+			if err := c.Override("session", sessionName, "flag --session"); err != nil {
+				return err
+			}
+		}
+		if runtimeName != "" {
+			if err := c.Override("runtime", runtimeName, "flag --runtime"); err != nil {
+				return err
+			}
+		}
+
+		if configShowJSON {
+			data, err := json.MarshalIndent(map[string]any{
+				"file":     c.File,
+				"settings": c.Values(),
+			}, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		printConfig(os.Stdout, c, config.Path())
+		return nil
+	},
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&configShowJSON, "json", false, "output as JSON")
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// printConfig prints the settings of c as a table, followed by the config
+// file looked up at path.
+func printConfig(out io.Writer, c *config.Config, path string) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "KEY\tVALUE\tSOURCE\tENV\n")
+	for _, v := range c.Values() {
+		value := v.Value
+		if value == "" {
+			value = "—"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Key, value, v.Source, v.Env)
+	}
+	w.Flush()
+
+	switch {
+	case c.File != "":
+		fmt.Fprintf(out, "\nConfig file: %s\n", c.File)
+	case path != "":
+		fmt.Fprintf(out, "\nConfig file: %s (not found)\n", path)
+	default:
+		fmt.Fprintf(out, "\nConfig file: none (not in a git repository)\n")
+	}
+}
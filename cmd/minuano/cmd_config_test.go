@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/otavio/minuano/internal/config"
+)
+
+func TestConfigCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "config" {
+			for _, sub := range c.Commands() {
+				if sub.Use == "show" {
+					return
+				}
+			}
+		}
+	}
+	t.Error("expected 'config show' command to be registered")
+}
+
+func TestConfigShowFlags(t *testing.T) {
+	if configShowCmd.Flags().Lookup("json") == nil {
+		t.Error("expected --json flag on config show command")
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	c, err := config.Resolve("/repo/.minuano.yaml", []byte("project: web\n"), func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printConfig(&buf, c, "/repo/.minuano.yaml")
+	out := buf.String()
+	for _, want := range []string{"KEY", "project ", "web", "/repo/.minuano.yaml", "go test ./...", "built-in", "Config file: /repo/.minuano.yaml\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+
+	c, _ = config.Resolve("", nil, func(string) string { return "" })
+	buf.Reset()
+	printConfig(&buf, c, "/repo/.minuano.yaml")
+	if !strings.Contains(buf.String(), "(not found)") {
+		t.Errorf("expected a missing file note, got %q", buf.String())
+	}
+}
+
+func TestDefaultProjectFromEnv(t *testing.T) {
+	t.Setenv("MINUANO_PROJECT", "web")
+	if got := defaultProject(); got != "web" {
+		t.Errorf("defaultProject() = %q, want web", got)
+	}
+}
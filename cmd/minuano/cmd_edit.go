@@ -5,7 +5,7 @@ import (
 	"os"
 	"os/exec"
 
-	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/service"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +18,8 @@ var editCmd = &cobra.Command{
 			return err
 		}
 
-		task, err := db.GetTask(pool, args[0])
+		svc := newService()
+		task, err := svc.GetTask(args[0])
 		if err != nil {
 			return err
 		}
@@ -62,7 +63,9 @@ var editCmd = &cobra.Command{
 			return nil
 		}
 
-		if err := db.UpdateTask(pool, task.ID, task.Title, string(newBody)); err != nil {
+		// Conditional on the version we opened, so edits made meanwhile aren't lost.
+		body := string(newBody)
+		if _, err := svc.UpdateTask(task.ID, service.UpdateTaskInput{Body: &body}, &task.Version); err != nil {
 			return err
 		}
 
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var eventsPruneOlderThan string
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Manage the event log behind /v1/events",
+}
+
+var eventsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete logged events older than the retention period",
+	Long: `Delete logged events older than the retention period. Database triggers log
+every change whether or not anything serves /v1/events; minuano serve --api
+prunes the log hourly, so run this where no API server does.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		retention, err := parseWindow(eventsPruneOlderThan)
+		if err != nil {
+			return err
+		}
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		n, err := db.PruneEvents(pool, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Pruned %d event(s) older than %s\n", n, eventsPruneOlderThan)
+		return nil
+	},
+}
+
+func init() {
+	eventsPruneCmd.Flags().StringVar(&eventsPruneOlderThan, "older-than", "7d", "retention, e.g. 72h, 14d")
+	eventsCmd.AddCommand(eventsPruneCmd)
+	rootCmd.AddCommand(eventsCmd)
+}
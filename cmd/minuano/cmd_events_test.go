@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestEventsCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "events" {
+			for _, sub := range c.Commands() {
+				if sub.Use == "prune" {
+					return
+				}
+			}
+			t.Fatal("expected 'events prune' subcommand")
+		}
+	}
+	t.Error("expected 'events' command to be registered")
+}
+
+func TestEventsPruneFlags(t *testing.T) {
+	f := eventsPruneCmd.Flags().Lookup("older-than")
+	if f == nil {
+		t.Fatal("expected --older-than flag on events prune")
+	}
+	if f.DefValue != "7d" {
+		t.Errorf("expected the default retention to match serve's, got %q", f.DefValue)
+	}
+}
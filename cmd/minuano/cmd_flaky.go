@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flakyLimit int
+	flakyJSON  bool
+)
+
+var flakyCmd = &cobra.Command{
+	Use:   "flaky",
+	Short: "List the tests found flaky most often",
+	Long: `List the tests that failed in a go test gate, then passed when re-run
+(see flaky_reruns), most occurrences first. Each of these cost an agent a
+re-run, or without re-runs an attempt, over work that was fine.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flakyLimit < 0 {
+			return fmt.Errorf("--limit must not be negative")
+		}
+		if err := connectDB(); err != nil {
+			return err
+		}
+		tests, err := db.ListFlakyTests(pool, flakyLimit)
+		if err != nil {
+			return err
+		}
+
+		if flakyJSON {
+			if tests == nil {
+				tests = []*db.FlakyTest{}
+			}
+			data, err := json.MarshalIndent(tests, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling JSON: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		if len(tests) == 0 {
+			fmt.Println("No flaky tests.")
+			return nil
+		}
+		printFlakyTests(os.Stdout, tests)
+		return nil
+	},
+}
+
+func init() {
+	flakyCmd.Flags().IntVar(&flakyLimit, "limit", 20, "show at most this many tests, 0 for all")
+	flakyCmd.Flags().BoolVar(&flakyJSON, "json", false, "output as JSON")
+	rootCmd.AddCommand(flakyCmd)
+}
+
+// printFlakyTests prints flaky tests, one per line.
+func printFlakyTests(out io.Writer, tests []*db.FlakyTest) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TIMES\tTEST\tPACKAGE\tFIRST SEEN\tLAST SEEN\tLAST TASK\n")
+	for _, t := range tests {
+		task := "—"
+		if t.LastTaskID != nil {
+			task = *t.LastTaskID
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", t.Occurrences, t.Test, t.Package,
+			t.FirstSeen.Local().Format("2006-01-02"), relativeTime(t.LastSeen), task)
+	}
+	w.Flush()
+}
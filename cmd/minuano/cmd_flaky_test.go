@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+func TestFlakyCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "flaky" {
+			return
+		}
+	}
+	t.Error("expected 'flaky' command to be registered")
+}
+
+func TestFlakyCommandFlags(t *testing.T) {
+	for _, name := range []string{"limit", "json"} {
+		if flakyCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on flaky command", name)
+		}
+	}
+	if got := flakyCmd.Flags().Lookup("limit").DefValue; got != "20" {
+		t.Errorf("--limit default = %s, want 20", got)
+	}
+}
+
+func TestPrintFlakyTests(t *testing.T) {
+	task := "auth-1"
+	tests := []*db.FlakyTest{
+		{Package: "example.com/auth", Test: "TestLogin/timeout", Occurrences: 7, FirstSeen: time.Now().Add(-48 * time.Hour), LastSeen: time.Now().Add(-2 * time.Hour), LastTaskID: &task},
+		{Package: "example.com/db", Test: "TestPool", Occurrences: 1, FirstSeen: time.Now(), LastSeen: time.Now()},
+	}
+	var out bytes.Buffer
+	printFlakyTests(&out, tests)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 tests, got:\n%s", out.String())
+	}
+	for _, want := range []string{"7", "TestLogin/timeout", "example.com/auth", "2h ago", "auth-1"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("line %q is missing %q", lines[1], want)
+		}
+	}
+	if !strings.HasPrefix(lines[2], "1 ") || !strings.HasSuffix(lines[2], "—") {
+		t.Errorf("unexpected line %q", lines[2])
+	}
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphFormat  string
+	graphProject string
+	graphLabel   string
+	graphSubtree string
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Emit the task DAG as Graphviz DOT, Mermaid or JSON",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		proj := graphProject
+		if proj == "" {
+			proj = defaultProject()
+		}
+		var projPtr *string
+		if proj != "" {
+			projPtr = &proj
+		}
+
+		tasks, err := db.ListTasks(pool, projPtr)
+		if err != nil {
+			return err
+		}
+		deps, err := db.ListDependencies(pool, projPtr)
+		if err != nil {
+			return err
+		}
+
+		g := buildTaskGraph(tasks, deps)
+		if graphSubtree != "" {
+			rootID, err := db.ResolvePartialID(pool, graphSubtree)
+			if err != nil {
+				return err
+			}
+			g = g.subtree(rootID)
+		}
+		if graphLabel != "" {
+			g = g.withLabel(graphLabel)
+		}
+		g.markCriticalPath()
+
+		switch graphFormat {
+		case "dot":
+			fmt.Print(renderDOT(g))
+		case "mermaid":
+			fmt.Print(renderMermaid(g))
+		case "json":
+			data, err := json.MarshalIndent(g.toJSON(), "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling JSON: %w", err)
+			}
+			fmt.Println(string(data))
+		default:
+			return fmt.Errorf("invalid --format %q: must be 'dot', 'mermaid' or 'json'", graphFormat)
+		}
+		return nil
+	},
+}
+
+func init() {
+	graphCmd.Flags().StringVar(&graphFormat, "format", "mermaid", "output format: dot, mermaid, json")
+	graphCmd.Flags().StringVar(&graphProject, "project", "", "filter by project ID")
+	graphCmd.Flags().StringVar(&graphLabel, "label", "", "only include tasks with this label")
+	graphCmd.Flags().StringVar(&graphSubtree, "subtree", "", "only include this task and everything that depends on it")
+	rootCmd.AddCommand(graphCmd)
+}
+
+// epicLabelPrefix marks the label that groups a task into an epic cluster.
+const epicLabelPrefix = "epic:"
+
+// taskGraph is the task DAG with each task appearing exactly once.
+// Edges point from a dependency to the task that depends on it.
+type taskGraph struct {
+	nodes []*db.Task
+	edges []graphEdge
+
+	// Set by markCriticalPath.
+	path          []string
+	critical      map[string]bool
+	criticalEdges map[graphEdge]bool
+}
+
+type graphEdge struct {
+	From string
+	To   string
+}
+
+// buildTaskGraph wires tasks with their dependency edges, dropping edges whose
+// endpoints are outside the task set.
+func buildTaskGraph(tasks []*db.Task, deps map[string][]string) *taskGraph {
+	present := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		present[t.ID] = true
+	}
+
+	g := &taskGraph{nodes: tasks}
+	for _, t := range tasks {
+		for _, depID := range deps[t.ID] {
+			if present[depID] {
+				g.edges = append(g.edges, graphEdge{From: depID, To: t.ID})
+			}
+		}
+	}
+	return g
+}
+
+// keep returns a graph restricted to the given task IDs.
+func (g *taskGraph) keep(ids map[string]bool) *taskGraph {
+	out := &taskGraph{}
+	for _, t := range g.nodes {
+		if ids[t.ID] {
+			out.nodes = append(out.nodes, t)
+		}
+	}
+	for _, e := range g.edges {
+		if ids[e.From] && ids[e.To] {
+			out.edges = append(out.edges, e)
+		}
+	}
+	return out
+}
+
+// subtree keeps rootID and every task transitively depending on it.
+func (g *taskGraph) subtree(rootID string) *taskGraph {
+	children := make(map[string][]string)
+	for _, e := range g.edges {
+		children[e.From] = append(children[e.From], e.To)
+	}
+
+	ids := map[string]bool{rootID: true}
+	queue := []string{rootID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, c := range children[id] {
+			if !ids[c] {
+				ids[c] = true
+				queue = append(queue, c)
+			}
+		}
+	}
+	return g.keep(ids)
+}
+
+// withLabel keeps tasks carrying the given label.
+func (g *taskGraph) withLabel(label string) *taskGraph {
+	ids := make(map[string]bool)
+	for _, t := range g.nodes {
+		for _, l := range t.Meta().Labels {
+			if l == label {
+				ids[t.ID] = true
+				break
+			}
+		}
+	}
+	return g.keep(ids)
+}
+
+// markCriticalPath finds the longest chain of unfinished tasks. Done tasks weigh
+// nothing, so the path is the remaining sequential work that bounds completion.
+func (g *taskGraph) markCriticalPath() {
+	g.path = nil
+	g.critical = map[string]bool{}
+	g.criticalEdges = map[graphEdge]bool{}
+
+	indeg := make(map[string]int)
+	children := make(map[string][]string)
+	for _, e := range g.edges {
+		indeg[e.To]++
+		children[e.From] = append(children[e.From], e.To)
+	}
+
+	weight := make(map[string]int)
+	dist := make(map[string]int)
+	prev := make(map[string]string)
+	var queue []string
+	for _, t := range g.nodes {
+		if t.Status != "done" {
+			weight[t.ID] = 1
+		}
+		dist[t.ID] = weight[t.ID]
+		if indeg[t.ID] == 0 {
+			queue = append(queue, t.ID)
+		}
+	}
+
+	best := ""
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if best == "" || dist[id] > dist[best] {
+			best = id
+		}
+		for _, c := range children[id] {
+			if d := dist[id] + weight[c]; d > dist[c] {
+				dist[c] = d
+				prev[c] = id
+			}
+			indeg[c]--
+			if indeg[c] == 0 {
+				queue = append(queue, c)
+			}
+		}
+	}
+	if best == "" || dist[best] == 0 {
+		return
+	}
+
+	var path []string
+	for id := best; id != ""; id = prev[id] {
+		path = append([]string{id}, path...)
+	}
+	// Leading done tasks add no length; trim them so the path starts at real work.
+	for len(path) > 0 && weight[path[0]] == 0 {
+		path = path[1:]
+	}
+
+	g.path = path
+	for i, id := range path {
+		g.critical[id] = true
+		if i > 0 {
+			g.criticalEdges[graphEdge{From: path[i-1], To: id}] = true
+		}
+	}
+}
+
+// epics groups task IDs by their epic label, in first-seen order.
+func (g *taskGraph) epics() ([]string, map[string][]*db.Task) {
+	var names []string
+	members := make(map[string][]*db.Task)
+	for _, t := range g.nodes {
+		epic := taskEpic(t)
+		if epic == "" {
+			continue
+		}
+		if _, ok := members[epic]; !ok {
+			names = append(names, epic)
+		}
+		members[epic] = append(members[epic], t)
+	}
+	return names, members
+}
+
+func taskEpic(t *db.Task) string {
+	for _, l := range t.Meta().Labels {
+		if strings.HasPrefix(l, epicLabelPrefix) {
+			return strings.TrimPrefix(l, epicLabelPrefix)
+		}
+	}
+	return ""
+}
+
+// statusColor is the fill color used for a task status in rendered graphs.
+func statusColor(status string) string {
+	switch status {
+	case "pending":
+		return "#e0e0e0"
+	case "draft":
+		return "#f5f5f5"
+	case "ready":
+		return "#b3e5fc"
+	case "claimed":
+		return "#fff59d"
+	case "done":
+		return "#c8e6c9"
+	case "failed":
+		return "#ffcdd2"
+	case "pending_approval":
+		return "#ffe0b2"
+	case "rejected":
+		return "#bdbdbd"
+	default:
+		return "#ffffff"
+	}
+}
+
+const criticalColor = "#d32f2f"
+
+func renderDOT(g *taskGraph) string {
+	var b strings.Builder
+	b.WriteString("digraph tasks {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+
+	writeNode := func(indent string, t *db.Task) {
+		attrs := fmt.Sprintf("label=%s, fillcolor=%q", dotQuote(t.ID+"\n"+t.Title), statusColor(t.Status))
+		if g.critical[t.ID] {
+			attrs += fmt.Sprintf(", color=%q, penwidth=2.5", criticalColor)
+		}
+		fmt.Fprintf(&b, "%s%s [%s];\n", indent, dotQuote(t.ID), attrs)
+	}
+
+	names, members := g.epics()
+	for i, name := range names {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(name))
+		b.WriteString("    style=dashed;\n")
+		for _, t := range members[name] {
+			writeNode("    ", t)
+		}
+		b.WriteString("  }\n")
+	}
+	for _, t := range g.nodes {
+		if taskEpic(t) == "" {
+			writeNode("  ", t)
+		}
+	}
+
+	for _, e := range g.edges {
+		attrs := ""
+		if g.criticalEdges[e] {
+			attrs = fmt.Sprintf(" [color=%q, penwidth=2.5]", criticalColor)
+		}
+		fmt.Fprintf(&b, "  %s -> %s%s;\n", dotQuote(e.From), dotQuote(e.To), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func renderMermaid(g *taskGraph) string {
+	// Mermaid IDs are restricted, so nodes are numbered and the task ID goes in the label.
+	ids := make(map[string]string, len(g.nodes))
+	for i, t := range g.nodes {
+		ids[t.ID] = fmt.Sprintf("t%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	writeNode := func(indent string, t *db.Task) {
+		label := fmt.Sprintf("%s %s<br/>%s", statusSymbol(t.Status), t.ID, t.Title)
+		fmt.Fprintf(&b, "%s%s[\"%s\"]:::%s\n", indent, ids[t.ID], mermaidEscape(label), mermaidClass(t.Status))
+	}
+
+	names, members := g.epics()
+	for i, name := range names {
+		fmt.Fprintf(&b, "  subgraph epic%d[\"%s\"]\n", i, mermaidEscape(name))
+		for _, t := range members[name] {
+			writeNode("    ", t)
+		}
+		b.WriteString("  end\n")
+	}
+	for _, t := range g.nodes {
+		if taskEpic(t) == "" {
+			writeNode("  ", t)
+		}
+	}
+
+	var criticalLinks []string
+	for i, e := range g.edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e.From], ids[e.To])
+		if g.criticalEdges[e] {
+			criticalLinks = append(criticalLinks, fmt.Sprint(i))
+		}
+	}
+
+	statuses := make(map[string]bool)
+	for _, t := range g.nodes {
+		statuses[t.Status] = true
+	}
+	var sorted []string
+	for s := range statuses {
+		sorted = append(sorted, s)
+	}
+	sort.Strings(sorted)
+	for _, s := range sorted {
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:#616161\n", mermaidClass(s), statusColor(s))
+	}
+
+	var criticalNodes []string
+	for _, t := range g.nodes {
+		if g.critical[t.ID] {
+			criticalNodes = append(criticalNodes, ids[t.ID])
+		}
+	}
+	if len(criticalNodes) > 0 {
+		fmt.Fprintf(&b, "  classDef critical stroke:%s,stroke-width:3px\n", criticalColor)
+		fmt.Fprintf(&b, "  class %s critical\n", strings.Join(criticalNodes, ","))
+	}
+	if len(criticalLinks) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:%s,stroke-width:3px\n", strings.Join(criticalLinks, ","), criticalColor)
+	}
+	return b.String()
+}
+
+func mermaidClass(status string) string {
+	if status == "" {
+		return "unknown"
+	}
+	return strings.ReplaceAll(status, "_", "")
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+// graphJSON is the JSON structure for `minuano graph --format json`.
+type graphJSON struct {
+	Nodes        []graphJSONNode `json:"nodes"`
+	Edges        []graphJSONEdge `json:"edges"`
+	CriticalPath []string        `json:"critical_path"`
+}
+
+type graphJSONNode struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Status    string   `json:"status"`
+	Priority  int      `json:"priority"`
+	ProjectID *string  `json:"project_id,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	Epic      string   `json:"epic,omitempty"`
+	Critical  bool     `json:"critical"`
+}
+
+type graphJSONEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Critical bool   `json:"critical"`
+}
+
+func (g *taskGraph) toJSON() graphJSON {
+	out := graphJSON{
+		Nodes:        []graphJSONNode{},
+		Edges:        []graphJSONEdge{},
+		CriticalPath: []string{},
+	}
+	for _, t := range g.nodes {
+		out.Nodes = append(out.Nodes, graphJSONNode{
+			ID:        t.ID,
+			Title:     t.Title,
+			Status:    t.Status,
+			Priority:  t.Priority,
+			ProjectID: t.ProjectID,
+			Labels:    t.Meta().Labels,
+			Epic:      taskEpic(t),
+			Critical:  g.critical[t.ID],
+		})
+	}
+	for _, e := range g.edges {
+		out.Edges = append(out.Edges, graphJSONEdge{From: e.From, To: e.To, Critical: g.criticalEdges[e]})
+	}
+	if g.path != nil {
+		out.CriticalPath = g.path
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+// diamondGraph builds a → {b, c} → d, with e independent and a already done.
+func diamondGraph() *taskGraph {
+	tasks := []*db.Task{
+		{ID: "a", Title: "A", Status: "done", Metadata: json.RawMessage(`{"labels":["epic:core"]}`)},
+		{ID: "b", Title: "B", Status: "ready", Metadata: json.RawMessage(`{"labels":["epic:core","api"]}`)},
+		{ID: "c", Title: "C \"quoted\"", Status: "ready"},
+		{ID: "d", Title: "D", Status: "pending", Metadata: json.RawMessage(`{"labels":["api"]}`)},
+		{ID: "e", Title: "E", Status: "ready"},
+	}
+	deps := map[string][]string{
+		"b": {"a"},
+		"c": {"a"},
+		"d": {"b", "c", "outside"},
+	}
+	return buildTaskGraph(tasks, deps)
+}
+
+func TestGraphCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "graph" {
+			return
+		}
+	}
+	t.Error("expected 'graph' command to be registered")
+}
+
+func TestGraphCommandFlags(t *testing.T) {
+	for _, name := range []string{"format", "project", "label", "subtree"} {
+		if graphCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on graph command", name)
+		}
+	}
+}
+
+func TestBuildTaskGraph_DropsOutsideEdges(t *testing.T) {
+	g := diamondGraph()
+	if len(g.edges) != 4 {
+		t.Errorf("expected 4 edges, got %d: %v", len(g.edges), g.edges)
+	}
+}
+
+func TestTaskGraph_Subtree(t *testing.T) {
+	g := diamondGraph().subtree("b")
+	var ids []string
+	for _, n := range g.nodes {
+		ids = append(ids, n.ID)
+	}
+	if strings.Join(ids, ",") != "b,d" {
+		t.Errorf("subtree(b) = %v, want [b d]", ids)
+	}
+	if len(g.edges) != 1 {
+		t.Errorf("expected 1 edge in subtree, got %v", g.edges)
+	}
+}
+
+func TestTaskGraph_WithLabel(t *testing.T) {
+	g := diamondGraph().withLabel("api")
+	if len(g.nodes) != 2 || len(g.edges) != 1 {
+		t.Errorf("withLabel(api) = %d nodes, %d edges", len(g.nodes), len(g.edges))
+	}
+}
+
+func TestTaskGraph_CriticalPath(t *testing.T) {
+	g := diamondGraph()
+	g.markCriticalPath()
+
+	if strings.Join(g.path, ",") != "b,d" {
+		t.Errorf("critical path = %v, want [b d]", g.path)
+	}
+	if !g.criticalEdges[graphEdge{From: "b", To: "d"}] {
+		t.Error("expected b → d to be a critical edge")
+	}
+	if g.criticalEdges[graphEdge{From: "c", To: "d"}] {
+		t.Error("c → d should not be critical")
+	}
+}
+
+func TestTaskGraph_CriticalPathAllDone(t *testing.T) {
+	g := buildTaskGraph([]*db.Task{{ID: "x", Status: "done"}}, nil)
+	g.markCriticalPath()
+	if len(g.path) != 0 {
+		t.Errorf("expected empty critical path, got %v", g.path)
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	g := diamondGraph()
+	g.markCriticalPath()
+	out := renderDOT(g)
+
+	checks := []string{
+		"digraph tasks {",
+		"subgraph cluster_0",
+		`label="core"`,
+		`"a" -> "b";`,
+		`"b" -> "d" [color="#d32f2f"`,
+		`C \"quoted\"`,
+		statusColor("ready"),
+	}
+	for _, c := range checks {
+		if !strings.Contains(out, c) {
+			t.Errorf("DOT output missing %q:\n%s", c, out)
+		}
+	}
+	// Every task is declared exactly once.
+	if n := strings.Count(out, `"d" [label=`); n != 1 {
+		t.Errorf("expected node d declared once, got %d", n)
+	}
+}
+
+func TestRenderMermaid(t *testing.T) {
+	g := diamondGraph()
+	g.markCriticalPath()
+	out := renderMermaid(g)
+
+	checks := []string{
+		"flowchart LR",
+		`subgraph epic0["core"]`,
+		"t0 --> t1",
+		"#quot;quoted#quot;",
+		"classDef ready",
+		"class t1,t3 critical",
+		"linkStyle",
+	}
+	for _, c := range checks {
+		if !strings.Contains(out, c) {
+			t.Errorf("Mermaid output missing %q:\n%s", c, out)
+		}
+	}
+}
+
+func TestGraphJSON(t *testing.T) {
+	g := diamondGraph()
+	g.markCriticalPath()
+	out := g.toJSON()
+
+	if len(out.Nodes) != 5 || len(out.Edges) != 4 {
+		t.Errorf("unexpected sizes: %d nodes, %d edges", len(out.Nodes), len(out.Edges))
+	}
+	if strings.Join(out.CriticalPath, ",") != "b,d" {
+		t.Errorf("critical_path = %v", out.CriticalPath)
+	}
+	if out.Nodes[0].Epic != "core" {
+		t.Errorf("expected epic core on a, got %q", out.Nodes[0].Epic)
+	}
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 
-	"github.com/otavio/minuano/internal/agent"
 	"github.com/spf13/cobra"
 )
 
@@ -17,10 +16,10 @@ var killCmd = &cobra.Command{
 			return err
 		}
 
-		session := getSessionName()
+		svc := newService()
 
 		if killAll {
-			if err := agent.KillAll(pool, session); err != nil {
+			if err := svc.KillAllAgents(); err != nil {
 				return err
 			}
 			fmt.Println("✓ All agents killed.")
@@ -32,7 +31,7 @@ var killCmd = &cobra.Command{
 		}
 
 		agentID := args[0]
-		if err := agent.Kill(pool, session, agentID); err != nil {
+		if err := svc.KillAgent(agentID); err != nil {
 			return err
 		}
 
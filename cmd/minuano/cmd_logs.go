@@ -1,24 +1,107 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/otavio/minuano/internal/db"
-	"github.com/otavio/minuano/internal/tmux"
+	"github.com/otavio/minuano/internal/panelog"
+	"github.com/otavio/minuano/internal/runtime"
 	"github.com/spf13/cobra"
 )
 
-var logsLines int
+var (
+	logsLines   int
+	logsTask    string
+	logsAttempt int
+	logsFollow  bool
+	logsGrep    string
+	logsRaw     bool
+
+	logsPruneOlderThan string
+	paneLogDir         string
+)
+
+// defaultLogRetention applies when MINUANO_LOG_RETENTION is unset.
+const defaultLogRetention = "30d"
 
 var logsCmd = &cobra.Command{
-	Use:   "logs <agent-id>",
-	Short: "Capture last N lines from agent's tmux window",
-	Args:  cobra.ExactArgs(1),
+	Use:   "logs [agent-id]",
+	Short: "Capture last N lines from an agent's output, a task's archived output, or follow agents live",
+	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 && logsTask != "" {
+			return fmt.Errorf("pass an agent ID or --task <id>, not both")
+		}
+		if len(args) == 0 && logsTask == "" && !logsFollow {
+			return fmt.Errorf("pass an agent ID, --task <id> or --follow")
+		}
+		if logsAttempt != 0 && logsTask == "" {
+			return fmt.Errorf("--attempt requires --task")
+		}
+		filter, err := newLineFilter(logsGrep, logsRaw)
+		if err != nil {
+			return err
+		}
 		if err := connectDB(); err != nil {
 			return err
 		}
 
+		if logsFollow {
+			backlog := 10
+			if cmd.Flags().Changed("lines") {
+				backlog = logsLines
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			emit := func(line string) {
+				if text, ok := filter.apply(line); ok {
+					fmt.Println(text)
+				}
+			}
+
+			switch {
+			case logsTask != "":
+				task, err := db.GetTask(pool, logsTask)
+				if err != nil {
+					return err
+				}
+				if task.Status != "claimed" || task.ClaimedBy == nil || (logsAttempt != 0 && logsAttempt != task.Attempt) {
+					// Nobody is working on it (or on that attempt): print what was archived.
+					return printTaskLogs(task.ID, logsAttempt, backlog, filter)
+				}
+				return followTask(ctx, task, backlog, filter, emit)
+			case len(args) == 1:
+				agent, err := db.GetAgent(pool, args[0])
+				if err != nil {
+					return err
+				}
+				if agent == nil {
+					return fmt.Errorf("agent %q not found", args[0])
+				}
+				return panelog.Follow(ctx, pool, agent.ID, backlog, emit)
+			default:
+				return followAllAgents(ctx, backlog, filter)
+			}
+		}
+
+		if logsTask != "" {
+			lines := 0
+			if cmd.Flags().Changed("lines") {
+				lines = logsLines
+			}
+			return printTaskLogs(logsTask, logsAttempt, lines, filter)
+		}
+
 		agentID := args[0]
 		agent, err := db.GetAgent(pool, agentID)
 		if err != nil {
@@ -28,17 +111,303 @@ var logsCmd = &cobra.Command{
 			return fmt.Errorf("agent %q not found", agentID)
 		}
 
-		output, err := tmux.CapturePane(agent.TmuxSession, agent.TmuxWindow, logsLines)
+		rt, err := runtime.For(pool, agent)
+		if err != nil {
+			return err
+		}
+		output, err := rt.Capture(agent, logsLines)
 		if err != nil {
 			return fmt.Errorf("capturing pane: %w", err)
 		}
 
-		fmt.Println(output)
+		fmt.Print(filter.text(output + "\n"))
 		return nil
 	},
 }
 
+var logsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete archived pane and gate output older than the retention period",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		window := logsPruneOlderThan
+		if window == "" {
+			window = logRetention()
+		}
+		if window == "0" {
+			fmt.Println("Retention is disabled (MINUANO_LOG_RETENTION=0); nothing pruned")
+			return nil
+		}
+		retention, err := parseWindow(window)
+		if err != nil {
+			return err
+		}
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		before := time.Now().Add(-retention)
+		n, err := panelog.Prune(pool, before)
+		g, gerr := panelog.PruneGates(pool, before)
+		fmt.Printf("Pruned %d pane log(s) and %d gate output(s) older than %s\n", n, g, window)
+		return errors.Join(err, gerr)
+	},
+}
+
+// paneLogCmd is what tmux pipes an agent's window into (see panelog.Command).
+var paneLogCmd = &cobra.Command{
+	Use:    "pane-log <agent-id>",
+	Short:  "Record an agent's pane output from stdin (started by spawn)",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		// Each new agent takes the chance to drop logs past retention.
+		if window := logRetention(); window != "0" {
+			if retention, err := parseWindow(window); err == nil {
+				before := time.Now().Add(-retention)
+				panelog.Prune(pool, before)
+				panelog.PruneGates(pool, before)
+			}
+		}
+
+		dir := paneLogDir
+		if dir == "" {
+			var err error
+			if dir, err = panelog.Dir(); err != nil {
+				return err
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		defer stop()
+		return panelog.NewRecorder(pool, dir, args[0]).Run(ctx, os.Stdin)
+	},
+}
+
 func init() {
-	logsCmd.Flags().IntVar(&logsLines, "lines", 50, "number of lines to capture")
+	logsCmd.Flags().IntVar(&logsLines, "lines", 50, "number of lines to capture (with --task: only the last N)")
+	logsCmd.Flags().StringVar(&logsTask, "task", "", "print a task's archived pane output instead")
+	logsCmd.Flags().IntVar(&logsAttempt, "attempt", 0, "with --task: which attempt (default: the latest)")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "stream new output (without an agent or --task: every agent, interleaved)")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "only lines matching this regular expression")
+	logsCmd.Flags().BoolVar(&logsRaw, "raw", false, "keep ANSI escape sequences")
+	logsPruneCmd.Flags().StringVar(&logsPruneOlderThan, "older-than", "", "retention, e.g. 72h, 14d (default: $MINUANO_LOG_RETENTION or "+defaultLogRetention+")")
+	paneLogCmd.Flags().StringVar(&paneLogDir, "dir", "", "directory to write segments under (default: $MINUANO_LOG_DIR or .minuano/logs)")
+	logsCmd.AddCommand(logsPruneCmd)
 	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(paneLogCmd)
+}
+
+// logRetention is how long archived pane and gate output is kept; "0" keeps
+// it forever.
+func logRetention() string {
+	if r := os.Getenv("MINUANO_LOG_RETENTION"); r != "" {
+		return r
+	}
+	return defaultLogRetention
+}
+
+// printTaskLogs prints one attempt's archived output, the latest attempt when
+// attempt is 0. lines > 0 keeps only the last lines.
+func printTaskLogs(taskRef string, attempt, lines int, filter *lineFilter) error {
+	task, err := db.GetTask(pool, taskRef)
+	if err != nil {
+		return err
+	}
+	logs, err := db.ListPaneLogs(pool, task.ID, attempt)
+	if err != nil {
+		return err
+	}
+	if attempt == 0 {
+		logs = latestAttempt(logs)
+	}
+	if len(logs) == 0 {
+		if attempt != 0 {
+			return fmt.Errorf("no archived output for %s attempt %d", task.ID, attempt)
+		}
+		return fmt.Errorf("no archived output for %s", task.ID)
+	}
+
+	var out strings.Builder
+	for _, l := range logs {
+		if len(logs) > 1 {
+			fmt.Fprintf(&out, "==> %s (agent %s, attempt %d) <==\n", l.Path, l.AgentID, *l.Attempt)
+		}
+		var buf bytes.Buffer
+		if err := panelog.Copy(&buf, l.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+		out.WriteString(filter.text(buf.String()))
+	}
+
+	text := out.String()
+	if lines > 0 {
+		text = lastLines(text, lines)
+	}
+	fmt.Print(text)
+	return nil
+}
+
+// followTask streams the output of the agent holding task for as long as it
+// works on this attempt. The agent's recorder starts the task's segment with
+// its first output after the claim, so that is waited for first; if the agent
+// lets go of the task meanwhile, what was archived of the attempt is printed
+// instead.
+func followTask(ctx context.Context, task *db.Task, backlog int, filter *lineFilter, emit func(string)) error {
+	agentID := *task.ClaimedBy
+	for ctx.Err() == nil {
+		seg, err := db.LatestPaneLog(pool, agentID)
+		if err != nil {
+			return err
+		}
+		if seg != nil && seg.TaskID != nil && *seg.TaskID == task.ID && *seg.Attempt == task.Attempt {
+			return panelog.FollowSegment(ctx, pool, seg, backlog, emit)
+		}
+		t, err := db.GetTaskByID(pool, task.ID)
+		if err != nil {
+			return err
+		}
+		if t.Status != "claimed" || t.ClaimedBy == nil || *t.ClaimedBy != agentID || t.Attempt != task.Attempt {
+			return printTaskLogs(task.ID, task.Attempt, backlog, filter)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+	return nil
+}
+
+// followAllAgents interleaves every agent's output, each line prefixed with
+// the agent's name. Agents spawned while following are picked up.
+func followAllAgents(ctx context.Context, backlog int, filter *lineFilter) error {
+	var (
+		mu    sync.Mutex
+		width int
+	)
+	followed := map[string]bool{}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		agents, err := db.ListAgents(pool)
+		if err != nil {
+			return err
+		}
+		for _, a := range agents {
+			if followed[a.ID] {
+				continue
+			}
+			followed[a.ID] = true
+			mu.Lock()
+			width = max(width, len(a.ID))
+			mu.Unlock()
+
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				err := panelog.Follow(ctx, pool, id, backlog, func(line string) {
+					text, ok := filter.apply(line)
+					if !ok {
+						return
+					}
+					mu.Lock()
+					fmt.Printf("%-*s | %s\n", width, id, text)
+					mu.Unlock()
+				})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "warning: following %s: %v\n", id, err)
+				}
+			}(a.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// lineFilter applies --grep and ANSI stripping to pane output.
+type lineFilter struct {
+	re  *regexp.Regexp
+	raw bool
+}
+
+func newLineFilter(pattern string, raw bool) (*lineFilter, error) {
+	f := &lineFilter{raw: raw}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --grep: %w", err)
+		}
+		f.re = re
+	}
+	return f, nil
+}
+
+// apply returns the line as it should be printed, and false if it is
+// filtered out. Matching is done on the plain text even with --raw.
+func (f *lineFilter) apply(line string) (string, bool) {
+	clean := panelog.CleanLine(line)
+	if f.re != nil && !f.re.MatchString(clean) {
+		return "", false
+	}
+	if f.raw {
+		return line, true
+	}
+	return clean, true
+}
+
+// text applies the filter to every line of s.
+func (f *lineFilter) text(s string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line == "" {
+			continue
+		}
+		text, ok := f.apply(strings.TrimSuffix(line, "\n"))
+		if !ok {
+			continue
+		}
+		b.WriteString(text)
+		if strings.HasSuffix(line, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// latestAttempt keeps the segments of the highest attempt.
+func latestAttempt(logs []*db.PaneLog) []*db.PaneLog {
+	latest := 0
+	for _, l := range logs {
+		if l.Attempt != nil && *l.Attempt > latest {
+			latest = *l.Attempt
+		}
+	}
+	var out []*db.PaneLog
+	for _, l := range logs {
+		if l.Attempt != nil && *l.Attempt == latest {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// lastLines returns the last n lines of s.
+func lastLines(s string, n int) string {
+	trimmed := strings.TrimSuffix(s, "\n")
+	idx := len(trimmed)
+	for i := 0; i < n; i++ {
+		idx = strings.LastIndexByte(trimmed[:idx], '\n')
+		if idx < 0 {
+			return s
+		}
+	}
+	return s[idx+1:]
 }
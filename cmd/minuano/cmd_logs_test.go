@@ -2,11 +2,13 @@ package main
 
 import (
 	"testing"
+
+	"github.com/otavio/minuano/internal/db"
 )
 
 func TestLogsCommandRegistered(t *testing.T) {
 	for _, c := range rootCmd.Commands() {
-		if c.Use == "logs <agent-id>" {
+		if c.Use == "logs [agent-id]" {
 			return
 		}
 	}
@@ -22,7 +24,93 @@ func TestLogsRequiresArg(t *testing.T) {
 }
 
 func TestLogsCommandFlags(t *testing.T) {
-	if logsCmd.Flags().Lookup("lines") == nil {
-		t.Error("expected --lines flag on logs command")
+	for _, name := range []string{"lines", "task", "attempt"} {
+		if logsCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on logs command", name)
+		}
+	}
+	if logsPruneCmd.Flags().Lookup("older-than") == nil {
+		t.Error("expected --older-than flag on logs prune")
+	}
+}
+
+func TestPaneLogCommandHidden(t *testing.T) {
+	if !paneLogCmd.Hidden {
+		t.Error("expected pane-log to be hidden")
+	}
+	if paneLogCmd.Flags().Lookup("dir") == nil {
+		t.Error("expected --dir flag on pane-log")
+	}
+}
+
+func TestLatestAttempt(t *testing.T) {
+	one, two := 1, 2
+	logs := []*db.PaneLog{
+		{ID: 1, Attempt: &one},
+		{ID: 2, Attempt: &two},
+		{ID: 3, Attempt: &two},
+	}
+	got := latestAttempt(logs)
+	if len(got) != 2 || got[0].ID != 2 || got[1].ID != 3 {
+		t.Errorf("expected segments 2 and 3, got %+v", got)
+	}
+	if got := latestAttempt(nil); len(got) != 0 {
+		t.Errorf("expected nothing, got %+v", got)
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\n", 5, "a\nb\n"},
+		{"", 3, ""},
+	}
+	for _, tt := range tests {
+		if got := lastLines(tt.in, tt.n); got != tt.want {
+			t.Errorf("lastLines(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLogsFollowFlags(t *testing.T) {
+	f := logsCmd.Flags().Lookup("follow")
+	if f == nil || f.Shorthand != "f" {
+		t.Error("expected --follow/-f flag on logs command")
+	}
+	for _, name := range []string{"grep", "raw"} {
+		if logsCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on logs command", name)
+		}
+	}
+}
+
+func TestLineFilter(t *testing.T) {
+	f, err := newLineFilter(`FAIL`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.apply("\x1b[32mok\x1b[0m  pkg/a"); ok {
+		t.Error("expected non-matching line to be dropped")
+	}
+	if got, ok := f.apply("\x1b[31mFAIL\x1b[0m pkg/b"); !ok || got != "FAIL pkg/b" {
+		t.Errorf("got %q, %v", got, ok)
+	}
+
+	raw, _ := newLineFilter(`FAIL`, true)
+	if got, ok := raw.apply("\x1b[31mFAIL\x1b[0m"); !ok || got != "\x1b[31mFAIL\x1b[0m" {
+		t.Errorf("--raw should keep escapes, got %q, %v", got, ok)
+	}
+
+	if got := f.text("ok a\nFAIL b\nok c\nFAIL d"); got != "FAIL b\nFAIL d" {
+		t.Errorf("text = %q", got)
+	}
+
+	if _, err := newLineFilter(`(`, false); err == nil {
+		t.Error("expected error for invalid --grep")
 	}
 }
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+	"github.com/otavio/minuano/internal/service"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// specFrontmatter is the YAML header of a markdown task spec.
+type specFrontmatter struct {
+	ID               string     `yaml:"id,omitempty"`
+	Title            string     `yaml:"title,omitempty"`
+	After            []string   `yaml:"after,omitempty"`
+	Priority         *int       `yaml:"priority,omitempty"`
+	TestCmd          string     `yaml:"test_cmd,omitempty"`
+	Gates            []gate.Def `yaml:"gates,omitempty"`
+	Profile          string     `yaml:"profile,omitempty"`
+	Labels           []string   `yaml:"labels,omitempty"`
+	RequiresApproval bool       `yaml:"requires_approval,omitempty"`
+}
+
+// taskSpec is one markdown file: frontmatter plus the body used as the task body.
+type taskSpec struct {
+	File  string // base filename, e.g. "09-cmd-add.md"
+	Front specFrontmatter
+	Body  string
+}
+
+// Ref is the name other specs use in `after` to refer to this one.
+func (s *taskSpec) Ref() string {
+	return strings.TrimSuffix(s.File, ".md")
+}
+
+var (
+	importMDProject string
+	importMDStatus  string
+)
+
+var importMDCmd = &cobra.Command{
+	Use:   "import-md <dir>",
+	Short: "Create or update tasks from a directory of markdown specs",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		if importMDStatus != "ready" && importMDStatus != "draft" {
+			return fmt.Errorf("invalid --status %q: must be 'ready' or 'draft'", importMDStatus)
+		}
+
+		proj := importMDProject
+		if proj == "" {
+			proj = defaultProject()
+		}
+		var projPtr *string
+		if proj != "" {
+			projPtr = &proj
+		}
+
+		specs, err := loadTaskSpecs(args[0])
+		if err != nil {
+			return err
+		}
+		if len(specs) == 0 {
+			fmt.Println("No markdown specs found.")
+			return nil
+		}
+		if err := checkSpecCycles(specs); err != nil {
+			return err
+		}
+
+		return importTaskSpecs(specs, projPtr, importMDStatus)
+	},
+}
+
+var exportMDProject string
+
+var exportMDCmd = &cobra.Command{
+	Use:   "export-md <dir>",
+	Short: "Write tasks as markdown specs with frontmatter",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		proj := exportMDProject
+		if proj == "" {
+			proj = defaultProject()
+		}
+		var projPtr *string
+		if proj != "" {
+			projPtr = &proj
+		}
+
+		tasks, err := db.ListTasks(pool, projPtr)
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			fmt.Println("No tasks.")
+			return nil
+		}
+
+		deps, err := db.ListDependencies(pool, projPtr)
+		if err != nil {
+			return err
+		}
+
+		dir := args[0]
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating %s: %w", dir, err)
+		}
+
+		for _, spec := range tasksToSpecs(tasks, deps) {
+			data, err := renderTaskSpec(spec)
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(dir, spec.File)
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				return fmt.Errorf("writing %s: %w", path, err)
+			}
+			fmt.Printf("Wrote: %s\n", path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	importMDCmd.Flags().StringVar(&importMDProject, "project", "", "project ID (or MINUANO_PROJECT env)")
+	importMDCmd.Flags().StringVar(&importMDStatus, "status", "ready", "status for newly created tasks: ready, draft")
+	rootCmd.AddCommand(importMDCmd)
+
+	exportMDCmd.Flags().StringVar(&exportMDProject, "project", "", "filter by project ID")
+	rootCmd.AddCommand(exportMDCmd)
+}
+
+// importTaskSpecs upserts every spec, then rewires dependencies, then settles statuses,
+// all in one transaction. Tasks are matched by frontmatter id first, then by spec
+// filename within the project.
+func importTaskSpecs(specs []*taskSpec, projectID *string, newStatus string) error {
+	ids := make(map[string]string) // spec ref → task ID
+	created := make(map[string]bool)
+	maxAttempts, err := newService().TaskMaxAttempts(projectID)
+	if err != nil {
+		return err
+	}
+
+	err = db.InTx(pool, func(tx db.Querier) error {
+		for _, spec := range specs {
+			existing, err := findSpecTask(tx, spec, projectID)
+			if err != nil {
+				return fmt.Errorf("%s: %w", spec.File, err)
+			}
+
+			priority := 5
+			if existing != nil {
+				priority = existing.Priority
+			}
+			if spec.Front.Priority != nil {
+				priority = *spec.Front.Priority
+			}
+
+			var prevMeta json.RawMessage
+			if existing != nil {
+				prevMeta = existing.Metadata
+			}
+			metadata, err := specMetadata(prevMeta, spec)
+			if err != nil {
+				return fmt.Errorf("%s: %w", spec.File, err)
+			}
+
+			if existing == nil {
+				id := spec.Front.ID
+				if id == "" {
+					id = service.GenerateID(spec.Front.Title)
+				}
+				if err := db.CreateTask(tx, id, spec.Front.Title, spec.Body, priority, maxAttempts, projectID, metadata, spec.Front.RequiresApproval); err != nil {
+					return fmt.Errorf("%s: %w", spec.File, err)
+				}
+				ids[spec.Ref()] = id
+				created[id] = true
+				continue
+			}
+
+			if err := db.UpdateTaskSpec(tx, existing.ID, spec.Front.Title, spec.Body, priority, metadata, spec.Front.RequiresApproval); err != nil {
+				return fmt.Errorf("%s: %w", spec.File, err)
+			}
+			ids[spec.Ref()] = existing.ID
+		}
+
+		for _, spec := range specs {
+			id := ids[spec.Ref()]
+			if err := db.ClearDependencies(tx, id); err != nil {
+				return err
+			}
+			for _, ref := range spec.Front.After {
+				depID, ok := ids[strings.TrimSuffix(ref, ".md")]
+				if !ok {
+					resolved, err := db.ResolvePartialID(tx, ref)
+					if err != nil {
+						return fmt.Errorf("%s: resolving after %q: %w", spec.File, ref, err)
+					}
+					depID = resolved
+				}
+				if err := db.AddDependency(tx, id, depID); err != nil {
+					return fmt.Errorf("%s: %w", spec.File, err)
+				}
+			}
+		}
+
+		for _, spec := range specs {
+			id := ids[spec.Ref()]
+			if err := settleImportedStatus(tx, id, created[id], newStatus); err != nil {
+				return fmt.Errorf("%s: %w", spec.File, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		id := ids[spec.Ref()]
+		verb := "Updated"
+		if created[id] {
+			verb = "Created"
+		}
+		fmt.Printf("%s: %s  %s\n", verb, id, spec.File)
+	}
+	return nil
+}
+
+func findSpecTask(q db.Querier, spec *taskSpec, projectID *string) (*db.Task, error) {
+	if spec.Front.ID != "" {
+		t, err := db.GetTaskByID(q, spec.Front.ID)
+		if errors.Is(err, db.ErrNotFound) {
+			// An explicit id that doesn't exist yet is created on import.
+			return nil, nil
+		}
+		return t, err
+	}
+	return db.GetTaskBySpec(q, projectID, spec.File)
+}
+
+// settleImportedStatus applies the same readiness rule as `minuano add`. Existing tasks
+// are only recomputed while they are still waiting (pending/ready), so edits never
+// disturb claimed, done or draft work.
+func settleImportedStatus(q db.Querier, id string, isNew bool, newStatus string) error {
+	if isNew && newStatus == "draft" {
+		return db.SetTaskStatus(q, id, "draft")
+	}
+	if !isNew {
+		t, err := db.GetTaskByID(q, id)
+		if err != nil {
+			return err
+		}
+		if t.Status != "pending" && t.Status != "ready" {
+			return nil
+		}
+	}
+
+	hasUnmet, err := db.HasUnmetDeps(q, id)
+	if err != nil {
+		return err
+	}
+	status := "ready"
+	if hasUnmet {
+		status = "pending"
+	}
+	return db.SetTaskStatus(q, id, status)
+}
+
+// specMetadata merges the spec's fields into existing metadata, preserving unknown keys.
+func specMetadata(existing json.RawMessage, spec *taskSpec) (json.RawMessage, error) {
+	m := map[string]any{}
+	if len(existing) > 0 {
+		if err := json.Unmarshal(existing, &m); err != nil {
+			return nil, fmt.Errorf("decoding existing metadata: %w", err)
+		}
+	}
+
+	m["spec"] = spec.File
+	if spec.Front.TestCmd != "" {
+		m["test_cmd"] = spec.Front.TestCmd
+	} else {
+		delete(m, "test_cmd")
+	}
+	if len(spec.Front.Gates) > 0 {
+		m["gates"] = spec.Front.Gates
+	} else {
+		delete(m, "gates")
+	}
+	if spec.Front.Profile != "" {
+		m["profile"] = spec.Front.Profile
+	} else {
+		delete(m, "profile")
+	}
+	if len(spec.Front.Labels) > 0 {
+		m["labels"] = spec.Front.Labels
+	} else {
+		delete(m, "labels")
+	}
+	return json.Marshal(m)
+}
+
+// loadTaskSpecs parses every *.md file directly inside dir, sorted by filename.
+func loadTaskSpecs(dir string) ([]*taskSpec, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".md") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var specs []*taskSpec
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		spec, err := parseTaskSpec(name, data)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// parseTaskSpec splits optional YAML frontmatter from the markdown body. When the
+// frontmatter has no title, the first "# " heading is used and removed from the body.
+func parseTaskSpec(file string, data []byte) (*taskSpec, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	spec := &taskSpec{File: file}
+
+	if strings.HasPrefix(text, "---\n") {
+		rest := text[len("---\n"):]
+		end := strings.Index(rest, "\n---\n")
+		var header string
+		switch {
+		case strings.HasPrefix(rest, "---\n"):
+			header, rest = "", rest[len("---\n"):]
+		case end >= 0:
+			header, rest = rest[:end+1], rest[end+len("\n---\n"):]
+		case strings.HasSuffix(rest, "\n---"):
+			header, rest = strings.TrimSuffix(rest, "---"), ""
+		default:
+			return nil, fmt.Errorf("%s: unterminated frontmatter", file)
+		}
+		if err := yaml.Unmarshal([]byte(header), &spec.Front); err != nil {
+			return nil, fmt.Errorf("%s: parsing frontmatter: %w", file, err)
+		}
+		if err := gate.ValidateDefs(spec.Front.Gates); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		text = rest
+	}
+
+	body := strings.TrimSpace(text)
+	if spec.Front.Title == "" {
+		first, remainder, _ := strings.Cut(body, "\n")
+		if strings.HasPrefix(first, "# ") {
+			spec.Front.Title = strings.TrimSpace(strings.TrimPrefix(first, "# "))
+			body = strings.TrimSpace(remainder)
+		}
+	}
+	if spec.Front.Title == "" {
+		return nil, fmt.Errorf("%s: no title in frontmatter or leading heading", file)
+	}
+	spec.Body = body
+	return spec, nil
+}
+
+// renderTaskSpec is the inverse of parseTaskSpec.
+func renderTaskSpec(spec *taskSpec) ([]byte, error) {
+	header, err := yaml.Marshal(spec.Front)
+	if err != nil {
+		return nil, fmt.Errorf("%s: encoding frontmatter: %w", spec.File, err)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	b.Write(header)
+	b.WriteString("---\n")
+	if spec.Body != "" {
+		b.WriteString("\n")
+		b.WriteString(strings.TrimRight(spec.Body, "\n"))
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
+
+// checkSpecCycles rejects dependency cycles between specs in the same directory.
+func checkSpecCycles(specs []*taskSpec) error {
+	byRef := make(map[string]*taskSpec)
+	for _, s := range specs {
+		byRef[s.Ref()] = s
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+
+	var visit func(ref string, path []string) error
+	visit = func(ref string, path []string) error {
+		switch state[ref] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, ref), " → "))
+		case visited:
+			return nil
+		}
+		state[ref] = visiting
+		for _, dep := range byRef[ref].Front.After {
+			dep = strings.TrimSuffix(dep, ".md")
+			if _, ok := byRef[dep]; !ok {
+				continue // external task ID, resolved against the DB on import
+			}
+			if err := visit(dep, append(path, ref)); err != nil {
+				return err
+			}
+		}
+		state[ref] = visited
+		return nil
+	}
+
+	for _, s := range specs {
+		if err := visit(s.Ref(), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tasksToSpecs converts tasks into specs for export. Tasks keep the filename they were
+// imported from; others are named after their ID. Dependencies within the exported set
+// are written as spec refs, others as task IDs.
+func tasksToSpecs(tasks []*db.Task, deps map[string][]string) []*taskSpec {
+	files := make(map[string]string) // task ID → filename
+	used := make(map[string]bool)
+	for _, t := range tasks {
+		file := t.Meta().Spec
+		if file == "" || used[file] {
+			file = t.ID + ".md"
+		}
+		files[t.ID] = file
+		used[file] = true
+	}
+
+	var specs []*taskSpec
+	for _, t := range tasks {
+		meta := t.Meta()
+		priority := t.Priority
+		spec := &taskSpec{
+			File: files[t.ID],
+			Front: specFrontmatter{
+				ID:               t.ID,
+				Title:            t.Title,
+				Priority:         &priority,
+				TestCmd:          meta.TestCmd,
+				Gates:            meta.Gates,
+				Labels:           meta.Labels,
+				RequiresApproval: t.RequiresApproval,
+			},
+			Body: t.Body,
+		}
+		for _, depID := range deps[t.ID] {
+			if file, ok := files[depID]; ok {
+				spec.Front.After = append(spec.Front.After, strings.TrimSuffix(file, ".md"))
+			} else {
+				spec.Front.After = append(spec.Front.After, depID)
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+func TestMarkdownCommandsRegistered(t *testing.T) {
+	found := map[string]bool{}
+	for _, c := range rootCmd.Commands() {
+		found[c.Use] = true
+	}
+	for _, use := range []string{"import-md <dir>", "export-md <dir>"} {
+		if !found[use] {
+			t.Errorf("expected %q command to be registered", use)
+		}
+	}
+}
+
+func TestMarkdownCommandFlags(t *testing.T) {
+	if importMDCmd.Flags().Lookup("project") == nil {
+		t.Error("expected --project flag on import-md command")
+	}
+	if importMDCmd.Flags().Lookup("status") == nil {
+		t.Error("expected --status flag on import-md command")
+	}
+	if exportMDCmd.Flags().Lookup("project") == nil {
+		t.Error("expected --project flag on export-md command")
+	}
+}
+
+func TestParseTaskSpec_Frontmatter(t *testing.T) {
+	data := `---
+title: Implement endpoints
+after: [01-design, 02-schema.md]
+priority: 8
+test_cmd: go test ./internal/...
+labels: [auth, backend]
+requires_approval: true
+---
+
+## Goal
+
+Build it.
+`
+	spec, err := parseTaskSpec("03-endpoints.md", []byte(data))
+	if err != nil {
+		t.Fatalf("parseTaskSpec: %v", err)
+	}
+	if spec.Front.Title != "Implement endpoints" {
+		t.Errorf("title = %q", spec.Front.Title)
+	}
+	if len(spec.Front.After) != 2 || spec.Front.After[1] != "02-schema.md" {
+		t.Errorf("after = %v", spec.Front.After)
+	}
+	if spec.Front.Priority == nil || *spec.Front.Priority != 8 {
+		t.Errorf("priority = %v", spec.Front.Priority)
+	}
+	if spec.Front.TestCmd != "go test ./internal/..." {
+		t.Errorf("test_cmd = %q", spec.Front.TestCmd)
+	}
+	if !spec.Front.RequiresApproval {
+		t.Error("expected requires_approval")
+	}
+	if spec.Body != "## Goal\n\nBuild it." {
+		t.Errorf("body = %q", spec.Body)
+	}
+	if spec.Ref() != "03-endpoints" {
+		t.Errorf("ref = %q", spec.Ref())
+	}
+}
+
+func TestParseTaskSpec_HeadingTitle(t *testing.T) {
+	spec, err := parseTaskSpec("09-cmd-add.md", []byte("# Task 09 — `minuano add`\n\n## Goal\n\nAdd tasks.\n"))
+	if err != nil {
+		t.Fatalf("parseTaskSpec: %v", err)
+	}
+	if spec.Front.Title != "Task 09 — `minuano add`" {
+		t.Errorf("title = %q", spec.Front.Title)
+	}
+	if strings.HasPrefix(spec.Body, "#") && !strings.HasPrefix(spec.Body, "##") {
+		t.Errorf("expected heading stripped from body, got %q", spec.Body)
+	}
+}
+
+func TestParseTaskSpec_Errors(t *testing.T) {
+	if _, err := parseTaskSpec("a.md", []byte("no title here")); err == nil {
+		t.Error("expected error for spec without title")
+	}
+	if _, err := parseTaskSpec("b.md", []byte("---\ntitle: x\n")); err == nil {
+		t.Error("expected error for unterminated frontmatter")
+	}
+}
+
+func TestRenderTaskSpec_RoundTrip(t *testing.T) {
+	prio := 7
+	orig := &taskSpec{
+		File: "auth.md",
+		Front: specFrontmatter{
+			ID:       "auth-abc12",
+			Title:    "Auth",
+			After:    []string{"schema"},
+			Priority: &prio,
+			Labels:   []string{"epic:auth"},
+		},
+		Body: "Do the auth.",
+	}
+	data, err := renderTaskSpec(orig)
+	if err != nil {
+		t.Fatalf("renderTaskSpec: %v", err)
+	}
+	got, err := parseTaskSpec("auth.md", data)
+	if err != nil {
+		t.Fatalf("parseTaskSpec: %v\n%s", err, data)
+	}
+	if got.Front.ID != orig.Front.ID || got.Front.Title != orig.Front.Title ||
+		*got.Front.Priority != prio || got.Body != orig.Body ||
+		len(got.Front.After) != 1 || len(got.Front.Labels) != 1 {
+		t.Errorf("round trip mismatch:\n%s", data)
+	}
+}
+
+func TestLoadTaskSpecs(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "02-b.md"), []byte("# B\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "01-a.md"), []byte("# A\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644)
+
+	specs, err := loadTaskSpecs(dir)
+	if err != nil {
+		t.Fatalf("loadTaskSpecs: %v", err)
+	}
+	if len(specs) != 2 || specs[0].File != "01-a.md" || specs[1].File != "02-b.md" {
+		t.Errorf("unexpected specs: %+v", specs)
+	}
+}
+
+func TestCheckSpecCycles(t *testing.T) {
+	ok := []*taskSpec{
+		{File: "a.md", Front: specFrontmatter{Title: "A"}},
+		{File: "b.md", Front: specFrontmatter{Title: "B", After: []string{"a", "external-id"}}},
+	}
+	if err := checkSpecCycles(ok); err != nil {
+		t.Errorf("unexpected cycle error: %v", err)
+	}
+
+	cyclic := []*taskSpec{
+		{File: "a.md", Front: specFrontmatter{Title: "A", After: []string{"b.md"}}},
+		{File: "b.md", Front: specFrontmatter{Title: "B", After: []string{"a"}}},
+	}
+	if err := checkSpecCycles(cyclic); err == nil {
+		t.Error("expected cycle error")
+	}
+}
+
+func TestParseTaskSpec_Gates(t *testing.T) {
+	data := `---
+title: Harden login
+gates:
+  - name: build
+    run: go build ./...
+  - name: lint
+    run: golangci-lint run
+    timeout: 5m
+    advisory: true
+---
+`
+	spec, err := parseTaskSpec("04-login.md", []byte(data))
+	if err != nil {
+		t.Fatalf("parseTaskSpec: %v", err)
+	}
+	if len(spec.Front.Gates) != 2 || spec.Front.Gates[1].Timeout != "5m" || !spec.Front.Gates[1].Advisory {
+		t.Errorf("gates = %+v", spec.Front.Gates)
+	}
+
+	meta, err := specMetadata(json.RawMessage(`{"gates":[{"name":"old","run":"true"}]}`), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m db.TaskMeta
+	json.Unmarshal(meta, &m)
+	if len(m.Gates) != 2 || m.Gates[0].Name != "build" {
+		t.Errorf("metadata gates = %+v", m.Gates)
+	}
+
+	bad := "---\ntitle: X\ngates:\n  - name: build\n---\n"
+	if _, err := parseTaskSpec("05-bad.md", []byte(bad)); err == nil || !strings.Contains(err.Error(), "05-bad.md") {
+		t.Errorf("expected an error naming the file for a gate without a command, got %v", err)
+	}
+}
+
+func TestSpecMetadata_PreservesUnknownKeys(t *testing.T) {
+	existing := json.RawMessage(`{"test_cmd":"old","custom":1}`)
+	spec := &taskSpec{File: "x.md", Front: specFrontmatter{Labels: []string{"l"}}}
+
+	data, err := specMetadata(existing, spec)
+	if err != nil {
+		t.Fatalf("specMetadata: %v", err)
+	}
+	var m map[string]any
+	json.Unmarshal(data, &m)
+	if m["custom"] != float64(1) {
+		t.Errorf("expected custom key preserved, got %v", m)
+	}
+	if _, ok := m["test_cmd"]; ok {
+		t.Errorf("expected test_cmd removed, got %v", m)
+	}
+	if m["spec"] != "x.md" {
+		t.Errorf("expected spec=x.md, got %v", m)
+	}
+}
+
+func TestTasksToSpecs(t *testing.T) {
+	tasks := []*db.Task{
+		{ID: "schema-aaa", Title: "Schema", Priority: 5, Metadata: json.RawMessage(`{"spec":"01-schema.md"}`)},
+		{ID: "api-bbb", Title: "API", Priority: 6},
+	}
+	deps := map[string][]string{"api-bbb": {"schema-aaa", "other-ccc"}}
+
+	specs := tasksToSpecs(tasks, deps)
+	if specs[0].File != "01-schema.md" || specs[1].File != "api-bbb.md" {
+		t.Errorf("unexpected files: %s, %s", specs[0].File, specs[1].File)
+	}
+	after := specs[1].Front.After
+	if len(after) != 2 || after[0] != "01-schema" || after[1] != "other-ccc" {
+		t.Errorf("unexpected after: %v", after)
+	}
+}
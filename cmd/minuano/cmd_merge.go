@@ -1,30 +1,74 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
+	"github.com/otavio/minuano/internal/agent"
 	"github.com/otavio/minuano/internal/db"
 	"github.com/otavio/minuano/internal/git"
 	"github.com/spf13/cobra"
 )
 
-var mergeWatch bool
+var (
+	mergeWatch  bool
+	mergeReopen bool
+)
 
 var mergeCmd = &cobra.Command{
 	Use:   "merge",
 	Short: "Process merge queue entries",
+	Long: `Merge queued branches into their base branches, in the current repository.
+Entries of projects whose repo_path is another repository are left for a merge
+processor running there.
+
+Merges are done in an integration worktree of their own, .minuano/merge, so
+the checkout minuano runs from is left alone. The task's gates then run on the
+merge result, as minuano verify runs them; only if they pass is the base branch
+fast-forwarded to it (if the base branch moved meanwhile, the entry goes back
+in the queue to be merged again). If they fail, the entry is test_failed, the
+base branch is left alone, and the task gets an observation; with --reopen it
+also goes back to ready for another attempt. One merge processor runs per
+repository; another one exits, and entries a crashed one left merging are
+requeued.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := connectDB(); err != nil {
 			return err
 		}
+		root, err := git.RepoRoot()
+		if err != nil {
+			return err
+		}
+		unlock, err := db.LockMerges(pool, root)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		// Holding the lock, anything still merging was left by a processor
+		// that died mid-merge.
+		if n, err := db.RequeueStaleMerges(pool, root); err != nil {
+			return err
+		} else if n > 0 {
+			fmt.Printf("Requeued %d merge(s) left unfinished\n", n)
+		}
+		dir, err := git.MergeWorktree(root)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
 		if mergeWatch {
-			return mergeWatchLoop()
+			return mergeWatchLoop(ctx, root, dir)
 		}
-		return mergeOne()
+		return mergeOne(ctx, root, dir)
 	},
 }
 
@@ -41,12 +85,13 @@ var mergeStatusCmd = &cobra.Command{
 
 func init() {
 	mergeCmd.Flags().BoolVar(&mergeWatch, "watch", false, "poll every 5s and process continuously")
+	mergeCmd.Flags().BoolVar(&mergeReopen, "reopen", false, "send tasks whose merge fails its gates back to ready for another attempt")
 	mergeCmd.AddCommand(mergeStatusCmd)
 	rootCmd.AddCommand(mergeCmd)
 }
 
-func mergeOne() error {
-	entry, err := db.ClaimMergeEntry(pool)
+func mergeOne(ctx context.Context, root, dir string) error {
+	entry, err := db.ClaimMergeEntry(pool, root)
 	if err != nil {
 		return fmt.Errorf("claiming merge entry: %w", err)
 	}
@@ -55,38 +100,67 @@ func mergeOne() error {
 		return nil
 	}
 
-	return processMerge(entry)
+	return processMerge(ctx, root, dir, entry)
 }
 
-func mergeWatchLoop() error {
+func mergeWatchLoop(ctx context.Context, root, dir string) error {
 	fmt.Println("Watching merge queue (Ctrl+C to stop)...")
-	for {
-		entry, err := db.ClaimMergeEntry(pool)
+	for ctx.Err() == nil {
+		entry, err := db.ClaimMergeEntry(pool, root)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error claiming entry: %v\n", err)
-			time.Sleep(5 * time.Second)
+			sleepCtx(ctx, 5*time.Second)
 			continue
 		}
 		if entry == nil {
-			time.Sleep(5 * time.Second)
+			sleepCtx(ctx, 5*time.Second)
 			continue
 		}
 
-		if err := processMerge(entry); err != nil {
+		if err := processMerge(ctx, root, dir, entry); err != nil {
 			fmt.Fprintf(os.Stderr, "error processing merge: %v\n", err)
 		}
 	}
+	return nil
+}
+
+// sleepCtx sleeps for d, or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
 }
 
-func processMerge(entry *db.MergeQueueEntry) error {
+// processMerge merges an entry's branch in the integration worktree dir, runs
+// the task's gates on the result, then fast-forwards its base branch in the
+// repository at root to the merge if they pass.
+func processMerge(ctx context.Context, root, dir string, entry *db.MergeQueueEntry) error {
 	fmt.Printf("Merging: %s (task %s, branch %s → %s)\n", fmt.Sprint(entry.ID), entry.TaskID, entry.Branch, entry.BaseBranch)
 
 	message := fmt.Sprintf("Merge %s: task %s", entry.Branch, entry.TaskID)
-	mergeSHA, err := git.MergeNoFF(entry.Branch, entry.BaseBranch, message)
+	mergeSHA, err := git.MergeNoFF(dir, entry.Branch, entry.BaseBranch, message)
+	if err == nil {
+		var out *agent.Outcome
+		out, err = testMerge(ctx, dir, entry)
+		if ctx.Err() != nil {
+			// Stopped mid-gate: the merge wasn't judged, so try it again later.
+			if dbErr := db.RequeueMerge(pool, entry.ID); dbErr != nil {
+				return fmt.Errorf("requeuing merge: %w", dbErr)
+			}
+			fmt.Println("  Interrupted: left in the queue")
+			return nil
+		}
+		if err == nil && !out.Passed() {
+			return testFailed(entry, out)
+		}
+	}
+	if err == nil {
+		err = git.FastForward(root, entry.BaseBranch, mergeSHA)
+	}
 	if err != nil {
 		// Check for conflict.
 		if conflictErr, ok := err.(*git.ConflictError); ok {
-			git.AbortMerge()
 			if dbErr := db.ConflictMerge(pool, entry.ID, conflictErr.Files); dbErr != nil {
 				return fmt.Errorf("recording conflict: %w", dbErr)
 			}
@@ -97,6 +171,15 @@ func processMerge(entry *db.MergeQueueEntry) error {
 			return nil
 		}
 
+		// The base moved on meanwhile: merge again on top of it.
+		if errors.Is(err, git.ErrBaseMoved) {
+			if dbErr := db.RequeueMerge(pool, entry.ID); dbErr != nil {
+				return fmt.Errorf("requeuing merge: %w", dbErr)
+			}
+			fmt.Printf("  %v: left in the queue\n", err)
+			return nil
+		}
+
 		// Other merge failure.
 		if dbErr := db.FailMerge(pool, entry.ID, err.Error()); dbErr != nil {
 			return fmt.Errorf("recording failure: %w", dbErr)
@@ -118,8 +201,70 @@ func processMerge(entry *db.MergeQueueEntry) error {
 	return nil
 }
 
+// testMerge runs the gates of an entry's task on the merge result in dir, as
+// `minuano verify` would.
+func testMerge(ctx context.Context, dir string, entry *db.MergeQueueEntry) (*agent.Outcome, error) {
+	task, err := db.GetTask(pool, entry.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	var proj *db.Project
+	if task.ProjectID != nil {
+		if proj, err = db.GetProject(pool, *task.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	c := cfg()
+	out, _, err := agent.RunGates(ctx, agent.Verification{
+		Task:    task,
+		Project: proj,
+		AgentID: "merge-queue",
+		Dir:     dir,
+		Env:     verifyEnv(os.Getenv, c.TestCmd),
+		Timeout: c.GateTimeout,
+		Reruns:  c.FlakyReruns,
+		Output:  os.Stdout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("running gates on the merge: %w", err)
+	}
+	return out, nil
+}
+
+// testFailed records a merge whose result failed its task's gates: the entry
+// is test_failed with the gate report, the task gets it as an observation
+// and, with --reopen, goes back for another attempt.
+func testFailed(entry *db.MergeQueueEntry, out *agent.Outcome) error {
+	report := mergeTestReport(entry, out)
+	if err := db.TestFailMerge(pool, entry.ID, out.Describe(), report); err != nil {
+		return err
+	}
+	if err := db.AddObservation(pool, entry.TaskID, "merge-queue", report); err != nil {
+		fmt.Printf("  warning: %v\n", err)
+	}
+	fmt.Printf("  Test failed: %s; %s left as it was\n", out.Describe(), entry.BaseBranch)
+
+	if !mergeReopen {
+		return nil
+	}
+	status, err := db.ReopenTask(pool, entry.TaskID, "merge-queue",
+		fmt.Sprintf("Reopened: merging into %s failed gate %s; see the merge-queue observation.", entry.BaseBranch, out.Failed.Def.Name))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  Task %s reopened: %s\n", entry.TaskID, status)
+	return nil
+}
+
+// mergeTestReport is the report on a merge that failed its gates, attached to
+// the entry and the task.
+func mergeTestReport(entry *db.MergeQueueEntry, out *agent.Outcome) string {
+	return fmt.Sprintf("Merging branch %s into %s failed gate %s; %s was left as it was. %s",
+		entry.Branch, entry.BaseBranch, out.Failed.Def.Name, entry.BaseBranch, out.Report())
+}
+
 func printMergeQueue() error {
-	entries, err := db.ListMergeQueue(pool)
+	entries, err := newService().MergeQueue()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/agent"
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+)
+
+func TestMergeCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "merge" {
+			return
+		}
+	}
+	t.Error("expected 'merge' command to be registered")
+}
+
+func TestMergeCommandFlags(t *testing.T) {
+	for _, name := range []string{"watch", "reopen"} {
+		if mergeCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on merge command", name)
+		}
+	}
+}
+
+func TestMergeTestReport(t *testing.T) {
+	build := &agent.GateResult{Def: gate.Def{Name: "build", Run: "go build ./..."}, Result: &gate.Result{}}
+	test := &agent.GateResult{
+		Def:    gate.Def{Name: "test", Run: "go test ./..."},
+		Result: &gate.Result{ExitCode: 1, Duration: 2 * time.Second, Excerpt: "--- FAIL: TestLogin"},
+	}
+	entry := &db.MergeQueueEntry{Branch: "minuano/a1", BaseBranch: "main"}
+	got := mergeTestReport(entry, &agent.Outcome{Gates: []*agent.GateResult{build, test}, Failed: test})
+	want := "Merging branch minuano/a1 into main failed gate test; main was left as it was. " +
+		"Command: go test ./... (exit 1 after 2s)\nPassed: build\n\n--- FAIL: TestLogin"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
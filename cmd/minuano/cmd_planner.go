@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/otavio/minuano/internal/agent"
 	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/profile"
 	"github.com/otavio/minuano/internal/tmux"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +22,7 @@ var plannerCmd = &cobra.Command{
 var (
 	plannerStartTopic   string
 	plannerStartProject string
+	plannerProfile      string
 )
 
 var plannerStartCmd = &cobra.Command{
@@ -36,11 +40,14 @@ var plannerStartCmd = &cobra.Command{
 
 		proj := plannerStartProject
 		if proj == "" {
-			proj = os.Getenv("MINUANO_PROJECT")
+			proj = defaultProject()
 		}
 		if proj == "" {
 			return fmt.Errorf("--project is required")
 		}
+		if _, err := newService().OpenProject(proj); err != nil {
+			return err
+		}
 
 		// Check for existing session.
 		existing, err := db.GetPlannerSession(pool, topicID)
@@ -52,10 +59,11 @@ var plannerStartCmd = &cobra.Command{
 		}
 
 		windowName := fmt.Sprintf("planner-%d", topicID)
-		session := os.Getenv("MINUANO_SESSION")
-		if session == "" {
-			session = "minuano"
+		command, err := plannerCommand(windowName)
+		if err != nil {
+			return err
 		}
+		session := getSessionName()
 
 		// Ensure tmux session exists.
 		if err := tmux.EnsureSession(session); err != nil {
@@ -81,10 +89,8 @@ var plannerStartCmd = &cobra.Command{
 			return err
 		}
 
-		// Send Claude Code with planner system prompt.
-		plannerPromptPath := findPlannerPrompt()
-		tmux.SendKeys(session, windowName,
-			fmt.Sprintf("claude --dangerously-skip-permissions -p \"$(cat %s)\"", plannerPromptPath))
+		// Run the agent with the planner system prompt.
+		tmux.SendKeys(session, windowName, command)
 
 		fmt.Printf("Planner started: topic=%d window=%s project=%s\n", topicID, windowName, proj)
 		return nil
@@ -115,10 +121,7 @@ var plannerStopCmd = &cobra.Command{
 		}
 
 		// Kill tmux window.
-		tmuxSession := os.Getenv("MINUANO_SESSION")
-		if tmuxSession == "" {
-			tmuxSession = "minuano"
-		}
+		tmuxSession := getSessionName()
 		if session.TmuxWindow != nil {
 			tmux.KillWindow(tmuxSession, *session.TmuxWindow)
 		}
@@ -148,10 +151,11 @@ var plannerReopenCmd = &cobra.Command{
 		}
 
 		windowName := fmt.Sprintf("planner-%d", topicID)
-		tmuxSession := os.Getenv("MINUANO_SESSION")
-		if tmuxSession == "" {
-			tmuxSession = "minuano"
+		command, err := plannerCommand(windowName)
+		if err != nil {
+			return err
 		}
+		tmuxSession := getSessionName()
 
 		session, ropErr := db.ReopenPlannerSession(pool, topicID, windowName)
 		if ropErr != nil {
@@ -178,9 +182,7 @@ var plannerReopenCmd = &cobra.Command{
 			return fmt.Errorf("creating planner window: %w", err)
 		}
 
-		plannerPromptPath := findPlannerPrompt()
-		tmux.SendKeys(tmuxSession, windowName,
-			fmt.Sprintf("claude --dangerously-skip-permissions -p \"$(cat %s)\"", plannerPromptPath))
+		tmux.SendKeys(tmuxSession, windowName, command)
 
 		fmt.Printf("Planner reopened: topic=%d window=%s\n", topicID, windowName)
 		return nil
@@ -200,7 +202,7 @@ var plannerStatusCmd = &cobra.Command{
 		var projPtr *string
 		proj := plannerStatusProject
 		if proj == "" {
-			proj = os.Getenv("MINUANO_PROJECT")
+			proj = defaultProject()
 		}
 		if proj != "" {
 			projPtr = &proj
@@ -238,7 +240,28 @@ var plannerStatusCmd = &cobra.Command{
 	},
 }
 
+// plannerCommand is the shell command that runs the planner agent in window,
+// using the --profile agent profile.
+func plannerCommand(window string) (string, error) {
+	prof, err := newService().AgentProfile(plannerProfile)
+	if err != nil {
+		return "", err
+	}
+	command, err := prof.Script(profile.Vars{
+		AgentID:    window,
+		SessionID:  agent.NewSessionID(),
+		PromptFile: findPlannerPrompt(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(append(prof.Exports(), command), "; "), nil
+}
+
 func findPlannerPrompt() string {
+	if p := cfg().PlannerPrompt; p != "" {
+		return p
+	}
 	// Look for planner system prompt relative to binary or cwd.
 	candidates := []string{
 		"claude/planner-system-prompt.md",
@@ -255,12 +278,14 @@ func findPlannerPrompt() string {
 func init() {
 	plannerStartCmd.Flags().StringVar(&plannerStartTopic, "topic", "", "Telegram thread ID")
 	plannerStartCmd.Flags().StringVar(&plannerStartProject, "project", "", "project ID")
+	plannerStartCmd.Flags().StringVar(&plannerProfile, "profile", "", "agent profile to run (overrides MINUANO_PROFILE; default claude)")
 	plannerStartCmd.MarkFlagRequired("topic")
 
 	plannerStopCmd.Flags().StringVar(&plannerStopTopic, "topic", "", "Telegram thread ID")
 	plannerStopCmd.MarkFlagRequired("topic")
 
 	plannerReopenCmd.Flags().StringVar(&plannerReopenTopic, "topic", "", "Telegram thread ID")
+	plannerReopenCmd.Flags().StringVar(&plannerProfile, "profile", "", "agent profile to run (overrides MINUANO_PROFILE; default claude)")
 	plannerReopenCmd.MarkFlagRequired("topic")
 
 	plannerStatusCmd.Flags().StringVar(&plannerStatusProject, "project", "", "filter by project")
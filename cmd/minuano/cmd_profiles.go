@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/otavio/minuano/internal/profile"
+	"github.com/spf13/cobra"
+)
+
+var profilesJSON bool
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List agent profiles (built-in and from the profiles file)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := profile.Load()
+		if err != nil {
+			return err
+		}
+
+		if profilesJSON {
+			list := make([]*profile.Profile, 0, len(profiles))
+			for _, name := range profiles.Names() {
+				list = append(list, profiles[name])
+			}
+			data, err := json.MarshalIndent(list, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		printProfiles(os.Stdout, profiles)
+		return nil
+	},
+}
+
+func init() {
+	profilesCmd.Flags().BoolVar(&profilesJSON, "json", false, "output as JSON")
+	rootCmd.AddCommand(profilesCmd)
+}
+
+func printProfiles(out io.Writer, profiles profile.Set) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NAME\tPROMPT\tCOMPLETION\tSOURCE\tCOMMAND\n")
+	for _, name := range profiles.Names() {
+		p := profiles[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.Prompt, p.Completion, p.Source, p.Command)
+	}
+	w.Flush()
+	if f := profile.File(); f != "" {
+		fmt.Fprintf(out, "\nProfiles file: %s\n", f)
+	}
+}
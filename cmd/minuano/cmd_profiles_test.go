@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/otavio/minuano/internal/profile"
+)
+
+func TestProfilesCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "profiles" {
+			return
+		}
+	}
+	t.Error("expected 'profiles' command to be registered")
+}
+
+func TestProfilesCommandFlags(t *testing.T) {
+	if profilesCmd.Flags().Lookup("json") == nil {
+		t.Error("expected --json flag on profiles command")
+	}
+}
+
+func TestPrintProfiles(t *testing.T) {
+	t.Setenv("MINUANO_PROFILES", "/nonexistent/profiles.yaml")
+	var buf bytes.Buffer
+	printProfiles(&buf, profile.Builtins())
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "NAME") {
+		t.Errorf("expected a header, got %q", out)
+	}
+	for _, name := range []string{"aider", "claude", "codex", "gemini"} {
+		if !strings.Contains(out, "\n"+name+" ") {
+			t.Errorf("expected a row for %s in %q", name, out)
+		}
+	}
+	if !strings.Contains(out, "Profiles file: /nonexistent/profiles.yaml") {
+		t.Errorf("expected the profiles file, got %q", out)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	projectName        string
+	projectRepo        string
+	projectBaseBranch  string
+	projectTestCmd     string
+	projectGates       []string
+	projectMaxAttempts int
+	projectMaxAgents   int
+	projectPaused      bool
+	projectAll         bool
+	projectJSON        bool
+)
+
+// projectStatuses orders the task counts in `project show`.
+var projectStatuses = []string{"draft", "pending_approval", "pending", "ready", "claimed", "done", "failed", "rejected"}
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manage projects and their settings",
+	Long: `Projects group tasks, and hold settings that override the configured defaults
+for their tasks and agents: the repository its branches are merged in, the base
+branch, the test command or completion gates, the attempts new tasks get and how many of its tasks
+may be claimed at once. A paused project's tasks are not claimed; an archived
+project takes no new tasks either.`,
+}
+
+var projectCreateCmd = &cobra.Command{
+	Use:   "create <id>",
+	Short: "Create a project",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := projectSettings(cmd)
+		if err != nil {
+			return err
+		}
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		p, err := db.CreateProject(pool, args[0], s)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created project: %s\n", p.ID)
+		return nil
+	},
+}
+
+var projectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List projects",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		projects, err := db.ListProjects(pool, projectAll)
+		if err != nil {
+			return err
+		}
+
+		if projectJSON {
+			if projects == nil {
+				projects = []*db.Project{}
+			}
+			data, err := json.MarshalIndent(projects, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		if len(projects) == 0 {
+			fmt.Println("No projects.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "ID\tNAME\tSTATUS\tBASE\tMAX AGENTS\tREPO\n")
+		for _, p := range projects {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				p.ID, orDash(p.Name), projectStatus(p), orDash(p.BaseBranch), maxAgentsLabel(p), orDash(p.RepoPath))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var projectShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a project's settings and tasks",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		p, err := db.GetProject(pool, args[0])
+		if err != nil {
+			return err
+		}
+		counts, err := db.CountProjectTasks(pool, p.ID)
+		if err != nil {
+			return err
+		}
+
+		if projectJSON {
+			data, err := json.MarshalIndent(struct {
+				*db.Project
+				Tasks map[string]int `json:"tasks"`
+			}{p, counts}, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		printProject(os.Stdout, p, counts)
+		return nil
+	},
+}
+
+var projectEditCmd = &cobra.Command{
+	Use:   "edit <id>",
+	Short: "Change a project's settings",
+	Long: `Change the settings given as flags; the others are left alone. An empty
+string or 0 clears a setting back to the configured default. --gate replaces the
+whole gate list; --gate "" clears it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := projectSettings(cmd)
+		if err != nil {
+			return err
+		}
+		if s == (db.ProjectSettings{}) {
+			return fmt.Errorf("nothing to change; see `minuano project edit --help`")
+		}
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		p, err := db.UpdateProject(pool, args[0], s)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Updated project: %s\n", p.ID)
+		return nil
+	},
+}
+
+var projectArchiveCmd = &cobra.Command{
+	Use:   "archive <id>",
+	Short: "Archive a project: keep its tasks, but claim none and take no new ones",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		if err := db.ArchiveProject(pool, args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Archived project: %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{projectCreateCmd, projectEditCmd} {
+		c.Flags().StringVar(&projectName, "name", "", "display name")
+		c.Flags().StringVar(&projectRepo, "repo", "", "repository its branches are merged in")
+		c.Flags().StringVar(&projectBaseBranch, "base-branch", "", "branch its tasks' work is merged into")
+		c.Flags().StringVar(&projectTestCmd, "test-cmd", "", "test command for tasks without their own")
+		c.Flags().StringArrayVar(&projectGates, "gate", nil, "completion gate name[:timeout][:advisory]=command for tasks without their own, run in order instead of the test command (repeatable)")
+		c.Flags().IntVar(&projectMaxAttempts, "max-attempts", 0, "attempts new tasks get")
+		c.Flags().IntVar(&projectMaxAgents, "max-agents", 0, "most tasks claimed at once")
+		c.Flags().BoolVar(&projectPaused, "paused", false, "stop its tasks from being claimed")
+	}
+	projectListCmd.Flags().BoolVar(&projectAll, "all", false, "include archived projects")
+	projectListCmd.Flags().BoolVar(&projectJSON, "json", false, "output as JSON")
+	projectShowCmd.Flags().BoolVar(&projectJSON, "json", false, "output as JSON")
+
+	projectCmd.AddCommand(projectCreateCmd, projectListCmd, projectShowCmd, projectEditCmd, projectArchiveCmd)
+	rootCmd.AddCommand(projectCmd)
+}
+
+// projectSettings collects the settings flags given on the command line.
+func projectSettings(cmd *cobra.Command) (db.ProjectSettings, error) {
+	var s db.ProjectSettings
+	flags := cmd.Flags()
+	if flags.Changed("name") {
+		s.Name = &projectName
+	}
+	if flags.Changed("repo") {
+		repo := projectRepo
+		if repo != "" {
+			abs, err := filepath.Abs(repo)
+			if err != nil {
+				return s, err
+			}
+			repo = abs
+		}
+		s.RepoPath = &repo
+	}
+	if flags.Changed("base-branch") {
+		s.BaseBranch = &projectBaseBranch
+	}
+	if flags.Changed("test-cmd") {
+		s.TestCmd = &projectTestCmd
+	}
+	if flags.Changed("gate") {
+		// A single empty --gate clears the list.
+		var specs []string
+		for _, spec := range projectGates {
+			if spec != "" {
+				specs = append(specs, spec)
+			}
+		}
+		gates, err := parseGates(specs)
+		if err != nil {
+			return s, err
+		}
+		s.Gates = &gates
+	}
+	if flags.Changed("max-attempts") {
+		if projectMaxAttempts < 0 {
+			return s, fmt.Errorf("--max-attempts must be at least 1 (0 to use the default)")
+		}
+		s.MaxAttempts = &projectMaxAttempts
+	}
+	if flags.Changed("max-agents") {
+		if projectMaxAgents < 0 {
+			return s, fmt.Errorf("--max-agents must be at least 1 (0 for no limit)")
+		}
+		s.MaxAgents = &projectMaxAgents
+	}
+	if flags.Changed("paused") {
+		s.Paused = &projectPaused
+	}
+	return s, nil
+}
+
+// printProject writes a project's settings, with the configured defaults
+// standing in for unset ones, and its task counts.
+func printProject(out io.Writer, p *db.Project, counts map[string]int) {
+	c := cfg()
+	setting := func(v *string, def string) string {
+		if v != nil {
+			return *v
+		}
+		return def + " (default)"
+	}
+	attempts := strconv.Itoa(c.MaxAttempts) + " (default)"
+	if p.MaxAttempts != nil {
+		attempts = strconv.Itoa(*p.MaxAttempts)
+	}
+
+	fmt.Fprintf(out, "── Project: %s %s\n", p.ID, strings.Repeat("─", max(0, 57-len(p.ID))))
+	if p.Name != nil {
+		fmt.Fprintf(out, "Name:          %s\n", *p.Name)
+	}
+	fmt.Fprintf(out, "Status:        %s\n", projectStatus(p))
+	fmt.Fprintf(out, "Repository:    %s\n", setting(p.RepoPath, "any"))
+	fmt.Fprintf(out, "Base branch:   %s\n", setting(p.BaseBranch, c.BaseBranch))
+	fmt.Fprintf(out, "Test command:  %s\n", setting(p.TestCmd, c.TestCmd))
+	for i, g := range p.Gates {
+		label := ""
+		if i == 0 {
+			label = "Gates:"
+		}
+		fmt.Fprintf(out, "%-15s%d. %s\n", label, i+1, g)
+	}
+	fmt.Fprintf(out, "Max attempts:  %s\n", attempts)
+	fmt.Fprintf(out, "Max agents:    %s\n", maxAgentsLabel(p))
+	fmt.Fprintf(out, "Created:       %s\n", p.CreatedAt.Local().Format(time.DateTime))
+
+	var tasks []string
+	for _, status := range projectStatuses {
+		if n := counts[status]; n > 0 {
+			tasks = append(tasks, fmt.Sprintf("%d %s", n, status))
+		}
+	}
+	if len(tasks) == 0 {
+		tasks = []string{"none"}
+	}
+	fmt.Fprintf(out, "Tasks:         %s\n", strings.Join(tasks, ", "))
+}
+
+// projectStatus is active, paused or archived.
+func projectStatus(p *db.Project) string {
+	switch {
+	case p.ArchivedAt != nil:
+		return "archived " + p.ArchivedAt.Local().Format(time.DateOnly)
+	case p.Paused:
+		return "paused"
+	default:
+		return "active"
+	}
+}
+
+func maxAgentsLabel(p *db.Project) string {
+	if p.MaxAgents == nil {
+		return "no limit"
+	}
+	return strconv.Itoa(*p.MaxAgents)
+}
+
+func orDash(s *string) string {
+	if s == nil {
+		return "—"
+	}
+	return *s
+}
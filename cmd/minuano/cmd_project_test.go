@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+)
+
+func TestProjectCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "project" {
+			want := map[string]bool{"create <id>": false, "list": false, "show <id>": false, "edit <id>": false, "archive <id>": false}
+			for _, sub := range c.Commands() {
+				if _, ok := want[sub.Use]; ok {
+					want[sub.Use] = true
+				}
+			}
+			for use, found := range want {
+				if !found {
+					t.Errorf("expected 'project %s' subcommand", use)
+				}
+			}
+			return
+		}
+	}
+	t.Error("expected 'project' command to be registered")
+}
+
+func TestProjectCommandFlags(t *testing.T) {
+	for _, name := range []string{"name", "repo", "base-branch", "test-cmd", "gate", "max-attempts", "max-agents", "paused"} {
+		if projectCreateCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on project create", name)
+		}
+		if projectEditCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on project edit", name)
+		}
+	}
+	for _, name := range []string{"all", "json"} {
+		if projectListCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on project list", name)
+		}
+	}
+	if projectShowCmd.Flags().Lookup("json") == nil {
+		t.Error("expected --json flag on project show")
+	}
+}
+
+func TestProjectSettings(t *testing.T) {
+	defer func() {
+		for _, name := range []string{"repo", "max-agents", "paused"} {
+			projectEditCmd.Flags().Lookup(name).Changed = false
+		}
+		projectRepo, projectMaxAgents, projectPaused = "", 0, false
+	}()
+
+	s, err := projectSettings(projectEditCmd)
+	if err != nil || s != (db.ProjectSettings{}) {
+		t.Fatalf("no flags: got %+v, %v", s, err)
+	}
+
+	projectEditCmd.Flags().Set("repo", "sub/dir")
+	projectEditCmd.Flags().Set("max-agents", "2")
+	projectEditCmd.Flags().Set("paused", "true")
+	s, err = projectSettings(projectEditCmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RepoPath == nil || !strings.HasSuffix(*s.RepoPath, "/sub/dir") || !strings.HasPrefix(*s.RepoPath, "/") {
+		t.Errorf("repo should be made absolute, got %v", s.RepoPath)
+	}
+	if s.MaxAgents == nil || *s.MaxAgents != 2 {
+		t.Errorf("max agents = %v", s.MaxAgents)
+	}
+	if s.Paused == nil || !*s.Paused {
+		t.Errorf("paused = %v", s.Paused)
+	}
+	if s.Name != nil || s.BaseBranch != nil || s.TestCmd != nil || s.Gates != nil || s.MaxAttempts != nil {
+		t.Errorf("unset flags should be left alone: %+v", s)
+	}
+
+	projectEditCmd.Flags().Set("max-agents", "-1")
+	if _, err := projectSettings(projectEditCmd); err == nil {
+		t.Error("expected error for negative --max-agents")
+	}
+}
+
+func TestProjectSettingsGates(t *testing.T) {
+	defer func() {
+		projectEditCmd.Flags().Lookup("gate").Changed = false
+		projectGates = nil
+	}()
+
+	projectEditCmd.Flags().Set("gate", "build=go build ./...")
+	projectEditCmd.Flags().Set("gate", "test:20m=go test ./...")
+	s, err := projectSettings(projectEditCmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Gates == nil || len(*s.Gates) != 2 || (*s.Gates)[1].Timeout != "20m" {
+		t.Errorf("gates = %v", s.Gates)
+	}
+
+	projectGates = []string{""}
+	s, err = projectSettings(projectEditCmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Gates == nil || len(*s.Gates) != 0 {
+		t.Errorf("--gate \"\" should clear the gates, got %v", s.Gates)
+	}
+
+	projectGates = []string{"lint"}
+	if _, err := projectSettings(projectEditCmd); err == nil {
+		t.Error("expected an error for a gate without a command")
+	}
+}
+
+func TestProjectStatus(t *testing.T) {
+	p := &db.Project{ID: "auth"}
+	if got := projectStatus(p); got != "active" {
+		t.Errorf("got %q", got)
+	}
+	p.Paused = true
+	if got := projectStatus(p); got != "paused" {
+		t.Errorf("got %q", got)
+	}
+	archived := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	p.ArchivedAt = &archived
+	if got := projectStatus(p); got != "archived 2026-03-01" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestPrintProject(t *testing.T) {
+	t.Setenv("MINUANO_BASE_BRANCH", "develop")
+	branch, agents := "release", 2
+	gates := []gate.Def{{Name: "build", Run: "go build ./..."}, {Name: "lint", Run: "golangci-lint run", Timeout: "5m", Advisory: true}}
+	p := &db.Project{ID: "auth", BaseBranch: &branch, MaxAgents: &agents, Gates: gates}
+
+	var out bytes.Buffer
+	printProject(&out, p, map[string]int{"done": 4, "ready": 1})
+	got := out.String()
+	for _, want := range []string{
+		"Base branch:   release\n",
+		"Test command:  go test ./... (default)\n",
+		"Gates:         1. build=go build ./...\n",
+		"               2. lint:5m:advisory=golangci-lint run\n",
+		"Max attempts:  3 (default)\n",
+		"Max agents:    2\n",
+		"Status:        active\n",
+		"Tasks:         1 ready, 4 done\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Name:") {
+		t.Errorf("unnamed project should have no Name line:\n%s", got)
+	}
+
+	out.Reset()
+	printProject(&out, &db.Project{ID: "web"}, nil)
+	for _, want := range []string{"Base branch:   develop (default)\n", "Max agents:    no limit\n", "Tasks:         none\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/otavio/minuano/internal/db"
@@ -55,7 +54,7 @@ var promptAutoCmd = &cobra.Command{
 
 		proj := autoProject
 		if proj == "" {
-			proj = os.Getenv("MINUANO_PROJECT")
+			proj = defaultProject()
 		}
 		if proj == "" {
 			return fmt.Errorf("--project is required for auto mode")
@@ -142,6 +141,40 @@ func buildSinglePrompt(task *db.Task, ctxs []*db.TaskContext) string {
 	return b.String()
 }
 
+// buildWorkerPrompt is the single-task prompt for `minuano worker`, which
+// claims the task beforehand and runs the tests itself once Claude exits.
+func buildWorkerPrompt(task *db.Task, ctxs []*db.TaskContext) string {
+	var b strings.Builder
+
+	b.WriteString("# Task: " + task.Title + "\n\n")
+	b.WriteString("**ID:** `" + task.ID + "`\n")
+	b.WriteString(fmt.Sprintf("**Priority:** %d\n", task.Priority))
+	b.WriteString(fmt.Sprintf("**Attempt:** %d/%d\n", task.Attempt, task.MaxAttempts))
+	b.WriteString("\n")
+
+	if task.Body != "" {
+		b.WriteString("## Specification\n\n")
+		b.WriteString(task.Body + "\n\n")
+	}
+
+	writeContext(&b, ctxs)
+
+	b.WriteString("## Instructions\n\n")
+	b.WriteString("This task is already claimed for you.\n\n")
+	b.WriteString("1. Read the context above (inherited findings, handoffs, test failures).\n")
+	b.WriteString("2. Work on the task. Use `minuano-observe " + task.ID + " \"<note>\"` to record findings.\n")
+	b.WriteString("3. Use `minuano-handoff " + task.ID + " \"<note>\"` before long operations.\n")
+	b.WriteString("4. Commit your changes (skip if in worktree mode — the worker auto-commits):\n")
+	b.WriteString("   `git add <files> && git commit -m \"<message>\"`\n")
+	b.WriteString("5. Finish your reply with a one-paragraph summary of what you did.\n")
+	b.WriteString("\n**CRITICAL:** Do NOT call `minuano-done` or `minuano-pick`. When you exit, the worker runs the tests and records your summary as the result, or the failure for the next attempt.\n")
+	b.WriteString("\n**Rule:** Do NOT loop. Complete this single task and exit.\n\n")
+
+	b.WriteString(promptEnvSection())
+
+	return b.String()
+}
+
 func buildAutoPrompt(project string) string {
 	var b strings.Builder
 
@@ -68,6 +68,43 @@ func TestBuildSinglePrompt(t *testing.T) {
 	}
 }
 
+func TestBuildWorkerPrompt(t *testing.T) {
+	task := &db.Task{
+		ID:          "design-auth-a1b",
+		Title:       "Design Auth Flow",
+		Body:        "Implement OAuth2 authentication",
+		Status:      "claimed",
+		Priority:    7,
+		Attempt:     2,
+		MaxAttempts: 3,
+	}
+	ctxs := []*db.TaskContext{
+		{Kind: "test_failure", Content: "TestLogin failed"},
+	}
+
+	prompt := buildWorkerPrompt(task, ctxs)
+
+	checks := []string{
+		"# Task: Design Auth Flow",
+		"**Attempt:** 2/3",
+		"TEST_FAILURE",
+		"TestLogin failed",
+		"already claimed for you",
+		"minuano-observe design-auth-a1b",
+		"one-paragraph summary",
+		"Do NOT loop",
+		"## Environment",
+	}
+	for _, c := range checks {
+		if !strings.Contains(prompt, c) {
+			t.Errorf("worker prompt missing %q", c)
+		}
+	}
+	if strings.Contains(prompt, "minuano-done "+task.ID) || strings.Contains(prompt, "minuano-pick "+task.ID) {
+		t.Error("worker prompt should not ask Claude to claim or complete the task")
+	}
+}
+
 func TestBuildAutoPrompt(t *testing.T) {
 	prompt := buildAutoPrompt("auth-system")
 
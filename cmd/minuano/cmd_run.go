@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/otavio/minuano/internal/agent"
+	"github.com/otavio/minuano/internal/autoscale"
+	"github.com/otavio/minuano/internal/db"
 	"github.com/otavio/minuano/internal/git"
+	"github.com/otavio/minuano/internal/profile"
+	"github.com/otavio/minuano/internal/runtime"
 	"github.com/otavio/minuano/internal/tmux"
 	"github.com/spf13/cobra"
 )
@@ -17,35 +26,82 @@ var (
 	runNames     string
 	runAttach    bool
 	runWorktrees bool
+	runProfile   string
+	runProject   string
+
+	runAutoscale         string
+	runScaleInterval     time.Duration
+	runScaleUpCooldown   time.Duration
+	runScaleDownCooldown time.Duration
 )
 
 var runCmd = &cobra.Command{
 	Use:   "run",
-	Short: "Spawn agents in tmux",
+	Short: "Spawn agents (in tmux, or as supervised subprocesses with --runtime subprocess)",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := connectDB(); err != nil {
 			return err
 		}
+		if !cmd.Flags().Changed("worktrees") {
+			runWorktrees = cfg().Worktrees
+		}
 
 		session := getSessionName()
-		if err := tmux.EnsureSession(session); err != nil {
-			return err
+		svc := newService()
+
+		proj := runProject
+		if proj == "" {
+			proj = defaultProject()
+		}
+		var projPtr *string
+		var record *db.Project
+		if proj != "" {
+			r, err := svc.OpenProject(proj)
+			if err != nil {
+				return err
+			}
+			record, projPtr = r, &proj
+		}
+		if !cmd.Flags().Changed("agents") {
+			runAgents = defaultAgents(record)
 		}
 
-		claudeMD, err := findClaudeMD()
+		rt, err := svc.AgentRuntime()
 		if err != nil {
 			return err
 		}
-
-		dbURL := dbURL
-		if dbURL == "" {
-			dbURL = os.Getenv("DATABASE_URL")
+		prof, err := svc.AgentProfile(runProfile)
+		if err != nil {
+			return err
+		}
+		if prof.Completion == profile.CompletionExit {
+			return fmt.Errorf("profile %s finishes a task by exiting; run it with `minuano worker --profile %s`", prof.Name, prof.Name)
+		}
+		if runAttach && rt.Name() != runtime.Tmux {
+			return fmt.Errorf("--attach needs the tmux runtime")
+		}
+		var policy autoscale.Policy
+		if runAutoscale != "" {
+			if cmd.Flags().Changed("agents") || runNames != "" || runAttach {
+				return fmt.Errorf("--autoscale cannot be combined with --agents, --names or --attach")
+			}
+			if policy.Min, policy.Max, err = autoscale.ParseRange(runAutoscale); err != nil {
+				return err
+			}
+			policy.UpCooldown, policy.DownCooldown = runScaleUpCooldown, runScaleDownCooldown
+			if record != nil && record.MaxAgents != nil && policy.Max > *record.MaxAgents {
+				policy.Max = max(policy.Min, *record.MaxAgents)
+				fmt.Printf("Scaling up to %d agents, project %s's max\n", policy.Max, proj)
+			}
 		}
 
-		env := map[string]string{
-			"DATABASE_URL": dbURL,
+		claudeMD, err := findClaudeMD()
+		if err != nil {
+			return err
 		}
 
+		env := agentEnv()
+
 		// Pre-flight checks for worktree mode.
 		if runWorktrees {
 			if _, err := git.RepoRoot(); err != nil {
@@ -56,6 +112,44 @@ var runCmd = &cobra.Command{
 			}
 		}
 
+		spawn := func(name string) error {
+			var a *agent.Agent
+			var err error
+			if runWorktrees {
+				a, err = agent.SpawnWithWorktree(pool, rt, prof, name, projPtr, claudeMD, env)
+			} else {
+				a, err = agent.Spawn(pool, rt, prof, name, projPtr, claudeMD, env)
+			}
+			if err != nil {
+				return fmt.Errorf("spawning %s: %w", name, err)
+			}
+			where := agentLocation(a.Runtime, a.TmuxSession, a.TmuxWindow, a.PID)
+			if a.WorktreeDir != nil {
+				fmt.Printf("Spawned: %s  →  %s  (worktree: %s, branch: %s)\n", a.ID, where, *a.WorktreeDir, *a.Branch)
+			} else {
+				fmt.Printf("Spawned: %s  →  %s\n", a.ID, where)
+			}
+			return nil
+		}
+
+		if projPtr != nil {
+			fmt.Printf("Agents are bound to project: %s\n", proj)
+			if record.Paused {
+				fmt.Printf("warning: project %s is paused; its tasks won't be claimed until `minuano project edit %s --paused=false`\n", proj, proj)
+			}
+		}
+		if runAutoscale != "" {
+			s := &autoscaler{
+				rt:      rt,
+				profile: prof.Name,
+				project: projPtr,
+				ctrl:    autoscale.NewController(policy),
+				spawn:   spawn,
+				agents:  map[string]bool{},
+			}
+			return s.run(runScaleInterval)
+		}
+
 		// Determine agent names.
 		var names []string
 		if runNames != "" {
@@ -67,22 +161,16 @@ var runCmd = &cobra.Command{
 			}
 		}
 
+		var spawned []string
 		for _, name := range names {
-			var a *agent.Agent
-			var err error
-			if runWorktrees {
-				a, err = agent.SpawnWithWorktree(pool, session, name, claudeMD, env)
-			} else {
-				a, err = agent.Spawn(pool, session, name, claudeMD, env)
-			}
-			if err != nil {
-				return fmt.Errorf("spawning %s: %w", name, err)
-			}
-			if a.WorktreeDir != nil {
-				fmt.Printf("Spawned: %s  →  %s:%s  (worktree: %s, branch: %s)\n", a.ID, a.TmuxSession, a.TmuxWindow, *a.WorktreeDir, *a.Branch)
-			} else {
-				fmt.Printf("Spawned: %s  →  %s:%s\n", a.ID, a.TmuxSession, a.TmuxWindow)
+			if err := spawn(name); err != nil {
+				return err
 			}
+			spawned = append(spawned, name)
+		}
+
+		if sub, ok := rt.(*runtime.SubprocessRuntime); ok {
+			return superviseAgents(sub, spawned)
 		}
 
 		if runAttach {
@@ -94,13 +182,258 @@ var runCmd = &cobra.Command{
 }
 
 func init() {
-	runCmd.Flags().IntVar(&runAgents, "agents", 1, "number of agents to spawn")
+	runCmd.Flags().IntVar(&runAgents, "agents", 1, "number of agents to spawn (default: the project's max_agents, else from config)")
 	runCmd.Flags().StringVar(&runNames, "names", "", "comma-separated agent names")
 	runCmd.Flags().BoolVar(&runAttach, "attach", false, "attach to tmux session after spawning")
-	runCmd.Flags().BoolVar(&runWorktrees, "worktrees", false, "isolate each agent in a git worktree")
+	runCmd.Flags().BoolVar(&runWorktrees, "worktrees", false, "isolate each agent in a git worktree (default from config)")
+	runCmd.Flags().StringVar(&runProject, "project", "", "only claim tasks from this project (default $MINUANO_PROJECT)")
+	runCmd.Flags().StringVar(&runProfile, "profile", "", "agent profile to run (overrides MINUANO_PROFILE; default claude)")
+	runCmd.Flags().StringVar(&runAutoscale, "autoscale", "", "keep between min and max agents, following the ready queue (min:max)")
+	runCmd.Flags().DurationVar(&runScaleInterval, "scale-interval", 10*time.Second, "with --autoscale: how often to check the queue")
+	runCmd.Flags().DurationVar(&runScaleUpCooldown, "scale-up-cooldown", 30*time.Second, "with --autoscale: least time between scale-ups")
+	runCmd.Flags().DurationVar(&runScaleDownCooldown, "scale-down-cooldown", 2*time.Minute, "with --autoscale: least time after any scaling before draining idle agents")
 	rootCmd.AddCommand(runCmd)
 }
 
+// defaultAgents is how many agents run starts without --agents: as many as
+// the project (which may be nil) lets claim at once, else the configured count.
+func defaultAgents(p *db.Project) int {
+	if p != nil && p.MaxAgents != nil {
+		return *p.MaxAgents
+	}
+	return cfg().Agents
+}
+
+// agentLocation describes where an agent runs: its tmux window, or its
+// process for runtimes without one.
+func agentLocation(rt, session, window string, pid *int) string {
+	if rt == runtime.Tmux || rt == "" {
+		return session + ":" + window
+	}
+	if pid != nil {
+		return fmt.Sprintf("%s pid %d", rt, *pid)
+	}
+	return rt
+}
+
+// superviseAgents keeps run in the foreground while subprocess agents work.
+// An agent whose process exits is cleaned up as `minuano kill` would (its
+// claim released, its row removed); interrupting run stops the rest.
+func superviseAgents(rt *runtime.SubprocessRuntime, ids []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Supervising %d agent(s); interrupt to stop them.\n", len(ids))
+	exited := make(chan string)
+	running := map[string]bool{}
+	for _, id := range ids {
+		running[id] = true
+		go func(id string) {
+			select {
+			case <-rt.Exited(id):
+				exited <- id
+			case <-ctx.Done():
+			}
+		}(id)
+	}
+
+	for len(running) > 0 {
+		select {
+		case id := <-exited:
+			delete(running, id)
+			fmt.Printf("Agent %s exited\n", id)
+			if err := agent.Kill(pool, id, cfg().BaseBranch); err != nil {
+				fmt.Printf("warning: cleaning up %s: %v\n", id, err)
+			}
+		case <-ctx.Done():
+			for id := range running {
+				if err := agent.Kill(pool, id, cfg().BaseBranch); err != nil {
+					fmt.Printf("warning: failed to kill agent %s: %v\n", id, err)
+				}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// autoscaler keeps `run --autoscale` in the foreground, spawning agents while
+// ready tasks outnumber idle agents and draining idle ones when they don't.
+type autoscaler struct {
+	rt      runtime.Runtime
+	profile string
+	project *string // the agents' binding, if any
+	ctrl    *autoscale.Controller
+	spawn   func(name string) error
+
+	agents map[string]bool // spawned by this run and still registered
+	next   int
+}
+
+// scaleEvent is the payload of an autoscale event.
+type scaleEvent struct {
+	Action         string         `json:"action"`
+	From           int            `json:"from"`
+	To             int            `json:"to"`
+	Reason         string         `json:"reason"`
+	Ready          int            `json:"ready"`
+	ReadyByProject map[string]int `json:"ready_by_project"`
+	Idle           int            `json:"idle"`
+	Working        int            `json:"working"`
+	Agents         []string       `json:"agents"`
+	Profile        string         `json:"profile"`
+	Error          string         `json:"error,omitempty"`
+}
+
+// run scales every interval, and as soon as a task becomes ready, until
+// interrupted; then it stops its agents.
+func (s *autoscaler) run(interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	wake := make(chan struct{}, 1)
+	go func() {
+		// Without notifications, new work waits for the next tick.
+		err := db.Listen(ctx, pool, "task_events", func(payload string) {
+			if taskBecameReady(payload) {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: not listening for ready tasks: %v\n", err)
+		}
+	}()
+
+	fmt.Printf("Autoscaling %d to %d %s agent(s); interrupt to stop them.\n", s.ctrl.Min, s.ctrl.Max, s.profile)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.tick(time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: autoscaling: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			for id := range s.agents {
+				if err := agent.Kill(pool, id, cfg().BaseBranch); err != nil {
+					fmt.Printf("warning: failed to kill agent %s: %v\n", id, err)
+				}
+			}
+			return nil
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// tick looks at the queue and the agents once and carries out the
+// controller's decision, recording it as an event.
+func (s *autoscaler) tick(now time.Time) error {
+	all, err := db.ListAgents(pool)
+	if err != nil {
+		return err
+	}
+	var state autoscale.State
+	var idle []*db.Agent
+	registered := map[string]bool{}
+	for _, a := range all {
+		if !s.agents[a.ID] {
+			continue
+		}
+		registered[a.ID] = true
+		if !s.rt.Alive(a) {
+			fmt.Printf("Agent %s exited\n", a.ID)
+			if err := agent.Kill(pool, a.ID, cfg().BaseBranch); err != nil {
+				fmt.Printf("warning: cleaning up %s: %v\n", a.ID, err)
+			}
+			delete(s.agents, a.ID)
+			continue
+		}
+		if a.TaskID == nil && a.Status == "idle" {
+			state.Idle++
+			idle = append(idle, a)
+		} else {
+			state.Working++
+		}
+	}
+	for id := range s.agents {
+		if !registered[id] {
+			delete(s.agents, id) // killed from outside
+		}
+	}
+
+	ready, err := db.CountClaimable(pool, s.profile, s.project)
+	if err != nil {
+		return err
+	}
+	for _, n := range ready {
+		state.Ready += n
+	}
+
+	d := s.ctrl.Decide(state, now)
+	if d == nil {
+		return nil
+	}
+
+	ev := scaleEvent{
+		Action:         d.Action,
+		From:           state.Agents(),
+		Reason:         d.Reason,
+		Ready:          state.Ready,
+		ReadyByProject: ready,
+		Idle:           state.Idle,
+		Working:        state.Working,
+		Profile:        s.profile,
+	}
+	var scaleErr error
+	if d.Delta > 0 {
+		for range d.Delta {
+			s.next++
+			name := fmt.Sprintf("agent-%d-%d", os.Getpid(), s.next)
+			if scaleErr = s.spawn(name); scaleErr != nil {
+				break
+			}
+			s.agents[name] = true
+			ev.Agents = append(ev.Agents, name)
+		}
+	} else {
+		// Drain the agents idle the longest first.
+		slices.SortFunc(idle, func(a, b *db.Agent) int { return lastSeen(a).Compare(lastSeen(b)) })
+		for _, a := range idle[:-d.Delta] {
+			// It may have claimed a task, or gone, since it was listed.
+			if idle, err := db.DrainAgent(pool, a.ID); err != nil || !idle {
+				continue
+			}
+			if scaleErr = agent.Kill(pool, a.ID, cfg().BaseBranch); scaleErr != nil {
+				// Let it go back to work rather than sit drained.
+				db.UpdateAgentStatus(pool, a.ID, "idle")
+				break
+			}
+			delete(s.agents, a.ID)
+			ev.Agents = append(ev.Agents, a.ID)
+		}
+	}
+	ev.To = len(s.agents)
+	if scaleErr != nil {
+		ev.Error = scaleErr.Error()
+	}
+
+	fmt.Printf("Autoscale: %s %d → %d (%s)\n", strings.ReplaceAll(d.Action, "_", " "), ev.From, ev.To, d.Reason)
+	if err := db.RecordEvent(pool, "autoscale", s.project, ev); err != nil {
+		fmt.Printf("warning: %v\n", err)
+	}
+	return scaleErr
+}
+
+func lastSeen(a *db.Agent) time.Time {
+	if a.LastSeen != nil {
+		return *a.LastSeen
+	}
+	return a.StartedAt
+}
+
 // findClaudeMD locates the claude/CLAUDE.md file.
 func findClaudeMD() (string, error) {
 	candidates := []string{
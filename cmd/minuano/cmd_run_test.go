@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
 )
 
 func TestRunCommandRegistered(t *testing.T) {
@@ -17,7 +20,7 @@ func TestRunCommandRegistered(t *testing.T) {
 
 func TestRunCommandFlags(t *testing.T) {
 	flags := runCmd.Flags()
-	expected := []string{"agents", "names", "attach"}
+	expected := []string{"agents", "names", "attach", "profile", "project", "autoscale", "scale-interval", "scale-up-cooldown", "scale-down-cooldown"}
 	for _, name := range expected {
 		if flags.Lookup(name) == nil {
 			t.Errorf("expected flag --%s on run command", name)
@@ -56,3 +59,61 @@ func TestFindClaudeMD_NotFound(t *testing.T) {
 		t.Error("expected error when claude/CLAUDE.md not found")
 	}
 }
+
+func TestAgentLocation(t *testing.T) {
+	pid := 4242
+	tests := []struct {
+		rt, session, window string
+		pid                 *int
+		want                string
+	}{
+		{"tmux", "minuano", "agent-1", nil, "minuano:agent-1"},
+		{"", "minuano", "agent-1", nil, "minuano:agent-1"},
+		{"subprocess", "", "", &pid, "subprocess pid 4242"},
+		{"subprocess", "", "", nil, "subprocess"},
+	}
+	for _, tt := range tests {
+		if got := agentLocation(tt.rt, tt.session, tt.window, tt.pid); got != tt.want {
+			t.Errorf("agentLocation(%q, %q, %q) = %q, want %q", tt.rt, tt.session, tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestRunScaleDefaults(t *testing.T) {
+	flags := runCmd.Flags()
+	for name, want := range map[string]string{
+		"autoscale":           "",
+		"scale-interval":      "10s",
+		"scale-up-cooldown":   "30s",
+		"scale-down-cooldown": "2m0s",
+	} {
+		if got := flags.Lookup(name).DefValue; got != want {
+			t.Errorf("--%s default = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLastSeen(t *testing.T) {
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	seen := started.Add(time.Minute)
+	if got := lastSeen(&db.Agent{StartedAt: started}); !got.Equal(started) {
+		t.Errorf("without last_seen got %v, want %v", got, started)
+	}
+	if got := lastSeen(&db.Agent{StartedAt: started, LastSeen: &seen}); !got.Equal(seen) {
+		t.Errorf("got %v, want %v", got, seen)
+	}
+}
+
+func TestDefaultAgents(t *testing.T) {
+	t.Setenv("MINUANO_AGENTS", "3")
+	if got := defaultAgents(nil); got != 3 {
+		t.Errorf("without a project: got %d, want 3", got)
+	}
+	if got := defaultAgents(&db.Project{ID: "auth"}); got != 3 {
+		t.Errorf("uncapped project: got %d, want 3", got)
+	}
+	capped := 2
+	if got := defaultAgents(&db.Project{ID: "auth", MaxAgents: &capped}); got != 2 {
+		t.Errorf("capped project: got %d, want 2", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/service"
 	"github.com/robfig/cron/v3"
 	"github.com/spf13/cobra"
 )
@@ -57,9 +58,12 @@ var scheduleAddCmd = &cobra.Command{
 		var projPtr *string
 		proj := schedAddProject
 		if proj == "" {
-			proj = os.Getenv("MINUANO_PROJECT")
+			proj = defaultProject()
 		}
 		if proj != "" {
+			if _, err := newService().OpenProject(proj); err != nil {
+				return err
+			}
 			projPtr = &proj
 		}
 
@@ -92,7 +96,7 @@ var scheduleListCmd = &cobra.Command{
 		var projPtr *string
 		proj := schedListProject
 		if proj == "" {
-			proj = os.Getenv("MINUANO_PROJECT")
+			proj = defaultProject()
 		}
 		if proj != "" {
 			projPtr = &proj
@@ -211,12 +215,17 @@ func instantiateTemplate(templateJSON json.RawMessage, projectID *string) ([]str
 		return nil, fmt.Errorf("parsing template: %w", err)
 	}
 
+	maxAttempts, err := newService().TaskMaxAttempts(projectID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Map ref → created task ID.
 	refMap := make(map[string]string)
 	var createdIDs []string
 
 	for _, node := range nodes {
-		id := generateID(node.Title)
+		id := service.GenerateID(node.Title)
 
 		var metadata json.RawMessage
 		if node.TestCmd != "" {
@@ -229,7 +238,7 @@ func instantiateTemplate(templateJSON json.RawMessage, projectID *string) ([]str
 			priority = 5
 		}
 
-		if err := db.CreateTask(pool, id, node.Title, node.Body, priority, projectID, metadata, node.RequiresApproval); err != nil {
+		if err := db.CreateTask(pool, id, node.Title, node.Body, priority, maxAttempts, projectID, metadata, node.RequiresApproval); err != nil {
 			return createdIDs, fmt.Errorf("creating task %q: %w", node.Title, err)
 		}
 
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/otavio/minuano/internal/db"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +17,7 @@ var searchCmd = &cobra.Command{
 		}
 
 		query := strings.Join(args, " ")
-		results, err := db.SearchContext(pool, query)
+		results, err := newService().Search(query)
 		if err != nil {
 			return err
 		}
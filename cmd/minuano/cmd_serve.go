@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/otavio/minuano/internal/api"
+	"github.com/otavio/minuano/internal/dashboard"
+	"github.com/otavio/minuano/internal/metrics"
+	"github.com/otavio/minuano/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveMetrics  string
+	serveAPI      string
+	serveAPIToken string
+
+	serveEventRetention time.Duration
+	serveWebhooks       bool
+	serveDashboard      bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run HTTP endpoints (REST API, dashboard, Prometheus metrics) and webhook delivery until interrupted",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveMetrics == "" && serveAPI == "" && !serveWebhooks {
+			return fmt.Errorf("nothing to serve: pass --api <addr>, --metrics <addr> and/or --webhooks")
+		}
+
+		token := serveAPIToken
+		if token == "" {
+			token = os.Getenv("MINUANO_API_TOKEN")
+		}
+		if serveDashboard && serveAPI == "" {
+			return fmt.Errorf("--dashboard is served alongside the API: pass --api <addr> too")
+		}
+		if serveAPI != "" && token == "" {
+			return fmt.Errorf("--api requires a token (--api-token or MINUANO_API_TOKEN)")
+		}
+
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		// Endpoints sharing an address share a server.
+		muxes := map[string]*http.ServeMux{}
+		muxFor := func(addr string) *http.ServeMux {
+			if muxes[addr] == nil {
+				muxes[addr] = http.NewServeMux()
+			}
+			return muxes[addr]
+		}
+
+		if serveMetrics != "" {
+			collector := metrics.NewCollector(pool)
+			if err := collector.Refresh(); err != nil {
+				return err
+			}
+			go collector.Run(ctx)
+			muxFor(serveMetrics).Handle("GET /metrics", collector)
+			log.Printf("serve: metrics on http://%s/metrics", serveMetrics)
+		}
+
+		if serveAPI != "" {
+			svc := newService()
+			svc.AgentEnv = agentEnv()
+			if claudeMD, err := findClaudeMD(); err == nil {
+				svc.ClaudeMD = claudeMD
+			} else {
+				log.Printf("serve: agent spawning disabled: %v", err)
+			}
+			apiServer := api.New(svc, token)
+			apiServer.EventRetention = serveEventRetention
+			go apiServer.Run(ctx)
+			muxFor(serveAPI).Handle("/v1/", apiServer)
+			log.Printf("serve: API on http://%s/v1/ (spec at /v1/openapi.json)", serveAPI)
+			if serveDashboard {
+				muxFor(serveAPI).Handle("/", dashboard.Handler())
+				log.Printf("serve: dashboard on http://%s/", serveAPI)
+			}
+		}
+
+		if serveWebhooks {
+			go webhook.NewDispatcher(pool).Run(ctx)
+			log.Printf("serve: delivering webhooks")
+		}
+
+		errc := make(chan error, len(muxes))
+		var servers []*http.Server
+		for addr, mux := range muxes {
+			srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			servers = append(servers, srv)
+			go func() {
+				if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					errc <- fmt.Errorf("serving on %s: %w", srv.Addr, err)
+				}
+			}()
+		}
+
+		var runErr error
+		select {
+		case <-ctx.Done():
+		case runErr = <-errc:
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, srv := range servers {
+			srv.Shutdown(shutdownCtx)
+		}
+		return runErr
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveMetrics, "metrics", "", "address for the Prometheus /metrics endpoint, e.g. :9090")
+	serveCmd.Flags().StringVar(&serveAPI, "api", "", "address for the REST API under /v1/, e.g. :8080")
+	serveCmd.Flags().StringVar(&serveAPIToken, "api-token", "", "bearer token required by the API (or MINUANO_API_TOKEN)")
+	serveCmd.Flags().DurationVar(&serveEventRetention, "event-retention", 7*24*time.Hour, "how long to keep the /v1/events log (0 keeps everything); only pruned with --api")
+	serveCmd.Flags().BoolVar(&serveDashboard, "dashboard", false, "serve the web dashboard at / on the --api address")
+	serveCmd.Flags().BoolVar(&serveWebhooks, "webhooks", false, "deliver queued webhooks (see `minuano webhook`)")
+	rootCmd.AddCommand(serveCmd)
+}
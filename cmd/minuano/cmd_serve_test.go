@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestServeCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "serve" {
+			return
+		}
+	}
+	t.Error("expected 'serve' command to be registered")
+}
+
+func TestServeCommandFlags(t *testing.T) {
+	for _, name := range []string{"metrics", "api", "api-token", "event-retention", "webhooks", "dashboard"} {
+		if serveCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on serve command", name)
+		}
+	}
+}
@@ -3,7 +3,11 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/otavio/minuano/internal/db"
 	"github.com/spf13/cobra"
@@ -13,6 +17,8 @@ import (
 type ShowOutput struct {
 	Task    *db.Task          `json:"task"`
 	Context []*db.TaskContext  `json:"context"`
+	Usage   []*db.AttemptUsage `json:"usage"`
+	Gates   []*db.GateRun      `json:"gates"`
 }
 
 var showJSON bool
@@ -31,11 +37,28 @@ var showCmd = &cobra.Command{
 			return err
 		}
 
+		syncUsage()
+		usage, err := db.GetTaskUsage(pool, task.ID)
+		if err != nil {
+			return err
+		}
+
+		gates, err := db.ListGateRuns(pool, task.ID)
+		if err != nil {
+			return err
+		}
+
 		if showJSON {
+			if gates == nil {
+				gates = []*db.GateRun{}
+			}
 			if ctxs == nil {
 				ctxs = []*db.TaskContext{}
 			}
-			out := ShowOutput{Task: task, Context: ctxs}
+			if usage == nil {
+				usage = []*db.AttemptUsage{}
+			}
+			out := ShowOutput{Task: task, Context: ctxs, Usage: usage, Gates: gates}
 			data, err := json.MarshalIndent(out, "", "  ")
 			if err != nil {
 				return fmt.Errorf("marshaling JSON: %w", err)
@@ -67,6 +90,18 @@ var showCmd = &cobra.Command{
 			fmt.Println(task.Body)
 		}
 
+		// Token usage.
+		if len(usage) > 0 {
+			fmt.Println()
+			printTaskUsage(usage)
+		}
+
+		// Completion gate runs.
+		if len(gates) > 0 {
+			fmt.Println()
+			printGateRuns(os.Stdout, gates)
+		}
+
 		// Context log.
 		if len(ctxs) > 0 {
 			fmt.Printf("\n── Context %s\n", strings.Repeat("─", 60))
@@ -98,3 +133,62 @@ func init() {
 	showCmd.Flags().BoolVar(&showJSON, "json", false, "output as JSON")
 	rootCmd.AddCommand(showCmd)
 }
+
+// printTaskUsage prints usage per attempt and model, then the total.
+func printTaskUsage(usage []*db.AttemptUsage) {
+	var total db.UsageTotals
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Usage:\tATTEMPT\tAGENT\tMODEL\tINPUT\tOUTPUT\tCACHE W\tCACHE R\tCOST\n")
+	for _, u := range usage {
+		fmt.Fprintf(w, "\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			u.Attempt, u.AgentID, u.Model,
+			formatTokens(u.InputTokens), formatTokens(u.OutputTokens),
+			formatTokens(u.CacheCreationTokens), formatTokens(u.CacheReadTokens),
+			formatCost(u.CostUSD))
+		total.Add(u.UsageTotals)
+	}
+	w.Flush()
+	fmt.Printf("Total:    %s tokens, %s\n", formatTokens(total.Tokens()), formatCost(total.CostUSD))
+}
+
+// printGateRuns prints a task's completion gate runs, one per line.
+func printGateRuns(out io.Writer, runs []*db.GateRun) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Gates:\tATTEMPT\tGATE\tRESULT\tDURATION\tCOMMAND\tOUTPUT\n")
+	for _, r := range runs {
+		result := "passed"
+		switch {
+		case r.TimedOut:
+			result = "timed out"
+		case !r.Passed:
+			result = fmt.Sprintf("exit %d", r.ExitCode)
+		}
+		if r.Advisory && !r.Passed {
+			result += " (advisory)"
+		}
+		if r.Flaky {
+			result = "passed on re-run"
+		}
+		artifact := "—"
+		if r.Artifact != nil {
+			artifact = *r.Artifact
+		}
+		d := (time.Duration(r.DurationMS) * time.Millisecond).Round(100 * time.Millisecond)
+		fmt.Fprintf(w, "\t%d\t%s\t%s\t%s\t%s\t%s\n", r.Attempt, r.Name, result, d, r.Command, artifact)
+		for _, f := range r.FailedTests {
+			line := "✗ " + f.Name()
+			switch {
+			case f.Flaky:
+				line = "~ " + f.Name() + " (flaky)"
+			case f.Repeat:
+				line += " (repeat)"
+			}
+			if reason := f.Reason(); reason != "" {
+				line += " — " + reason
+			}
+			// The last cell doesn't widen the columns.
+			fmt.Fprintf(w, "\t\t\t%s\n", line)
+		}
+	}
+	w.Flush()
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"strings"
 	"testing"
 
 	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gotest"
 )
 
 func TestShowCommandRegistered(t *testing.T) {
@@ -48,4 +50,53 @@ func TestShowOutputStruct(t *testing.T) {
 	if !strings.Contains(s, `"context"`) {
 		t.Error("expected 'context' key in JSON output")
 	}
+	if !strings.Contains(s, `"usage"`) {
+		t.Error("expected 'usage' key in JSON output")
+	}
+	if !strings.Contains(s, `"gates"`) {
+		t.Error("expected 'gates' key in JSON output")
+	}
+}
+
+func TestPrintGateRuns(t *testing.T) {
+	artifact := "/repo/.minuano/logs/gates/t1/run.log"
+	runs := []*db.GateRun{
+		{Attempt: 1, Name: "test", Command: "go test ./...", ExitCode: 1, DurationMS: 3240, Artifact: &artifact},
+		{Attempt: 2, Name: "test", Command: "go test ./...", ExitCode: -1, TimedOut: true, DurationMS: 600000},
+		{Attempt: 3, Name: "lint", Command: "golangci-lint run", ExitCode: 1, Advisory: true, DurationMS: 2000},
+		{Attempt: 3, Name: "test", Command: "go test ./...", Passed: true, DurationMS: 2000},
+	}
+	var out bytes.Buffer
+	printGateRuns(&out, runs)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected a header and 4 runs, got:\n%s", out.String())
+	}
+	for i, want := range [][2]string{{"exit 1", "3.2s"}, {"timed out", "10m0s"}, {"lint  exit 1 (advisory)", "2s"}, {"test  passed", "2s"}} {
+		if !strings.Contains(lines[i+1], want[0]) || !strings.Contains(lines[i+1], want[1]) {
+			t.Errorf("line %d = %q, want %q", i+1, lines[i+1], want)
+		}
+	}
+	if !strings.HasSuffix(lines[1], artifact) || !strings.HasSuffix(lines[2], "—") {
+		t.Errorf("expected the artifact path, or a dash:\n%s", out.String())
+	}
+
+	runs[0].FailedTests = []*db.FailedTest{{Failure: gotest.Failure{Package: "pkg/auth", Test: "TestLogin", Output: "x_test.go:3: expected 401\n"}, Repeat: true}}
+	out.Reset()
+	printGateRuns(&out, runs)
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 || !strings.HasSuffix(lines[2], "✗ TestLogin in pkg/auth (repeat) — x_test.go:3: expected 401") {
+		t.Errorf("expected the failed test under its run:\n%s", out.String())
+	}
+	if !strings.HasSuffix(lines[1], artifact) {
+		t.Errorf("a failed test should not widen the columns:\n%s", out.String())
+	}
+
+	runs[3].Flaky = true
+	runs[3].FailedTests = []*db.FailedTest{{Failure: gotest.Failure{Package: "pkg/db", Test: "TestPool"}, Flaky: true}}
+	out.Reset()
+	printGateRuns(&out, runs)
+	if !strings.Contains(out.String(), "passed on re-run") || !strings.Contains(out.String(), "~ TestPool in pkg/db (flaky)") {
+		t.Errorf("expected the flaky run and test:\n%s", out.String())
+	}
 }
@@ -2,16 +2,17 @@ package main
 
 import (
 	"fmt"
-	"os"
 
-	"github.com/otavio/minuano/internal/agent"
 	"github.com/otavio/minuano/internal/git"
-	"github.com/otavio/minuano/internal/tmux"
+	"github.com/otavio/minuano/internal/runtime"
+	"github.com/otavio/minuano/internal/service"
 	"github.com/spf13/cobra"
 )
 
 var (
 	spawnWorktrees bool
+	spawnProfile   string
+	spawnProject   string
 )
 
 var spawnCmd = &cobra.Command{
@@ -22,10 +23,8 @@ var spawnCmd = &cobra.Command{
 		if err := connectDB(); err != nil {
 			return err
 		}
-
-		session := getSessionName()
-		if err := tmux.EnsureSession(session); err != nil {
-			return err
+		if !cmd.Flags().Changed("worktrees") {
+			spawnWorktrees = cfg().Worktrees
 		}
 
 		claudeMD, err := findClaudeMD()
@@ -33,46 +32,49 @@ var spawnCmd = &cobra.Command{
 			return err
 		}
 
-		dbURL := dbURL
-		if dbURL == "" {
-			dbURL = os.Getenv("DATABASE_URL")
-		}
-
-		env := map[string]string{
-			"DATABASE_URL": dbURL,
-		}
-
-		// Pre-flight checks for worktree mode.
 		if spawnWorktrees {
-			if _, err := git.RepoRoot(); err != nil {
-				return fmt.Errorf("--worktrees requires a git repository: %w", err)
-			}
 			if dirty, _ := git.HasUncommittedChanges(); dirty {
 				fmt.Println("warning: working tree has uncommitted changes")
 			}
 		}
 
-		name := args[0]
-		var a *agent.Agent
-		if spawnWorktrees {
-			a, err = agent.SpawnWithWorktree(pool, session, name, claudeMD, env)
-		} else {
-			a, err = agent.Spawn(pool, session, name, claudeMD, env)
+		svc := newService()
+		svc.ClaudeMD = claudeMD
+		svc.AgentEnv = agentEnv()
+
+		proj := spawnProject
+		if proj == "" {
+			proj = defaultProject()
 		}
+		a, err := svc.SpawnAgent(service.SpawnAgentInput{
+			Name:      args[0],
+			Profile:   spawnProfile,
+			ProjectID: proj,
+			Worktrees: spawnWorktrees,
+		})
 		if err != nil {
-			return fmt.Errorf("spawning %s: %w", name, err)
+			return err
 		}
 
+		where := agentLocation(a.Runtime, a.TmuxSession, a.TmuxWindow, a.PID)
 		if a.WorktreeDir != nil {
-			fmt.Printf("Spawned: %s  →  %s:%s  (worktree: %s, branch: %s)\n", a.ID, a.TmuxSession, a.TmuxWindow, *a.WorktreeDir, *a.Branch)
+			fmt.Printf("Spawned: %s  →  %s  (worktree: %s, branch: %s)\n", a.ID, where, *a.WorktreeDir, *a.Branch)
 		} else {
-			fmt.Printf("Spawned: %s  →  %s:%s\n", a.ID, a.TmuxSession, a.TmuxWindow)
+			fmt.Printf("Spawned: %s  →  %s\n", a.ID, where)
+		}
+		if a.ProjectID != nil {
+			fmt.Printf("Bound to project: %s\n", *a.ProjectID)
+		}
+		if a.Runtime == runtime.Subprocess {
+			fmt.Println("The agent runs detached and unsupervised; `minuano run --runtime subprocess` supervises its agents.")
 		}
 		return nil
 	},
 }
 
 func init() {
-	spawnCmd.Flags().BoolVar(&spawnWorktrees, "worktrees", false, "isolate agent in a git worktree")
+	spawnCmd.Flags().BoolVar(&spawnWorktrees, "worktrees", false, "isolate agent in a git worktree (default from config)")
+	spawnCmd.Flags().StringVar(&spawnProject, "project", "", "only claim tasks from this project (default $MINUANO_PROJECT)")
+	spawnCmd.Flags().StringVar(&spawnProfile, "profile", "", "agent profile to run (overrides MINUANO_PROFILE; default claude)")
 	rootCmd.AddCommand(spawnCmd)
 }
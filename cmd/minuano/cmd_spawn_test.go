@@ -22,7 +22,9 @@ func TestSpawnRequiresArg(t *testing.T) {
 }
 
 func TestSpawnCommandFlags(t *testing.T) {
-	if spawnCmd.Flags().Lookup("worktrees") == nil {
-		t.Error("expected --worktrees flag on spawn command")
+	for _, name := range []string{"worktrees", "profile", "project"} {
+		if spawnCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on spawn command", name)
+		}
 	}
 }
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsProject string
+	statsSince   string
+	statsJSON    bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show throughput, cycle times and success rates",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		window, err := parseWindow(statsSince)
+		if err != nil {
+			return err
+		}
+
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		proj := statsProject
+		if proj == "" {
+			proj = defaultProject()
+		}
+		var projPtr *string
+		if proj != "" {
+			projPtr = &proj
+		}
+
+		since := time.Now().Add(-window)
+		completions, err := db.ListCompletions(pool, projPtr, since)
+		if err != nil {
+			return err
+		}
+		claims, err := db.ListClaims(pool, projPtr, since)
+		if err != nil {
+			return err
+		}
+		failures, err := db.ListTestFailures(pool, projPtr, since)
+		if err != nil {
+			return err
+		}
+		merges, err := db.ListMergeOutcomes(pool, projPtr, since)
+		if err != nil {
+			return err
+		}
+		gates, err := db.ListGateSamples(pool, projPtr, since)
+		if err != nil {
+			return err
+		}
+		syncUsage()
+		usage, err := db.ListUsage(pool, projPtr, since)
+		if err != nil {
+			return err
+		}
+
+		report := buildStatsReport(since, completions, claims, failures, merges, usage)
+		report.Gates = buildGateStats(gates)
+
+		if statsJSON {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling JSON: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		printStatsReport(report)
+		return nil
+	},
+}
+
+func init() {
+	statsCmd.Flags().StringVar(&statsProject, "project", "", "filter by project ID")
+	statsCmd.Flags().StringVar(&statsSince, "since", "7d", "time window, e.g. 24h, 7d, 4w")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "output as JSON")
+	rootCmd.AddCommand(statsCmd)
+}
+
+// parseWindow accepts Go durations plus d (days) and w (weeks) suffixes.
+func parseWindow(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit != 0 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid time window %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid time window %q", s)
+	}
+	return d, nil
+}
+
+type statsReport struct {
+	Since    time.Time      `json:"since"`
+	Projects []projectStats `json:"projects"`
+	Agents   []agentStats   `json:"agents"`
+	Attempts []attemptStats `json:"attempts"`
+	Gates    []gateStats    `json:"gates"`
+}
+
+type projectStats struct {
+	Project           string  `json:"project"`
+	Done              int     `json:"done"`
+	ClaimToDoneP50    float64 `json:"claim_to_done_p50_seconds"`
+	ClaimToDoneP90    float64 `json:"claim_to_done_p90_seconds"`
+	QueueWaitP50      float64 `json:"queue_wait_p50_seconds"`
+	FirstAttemptRate  float64 `json:"first_attempt_pass_rate"`
+	TestFailures      int     `json:"test_failures"`
+	FailuresPerTask   float64 `json:"failures_per_task"`
+	Merges            int     `json:"merges"`
+	MergeConflictRate float64 `json:"merge_conflict_rate"`
+	Tokens            int64   `json:"tokens"`
+	CostUSD           float64 `json:"cost_usd"`
+	CostPerDone       float64 `json:"cost_per_done_usd"`
+}
+
+type agentStats struct {
+	Agent        string  `json:"agent"`
+	Claims       int     `json:"claims"`
+	Done         int     `json:"done"`
+	TestFailures int     `json:"test_failures"`
+	SuccessRate  float64 `json:"success_rate"`
+	Tokens       int64   `json:"tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// attemptStats shows what retries cost: usage spent on first attempts,
+// second attempts, and so on.
+type attemptStats struct {
+	Attempt int     `json:"attempt"`
+	Tasks   int     `json:"tasks"`
+	Tokens  int64   `json:"tokens"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// gateStats shows how often a completion gate fails, across the tasks
+// that run it.
+type gateStats struct {
+	Gate        string  `json:"gate"`
+	Advisory    bool    `json:"advisory,omitempty"` // advisory wherever it ran
+	Runs        int     `json:"runs"`
+	Failures    int     `json:"failures"`
+	Timeouts    int     `json:"timeouts"`
+	FailureRate float64 `json:"failure_rate"`
+	DurationP50 float64 `json:"duration_p50_seconds"`
+}
+
+// buildStatsReport aggregates raw samples into per-project and per-agent rows.
+// Tasks without a project are grouped under "—". Usage between tasks counts
+// toward its agent only.
+func buildStatsReport(since time.Time, completions []db.CompletionSample, claims []db.ClaimSample,
+	failures []db.FailureSample, merges []db.MergeOutcome, usage []db.UsageSample) statsReport {
+
+	type projAcc struct {
+		done, firstAttempt int
+		cycle, wait        []time.Duration
+		failures           int
+		tasks              map[string]bool // finished or failed in the window
+		merges, conflicts  int
+		usage              db.UsageTotals
+	}
+	projects := map[string]*projAcc{}
+	proj := func(id string) *projAcc {
+		if id == "" {
+			id = "—"
+		}
+		p, ok := projects[id]
+		if !ok {
+			p = &projAcc{tasks: map[string]bool{}}
+			projects[id] = p
+		}
+		return p
+	}
+	agents := map[string]*agentStats{}
+	agent := func(id string) *agentStats {
+		a, ok := agents[id]
+		if !ok {
+			a = &agentStats{Agent: id}
+			agents[id] = a
+		}
+		return a
+	}
+
+	for _, c := range completions {
+		p := proj(c.ProjectID)
+		p.done++
+		p.tasks[c.TaskID] = true
+		if c.Attempt <= 1 {
+			p.firstAttempt++
+		}
+		if c.ClaimToDone != nil {
+			p.cycle = append(p.cycle, *c.ClaimToDone)
+		}
+		if c.AgentID != "" {
+			agent(c.AgentID).Done++
+		}
+	}
+	for _, c := range claims {
+		p := proj(c.ProjectID)
+		if c.Wait != nil {
+			p.wait = append(p.wait, *c.Wait)
+		}
+		if c.AgentID != "" {
+			agent(c.AgentID).Claims++
+		}
+	}
+	for _, f := range failures {
+		p := proj(f.ProjectID)
+		p.failures++
+		p.tasks[f.TaskID] = true
+		if f.AgentID != "" {
+			agent(f.AgentID).TestFailures++
+		}
+	}
+	for _, m := range merges {
+		p := proj(m.ProjectID)
+		p.merges++
+		if m.Status == "conflict" {
+			p.conflicts++
+		}
+	}
+
+	type attemptAcc struct {
+		tasks map[string]bool
+		usage db.UsageTotals
+	}
+	attempts := map[int]*attemptAcc{}
+	for _, u := range usage {
+		a := agent(u.AgentID)
+		a.Tokens += u.Tokens()
+		a.CostUSD += u.CostUSD
+		if u.TaskID == "" {
+			continue
+		}
+		proj(u.ProjectID).usage.Add(u.UsageTotals)
+		acc, ok := attempts[u.Attempt]
+		if !ok {
+			acc = &attemptAcc{tasks: map[string]bool{}}
+			attempts[u.Attempt] = acc
+		}
+		acc.tasks[u.TaskID] = true
+		acc.usage.Add(u.UsageTotals)
+	}
+
+	report := statsReport{Since: since, Projects: []projectStats{}, Agents: []agentStats{}, Attempts: []attemptStats{}, Gates: []gateStats{}}
+	for id, p := range projects {
+		report.Projects = append(report.Projects, projectStats{
+			Project:           id,
+			Done:              p.done,
+			ClaimToDoneP50:    percentile(p.cycle, 50).Seconds(),
+			ClaimToDoneP90:    percentile(p.cycle, 90).Seconds(),
+			QueueWaitP50:      percentile(p.wait, 50).Seconds(),
+			FirstAttemptRate:  ratio(p.firstAttempt, p.done),
+			TestFailures:      p.failures,
+			FailuresPerTask:   ratio(p.failures, len(p.tasks)),
+			Merges:            p.merges,
+			MergeConflictRate: ratio(p.conflicts, p.merges),
+			Tokens:            p.usage.Tokens(),
+			CostUSD:           p.usage.CostUSD,
+			CostPerDone:       costPer(p.usage.CostUSD, p.done),
+		})
+	}
+	for _, a := range agents {
+		a.SuccessRate = ratio(a.Done, a.Claims)
+		report.Agents = append(report.Agents, *a)
+	}
+
+	for n, acc := range attempts {
+		report.Attempts = append(report.Attempts, attemptStats{
+			Attempt: n,
+			Tasks:   len(acc.tasks),
+			Tokens:  acc.usage.Tokens(),
+			CostUSD: acc.usage.CostUSD,
+		})
+	}
+
+	sort.Slice(report.Projects, func(i, j int) bool { return report.Projects[i].Project < report.Projects[j].Project })
+	sort.Slice(report.Agents, func(i, j int) bool { return report.Agents[i].Agent < report.Agents[j].Agent })
+	sort.Slice(report.Attempts, func(i, j int) bool { return report.Attempts[i].Attempt < report.Attempts[j].Attempt })
+	return report
+}
+
+// buildGateStats aggregates gate runs per gate name, the gates failing most
+// first.
+func buildGateStats(samples []db.GateSample) []gateStats {
+	type gateAcc struct {
+		gateStats
+		durations []time.Duration
+	}
+	gates := map[string]*gateAcc{}
+	for _, s := range samples {
+		g, ok := gates[s.Name]
+		if !ok {
+			g = &gateAcc{gateStats: gateStats{Gate: s.Name, Advisory: true}}
+			gates[s.Name] = g
+		}
+		g.Runs++
+		g.Advisory = g.Advisory && s.Advisory
+		if !s.Passed {
+			g.Failures++
+		}
+		if s.TimedOut {
+			g.Timeouts++
+		}
+		g.durations = append(g.durations, s.Duration)
+	}
+
+	stats := []gateStats{}
+	for _, g := range gates {
+		g.FailureRate = ratio(g.Failures, g.Runs)
+		g.DurationP50 = percentile(g.durations, 50).Seconds()
+		stats = append(stats, g.gateStats)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Failures != stats[j].Failures {
+			return stats[i].Failures > stats[j].Failures
+		}
+		return stats[i].Gate < stats[j].Gate
+	})
+	return stats
+}
+
+// percentile returns the nearest-rank percentile, or 0 for no samples.
+func percentile(ds []time.Duration, p float64) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+func costPer(cost float64, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return cost / float64(n)
+}
+
+func printStatsReport(r statsReport) {
+	fmt.Printf("Since %s\n\n", r.Since.Local().Format("2006-01-02 15:04"))
+
+	if len(r.Projects) == 0 {
+		fmt.Println("No activity.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "PROJECT\tDONE\tCYCLE P50\tCYCLE P90\tWAIT P50\t1ST PASS\tFAIL/TASK\tMERGES\tCONFLICTS\tCOST\tCOST/DONE\n")
+	for _, p := range r.Projects {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%.2f\t%d\t%s\t%s\t%s\n",
+			p.Project, p.Done,
+			formatSeconds(p.ClaimToDoneP50), formatSeconds(p.ClaimToDoneP90), formatSeconds(p.QueueWaitP50),
+			formatRate(p.FirstAttemptRate, p.Done), p.FailuresPerTask,
+			p.Merges, formatRate(p.MergeConflictRate, p.Merges),
+			formatCost(p.CostUSD), formatCost(p.CostPerDone))
+	}
+	w.Flush()
+
+	if len(r.Gates) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "GATE\tRUNS\tFAILURES\tFAIL RATE\tTIMEOUTS\tDURATION P50\n")
+		for _, g := range r.Gates {
+			name := g.Gate
+			if g.Advisory {
+				name += " (advisory)"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%s\n",
+				name, g.Runs, g.Failures, formatRate(g.FailureRate, g.Runs), g.Timeouts, formatSeconds(g.DurationP50))
+		}
+		w.Flush()
+	}
+
+	if len(r.Agents) == 0 {
+		return
+	}
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "AGENT\tCLAIMS\tDONE\tTEST FAILURES\tSUCCESS\tTOKENS\tCOST\n")
+	for _, a := range r.Agents {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\t%s\n",
+			a.Agent, a.Claims, a.Done, a.TestFailures, formatRate(a.SuccessRate, a.Claims),
+			formatTokens(a.Tokens), formatCost(a.CostUSD))
+	}
+	w.Flush()
+
+	if len(r.Attempts) == 0 {
+		return
+	}
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ATTEMPT\tTASKS\tTOKENS\tCOST\n")
+	for _, a := range r.Attempts {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", a.Attempt, a.Tasks, formatTokens(a.Tokens), formatCost(a.CostUSD))
+	}
+	w.Flush()
+}
+
+// formatSeconds renders a duration compactly, or "—" when there were no samples.
+func formatSeconds(secs float64) string {
+	if secs == 0 {
+		return "—"
+	}
+	d := time.Duration(secs * float64(time.Second))
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%.1fh", d.Hours())
+	default:
+		return fmt.Sprintf("%.1fd", d.Hours()/24)
+	}
+}
+
+func formatRate(r float64, n int) string {
+	if n == 0 {
+		return "—"
+	}
+	return fmt.Sprintf("%.0f%%", r*100)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+func durPtr(d time.Duration) *time.Duration { return &d }
+
+func TestStatsCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "stats" {
+			return
+		}
+	}
+	t.Error("expected 'stats' command to be registered")
+}
+
+func TestStatsCommandFlags(t *testing.T) {
+	for _, name := range []string{"project", "since", "json"} {
+		if statsCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on stats command", name)
+		}
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"24h", 24 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseWindow(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseWindow(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseWindow(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var ds []time.Duration
+	for i := 1; i <= 10; i++ {
+		ds = append(ds, time.Duration(i)*time.Second)
+	}
+	if got := percentile(ds, 50); got != 5*time.Second {
+		t.Errorf("p50 = %v, want 5s", got)
+	}
+	if got := percentile(ds, 90); got != 9*time.Second {
+		t.Errorf("p90 = %v, want 9s", got)
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("p50 of empty = %v, want 0", got)
+	}
+}
+
+func TestBuildStatsReport(t *testing.T) {
+	completions := []db.CompletionSample{
+		{TaskID: "a", ProjectID: "web", AgentID: "agent-1", Attempt: 1, ClaimToDone: durPtr(10 * time.Minute)},
+		{TaskID: "b", ProjectID: "web", AgentID: "agent-2", Attempt: 2, ClaimToDone: durPtr(30 * time.Minute)},
+		{TaskID: "c", Attempt: 1},
+	}
+	claims := []db.ClaimSample{
+		{TaskID: "a", ProjectID: "web", AgentID: "agent-1", Wait: durPtr(time.Minute)},
+		{TaskID: "b", ProjectID: "web", AgentID: "agent-2", Wait: durPtr(3 * time.Minute)},
+		{TaskID: "b", ProjectID: "web", AgentID: "agent-2", Wait: durPtr(5 * time.Minute)},
+	}
+	failures := []db.FailureSample{
+		{TaskID: "b", ProjectID: "web", AgentID: "agent-2"},
+		{TaskID: "d", ProjectID: "web", AgentID: "agent-1"},
+	}
+	merges := []db.MergeOutcome{
+		{ProjectID: "web", Status: "merged"},
+		{ProjectID: "web", Status: "conflict"},
+	}
+
+	r := buildStatsReport(time.Now(), completions, claims, failures, merges, nil)
+
+	if len(r.Projects) != 2 {
+		t.Fatalf("expected 2 projects, got %+v", r.Projects)
+	}
+	var web projectStats
+	for _, p := range r.Projects {
+		if p.Project == "web" {
+			web = p
+		}
+	}
+	if web.Done != 2 {
+		t.Errorf("done = %d, want 2", web.Done)
+	}
+	if web.ClaimToDoneP90 != (30 * time.Minute).Seconds() {
+		t.Errorf("cycle p90 = %v", web.ClaimToDoneP90)
+	}
+	if web.QueueWaitP50 != (3 * time.Minute).Seconds() {
+		t.Errorf("wait p50 = %v", web.QueueWaitP50)
+	}
+	if web.FirstAttemptRate != 0.5 {
+		t.Errorf("first attempt rate = %v, want 0.5", web.FirstAttemptRate)
+	}
+	// Two failures over three distinct tasks (a, b, d).
+	if web.FailuresPerTask != 2.0/3.0 {
+		t.Errorf("failures per task = %v", web.FailuresPerTask)
+	}
+	if web.MergeConflictRate != 0.5 {
+		t.Errorf("merge conflict rate = %v, want 0.5", web.MergeConflictRate)
+	}
+
+	if len(r.Agents) != 2 {
+		t.Fatalf("expected 2 agents, got %+v", r.Agents)
+	}
+	a2 := r.Agents[1]
+	if a2.Agent != "agent-2" || a2.Claims != 2 || a2.Done != 1 || a2.SuccessRate != 0.5 {
+		t.Errorf("unexpected agent-2 stats: %+v", a2)
+	}
+}
+
+func TestBuildGateStats(t *testing.T) {
+	samples := []db.GateSample{
+		{Name: "build", Passed: true, Duration: 10 * time.Second},
+		{Name: "build", Passed: true, Duration: 20 * time.Second},
+		{Name: "test", Passed: false, Duration: time.Minute},
+		{Name: "test", Passed: false, TimedOut: true, Duration: 10 * time.Minute},
+		{Name: "test", Passed: true, Duration: 2 * time.Minute},
+		{Name: "lint", Advisory: true, Passed: false, Duration: 5 * time.Second},
+	}
+	stats := buildGateStats(samples)
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 gates, got %+v", stats)
+	}
+	if stats[0].Gate != "test" || stats[1].Gate != "lint" || stats[2].Gate != "build" {
+		t.Errorf("expected gates by failures, got %+v", stats)
+	}
+	test := stats[0]
+	if test.Runs != 3 || test.Failures != 2 || test.Timeouts != 1 || test.DurationP50 != 120 {
+		t.Errorf("test gate: %+v", test)
+	}
+	if !stats[1].Advisory || stats[0].Advisory {
+		t.Error("only lint is advisory")
+	}
+	if stats[2].FailureRate != 0 || stats[2].DurationP50 != 10 {
+		t.Errorf("build gate: %+v", stats[2])
+	}
+	if got := buildGateStats(nil); got == nil || len(got) != 0 {
+		t.Errorf("expected an empty list, got %v", got)
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	if got := formatRate(0, 0); got != "—" {
+		t.Errorf("formatRate(0, 0) = %q", got)
+	}
+	if got := formatRate(0.25, 4); got != "25%" {
+		t.Errorf("formatRate(0.25, 4) = %q", got)
+	}
+}
+
+func TestBuildStatsReportUsage(t *testing.T) {
+	completions := []db.CompletionSample{
+		{TaskID: "a", ProjectID: "web", AgentID: "agent-1", Attempt: 2},
+	}
+	usage := []db.UsageSample{
+		{ProjectID: "web", AgentID: "agent-1", TaskID: "a", Attempt: 1, UsageTotals: db.UsageTotals{InputTokens: 100, CostUSD: 1}},
+		{ProjectID: "web", AgentID: "agent-1", TaskID: "a", Attempt: 2, UsageTotals: db.UsageTotals{OutputTokens: 50, CostUSD: 2}},
+		{ProjectID: "web", AgentID: "agent-2", TaskID: "b", Attempt: 1, UsageTotals: db.UsageTotals{InputTokens: 10, CostUSD: 0.5}},
+		// Between tasks: the agent pays, no project or attempt does.
+		{AgentID: "agent-2", UsageTotals: db.UsageTotals{InputTokens: 5, CostUSD: 0.25}},
+	}
+
+	r := buildStatsReport(time.Now(), completions, nil, nil, nil, usage)
+
+	if len(r.Projects) != 1 {
+		t.Fatalf("expected only the web project, got %+v", r.Projects)
+	}
+	web := r.Projects[0]
+	if web.CostUSD != 3.5 || web.Tokens != 160 || web.CostPerDone != 3.5 {
+		t.Errorf("unexpected web usage %+v", web)
+	}
+
+	if len(r.Agents) != 2 || r.Agents[0].CostUSD != 3 || r.Agents[1].CostUSD != 0.75 || r.Agents[1].Tokens != 15 {
+		t.Errorf("unexpected agent usage %+v", r.Agents)
+	}
+
+	if len(r.Attempts) != 2 {
+		t.Fatalf("expected attempts 1 and 2, got %+v", r.Attempts)
+	}
+	if a := r.Attempts[0]; a.Attempt != 1 || a.Tasks != 2 || a.CostUSD != 1.5 {
+		t.Errorf("unexpected attempt 1 %+v", a)
+	}
+	if a := r.Attempts[1]; a.Attempt != 2 || a.Tasks != 1 || a.CostUSD != 2 {
+		t.Errorf("unexpected attempt 2 %+v", a)
+	}
+}
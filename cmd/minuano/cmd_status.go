@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/otavio/minuano/internal/db"
@@ -13,19 +15,28 @@ import (
 var (
 	statusProject string
 	statusJSON    bool
+	statusColumns string
 )
 
+// statusExtraColumns are the optional columns --columns can add.
+var statusExtraColumns = []string{"cost", "tokens"}
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Table view of all tasks",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		columns, err := parseStatusColumns(statusColumns)
+		if err != nil {
+			return err
+		}
+
 		if err := connectDB(); err != nil {
 			return err
 		}
 
 		proj := statusProject
 		if proj == "" {
-			proj = os.Getenv("MINUANO_PROJECT")
+			proj = defaultProject()
 		}
 		var projPtr *string
 		if proj != "" {
@@ -37,7 +48,18 @@ var statusCmd = &cobra.Command{
 			return err
 		}
 
+		var usageByTask map[string]db.UsageTotals
+		if len(columns) > 0 {
+			syncUsage()
+			if usageByTask, err = db.UsageByTask(pool, projPtr); err != nil {
+				return err
+			}
+		}
+
 		if statusJSON {
+			if len(columns) > 0 {
+				return printStatusJSONWithUsage(tasks, usageByTask)
+			}
 			if tasks == nil {
 				tasks = []*db.Task{}
 			}
@@ -55,15 +77,23 @@ var statusCmd = &cobra.Command{
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintf(w, "  \tID\tTITLE\tSTATUS\tCLAIMED BY\tATTEMPT\n")
+		header := "  \tID\tTITLE\tSTATUS\tCLAIMED BY\tATTEMPT"
+		for _, c := range columns {
+			header += "\t" + strings.ToUpper(c)
+		}
+		fmt.Fprintln(w, header)
 		for _, t := range tasks {
 			sym := statusSymbol(t.Status)
 			claimedBy := "—"
 			if t.ClaimedBy != nil {
 				claimedBy = *t.ClaimedBy
 			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d/%d\n",
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d/%d",
 				sym, truncateID(t.ID), t.Title, t.Status, claimedBy, t.Attempt, t.MaxAttempts)
+			for _, c := range columns {
+				fmt.Fprintf(w, "\t%s", statusColumnValue(c, usageByTask[t.ID]))
+			}
+			fmt.Fprintln(w)
 		}
 		w.Flush()
 		return nil
@@ -73,9 +103,56 @@ var statusCmd = &cobra.Command{
 func init() {
 	statusCmd.Flags().StringVar(&statusProject, "project", "", "filter by project ID")
 	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "output as JSON")
+	statusCmd.Flags().StringVar(&statusColumns, "columns", "", "extra columns, comma-separated: "+strings.Join(statusExtraColumns, ", "))
 	rootCmd.AddCommand(statusCmd)
 }
 
+// parseStatusColumns validates --columns.
+func parseStatusColumns(csv string) ([]string, error) {
+	var cols []string
+	for _, c := range strings.Split(csv, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		if !slices.Contains(statusExtraColumns, c) {
+			return nil, fmt.Errorf("unknown column %q (want %s)", c, strings.Join(statusExtraColumns, ", "))
+		}
+		if !slices.Contains(cols, c) {
+			cols = append(cols, c)
+		}
+	}
+	return cols, nil
+}
+
+func statusColumnValue(col string, u db.UsageTotals) string {
+	switch col {
+	case "cost":
+		return formatCost(u.CostUSD)
+	case "tokens":
+		return formatTokens(u.Tokens())
+	}
+	return ""
+}
+
+// printStatusJSONWithUsage adds each task's usage to the JSON output.
+func printStatusJSONWithUsage(tasks []*db.Task, usageByTask map[string]db.UsageTotals) error {
+	type taskWithUsage struct {
+		*db.Task
+		Usage db.UsageTotals `json:"usage"`
+	}
+	out := make([]taskWithUsage, 0, len(tasks))
+	for _, t := range tasks {
+		out = append(out, taskWithUsage{Task: t, Usage: usageByTask[t.ID]})
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
 func statusSymbol(status string) string {
 	switch status {
 	case "pending":
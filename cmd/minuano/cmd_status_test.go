@@ -2,6 +2,8 @@ package main
 
 import (
 	"testing"
+
+	"github.com/otavio/minuano/internal/db"
 )
 
 func TestStatusSymbol(t *testing.T) {
@@ -59,4 +61,36 @@ func TestStatusCommandFlags(t *testing.T) {
 	if statusCmd.Flags().Lookup("json") == nil {
 		t.Error("expected --json flag on status command")
 	}
+	if statusCmd.Flags().Lookup("columns") == nil {
+		t.Error("expected --columns flag on status command")
+	}
+}
+
+func TestParseStatusColumns(t *testing.T) {
+	cols, err := parseStatusColumns(" Cost,tokens,cost,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 2 || cols[0] != "cost" || cols[1] != "tokens" {
+		t.Errorf("got %v, want [cost tokens]", cols)
+	}
+	if cols, err := parseStatusColumns(""); err != nil || cols != nil {
+		t.Errorf("empty: got %v, %v", cols, err)
+	}
+	if _, err := parseStatusColumns("cost,price"); err == nil {
+		t.Error("expected error for unknown column")
+	}
+}
+
+func TestStatusColumnValue(t *testing.T) {
+	u := db.UsageTotals{InputTokens: 1500, OutputTokens: 500, CostUSD: 1.234}
+	if got := statusColumnValue("cost", u); got != "$1.23" {
+		t.Errorf("cost = %q", got)
+	}
+	if got := statusColumnValue("tokens", u); got != "2.0k" {
+		t.Errorf("tokens = %q", got)
+	}
+	if got := statusColumnValue("cost", db.UsageTotals{}); got != "—" {
+		t.Errorf("no usage = %q", got)
+	}
 }
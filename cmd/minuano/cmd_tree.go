@@ -2,13 +2,20 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/otavio/minuano/internal/db"
 	"github.com/spf13/cobra"
 )
 
-var treeProject string
+var (
+	treeProject      string
+	treeDepth        int
+	treeStatus       []string
+	treeCollapseDone bool
+)
 
 var treeCmd = &cobra.Command{
 	Use:   "tree",
@@ -20,7 +27,7 @@ var treeCmd = &cobra.Command{
 
 		proj := treeProject
 		if proj == "" {
-			proj = os.Getenv("MINUANO_PROJECT")
+			proj = defaultProject()
 		}
 		var projPtr *string
 		if proj != "" {
@@ -37,8 +44,20 @@ var treeCmd = &cobra.Command{
 			return nil
 		}
 
+		p := newTreePrinter(os.Stdout, treeOptions{
+			depth:        treeDepth,
+			statuses:     treeStatus,
+			collapseDone: treeCollapseDone,
+		})
+		printed := 0
 		for _, root := range roots {
-			printTreeNode(root, "", true)
+			if p.visible(root) {
+				p.print(root, "", true, 0)
+				printed++
+			}
+		}
+		if printed == 0 {
+			fmt.Println("No matching tasks.")
 		}
 		return nil
 	},
@@ -46,20 +65,80 @@ var treeCmd = &cobra.Command{
 
 func init() {
 	treeCmd.Flags().StringVar(&treeProject, "project", "", "filter by project ID")
+	treeCmd.Flags().IntVar(&treeDepth, "depth", 0, "maximum depth to print (0 = unlimited)")
+	treeCmd.Flags().StringSliceVar(&treeStatus, "status", nil, "only show branches leading to tasks with these statuses (repeatable)")
+	treeCmd.Flags().BoolVar(&treeCollapseDone, "collapse-done", false, "fold subtrees in which every task is done")
 	rootCmd.AddCommand(treeCmd)
 }
 
+// treeOptions controls how much of the dependency forest is printed.
+type treeOptions struct {
+	depth        int      // 0 = unlimited
+	statuses     []string // empty = all
+	collapseDone bool
+}
+
+// treePrinter renders a dependency forest. Tasks shared by several parents are
+// printed in full the first time and as a back-reference after that.
+type treePrinter struct {
+	w       io.Writer
+	opts    treeOptions
+	seen    map[string]bool
+	matches map[*db.TreeNode]bool // memoized status-filter visibility
+}
+
+func newTreePrinter(w io.Writer, opts treeOptions) *treePrinter {
+	return &treePrinter{
+		w:       w,
+		opts:    opts,
+		seen:    make(map[string]bool),
+		matches: make(map[*db.TreeNode]bool),
+	}
+}
+
+// printTreeNode prints a single tree to stdout with default options.
 func printTreeNode(node *db.TreeNode, prefix string, isLast bool) {
-	sym := statusSymbol(node.Task.Status)
+	newTreePrinter(os.Stdout, treeOptions{}).print(node, prefix, isLast, 0)
+}
+
+func (p *treePrinter) print(node *db.TreeNode, prefix string, isLast bool, depth int) {
 	connector := "├── "
 	if isLast {
 		connector = "└── "
 	}
-	if prefix == "" {
+	lead := "  "
+	if prefix != "" {
 		// Root node: no connector.
-		fmt.Printf("  %s  %s  %s\n", sym, truncateID(node.Task.ID), node.Task.Title)
-	} else {
-		fmt.Printf("%s%s%s  %s  %s\n", prefix, connector, sym, truncateID(node.Task.ID), node.Task.Title)
+		lead = prefix + connector
+	}
+
+	if p.seen[node.Task.ID] {
+		fmt.Fprintf(p.w, "%s↳ see %s\n", lead, truncateID(node.Task.ID))
+		return
+	}
+	p.seen[node.Task.ID] = true
+
+	var children []*db.TreeNode
+	for _, c := range node.Children {
+		if p.visible(c) {
+			children = append(children, c)
+		}
+	}
+
+	line := fmt.Sprintf("%s%s  %s  %s", lead, statusSymbol(node.Task.Status), truncateID(node.Task.ID), node.Task.Title)
+	folded := 0
+	if len(children) > 0 {
+		if p.opts.collapseDone && allDone(node) {
+			folded = countDescendants(node)
+			line += fmt.Sprintf("  (+%d done)", folded)
+		} else if p.opts.depth > 0 && depth >= p.opts.depth {
+			folded = countDescendants(node)
+			line += fmt.Sprintf("  (+%d more)", folded)
+		}
+	}
+	fmt.Fprintln(p.w, line)
+	if folded > 0 {
+		return
 	}
 
 	childPrefix := prefix
@@ -71,7 +150,67 @@ func printTreeNode(node *db.TreeNode, prefix string, isLast bool) {
 		childPrefix = prefix + "│   "
 	}
 
-	for i, child := range node.Children {
-		printTreeNode(child, childPrefix, i == len(node.Children)-1)
+	for i, child := range children {
+		p.print(child, childPrefix, i == len(children)-1, depth+1)
+	}
+}
+
+// visible reports whether a node passes the status filter itself or leads to a
+// descendant that does.
+func (p *treePrinter) visible(node *db.TreeNode) bool {
+	if len(p.opts.statuses) == 0 {
+		return true
+	}
+	if v, ok := p.matches[node]; ok {
+		return v
+	}
+	p.matches[node] = false // guards against cycles
+	v := false
+	for _, s := range p.opts.statuses {
+		if strings.EqualFold(node.Task.Status, s) {
+			v = true
+			break
+		}
+	}
+	for _, c := range node.Children {
+		if p.visible(c) {
+			v = true
+		}
+	}
+	p.matches[node] = v
+	return v
+}
+
+// allDone reports whether node and every descendant are done.
+func allDone(node *db.TreeNode) bool {
+	ok := true
+	walkDescendants(node, func(n *db.TreeNode) {
+		if n.Task.Status != "done" {
+			ok = false
+		}
+	})
+	return ok && node.Task.Status == "done"
+}
+
+// countDescendants counts distinct tasks below node.
+func countDescendants(node *db.TreeNode) int {
+	n := 0
+	walkDescendants(node, func(*db.TreeNode) { n++ })
+	return n
+}
+
+func walkDescendants(node *db.TreeNode, fn func(*db.TreeNode)) {
+	visited := make(map[string]bool)
+	var walk func(*db.TreeNode)
+	walk = func(n *db.TreeNode) {
+		for _, c := range n.Children {
+			if visited[c.Task.ID] {
+				continue
+			}
+			visited[c.Task.ID] = true
+			fn(c)
+			walk(c)
+		}
 	}
+	walk(node)
 }
@@ -80,3 +80,75 @@ func TestTreeCommandFlags(t *testing.T) {
 		t.Error("expected --project flag on tree command")
 	}
 }
+
+// diamondTree builds root → {left, right} → shared → leaf with shared nodes.
+func diamondTree() *db.TreeNode {
+	leaf := &db.TreeNode{Task: &db.Task{ID: "leaf", Title: "Leaf", Status: "pending"}}
+	shared := &db.TreeNode{Task: &db.Task{ID: "impl-auth-3f2", Title: "Shared", Status: "ready"}, Children: []*db.TreeNode{leaf}}
+	left := &db.TreeNode{Task: &db.Task{ID: "left", Title: "Left", Status: "done"}, Children: []*db.TreeNode{shared}}
+	right := &db.TreeNode{Task: &db.Task{ID: "right", Title: "Right", Status: "claimed"}, Children: []*db.TreeNode{shared}}
+	return &db.TreeNode{Task: &db.Task{ID: "root", Title: "Root", Status: "done"}, Children: []*db.TreeNode{left, right}}
+}
+
+func renderTree(root *db.TreeNode, opts treeOptions) string {
+	var buf bytes.Buffer
+	newTreePrinter(&buf, opts).print(root, "", true, 0)
+	return buf.String()
+}
+
+func TestTreePrinter_SharedSubtreeOnce(t *testing.T) {
+	out := renderTree(diamondTree(), treeOptions{})
+
+	if n := strings.Count(out, "Leaf"); n != 1 {
+		t.Errorf("expected shared subtree printed once, leaf appears %d times:\n%s", n, out)
+	}
+	if !strings.Contains(out, "↳ see impl-auth-3f2") {
+		t.Errorf("expected back-reference to shared task:\n%s", out)
+	}
+}
+
+func TestTreePrinter_Depth(t *testing.T) {
+	out := renderTree(diamondTree(), treeOptions{depth: 1})
+
+	if strings.Contains(out, "Shared") {
+		t.Errorf("expected depth 1 to hide grandchildren:\n%s", out)
+	}
+	if !strings.Contains(out, "(+2 more)") {
+		t.Errorf("expected folded count on truncated node:\n%s", out)
+	}
+}
+
+func TestTreePrinter_StatusFilter(t *testing.T) {
+	out := renderTree(diamondTree(), treeOptions{statuses: []string{"claimed"}})
+
+	if strings.Contains(out, "Left") {
+		t.Errorf("expected branch without claimed tasks to be pruned:\n%s", out)
+	}
+	if !strings.Contains(out, "Right") || !strings.Contains(out, "Root") {
+		t.Errorf("expected path to claimed task to be kept:\n%s", out)
+	}
+}
+
+func TestTreePrinter_CollapseDone(t *testing.T) {
+	child := &db.TreeNode{Task: &db.Task{ID: "c", Title: "C", Status: "done"}}
+	root := &db.TreeNode{Task: &db.Task{ID: "r", Title: "R", Status: "done"}, Children: []*db.TreeNode{child}}
+
+	out := renderTree(root, treeOptions{collapseDone: true})
+	if strings.Contains(out, "  C") || !strings.Contains(out, "(+1 done)") {
+		t.Errorf("expected done subtree folded:\n%s", out)
+	}
+
+	// Subtrees with open work are not folded.
+	out = renderTree(diamondTree(), treeOptions{collapseDone: true})
+	if !strings.Contains(out, "Leaf") {
+		t.Errorf("expected open subtree to stay expanded:\n%s", out)
+	}
+}
+
+func TestTreeCommandFilterFlags(t *testing.T) {
+	for _, name := range []string{"depth", "status", "collapse-done"} {
+		if treeCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on tree command", name)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/otavio/minuano/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var usageAgent string
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Token and cost accounting from Claude transcripts",
+}
+
+var usageSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Read agents' Claude transcripts and record token usage per task",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		n, err := usage.Sync(pool, usageAgent)
+		fmt.Printf("Read %d message(s) from %s\n", n, usage.ClaudeDir())
+		return err
+	},
+}
+
+func init() {
+	usageSyncCmd.Flags().StringVar(&usageAgent, "agent", "", "only this agent's sessions")
+	usageCmd.AddCommand(usageSyncCmd)
+	rootCmd.AddCommand(usageCmd)
+}
+
+// syncUsage brings task_usage up to date before a command reports cost.
+// Transcripts only exist on the agents' host, so failures are warnings.
+func syncUsage() {
+	if _, err := usage.Sync(pool, ""); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: syncing token usage: %v\n", err)
+	}
+}
+
+// formatCost renders USD, or "—" for nothing spent.
+func formatCost(usd float64) string {
+	switch {
+	case usd == 0:
+		return "—"
+	case usd < 0.01:
+		return "<$0.01"
+	default:
+		return fmt.Sprintf("$%.2f", usd)
+	}
+}
+
+// formatTokens renders a token count compactly (12.3k, 4.5M).
+func formatTokens(n int64) string {
+	switch {
+	case n == 0:
+		return "—"
+	case n < 1000:
+		return fmt.Sprintf("%d", n)
+	case n < 1_000_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestUsageCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "usage" {
+			for _, sub := range c.Commands() {
+				if sub.Use == "sync" {
+					return
+				}
+			}
+			t.Fatal("expected 'usage sync' subcommand")
+		}
+	}
+	t.Error("expected 'usage' command to be registered")
+}
+
+func TestUsageSyncFlags(t *testing.T) {
+	if usageSyncCmd.Flags().Lookup("agent") == nil {
+		t.Error("expected --agent flag on usage sync")
+	}
+}
+
+func TestFormatCost(t *testing.T) {
+	tests := []struct {
+		usd  float64
+		want string
+	}{
+		{0, "—"},
+		{0.004, "<$0.01"},
+		{0.5, "$0.50"},
+		{12.345, "$12.35"},
+	}
+	for _, tt := range tests {
+		if got := formatCost(tt.usd); got != tt.want {
+			t.Errorf("formatCost(%v) = %q, want %q", tt.usd, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "—"},
+		{999, "999"},
+		{12_345, "12.3k"},
+		{4_500_000, "4.5M"},
+	}
+	for _, tt := range tests {
+		if got := formatTokens(tt.n); got != tt.want {
+			t.Errorf("formatTokens(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
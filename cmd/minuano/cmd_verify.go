@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/otavio/minuano/internal/agent"
+	"github.com/otavio/minuano/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifySummary string
+	verifyAgent   string
+	verifyTimeout time.Duration
+	verifyReruns  int
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <task-id>",
+	Short: "Run a claimed task's completion gates and mark it done or failed",
+	Long: `Run the task's gates in order, each through bash in the agent's worktree (or
+the current directory), then settle the task: done with the summary if they
+pass, else a failed attempt, with the failing gate and the tail of its output as
+the test_failure context. The first failing gate stops the run, unless it is
+advisory. Each gate's whole output is kept under the log directory, and each
+run is recorded with its exit code and duration.
+
+The gates are a "test" gate running MINUANO_TEST_CMD if set, else the task's
+gates or test_cmd, else its project's, else the configured test command. Past
+its timeout (its own, else --timeout), a gate and everything it started are
+killed, and it fails.
+
+A gate running go test is run with -json, and its failing tests are recorded
+one by one. With --reruns (or flaky_reruns), failed tests are re-run; if they
+all pass, they are counted as flaky and the gate passes.
+
+minuano-done runs this on the agent's behalf.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if verifyAgent == "" {
+			return fmt.Errorf("no agent: set AGENT_ID or use --agent")
+		}
+		timeout := cfg().GateTimeout
+		if cmd.Flags().Changed("timeout") {
+			if verifyTimeout < 0 {
+				return fmt.Errorf("--timeout must not be negative (0 for no limit)")
+			}
+			timeout = verifyTimeout
+		}
+		reruns := cfg().FlakyReruns
+		if cmd.Flags().Changed("reruns") {
+			if verifyReruns < 0 {
+				return fmt.Errorf("--reruns must not be negative")
+			}
+			reruns = verifyReruns
+		}
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		id, err := db.ResolvePartialID(pool, args[0])
+		if err != nil {
+			return err
+		}
+		task, err := db.GetTask(pool, id)
+		if err != nil {
+			return err
+		}
+		if task.Status != "claimed" || task.ClaimedBy == nil || *task.ClaimedBy != verifyAgent {
+			return fmt.Errorf("%w: task %q is %s, not claimed by %s", db.ErrWrongStatus, id, task.Status, verifyAgent)
+		}
+		var proj *db.Project
+		if task.ProjectID != nil {
+			if proj, err = db.GetProject(pool, *task.ProjectID); err != nil {
+				return err
+			}
+		}
+		dir, err := verifyDir(verifyAgent)
+		if err != nil {
+			return err
+		}
+
+		summary := verifySummary
+		if summary == "" {
+			summary = "Completed by " + verifyAgent
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		res, err := agent.Verify(ctx, pool, agent.Verification{
+			Task:    task,
+			Project: proj,
+			AgentID: verifyAgent,
+			Dir:     dir,
+			Env:     verifyEnv(os.Getenv, cfg().TestCmd),
+			Timeout: timeout,
+			Reruns:  reruns,
+			Summary: summary,
+			Output:  os.Stdout,
+		})
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case res.Passed():
+			fmt.Printf("✓ Done: %s (%s)\n", id, res.Describe())
+			return nil
+		case task.Attempt >= task.MaxAttempts:
+			return fmt.Errorf("%s: %s failed after %d attempts", res.Describe(), id, task.Attempt)
+		default:
+			return fmt.Errorf("%s (attempt %d/%d): %s reset to ready", res.Describe(), task.Attempt, task.MaxAttempts, id)
+		}
+	},
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifySummary, "summary", "", "result summary if the gates pass (default \"Completed by <agent>\")")
+	verifyCmd.Flags().StringVar(&verifyAgent, "agent", os.Getenv("AGENT_ID"), "agent holding the claim (default $AGENT_ID)")
+	verifyCmd.Flags().DurationVar(&verifyTimeout, "timeout", 0, "kill gates without their own timeout after this long, 0 for no limit (default gate_timeout)")
+	verifyCmd.Flags().IntVar(&verifyReruns, "reruns", 0, "re-run a go test gate's failed tests up to this many times, counting those that pass as flaky (default flaky_reruns)")
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// verifyDir is where the gate runs: the agent's worktree, if it has one,
+// else the current directory.
+func verifyDir(agentID string) (string, error) {
+	a, err := db.GetAgent(pool, agentID)
+	if err != nil {
+		return "", err
+	}
+	if a != nil && a.WorktreeDir != nil {
+		return *a.WorktreeDir, nil
+	}
+	return os.Getwd()
+}
+
+// verifyEnv is the agent environment the test command is resolved from, as
+// minuano exports it to agents: the MINUANO_TEST_CMD override, if set, and
+// the default test command, MINUANO_DEFAULT_TEST_CMD or else testCmd.
+func verifyEnv(getenv func(string) string, testCmd string) map[string]string {
+	env := map[string]string{"MINUANO_DEFAULT_TEST_CMD": testCmd}
+	if v := getenv("MINUANO_DEFAULT_TEST_CMD"); v != "" {
+		env["MINUANO_DEFAULT_TEST_CMD"] = v
+	}
+	if v := getenv("MINUANO_TEST_CMD"); v != "" {
+		env["MINUANO_TEST_CMD"] = v
+	}
+	return env
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestVerifyCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "verify <task-id>" {
+			return
+		}
+	}
+	t.Error("expected 'verify' command to be registered")
+}
+
+func TestVerifyCommandFlags(t *testing.T) {
+	for _, name := range []string{"summary", "agent", "timeout", "reruns"} {
+		if verifyCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on verify command", name)
+		}
+	}
+}
+
+func TestVerifyEnv(t *testing.T) {
+	vars := map[string]string{}
+	getenv := func(k string) string { return vars[k] }
+
+	env := verifyEnv(getenv, "make test")
+	if len(env) != 1 || env["MINUANO_DEFAULT_TEST_CMD"] != "make test" {
+		t.Errorf("configured default: got %v", env)
+	}
+
+	vars["MINUANO_DEFAULT_TEST_CMD"] = "go test ./pkg/..."
+	vars["MINUANO_TEST_CMD"] = "true"
+	env = verifyEnv(getenv, "make test")
+	if env["MINUANO_DEFAULT_TEST_CMD"] != "go test ./pkg/..." || env["MINUANO_TEST_CMD"] != "true" {
+		t.Errorf("exported values should win: got %v", env)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+var (
+	webhookProject string
+	webhookEvents  string
+	webhookSecret  string
+	webhookJSON    bool
+	webhookLimit   int
+)
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Manage outbound webhooks (delivered by `minuano serve --webhooks`)",
+}
+
+var webhookAddCmd = &cobra.Command{
+	Use:   "add <url>",
+	Short: "Subscribe a URL to lifecycle events",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkWebhookURL(args[0]); err != nil {
+			return err
+		}
+		events, err := webhook.ParseEvents(webhookEvents)
+		if err != nil {
+			return err
+		}
+
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		var projectID *string
+		if webhookProject != "" {
+			projectID = &webhookProject
+		}
+		secret := webhookSecret
+		generated := secret == ""
+		if generated {
+			secret = webhook.NewSecret()
+		}
+
+		w, err := db.CreateWebhook(pool, args[0], secret, projectID, events)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Added webhook %d → %s (%s, %s)\n", w.ID, w.URL, webhookScope(w), webhookEventList(w))
+		if generated {
+			fmt.Printf("Signing secret (shown once): %s\n", secret)
+		}
+		return nil
+	},
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List webhook subscriptions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		hooks, err := db.ListWebhooks(pool)
+		if err != nil {
+			return err
+		}
+
+		if webhookJSON {
+			if hooks == nil {
+				hooks = []*db.Webhook{}
+			}
+			data, err := json.MarshalIndent(hooks, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		if len(hooks) == 0 {
+			fmt.Println("No webhooks.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "ID\tURL\tPROJECT\tEVENTS\n")
+		for _, h := range hooks {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", h.ID, h.URL, webhookScope(h), webhookEventList(h))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var webhookRmCmd = &cobra.Command{
+	Use:   "rm <id>",
+	Short: "Remove a webhook and its delivery log",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid webhook id %q", args[0])
+		}
+
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		if err := db.DeleteWebhook(pool, id); err != nil {
+			return err
+		}
+		fmt.Printf("Removed webhook %d\n", id)
+		return nil
+	},
+}
+
+var webhookDeliveriesCmd = &cobra.Command{
+	Use:   "deliveries [webhook-id]",
+	Short: "Show recent webhook deliveries",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var webhookID *int
+		if len(args) == 1 {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid webhook id %q", args[0])
+			}
+			webhookID = &id
+		}
+
+		if err := connectDB(); err != nil {
+			return err
+		}
+
+		deliveries, err := db.ListWebhookDeliveries(pool, webhookID, webhookLimit)
+		if err != nil {
+			return err
+		}
+
+		if webhookJSON {
+			if deliveries == nil {
+				deliveries = []*db.WebhookDelivery{}
+			}
+			data, err := json.MarshalIndent(deliveries, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		if len(deliveries) == 0 {
+			fmt.Println("No deliveries.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "ID\tHOOK\tEVENT\tSTATUS\tATTEMPTS\tCODE\tCREATED\tERROR\n")
+		for _, d := range deliveries {
+			code := "—"
+			if d.LastStatusCode != nil {
+				code = strconv.Itoa(*d.LastStatusCode)
+			}
+			errMsg := ""
+			if d.LastError != nil {
+				errMsg = *d.LastError
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				d.ID, d.WebhookID, d.Event, d.Status, d.Attempts, code, relativeTime(d.CreatedAt), errMsg)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+func init() {
+	webhookAddCmd.Flags().StringVar(&webhookProject, "project", "", "only events from this project (default: all projects)")
+	webhookAddCmd.Flags().StringVar(&webhookEvents, "events", "", "comma-separated events (default: all): "+strings.Join(db.WebhookEvents, ","))
+	webhookAddCmd.Flags().StringVar(&webhookSecret, "secret", "", "HMAC signing secret (default: generated)")
+	webhookListCmd.Flags().BoolVar(&webhookJSON, "json", false, "output as JSON")
+	webhookDeliveriesCmd.Flags().BoolVar(&webhookJSON, "json", false, "output as JSON")
+	webhookDeliveriesCmd.Flags().IntVar(&webhookLimit, "limit", 20, "number of deliveries to show")
+
+	webhookCmd.AddCommand(webhookAddCmd, webhookListCmd, webhookRmCmd, webhookDeliveriesCmd)
+	rootCmd.AddCommand(webhookCmd)
+}
+
+// checkWebhookURL rejects anything but an absolute http(s) URL.
+func checkWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q: want http(s)://host/...", raw)
+	}
+	return nil
+}
+
+func webhookScope(w *db.Webhook) string {
+	if w.ProjectID == nil {
+		return "all projects"
+	}
+	return *w.ProjectID
+}
+
+func webhookEventList(w *db.Webhook) string {
+	if len(w.Events) == 0 {
+		return "all events"
+	}
+	return strings.Join(w.Events, ",")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+func TestWebhookCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "webhook" {
+			want := map[string]bool{"add <url>": false, "list": false, "rm <id>": false, "deliveries [webhook-id]": false}
+			for _, sub := range c.Commands() {
+				if _, ok := want[sub.Use]; ok {
+					want[sub.Use] = true
+				}
+			}
+			for use, found := range want {
+				if !found {
+					t.Errorf("expected 'webhook %s' subcommand", use)
+				}
+			}
+			return
+		}
+	}
+	t.Error("expected 'webhook' command to be registered")
+}
+
+func TestWebhookCommandFlags(t *testing.T) {
+	for _, name := range []string{"project", "events", "secret"} {
+		if webhookAddCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on webhook add", name)
+		}
+	}
+	if webhookListCmd.Flags().Lookup("json") == nil {
+		t.Error("expected --json flag on webhook list")
+	}
+	for _, name := range []string{"json", "limit"} {
+		if webhookDeliveriesCmd.Flags().Lookup(name) == nil {
+			t.Errorf("expected --%s flag on webhook deliveries", name)
+		}
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	for _, u := range []string{"http://localhost:9000/hook", "https://example.com/x"} {
+		if err := checkWebhookURL(u); err != nil {
+			t.Errorf("%s: unexpected error %v", u, err)
+		}
+	}
+	for _, u := range []string{"", "example.com/hook", "ftp://example.com", "http://"} {
+		if err := checkWebhookURL(u); err == nil {
+			t.Errorf("%q: expected error", u)
+		}
+	}
+}
+
+func TestWebhookScopeAndEvents(t *testing.T) {
+	p := "web"
+	w := &db.Webhook{}
+	if webhookScope(w) != "all projects" || webhookEventList(w) != "all events" {
+		t.Errorf("unexpected defaults %q, %q", webhookScope(w), webhookEventList(w))
+	}
+	w = &db.Webhook{ProjectID: &p, Events: []string{"task.done", "merge.conflict"}}
+	if webhookScope(w) != "web" || webhookEventList(w) != "task.done,merge.conflict" {
+		t.Errorf("unexpected %q, %q", webhookScope(w), webhookEventList(w))
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/otavio/minuano/internal/agent"
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var (
+	workerAgents    int
+	workerProject   string
+	workerWorktrees bool
+	workerProfile   string
+)
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Work tasks headlessly, one fresh agent process per task (for CI and servers)",
+	Long: `Run in the foreground as a supervisor of --agents worker slots. Each slot
+claims a ready task, runs the agent profile's command on it (` + "`claude -p`" + ` by
+default) as a child process, then runs the task's tests and marks it done or
+records the failure, as minuano-done would. Output of both goes to the task's
+logs. A fresh agent process is started for every task, and none runs while
+the queue is empty.
+
+SIGTERM or an interrupt drains the workers: they stop claiming, their running
+tasks are stopped, and the claims are released.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !cmd.Flags().Changed("worktrees") {
+			workerWorktrees = cfg().Worktrees
+		}
+		if err := connectDB(); err != nil {
+			return err
+		}
+		svc := newService()
+
+		proj := workerProject
+		if proj == "" {
+			proj = defaultProject()
+		}
+		var projPtr *string
+		var record *db.Project
+		if proj != "" {
+			r, err := svc.OpenProject(proj)
+			if err != nil {
+				return err
+			}
+			record, projPtr = r, &proj
+		}
+		if !cmd.Flags().Changed("agents") {
+			workerAgents = defaultAgents(record)
+		}
+		if workerAgents < 1 {
+			return fmt.Errorf("--agents must be at least 1")
+		}
+
+		claudeMD, err := findClaudeMD()
+		if err != nil {
+			return err
+		}
+		if workerWorktrees {
+			if _, err := git.RepoRoot(); err != nil {
+				return fmt.Errorf("--worktrees requires a git repository: %w", err)
+			}
+		}
+
+		prof, err := svc.AgentProfile(workerProfile)
+		if err != nil {
+			return err
+		}
+
+		var workers []*agent.Worker
+		defer func() {
+			for _, w := range workers {
+				if err := w.Close(); err != nil {
+					fmt.Printf("warning: deregistering %s: %v\n", w.ID, err)
+				}
+			}
+		}()
+		pid := os.Getpid()
+		for i := 1; i <= workerAgents; i++ {
+			w, err := agent.NewWorker(pool, prof, fmt.Sprintf("worker-%d-%d", pid, i), claudeMD, agentEnv(), projPtr, workerWorktrees, buildWorkerPrompt)
+			if err != nil {
+				return err
+			}
+			workers = append(workers, w)
+			if w.WorktreeDir != nil {
+				fmt.Printf("Worker: %s  (worktree: %s, branch: %s)\n", w.ID, *w.WorktreeDir, *w.Branch)
+			} else {
+				fmt.Printf("Worker: %s\n", w.ID)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		fmt.Printf("Working with %d %s agent(s); interrupt or SIGTERM to drain.\n", len(workers), prof.Name)
+		err = superviseWorkers(ctx, workers)
+		fmt.Println("Draining: releasing claims")
+		return err
+	},
+}
+
+func init() {
+	workerCmd.Flags().IntVar(&workerAgents, "agents", 1, "number of tasks to work on in parallel (default: the project's max_agents, else from config)")
+	workerCmd.Flags().StringVar(&workerProject, "project", "", "only claim tasks from this project")
+	workerCmd.Flags().BoolVar(&workerWorktrees, "worktrees", false, "isolate each worker in a git worktree (default from config)")
+	workerCmd.Flags().StringVar(&workerProfile, "profile", "", "agent profile to run (overrides MINUANO_PROFILE; default claude)")
+	rootCmd.AddCommand(workerCmd)
+}
+
+// superviseWorkers runs workers until ctx is cancelled or one of them fails,
+// which stops the others so their claims can be released. Idle workers are
+// woken as soon as a task becomes ready.
+func superviseWorkers(ctx context.Context, workers []*agent.Worker) error {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	wakes := make([]chan struct{}, len(workers))
+	for i := range wakes {
+		wakes[i] = make(chan struct{}, 1)
+	}
+
+	listenCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// Without notifications, idle workers still poll.
+		err := db.Listen(listenCtx, pool, "task_events", func(payload string) {
+			if !taskBecameReady(payload) {
+				return
+			}
+			for _, c := range wakes {
+				select {
+				case c <- struct{}{}:
+				default:
+				}
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: not listening for ready tasks: %v\n", err)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(workers))
+	for i, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errs[i] = w.Run(ctx, wakes[i]); errs[i] != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", errs[i])
+				stop()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// taskBecameReady reports whether a task_events payload announces a task
+// that can be claimed.
+func taskBecameReady(payload string) bool {
+	var ev struct {
+		Status string `json:"status"`
+	}
+	return json.Unmarshal([]byte(payload), &ev) == nil && ev.Status == "ready"
+}
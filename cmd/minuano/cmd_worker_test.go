@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestWorkerCommandRegistered(t *testing.T) {
+	for _, c := range rootCmd.Commands() {
+		if c.Use == "worker" {
+			return
+		}
+	}
+	t.Error("expected 'worker' command to be registered")
+}
+
+func TestWorkerCommandFlags(t *testing.T) {
+	flags := workerCmd.Flags()
+	for _, name := range []string{"agents", "project", "worktrees", "profile"} {
+		if flags.Lookup(name) == nil {
+			t.Errorf("expected flag --%s on worker command", name)
+		}
+	}
+	if got := flags.Lookup("agents").DefValue; got != "1" {
+		t.Errorf("--agents default = %s, want 1", got)
+	}
+}
+
+func TestTaskBecameReady(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    bool
+	}{
+		{`{"task_id":"a","status":"ready","old_status":"pending"}`, true},
+		{`{"task_id":"a","status":"claimed","old_status":"ready"}`, false},
+		{`{"task_id":"a","status":"done"}`, false},
+		{`not json`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := taskBecameReady(tt.payload); got != tt.want {
+			t.Errorf("taskBecameReady(%q) = %v, want %v", tt.payload, got, tt.want)
+		}
+	}
+}
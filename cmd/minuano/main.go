@@ -3,9 +3,12 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
+	"github.com/otavio/minuano/internal/config"
 	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/service"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/spf13/cobra"
 )
@@ -13,18 +16,36 @@ import (
 var (
 	dbURL       string
 	sessionName string
+	runtimeName string
 	pool        *pgxpool.Pool
+
+	// configPath and configData are the config file, read before any
+	// command runs.
+	configPath string
+	configData []byte
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "minuano",
 	Short: "Agent task coordination via tmux + PostgreSQL",
 	Long:  "Minuano coordinates Claude Code agents via tmux, using PostgreSQL as the coordination substrate.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		path, data, err := config.ReadFile()
+		if err != nil {
+			return err
+		}
+		if _, err := config.Resolve(path, data, os.Getenv); err != nil {
+			return err
+		}
+		configPath, configData = path, data
+		return nil
+	},
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&dbURL, "db", "", "database URL (overrides DATABASE_URL)")
 	rootCmd.PersistentFlags().StringVar(&sessionName, "session", "", "tmux session name (overrides MINUANO_SESSION)")
+	rootCmd.PersistentFlags().StringVar(&runtimeName, "runtime", "", "runtime for new agents: tmux or subprocess (overrides MINUANO_RUNTIME)")
 }
 
 // connectDB initializes the database pool. Call from subcommands that need DB access.
@@ -45,15 +66,76 @@ func connectDB() error {
 	return nil
 }
 
-// getSessionName returns the tmux session name from flag, env, or default.
+// newService wraps the connected pool for commands that go through the service layer.
+func newService() *service.Service {
+	svc := service.New(pool, getSessionName())
+	svc.Runtime = getRuntimeName()
+	svc.Profile = cfg().Profile
+	svc.MaxAttempts = cfg().MaxAttempts
+	svc.BaseBranch = cfg().BaseBranch
+	return svc
+}
+
+// cfg returns the configuration resolved from the environment, the config
+// file and the built-ins. Outside a command run, as in tests, there is no
+// config file.
+func cfg() *config.Config {
+	c, err := config.Resolve(configPath, configData, os.Getenv)
+	if err != nil {
+		// Checked before the command ran; the environment changed since.
+		c, _ = config.Resolve(configPath, configData, func(string) string { return "" })
+	}
+	return c
+}
+
+// defaultProject is the project commands use when --project is not given.
+func defaultProject() string {
+	return cfg().Project
+}
+
+// agentEnv is the environment exported into spawned agents' windows: the
+// database, this binary, and the defaults minuano-done and workers need.
+func agentEnv() map[string]string {
+	url := dbURL
+	if url == "" {
+		url = os.Getenv("DATABASE_URL")
+	}
+	c := cfg()
+	env := map[string]string{
+		"DATABASE_URL":             url,
+		"MINUANO_BASE_BRANCH":      c.BaseBranch,
+		"MINUANO_DEFAULT_TEST_CMD": c.TestCmd,
+		"MINUANO_GATE_TIMEOUT":     c.GateTimeout.String(),
+		"MINUANO_FLAKY_RERUNS":     strconv.Itoa(c.FlakyReruns),
+	}
+	// minuano-done runs `minuano verify` with the binary that spawned it.
+	if exe, err := os.Executable(); err == nil {
+		env["MINUANO_BIN"] = exe
+	}
+	// Set in the environment, the test command overrides the tasks' own.
+	if cmd := os.Getenv("MINUANO_TEST_CMD"); cmd != "" {
+		env["MINUANO_TEST_CMD"] = cmd
+	}
+	if c.AgentPrompt != "" {
+		env["MINUANO_AGENT_PROMPT"] = c.AgentPrompt
+	}
+	return env
+}
+
+// getSessionName returns the tmux session name from flag, env, config file, or default.
 func getSessionName() string {
 	if sessionName != "" {
 		return sessionName
 	}
-	if s := os.Getenv("MINUANO_SESSION"); s != "" {
-		return s
+	return cfg().Session
+}
+
+// getRuntimeName returns the runtime for new agents from flag, env, config file, or default.
+func getRuntimeName() string {
+	if runtimeName != "" {
+		return runtimeName
 	}
-	return "minuano"
+	return cfg().Runtime
 }
 
 func main() {
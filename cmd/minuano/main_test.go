@@ -22,6 +22,9 @@ func TestPersistentFlags(t *testing.T) {
 	if f.Lookup("session") == nil {
 		t.Error("expected --session persistent flag to be registered")
 	}
+	if f.Lookup("runtime") == nil {
+		t.Error("expected --runtime persistent flag to be registered")
+	}
 }
 
 func TestGetSessionName_Default(t *testing.T) {
@@ -60,6 +63,26 @@ func TestGetSessionName_FlagOverridesEnv(t *testing.T) {
 	}
 }
 
+func TestGetRuntimeName(t *testing.T) {
+	runtimeName = ""
+	os.Unsetenv("MINUANO_RUNTIME")
+	if got := getRuntimeName(); got != "tmux" {
+		t.Errorf("default = %q, want tmux", got)
+	}
+
+	os.Setenv("MINUANO_RUNTIME", "subprocess")
+	defer os.Unsetenv("MINUANO_RUNTIME")
+	if got := getRuntimeName(); got != "subprocess" {
+		t.Errorf("from env = %q, want subprocess", got)
+	}
+
+	runtimeName = "tmux"
+	defer func() { runtimeName = "" }()
+	if got := getRuntimeName(); got != "tmux" {
+		t.Errorf("flag should override env, got %q", got)
+	}
+}
+
 func TestConnectDB_NoURL(t *testing.T) {
 	dbURL = ""
 	os.Unsetenv("DATABASE_URL")
@@ -1,7 +1,9 @@
 package agent
 
 import (
+	"crypto/rand"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"time"
@@ -9,14 +11,21 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/otavio/minuano/internal/db"
 	"github.com/otavio/minuano/internal/git"
-	"github.com/otavio/minuano/internal/tmux"
+	"github.com/otavio/minuano/internal/panelog"
+	"github.com/otavio/minuano/internal/profile"
+	"github.com/otavio/minuano/internal/runtime"
+	"github.com/otavio/minuano/internal/usage"
 )
 
 // Agent represents a running agent instance.
 type Agent struct {
 	ID          string
+	Runtime     string
 	TmuxSession string
 	TmuxWindow  string
+	PID         *int
+	Profile     string
+	ProjectID   *string
 	TaskID      *string
 	Status      string
 	StartedAt   time.Time
@@ -25,86 +34,176 @@ type Agent struct {
 	Branch      *string
 }
 
-// Spawn registers an agent in the DB, creates a tmux window, and sends the bootstrap command.
-// It returns immediately without waiting for the agent to claim a task.
-func Spawn(pool *pgxpool.Pool, tmuxSession, agentID, claudeMDPath string, env map[string]string) (*Agent, error) {
-	// Register in DB (no worktree).
-	if err := db.RegisterAgent(pool, agentID, tmuxSession, agentID, nil, nil); err != nil {
-		return nil, fmt.Errorf("registering agent: %w", err)
-	}
+// Spawn registers an agent in the DB and starts it under rt, running the
+// profile's command with the bootstrap prompt. A non-nil projectID binds the
+// agent to that project: it is exported as MINUANO_PROJECT and enforced when
+// the agent claims. Spawn returns immediately without waiting for the agent
+// to claim a task.
+func Spawn(pool *pgxpool.Pool, rt runtime.Runtime, prof *profile.Profile, agentID string, projectID *string, claudeMDPath string, env map[string]string) (*Agent, error) {
+	return spawn(pool, rt, prof, agentID, projectID, claudeMDPath, env, nil, nil)
+}
 
-	// Create tmux window.
-	if err := tmux.NewWindow(tmuxSession, agentID, env); err != nil {
-		// Clean up DB on failure.
-		db.DeleteAgent(pool, agentID)
-		return nil, fmt.Errorf("creating tmux window: %w", err)
+// SpawnWithWorktree registers an agent with an isolated git worktree.
+func SpawnWithWorktree(pool *pgxpool.Pool, rt runtime.Runtime, prof *profile.Profile, agentID string, projectID *string, claudeMDPath string, env map[string]string) (*Agent, error) {
+	if err := checkLoops(prof); err != nil {
+		return nil, err
+	}
+	worktreeDir, branch, err := addWorktree(agentID)
+	if err != nil {
+		return nil, err
 	}
 
-	sendBootstrap(tmuxSession, agentID, claudeMDPath, env, nil, nil)
-
-	now := time.Now()
-	return &Agent{
-		ID:          agentID,
-		TmuxSession: tmuxSession,
-		TmuxWindow:  agentID,
-		Status:      "idle",
-		StartedAt:   now,
-		LastSeen:    &now,
-	}, nil
+	a, err := spawn(pool, rt, prof, agentID, projectID, claudeMDPath, env, &worktreeDir, &branch)
+	if err != nil {
+		git.WorktreeRemove(worktreeDir)
+		return nil, err
+	}
+	return a, nil
 }
 
-// SpawnWithWorktree registers an agent with an isolated git worktree.
-func SpawnWithWorktree(pool *pgxpool.Pool, tmuxSession, agentID, claudeMDPath string, env map[string]string) (*Agent, error) {
+// addWorktree creates agentID's git worktree, on its own branch.
+func addWorktree(agentID string) (dir, branch string, err error) {
 	repoRoot, err := git.RepoRoot()
 	if err != nil {
-		return nil, fmt.Errorf("finding repo root: %w", err)
+		return "", "", fmt.Errorf("finding repo root: %w", err)
+	}
+
+	dir = filepath.Join(repoRoot, ".minuano", "worktrees", agentID)
+	branch = "minuano/" + agentID
+	if err := git.WorktreeAdd(dir, branch); err != nil {
+		return "", "", fmt.Errorf("creating worktree: %w", err)
 	}
+	return dir, branch, nil
+}
 
-	worktreeDir := filepath.Join(repoRoot, ".minuano", "worktrees", agentID)
-	branch := "minuano/" + agentID
+// checkLoops rejects profiles whose agents can't work the queue on their
+// own: they finish a task by exiting, so only a worker can run them.
+func checkLoops(prof *profile.Profile) error {
+	if prof.Completion == profile.CompletionExit {
+		return fmt.Errorf("profile %s finishes a task by exiting; run it with `minuano worker --profile %s`", prof.Name, prof.Name)
+	}
+	return nil
+}
 
-	// Create worktree.
-	if err := git.WorktreeAdd(worktreeDir, branch); err != nil {
-		return nil, fmt.Errorf("creating worktree: %w", err)
+func spawn(pool *pgxpool.Pool, rt runtime.Runtime, prof *profile.Profile, agentID string, projectID *string, claudeMDPath string, env map[string]string, worktreeDir, branch *string) (*Agent, error) {
+	if err := checkLoops(prof); err != nil {
+		return nil, err
 	}
+	session, window := rt.Placement(agentID)
+	env = agentEnv(env, prof, projectID)
 
-	// Register in DB with worktree info.
-	if err := db.RegisterAgent(pool, agentID, tmuxSession, agentID, &worktreeDir, &branch); err != nil {
-		git.WorktreeRemove(worktreeDir)
+	// Register in DB (with worktree info, if any).
+	if err := db.RegisterAgent(pool, agentID, rt.Name(), prof.Name, projectID, session, window, worktreeDir, branch); err != nil {
 		return nil, fmt.Errorf("registering agent: %w", err)
 	}
 
-	// Create tmux window starting in the worktree directory.
-	if err := tmux.NewWindowWithDir(tmuxSession, agentID, worktreeDir, env); err != nil {
+	var sessionID string
+	if prof.UsesSession() {
+		var err error
+		if sessionID, err = startSession(pool, agentID); err != nil {
+			db.DeleteAgent(pool, agentID)
+			return nil, err
+		}
+	}
+
+	script, err := bootstrapScript(prof, agentID, claudeMDPath, sessionID, env, worktreeDir, branch)
+	if err != nil {
 		db.DeleteAgent(pool, agentID)
-		git.WorktreeRemove(worktreeDir)
-		return nil, fmt.Errorf("creating tmux window: %w", err)
+		return nil, err
+	}
+	spec := runtime.Spec{
+		AgentID: agentID,
+		Env:     env,
+		Script:  script,
+	}
+	if worktreeDir != nil {
+		spec.Dir = *worktreeDir
+	}
+	if cmd, err := panelog.Command(agentID, env["DATABASE_URL"]); err == nil {
+		spec.LogCommand = cmd
+	} else {
+		fmt.Printf("warning: not archiving pane output of %s: %v\n", agentID, err)
 	}
 
-	sendBootstrap(tmuxSession, agentID, claudeMDPath, env, &worktreeDir, &branch)
+	pid, err := rt.Start(spec)
+	if err != nil {
+		// Clean up DB on failure.
+		db.DeleteAgent(pool, agentID)
+		return nil, err
+	}
 
-	now := time.Now()
-	return &Agent{
+	a := &Agent{
 		ID:          agentID,
-		TmuxSession: tmuxSession,
-		TmuxWindow:  agentID,
+		Runtime:     rt.Name(),
+		Profile:     prof.Name,
+		ProjectID:   projectID,
+		TmuxSession: session,
+		TmuxWindow:  window,
 		Status:      "idle",
-		StartedAt:   now,
-		LastSeen:    &now,
-		WorktreeDir: &worktreeDir,
-		Branch:      &branch,
-	}, nil
+		WorktreeDir: worktreeDir,
+		Branch:      branch,
+	}
+	if pid != 0 {
+		if err := db.SetAgentPID(pool, agentID, pid, runtime.ProcessStart(pid)); err != nil {
+			fmt.Printf("warning: %v\n", err)
+		}
+		a.PID = &pid
+	}
+
+	now := time.Now()
+	a.StartedAt = now
+	a.LastSeen = &now
+	return a, nil
+}
+
+// startSession records the Claude session ID the agent will run under, so its
+// transcript can be found for usage accounting.
+func startSession(pool *pgxpool.Pool, agentID string) (string, error) {
+	sessionID := NewSessionID()
+	if err := db.RegisterAgentSession(pool, sessionID, agentID); err != nil {
+		return "", err
+	}
+	return sessionID, nil
 }
 
-func sendBootstrap(tmuxSession, agentID, claudeMDPath string, env map[string]string, worktreeDir, branch *string) {
-	// Resolve scripts directory path (relative to CLAUDE.md).
-	scriptsDir := filepath.Join(filepath.Dir(claudeMDPath), "..", "scripts")
-	absScripts, _ := filepath.Abs(scriptsDir)
+// NewSessionID returns a random (version 4) UUID, the format claude's
+// --session-id expects.
+func NewSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// agentEnv returns env with the profile's variables, and the project the
+// agent is bound to, added.
+func agentEnv(env map[string]string, prof *profile.Profile, projectID *string) map[string]string {
+	out := maps.Clone(env)
+	if out == nil {
+		out = map[string]string{}
+	}
+	maps.Copy(out, prof.Env)
+	if projectID != nil {
+		out["MINUANO_PROJECT"] = *projectID
+	}
+	return out
+}
 
+// bootstrapScript returns the shell lines that set up an agent's environment
+// and run the profile's command with the CLAUDE.md prompt.
+func bootstrapScript(prof *profile.Profile, agentID, claudeMDPath, sessionID string, env map[string]string, worktreeDir, branch *string) ([]string, error) {
 	bootstrap := []string{
 		fmt.Sprintf("export AGENT_ID=%q", agentID),
 		fmt.Sprintf("export DATABASE_URL=%q", env["DATABASE_URL"]),
-		fmt.Sprintf("export PATH=\"$PATH:%s\"", absScripts),
+		fmt.Sprintf("export PATH=\"$PATH:%s\"", scriptsDir(claudeMDPath)),
+	}
+
+	if project := env["MINUANO_PROJECT"]; project != "" {
+		bootstrap = append(bootstrap, fmt.Sprintf("export MINUANO_PROJECT=%q", project))
+	}
+	if bin := env["MINUANO_BIN"]; bin != "" {
+		bootstrap = append(bootstrap, fmt.Sprintf("export MINUANO_BIN=%q", bin))
 	}
 
 	if worktreeDir != nil {
@@ -114,9 +213,12 @@ func sendBootstrap(tmuxSession, agentID, claudeMDPath string, env map[string]str
 		bootstrap = append(bootstrap, fmt.Sprintf("export BRANCH=%q", *branch))
 	}
 
-	// Resolve claudeMD path relative to worktree if applicable.
+	// A configured prompt replaces CLAUDE.md, which otherwise resolves
+	// relative to the worktree if applicable.
 	claudeMDArg := claudeMDPath
-	if worktreeDir != nil {
+	if p := env["MINUANO_AGENT_PROMPT"]; p != "" {
+		claudeMDArg = p
+	} else if worktreeDir != nil {
 		// Use the CLAUDE.md from the worktree copy.
 		wtClaudeMD := filepath.Join(*worktreeDir, "claude", "CLAUDE.md")
 		if _, err := os.Stat(wtClaudeMD); err == nil {
@@ -124,28 +226,51 @@ func sendBootstrap(tmuxSession, agentID, claudeMDPath string, env map[string]str
 		}
 	}
 
-	bootstrap = append(bootstrap, fmt.Sprintf("claude --dangerously-skip-permissions -p \"$(cat %s)\"", claudeMDArg))
-
-	for _, cmd := range bootstrap {
-		tmux.SendKeys(tmuxSession, agentID, cmd)
+	cmd, err := prof.Script(profile.Vars{AgentID: agentID, SessionID: sessionID, PromptFile: claudeMDArg})
+	if err != nil {
+		return nil, err
 	}
+	return append(bootstrap, cmd), nil
 }
 
-// Kill terminates an agent: kills the tmux window, releases claimed tasks, removes from DB.
-// If the agent has a worktree with unmerged changes, the worktree is preserved with a warning.
-func Kill(pool *pgxpool.Pool, tmuxSession, agentID string) error {
-	// Get agent info for worktree cleanup.
+// scriptsDir is the directory of the minuano-* helper scripts, which sits
+// next to the claude/ directory holding CLAUDE.md.
+func scriptsDir(claudeMDPath string) string {
+	dir, _ := filepath.Abs(filepath.Join(filepath.Dir(claudeMDPath), "..", "scripts"))
+	return dir
+}
+
+// Kill terminates an agent: stops it in its runtime, releases claimed tasks, removes from DB.
+// If the agent has a worktree with changes unmerged into its project's base
+// branch, or else base, the worktree is preserved with a warning.
+func Kill(pool *pgxpool.Pool, agentID, base string) error {
+	// Get agent info for runtime and worktree cleanup.
 	a, err := db.GetAgent(pool, agentID)
 	if err != nil {
 		return fmt.Errorf("getting agent: %w", err)
 	}
 
-	// Kill tmux window (ignore error if already gone).
-	tmux.KillWindow(tmuxSession, agentID)
+	if a != nil {
+		rt, err := runtime.For(pool, a)
+		if err != nil {
+			return err
+		}
+		if err := rt.Stop(a); err != nil {
+			fmt.Printf("warning: stopping %s: %v\n", agentID, err)
+		}
+	}
 
+	return deregister(pool, agentID, a, base)
+}
+
+// deregister cleans up after an agent whose process has stopped: removes its
+// worktree unless it holds work unmerged into the base branch, records its
+// token usage, and deletes its row, releasing any claimed task. a is nil if
+// the row is gone; base is the configured base branch.
+func deregister(pool *pgxpool.Pool, agentID string, a *db.Agent, base string) error {
 	// Handle worktree cleanup.
 	if a != nil && a.WorktreeDir != nil {
-		unmerged, err := git.HasUnmergedChanges(*a.Branch, "main")
+		unmerged, err := git.HasUnmergedChanges(*a.Branch, agentBase(pool, a, base))
 		if err != nil {
 			fmt.Printf("warning: could not check unmerged changes for %s: %v\n", agentID, err)
 		} else if unmerged {
@@ -157,6 +282,14 @@ func Kill(pool *pgxpool.Pool, tmuxSession, agentID string) error {
 		}
 	}
 
+	// Claude has stopped writing; take in the rest of its transcript while
+	// the claim is still in place.
+	if err := db.EndAgentSessions(pool, agentID); err != nil {
+		fmt.Printf("warning: %v\n", err)
+	} else if _, err := usage.Sync(pool, agentID); err != nil {
+		fmt.Printf("warning: recording token usage for %s: %v\n", agentID, err)
+	}
+
 	// Delete from DB (also releases claimed tasks).
 	if err := db.DeleteAgent(pool, agentID); err != nil {
 		return fmt.Errorf("deleting agent from DB: %w", err)
@@ -165,15 +298,29 @@ func Kill(pool *pgxpool.Pool, tmuxSession, agentID string) error {
 	return nil
 }
 
-// KillAll terminates all registered agents.
-func KillAll(pool *pgxpool.Pool, tmuxSession string) error {
+// agentBase is the branch a's work is merged into: its project's base branch,
+// else base, else main.
+func agentBase(pool *pgxpool.Pool, a *db.Agent, base string) string {
+	var proj *db.Project
+	if a.ProjectID != nil {
+		p, err := db.GetProject(pool, *a.ProjectID)
+		if err != nil {
+			fmt.Printf("warning: using the default base branch for %s: %v\n", a.ID, err)
+		}
+		proj = p
+	}
+	return baseBranch(proj, map[string]string{"MINUANO_BASE_BRANCH": base})
+}
+
+// KillAll terminates all registered agents. base is the configured base branch.
+func KillAll(pool *pgxpool.Pool, base string) error {
 	agents, err := db.ListAgents(pool)
 	if err != nil {
 		return fmt.Errorf("listing agents: %w", err)
 	}
 
 	for _, a := range agents {
-		if err := Kill(pool, tmuxSession, a.ID); err != nil {
+		if err := Kill(pool, a.ID, base); err != nil {
 			// Log but continue killing others.
 			fmt.Printf("warning: failed to kill agent %s: %v\n", a.ID, err)
 		}
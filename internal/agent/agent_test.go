@@ -1,7 +1,10 @@
 package agent
 
 import (
+	"regexp"
 	"testing"
+
+	"github.com/otavio/minuano/internal/profile"
 )
 
 func TestAgentStruct(t *testing.T) {
@@ -29,10 +32,94 @@ func TestAgentPackageExports(t *testing.T) {
 	// These are compile-time checks — if they compile, the functions exist.
 	var _ func(*interface{}, string, string, string, map[string]string) (*Agent, error)
 	_ = Spawn  // has correct signature at call sites
-	_ = Kill   // func(*pgxpool.Pool, string, string) error
+	_ = Kill   // func(*pgxpool.Pool, string) error
 	_ = KillAll
 	_ = Heartbeat
 	_ = List
 
 	t.Log("all expected functions are exported from the agent package")
 }
+
+func TestNewSessionID(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, b := NewSessionID(), NewSessionID()
+	if !re.MatchString(a) {
+		t.Errorf("%q is not a version 4 UUID", a)
+	}
+	if a == b {
+		t.Error("session IDs should be random")
+	}
+}
+
+func TestBootstrapScript(t *testing.T) {
+	env := map[string]string{"DATABASE_URL": "postgres://localhost/minuano"}
+	prof, _ := profile.Builtins().Get("")
+	script, err := bootstrapScript(prof, "agent-1", "/repo/claude/CLAUDE.md", "sid", env, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`export AGENT_ID="agent-1"`,
+		`export DATABASE_URL="postgres://localhost/minuano"`,
+		`export PATH="$PATH:/repo/scripts"`,
+		`claude --dangerously-skip-permissions --session-id 'sid' -p "$(cat '/repo/claude/CLAUDE.md')"`,
+	}
+	if len(script) != len(want) {
+		t.Fatalf("got %q, want %q", script, want)
+	}
+	for i := range want {
+		if script[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, script[i], want[i])
+		}
+	}
+
+	wt, branch := "/repo/.minuano/worktrees/agent-1", "minuano/agent-1"
+	script, err = bootstrapScript(prof, "agent-1", "/repo/claude/CLAUDE.md", "sid", env, &wt, &branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script[3] != `export WORKTREE_DIR="/repo/.minuano/worktrees/agent-1"` || script[4] != `export BRANCH="minuano/agent-1"` {
+		t.Errorf("expected worktree exports, got %q", script)
+	}
+
+	env["MINUANO_PROJECT"] = "web"
+	script, err = bootstrapScript(prof, "agent-1", "/repo/claude/CLAUDE.md", "sid", env, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script[3] != `export MINUANO_PROJECT="web"` {
+		t.Errorf("expected project export, got %q", script)
+	}
+
+	delete(env, "MINUANO_PROJECT")
+	env["MINUANO_BIN"] = "/usr/local/bin/minuano"
+	script, err = bootstrapScript(prof, "agent-1", "/repo/claude/CLAUDE.md", "sid", env, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script[3] != `export MINUANO_BIN="/usr/local/bin/minuano"` {
+		t.Errorf("expected binary export, got %q", script)
+	}
+}
+
+func TestAgentEnv(t *testing.T) {
+	env := map[string]string{"DATABASE_URL": "postgres://localhost/minuano"}
+	prof := &profile.Profile{Env: map[string]string{"OPENAI_BASE_URL": "http://proxy"}}
+
+	got := agentEnv(env, prof, nil)
+	if got["DATABASE_URL"] == "" || got["OPENAI_BASE_URL"] != "http://proxy" {
+		t.Errorf("expected base and profile variables, got %v", got)
+	}
+	if _, ok := got["MINUANO_PROJECT"]; ok {
+		t.Errorf("unbound agent should not get MINUANO_PROJECT: %v", got)
+	}
+	if len(env) != 1 {
+		t.Errorf("agentEnv modified its input: %v", env)
+	}
+
+	project := "web"
+	if got := agentEnv(env, prof, &project); got["MINUANO_PROJECT"] != "web" {
+		t.Errorf("expected MINUANO_PROJECT=web, got %v", got)
+	}
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+	"github.com/otavio/minuano/internal/gotest"
+	"github.com/otavio/minuano/internal/shell"
+)
+
+// rerunnable reports whether a failed go test gate is worth re-running: it
+// finished, and all that failed were tests, not packages that didn't build.
+func rerunnable(res *gate.Result, failed []*db.FailedTest) bool {
+	if res.TimedOut || len(failed) == 0 {
+		return false
+	}
+	for _, f := range failed {
+		if f.Test == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// rerun re-runs a go test gate's failed tests up to v.Reruns times, marking
+// those that pass as flaky, and reports whether all of them did. The output
+// goes to the gate's artifact w, and to v.Output.
+func rerun(ctx context.Context, v Verification, def gate.Def, failed []*db.FailedTest, w io.Writer) (bool, error) {
+	for i := 1; i <= v.Reruns; i++ {
+		var left []*db.FailedTest
+		for _, f := range failed {
+			if !f.Flaky {
+				left = append(left, f)
+			}
+		}
+		if len(left) == 0 {
+			return true, nil
+		}
+
+		run, _ := gotest.Command(def.Run)
+		cmd := rerunCommand(run, left)
+		header := fmt.Sprintf("↻ Re-running %s (%d/%d): %s\n", testNames(left), i, v.Reruns, cmd)
+		io.WriteString(w, header)
+		if v.Output != nil {
+			io.WriteString(v.Output, header)
+		}
+		var dec *gotest.Decoder
+		_, err := gate.Run(ctx, gate.Spec{
+			Command: cmd,
+			Dir:     v.Dir,
+			Env:     v.Environ,
+			Timeout: def.TimeoutOr(v.Timeout),
+			Output:  v.Output,
+			Filter: func(w io.Writer) io.WriteCloser {
+				dec = gotest.NewDecoder(w)
+				return dec
+			},
+		}, w)
+		if err != nil {
+			return false, err
+		}
+		for _, f := range left {
+			f.Flaky = dec.Passed(f.Package, f.Test)
+		}
+	}
+	for _, f := range failed {
+		if !f.Flaky {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// rerunCommand is the gate's go test command run re-running only the given
+// failed tests, uncached: the top-level tests they belong to. The gate's own
+// flags and packages are kept, so a test of the same name in another of its
+// packages runs too, which is harmless.
+func rerunCommand(run string, failed []*db.FailedTest) string {
+	var tests []string
+	for _, f := range failed {
+		top, _, _ := strings.Cut(f.Test, "/")
+		tests = append(tests, regexp.QuoteMeta(top))
+	}
+	slices.Sort(tests)
+	pattern := "^(" + strings.Join(slices.Compact(tests), "|") + ")$"
+	flags := " -count=1 -run " + shell.Quote(pattern)
+	// Whatever follows -args goes to the test binary, not to go test.
+	if i := strings.Index(run+" ", " -args "); i >= 0 {
+		return run[:i] + flags + run[i:]
+	}
+	return run + flags
+}
+
+// testNames lists failed tests for a progress line, e.g. "TestA in pkg/a,
+// TestB in pkg/b".
+func testNames(failed []*db.FailedTest) string {
+	names := make([]string, len(failed))
+	for i, f := range failed {
+		names[i] = f.Name()
+	}
+	return strings.Join(names, ", ")
+}
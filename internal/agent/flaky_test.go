@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+	"github.com/otavio/minuano/internal/gotest"
+)
+
+func failedTest(pkg, test string) *db.FailedTest {
+	return &db.FailedTest{Failure: gotest.Failure{Package: pkg, Test: test}}
+}
+
+func TestRerunCommand(t *testing.T) {
+	failed := []*db.FailedTest{
+		failedTest("example.com/b", "TestB/case_1"),
+		failedTest("example.com/a", "TestA"),
+		failedTest("example.com/b", "TestB/case_2"),
+	}
+	tests := map[string]string{
+		"go test -json ./...": "go test -json ./... -count=1 -run '^(TestA|TestB)$'",
+		"go test -json -race -tags integration -timeout 5m ./internal/...": "go test -json -race -tags integration -timeout 5m ./internal/... -count=1 -run '^(TestA|TestB)$'",
+		"go test -json -count=3 ./... -args -update":                       "go test -json -count=3 ./... -count=1 -run '^(TestA|TestB)$' -args -update",
+	}
+	for run, want := range tests {
+		if got := rerunCommand(run, failed); got != want {
+			t.Errorf("rerunCommand(%q) = %q, want %q", run, got, want)
+		}
+	}
+}
+
+func TestRerunnable(t *testing.T) {
+	tests := []struct {
+		res    gate.Result
+		failed []*db.FailedTest
+		want   bool
+	}{
+		{gate.Result{ExitCode: 1}, []*db.FailedTest{failedTest("a", "TestA")}, true},
+		{gate.Result{ExitCode: 1}, nil, false},
+		{gate.Result{ExitCode: -1, TimedOut: true}, []*db.FailedTest{failedTest("a", "TestA")}, false},
+		{gate.Result{ExitCode: 1}, []*db.FailedTest{failedTest("a", "TestA"), failedTest("b", "")}, false},
+	}
+	for i, tt := range tests {
+		if got := rerunnable(&tt.res, tt.failed); got != tt.want {
+			t.Errorf("case %d: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+// flakyModule writes a Go module whose TestSometimes fails on its first run
+// only, and, with always, a TestAlways that never passes. The tests build
+// only with the flaky tag, which a re-run must keep.
+func flakyModule(t *testing.T, always bool) string {
+	dir := t.TempDir()
+	src := `//go:build flaky
+
+package flaky
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSometimes(t *testing.T) {
+	if _, err := os.Stat("ran"); err != nil {
+		os.WriteFile("ran", nil, 0o644)
+		t.Fatal("failed on the first run")
+	}
+}
+`
+	if always {
+		src += `
+func TestAlways(t *testing.T) { t.Fatal("always fails") }
+`
+	}
+	for name, data := range map[string]string{"go.mod": "module example.com/flaky\n\ngo 1.21\n", "flaky_test.go": src} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunGateReruns(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	t.Setenv("MINUANO_LOG_DIR", t.TempDir())
+	def := gate.Def{Name: "test", Run: "go test -tags flaky ./..."}
+
+	v := Verification{Task: &db.Task{ID: "t1", Attempt: 1}, Dir: flakyModule(t, false), Reruns: 2}
+	g, run, err := runGate(context.Background(), v, def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.Passed() || !g.Flaky || !run.Passed || !run.Flaky || run.ExitCode != 1 {
+		t.Errorf("a gate whose failures pass on re-run should pass as flaky: %+v", run)
+	}
+	if len(g.FailedTests) != 1 || g.FailedTests[0].Test != "TestSometimes" || !g.FailedTests[0].Flaky {
+		t.Errorf("unexpected failed tests %+v", g.FailedTests)
+	}
+
+	v.Dir = flakyModule(t, true)
+	g, _, err = runGate(context.Background(), v, def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Passed() || len(g.FailedTests) != 2 {
+		t.Fatalf("a test failing on every re-run should fail the gate: %+v", g.FailedTests)
+	}
+	for _, f := range g.FailedTests {
+		if f.Flaky != (f.Test == "TestSometimes") {
+			t.Errorf("%s: flaky = %v", f.Test, f.Flaky)
+		}
+	}
+
+	v.Dir, v.Reruns = flakyModule(t, false), 0
+	if g, _, err = runGate(context.Background(), v, def); err != nil || g.Passed() {
+		t.Errorf("without re-runs the gate should fail: %v", err)
+	}
+}
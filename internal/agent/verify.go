@@ -0,0 +1,343 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+	"github.com/otavio/minuano/internal/gotest"
+	"github.com/otavio/minuano/internal/panelog"
+)
+
+// Verification is a run of a claimed task's completion gates.
+type Verification struct {
+	Task    *db.Task
+	Project *db.Project // the task's project, if it has one
+	AgentID string
+	// Dir is where the gates run: the agent's worktree, or the directory it
+	// works in.
+	Dir string
+	// Env is the agent environment the gates are resolved from (see
+	// gatePlan); Environ is the commands' whole environment, nil for the
+	// current one.
+	Env     map[string]string
+	Environ []string
+	// Timeout applies to each gate without a timeout of its own; 0: none.
+	Timeout time.Duration
+	// Reruns is how many times the failed tests of a go test gate are
+	// re-run before it fails; 0: never.
+	Reruns int
+	// Summary is the task's result if the gates pass.
+	Summary string
+	// Output, if not nil, receives the gates' output as it is written.
+	Output io.Writer
+}
+
+// GateResult is how one of a task's gates went.
+type GateResult struct {
+	Def      gate.Def
+	Result   *gate.Result
+	Artifact string // "" if the output couldn't be kept
+	// FailedTests are the failures of a go test gate, if its output could
+	// be parsed.
+	FailedTests []*db.FailedTest
+	// Flaky is set if the gate failed, but each of its failed tests passed
+	// when re-run.
+	Flaky bool
+}
+
+// Passed reports whether the gate passed, if only on re-running its failed
+// tests.
+func (g *GateResult) Passed() bool { return g.Result.Passed() || g.Flaky }
+
+// Outcome is how a task's gates went: those that ran, in order, and the one
+// that failed the attempt, if any.
+type Outcome struct {
+	Gates  []*GateResult
+	Failed *GateResult
+}
+
+// Passed reports whether the task passed its gates.
+func (o *Outcome) Passed() bool { return o.Failed == nil }
+
+// Describe summarizes the outcome, e.g. "4 gates passed" or "gate lint:
+// exit 1 after 3.2s".
+func (o *Outcome) Describe() string {
+	if o.Failed != nil {
+		return fmt.Sprintf("gate %s: %s", o.Failed.Def.Name, o.Failed.Result.Describe())
+	}
+	if len(o.Gates) == 1 {
+		return "1 gate passed"
+	}
+	return fmt.Sprintf("%d gates passed", len(o.Gates))
+}
+
+// Verify runs the task's gates, as RunGates does, and settles the task: done
+// if they pass, else a failed attempt. Each run, with its exit code and
+// duration, is recorded along with the task's new status. An error means the
+// task was left claimed.
+func Verify(ctx context.Context, pool *pgxpool.Pool, v Verification) (*Outcome, error) {
+	out, runs, err := RunGates(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var content string
+	if out.Failed != nil {
+		markRepeats(pool, v.Task, out.Failed.FailedTests)
+		content = failureContent(v.Task, out)
+	}
+	if err := db.SettleGate(pool, v.Task.ID, v.AgentID, runs, out.Passed(), v.Summary, content); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RunGates runs the task's gates in v.Dir, in order, stopping at the first
+// failure of a gate that isn't advisory. Each gate's whole output is kept in
+// an artifact file. It returns how they went, and the runs to record.
+func RunGates(ctx context.Context, v Verification) (*Outcome, []*db.GateRun, error) {
+	defs := gatePlan(v.Task, v.Project, v.Env)
+	out := &Outcome{}
+	var runs []*db.GateRun
+	for i, def := range defs {
+		if v.Output != nil {
+			fmt.Fprintf(v.Output, "▶ Gate %s (%d/%d): %s\n", def.Name, i+1, len(defs), def.Run)
+		}
+		g, run, err := runGate(ctx, v, def)
+		if err != nil {
+			return nil, nil, err
+		}
+		out.Gates = append(out.Gates, g)
+		runs = append(runs, run)
+
+		if v.Output != nil {
+			fmt.Fprintf(v.Output, "%s\n", gateVerdict(g))
+		}
+		if !g.Passed() && !def.Advisory {
+			out.Failed = g
+			break
+		}
+	}
+	return out, runs, nil
+}
+
+// runGate runs one of the task's gates, keeping its output in an artifact. A
+// plain go test gate runs with -json, its output decoded back to text, so
+// that its failures are known test by test.
+func runGate(ctx context.Context, v Verification, def gate.Def) (*GateResult, *db.GateRun, error) {
+	started := time.Now()
+	path, artifact := openArtifact(v.Task, def.Name, started)
+	var w io.Writer = io.Discard
+	if artifact != nil {
+		w = artifact
+	}
+	spec := gate.Spec{
+		Command: def.Run,
+		Dir:     v.Dir,
+		Env:     v.Environ,
+		Timeout: def.TimeoutOr(v.Timeout),
+		Output:  v.Output,
+	}
+	var dec *gotest.Decoder
+	if cmd, ok := gotest.Command(def.Run); ok {
+		spec.Command = cmd
+		spec.Filter = func(w io.Writer) io.WriteCloser {
+			dec = gotest.NewDecoder(w)
+			return dec
+		}
+	}
+	res, err := gate.Run(ctx, spec, w)
+	var failed []*db.FailedTest
+	var flaky bool
+	if err == nil && dec != nil && !res.Passed() {
+		for _, f := range dec.Failures() {
+			failed = append(failed, &db.FailedTest{Attempt: v.Task.Attempt, Failure: f})
+		}
+		if v.Reruns > 0 && rerunnable(res, failed) {
+			flaky, err = rerun(ctx, v, def, failed, w)
+		}
+	}
+	if artifact != nil {
+		if cerr := artifact.Close(); cerr != nil && err == nil {
+			fmt.Printf("warning: writing %s: %v\n", path, cerr)
+			path = ""
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g := &GateResult{Def: def, Result: res, Artifact: path, FailedTests: failed, Flaky: flaky}
+	run := &db.GateRun{
+		TaskID:      v.Task.ID,
+		AgentID:     &v.AgentID,
+		Attempt:     v.Task.Attempt,
+		Name:        def.Name,
+		Advisory:    def.Advisory,
+		Command:     spec.Command,
+		Dir:         v.Dir,
+		ExitCode:    res.ExitCode,
+		TimedOut:    res.TimedOut,
+		Passed:      g.Passed(),
+		Flaky:       flaky,
+		DurationMS:  res.Duration.Milliseconds(),
+		OutputBytes: res.Bytes,
+		StartedAt:   started,
+		FailedTests: failed,
+	}
+	if path != "" {
+		run.Artifact = &path
+	}
+	return g, run, nil
+}
+
+// markRepeats marks the tests that also failed in an earlier attempt of the
+// task. Not knowing which did is only worth a warning.
+func markRepeats(pool *pgxpool.Pool, task *db.Task, failed []*db.FailedTest) {
+	if len(failed) == 0 {
+		return
+	}
+	earlier, err := db.ListFailedTests(pool, task.ID)
+	if err != nil {
+		fmt.Printf("warning: looking up earlier failures of %s: %v\n", task.ID, err)
+		return
+	}
+	before := map[[2]string]bool{}
+	for _, f := range earlier {
+		if f.Attempt < task.Attempt {
+			before[[2]string{f.Package, f.Test}] = true
+		}
+	}
+	for _, f := range failed {
+		f.Repeat = before[[2]string{f.Package, f.Test}]
+	}
+}
+
+// gateVerdict is the line reporting how a gate went.
+func gateVerdict(g *GateResult) string {
+	switch {
+	case g.Result.Passed():
+		return fmt.Sprintf("✓ %s passed (%s)", g.Def.Name, g.Result.Describe())
+	case g.Flaky:
+		return fmt.Sprintf("✓ %s passed on re-run (%s), flaky: %s", g.Def.Name, g.Result.Describe(), testNames(g.FailedTests))
+	case g.Def.Advisory:
+		return fmt.Sprintf("⚠ %s failed (%s), advisory", g.Def.Name, g.Result.Describe())
+	default:
+		return fmt.Sprintf("✗ %s failed (%s)", g.Def.Name, g.Result.Describe())
+	}
+}
+
+// failureContent is the test_failure context of a failed attempt: the
+// attempt, and the outcome's Report.
+func failureContent(task *db.Task, out *Outcome) string {
+	return fmt.Sprintf("Attempt %d/%d failed at gate %s. %s", task.Attempt, task.MaxAttempts, out.Failed.Def.Name, out.Report())
+}
+
+// Report tells how the failed gate broke: its command, exit code or timeout,
+// what passed before it, where its whole output is, and either its failing
+// tests, each with its own output, or its last lines. It is empty if no gate
+// failed.
+func (o *Outcome) Report() string {
+	f := o.Failed
+	if f == nil {
+		return ""
+	}
+	var passed, advisory []string
+	for _, g := range o.Gates {
+		switch {
+		case g == f:
+		case g.Passed():
+			passed = append(passed, g.Def.Name)
+		default:
+			advisory = append(advisory, fmt.Sprintf("%s (%s)", g.Def.Name, g.Result.Describe()))
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Command: %s (%s)\n", f.Def.Run, f.Result.Describe())
+	if len(passed) > 0 {
+		fmt.Fprintf(&b, "Passed: %s\n", strings.Join(passed, ", "))
+	}
+	if len(advisory) > 0 {
+		fmt.Fprintf(&b, "Advisory failures: %s\n", strings.Join(advisory, ", "))
+	}
+	if f.Artifact != "" {
+		fmt.Fprintf(&b, "Full output: %s\n", f.Artifact)
+	}
+	if len(f.FailedTests) > 0 {
+		fmt.Fprintf(&b, "\n%s", testSummary(f.FailedTests))
+		return b.String()
+	}
+	fmt.Fprintf(&b, "\n%s", f.Result.Excerpt)
+	return b.String()
+}
+
+// testSummary lists failed tests with the line saying what went wrong, e.g.
+// "- TestLogin in pkg/auth (repeat) — expected 401, got 200", then their
+// output, as much of it as fits in a gate excerpt.
+func testSummary(failed []*db.FailedTest) string {
+	var b strings.Builder
+	noun := "failures"
+	if len(failed) == 1 {
+		noun = "failure"
+	}
+	fmt.Fprintf(&b, "%d %s:\n", len(failed), noun)
+	for _, f := range failed {
+		b.WriteString("- " + f.Name())
+		switch {
+		case f.Flaky:
+			b.WriteString(" (flaky: passed on re-run)")
+		case f.Repeat:
+			b.WriteString(" (repeat)")
+		}
+		if reason := f.Reason(); reason != "" {
+			b.WriteString(" — " + reason)
+		}
+		b.WriteString("\n")
+	}
+
+	budget := gate.ExcerptBytes
+	for i, f := range failed {
+		out := strings.TrimRight(f.Output, "\n")
+		if out == "" {
+			continue
+		}
+		if len(out) > budget {
+			fmt.Fprintf(&b, "\n(output of the other %d in the full output)", len(failed)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n=== %s\n%s\n", f.Name(), out)
+		budget -= len(out)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// openArtifact creates the file a gate run's output is kept in, under the
+// pane log directory. It returns "" and a nil file if it can't be written;
+// the run goes ahead without one.
+func openArtifact(task *db.Task, name string, t time.Time) (string, *os.File) {
+	dir, err := panelog.Dir()
+	if err == nil {
+		dir = filepath.Join(dir, "gates", strings.ReplaceAll(task.ID, string(filepath.Separator), "_"))
+		err = os.MkdirAll(dir, 0o755)
+	}
+	if err != nil {
+		fmt.Printf("warning: not keeping the gate output of %s: %v\n", task.ID, err)
+		return "", nil
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%d-%s.log", t.UTC().Format("20060102T150405.000Z"), task.Attempt, name))
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Printf("warning: not keeping the gate output of %s: %v\n", task.ID, err)
+		return "", nil
+	}
+	return path, f
+}
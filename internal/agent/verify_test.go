@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+	"github.com/otavio/minuano/internal/gotest"
+)
+
+func TestFailureContent(t *testing.T) {
+	task := &db.Task{ID: "auth-1", Attempt: 2, MaxAttempts: 3}
+	build := &GateResult{Def: gate.Def{Name: "build", Run: "go build ./..."}, Result: &gate.Result{Duration: time.Second}}
+	lint := &GateResult{
+		Def:    gate.Def{Name: "lint", Run: "golangci-lint run", Advisory: true},
+		Result: &gate.Result{ExitCode: 1, Duration: 2 * time.Second},
+	}
+	test := &GateResult{
+		Def:      gate.Def{Name: "test", Run: "go test ./..."},
+		Result:   &gate.Result{ExitCode: 1, Duration: 3200 * time.Millisecond, Excerpt: "--- FAIL: TestLogin"},
+		Artifact: "/logs/gates/auth-1/x-2-test.log",
+	}
+	out := &Outcome{Gates: []*GateResult{build, lint, test}, Failed: test}
+
+	got := failureContent(task, out)
+	want := "Attempt 2/3 failed at gate test. Command: go test ./... (exit 1 after 3.2s)\n" +
+		"Passed: build\n" +
+		"Advisory failures: lint (exit 1 after 2s)\n" +
+		"Full output: /logs/gates/auth-1/x-2-test.log\n\n--- FAIL: TestLogin"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if out.Passed() || out.Describe() != "gate test: exit 1 after 3.2s" {
+		t.Errorf("Passed() = %v, Describe() = %q", out.Passed(), out.Describe())
+	}
+	if (&Outcome{Gates: []*GateResult{build}}).Report() != "" {
+		t.Error("an outcome without a failed gate has nothing to report")
+	}
+
+	timeout := &GateResult{
+		Def:    gate.Def{Name: "test", Run: "make test"},
+		Result: &gate.Result{ExitCode: -1, TimedOut: true, Duration: time.Minute, Excerpt: "..."},
+	}
+	got = failureContent(task, &Outcome{Gates: []*GateResult{timeout}, Failed: timeout})
+	if got != "Attempt 2/3 failed at gate test. Command: make test (timed out after 1m0s)\n\n..." {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFailureContentTests(t *testing.T) {
+	task := &db.Task{ID: "auth-1", Attempt: 2, MaxAttempts: 3}
+	test := &GateResult{
+		Def:    gate.Def{Name: "test", Run: "go test ./..."},
+		Result: &gate.Result{ExitCode: 1, Duration: time.Second, Excerpt: "FAIL"},
+		FailedTests: []*db.FailedTest{
+			{Failure: gotest.Failure{Package: "pkg/auth", Test: "TestLogin", Output: "=== RUN   TestLogin\n    login_test.go:12: expected 401, got 200\n--- FAIL: TestLogin (0.00s)\n"}, Repeat: true},
+			{Failure: gotest.Failure{Package: "pkg/db"}},
+			{Failure: gotest.Failure{Package: "pkg/db", Test: "TestPool"}, Flaky: true},
+		},
+	}
+	got := failureContent(task, &Outcome{Gates: []*GateResult{test}, Failed: test})
+	want := "Attempt 2/3 failed at gate test. Command: go test ./... (exit 1 after 1s)\n\n" +
+		"3 failures:\n" +
+		"- TestLogin in pkg/auth (repeat) — login_test.go:12: expected 401, got 200\n" +
+		"- pkg/db\n" +
+		"- TestPool in pkg/db (flaky: passed on re-run)\n\n" +
+		"=== TestLogin in pkg/auth\n=== RUN   TestLogin\n    login_test.go:12: expected 401, got 200\n--- FAIL: TestLogin (0.00s)"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTestSummaryBounded(t *testing.T) {
+	big := strings.Repeat("x", gate.ExcerptBytes-10)
+	failed := []*db.FailedTest{
+		{Failure: gotest.Failure{Package: "a", Test: "TestA", Output: big}},
+		{Failure: gotest.Failure{Package: "a", Test: "TestB", Output: big}},
+		{Failure: gotest.Failure{Package: "a", Test: "TestC", Output: "short"}},
+	}
+	got := testSummary(failed)
+	if strings.Count(got, big) != 1 || !strings.HasSuffix(got, "(output of the other 2 in the full output)") {
+		t.Errorf("output should stop at the excerpt size, got %d bytes ending %q", len(got), got[len(got)-60:])
+	}
+	if !strings.HasPrefix(got, "3 failures:\n- TestA in a — "+big[:20]) {
+		t.Errorf("unexpected summary start %q", got[:40])
+	}
+}
+
+func TestOutcomeDescribe(t *testing.T) {
+	build := &GateResult{Def: gate.Def{Name: "build"}, Result: &gate.Result{}}
+	lint := &GateResult{Def: gate.Def{Name: "lint", Advisory: true}, Result: &gate.Result{ExitCode: 1}}
+	if got := (&Outcome{Gates: []*GateResult{build}}).Describe(); got != "1 gate passed" {
+		t.Errorf("got %q", got)
+	}
+	o := &Outcome{Gates: []*GateResult{build, lint}}
+	if !o.Passed() || o.Describe() != "2 gates passed" {
+		t.Errorf("an advisory failure should pass: %v, %q", o.Passed(), o.Describe())
+	}
+}
+
+func TestGateVerdict(t *testing.T) {
+	tests := []struct {
+		def  gate.Def
+		res  gate.Result
+		want string
+	}{
+		{gate.Def{Name: "build"}, gate.Result{Duration: time.Second}, "✓ build passed (exit 0 after 1s)"},
+		{gate.Def{Name: "lint", Advisory: true}, gate.Result{ExitCode: 1}, "⚠ lint failed (exit 1 after 0s), advisory"},
+		{gate.Def{Name: "test"}, gate.Result{ExitCode: -1, TimedOut: true, Duration: time.Minute}, "✗ test failed (timed out after 1m0s)"},
+	}
+	for _, tt := range tests {
+		if got := gateVerdict(&GateResult{Def: tt.def, Result: &tt.res}); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+
+	flaky := &GateResult{
+		Def:         gate.Def{Name: "test"},
+		Result:      &gate.Result{ExitCode: 1, Duration: time.Second},
+		FailedTests: []*db.FailedTest{{Failure: gotest.Failure{Package: "pkg/a", Test: "TestA"}, Flaky: true}},
+		Flaky:       true,
+	}
+	if got := gateVerdict(flaky); got != "✓ test passed on re-run (exit 1 after 1s), flaky: TestA in pkg/a" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestOpenArtifact(t *testing.T) {
+	t.Setenv("MINUANO_LOG_DIR", t.TempDir())
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	path, f := openArtifact(&db.Task{ID: "auth/1", Attempt: 2}, "test", at)
+	if f == nil {
+		t.Fatal("expected an artifact file")
+	}
+	f.Close()
+	if filepath.Base(filepath.Dir(path)) != "auth_1" || filepath.Base(path) != "20260301T120000.000Z-2-test.log" {
+		t.Errorf("unexpected artifact path %q", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,469 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+	"github.com/otavio/minuano/internal/git"
+	"github.com/otavio/minuano/internal/panelog"
+	"github.com/otavio/minuano/internal/profile"
+	"github.com/otavio/minuano/internal/runtime"
+	"github.com/otavio/minuano/internal/usage"
+)
+
+const (
+	// idleInterval is how often an idle worker polls for a ready task when
+	// no task event wakes it earlier.
+	idleInterval = 5 * time.Second
+
+	// failureLines is how much of the agent's output is kept as the task's
+	// failure context when it exits with an error.
+	failureLines = 80
+
+	// replyBytes bounds how much of the agent's output is kept in memory to
+	// take the task summary from.
+	replyBytes = 64 * 1024
+)
+
+// PromptFunc builds the prompt an agent is given for a task claimed on its behalf.
+type PromptFunc func(task *db.Task, ctxs []*db.TaskContext) string
+
+// Worker works tasks headlessly, one fresh agent process per task: it claims
+// a task itself, runs the profile's command on it (`claude -p` by default),
+// then runs the completion gate in the agent's place, as minuano-done would.
+// Both outputs go to the task's pane logs. The agent never loops, so no
+// tokens are spent between tasks.
+type Worker struct {
+	*Agent
+	pool      *pgxpool.Pool
+	profile   *profile.Profile
+	scripts   string
+	env       map[string]string
+	projectID *string
+	prompt    PromptFunc
+}
+
+// NewWorker registers a worker agent running prof. It is owned by the
+// subprocess runtime and recorded as this process, so `minuano kill` stops
+// the supervisor. With worktree set the worker gets its own git worktree, and
+// each task that passes its gate is committed there and queued for merge.
+func NewWorker(pool *pgxpool.Pool, prof *profile.Profile, agentID, claudeMDPath string, env map[string]string, projectID *string, worktree bool, prompt PromptFunc) (*Worker, error) {
+	var worktreeDir, branch *string
+	if worktree {
+		dir, br, err := addWorktree(agentID)
+		if err != nil {
+			return nil, err
+		}
+		worktreeDir, branch = &dir, &br
+	}
+
+	if err := db.RegisterAgent(pool, agentID, runtime.Subprocess, prof.Name, projectID, "", "", worktreeDir, branch); err != nil {
+		if worktreeDir != nil {
+			git.WorktreeRemove(*worktreeDir)
+		}
+		return nil, fmt.Errorf("registering agent: %w", err)
+	}
+	pid := os.Getpid()
+	if err := db.SetAgentPID(pool, agentID, pid, runtime.ProcessStart(pid)); err != nil {
+		fmt.Printf("warning: %v\n", err)
+	}
+
+	now := time.Now()
+	return &Worker{
+		Agent: &Agent{
+			ID:          agentID,
+			Runtime:     runtime.Subprocess,
+			PID:         &pid,
+			Profile:     prof.Name,
+			ProjectID:   projectID,
+			Status:      "idle",
+			StartedAt:   now,
+			LastSeen:    &now,
+			WorktreeDir: worktreeDir,
+			Branch:      branch,
+		},
+		pool:      pool,
+		profile:   prof,
+		scripts:   scriptsDir(claudeMDPath),
+		env:       agentEnv(env, prof, projectID),
+		projectID: projectID,
+		prompt:    prompt,
+	}, nil
+}
+
+// Run claims and works tasks until ctx is cancelled. While the queue is empty
+// it waits for a signal on wake, or polls. Cancelling ctx stops the task in
+// progress without settling it; Close then releases the claim. If the agent
+// can't be started on a task, the task is put back to ready and Run returns
+// the error.
+func (w *Worker) Run(ctx context.Context, wake <-chan struct{}) error {
+	for ctx.Err() == nil {
+		task, err := db.AtomicClaim(w.pool, w.ID, w.projectID)
+		if err != nil {
+			fmt.Printf("warning: %s: %v\n", w.ID, err)
+		}
+		if task == nil {
+			select {
+			case <-ctx.Done():
+			case <-wake:
+			case <-time.After(idleInterval):
+			}
+			continue
+		}
+		if err := w.work(ctx, task); err != nil {
+			// The worker is giving up; don't leave the task claimed.
+			if err := db.UnclaimTask(w.pool, task.ID); err != nil && !errors.Is(err, db.ErrWrongStatus) {
+				fmt.Printf("warning: %s: releasing %s: %v\n", w.ID, task.ID, err)
+			}
+			return fmt.Errorf("%s: %w", w.ID, err)
+		}
+	}
+	return nil
+}
+
+// Close deregisters the worker as `minuano kill` would, releasing the task
+// it holds, if any. The worker must not be running.
+func (w *Worker) Close() error {
+	a, err := db.GetAgent(w.pool, w.ID)
+	if err != nil {
+		return fmt.Errorf("getting agent: %w", err)
+	}
+	return deregister(w.pool, w.ID, a, w.env["MINUANO_BASE_BRANCH"])
+}
+
+// work runs the agent on a claimed task and settles it. Only failing to
+// start the agent at all is an error; everything else is recorded on the task.
+func (w *Worker) work(ctx context.Context, task *db.Task) error {
+	fmt.Printf("%s: claimed %s (attempt %d/%d)\n", w.ID, task.ID, task.Attempt, task.MaxAttempts)
+
+	out, closeLog := w.openLog()
+	defer closeLog()
+
+	_, ctxs, err := db.GetTaskWithContext(w.pool, task.ID)
+	if err != nil {
+		return err
+	}
+	var sessionID string
+	if w.profile.UsesSession() {
+		if sessionID, err = startSession(w.pool, w.ID); err != nil {
+			return err
+		}
+	}
+
+	promptFile, err := writePrompt(w.prompt(task, ctxs))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(promptFile)
+	script, err := w.profile.Script(profile.Vars{AgentID: w.ID, SessionID: sessionID, PromptFile: promptFile})
+	if err != nil {
+		return err
+	}
+
+	reply := gate.NewTailBuffer(replyBytes)
+	cmd := exec.CommandContext(ctx, "bash", "-c", script)
+	cmd.Stdout = io.MultiWriter(out, reply)
+	cmd.Stderr = out
+	w.prepare(cmd)
+	// Agents like claude -p print nothing until they are done; start the
+	// segment now so the task's logs can be followed from the start.
+	fmt.Fprintf(out, "▶ %s\n", script)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", w.profile.Name, err)
+	}
+	exitErr := cmd.Wait()
+	killGroup(cmd)
+
+	// The agent is gone; take in its transcript while the claim is still in place.
+	if sessionID != "" {
+		if err := db.EndAgentSessions(w.pool, w.ID); err != nil {
+			fmt.Printf("warning: %v\n", err)
+		} else if _, err := usage.Sync(w.pool, w.ID); err != nil {
+			fmt.Printf("warning: recording token usage for %s: %v\n", w.ID, err)
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Printf("%s: stopped working on %s\n", w.ID, task.ID)
+		return nil
+	}
+
+	// The agent may have settled the task itself (calling minuano-done anyway).
+	t, err := db.GetTask(w.pool, task.ID)
+	if err != nil {
+		return err
+	}
+	if t.Status != "claimed" || t.ClaimedBy == nil || *t.ClaimedBy != w.ID {
+		fmt.Printf("%s: %s is %s\n", w.ID, task.ID, t.Status)
+		return nil
+	}
+
+	if exitErr != nil {
+		fmt.Fprintf(out, "✗ %s exited: %v\n", w.profile.Name, exitErr)
+		w.fail(task, fmt.Sprintf("Attempt %d/%d failed: %s exited: %v\n\n%s",
+			task.Attempt, task.MaxAttempts, w.profile.Name, exitErr, gate.LastLines(reply.String(), failureLines)))
+		return nil
+	}
+
+	summary := summarize(reply.String())
+	if summary == "" {
+		summary = "Completed by " + w.ID
+	}
+	w.gate(ctx, task, out, summary)
+	return nil
+}
+
+// gate runs the task's gates, then marks it done or records the failure.
+func (w *Worker) gate(ctx context.Context, task *db.Task, out io.Writer, summary string) {
+	proj := w.project(task)
+	dir, _ := os.Getwd()
+	if w.WorktreeDir != nil {
+		dir = *w.WorktreeDir
+	}
+
+	res, err := Verify(ctx, w.pool, Verification{
+		Task:    task,
+		Project: proj,
+		AgentID: w.ID,
+		Dir:     dir,
+		Env:     w.env,
+		Environ: workerEnv(os.Environ(), w.ID, w.scripts, w.env, w.WorktreeDir, w.Branch),
+		Timeout: gateTimeout(w.env),
+		Reruns:  flakyReruns(w.env),
+		Summary: summary,
+		Output:  out,
+	})
+	if ctx.Err() != nil {
+		fmt.Printf("%s: stopped working on %s\n", w.ID, task.ID)
+		return
+	}
+	if err != nil {
+		w.fail(task, fmt.Sprintf("Attempt %d/%d failed: running the gates: %v", task.Attempt, task.MaxAttempts, err))
+		return
+	}
+	if !res.Passed() {
+		w.reportFailure(task)
+		return
+	}
+
+	fmt.Printf("%s: done %s\n", w.ID, task.ID)
+
+	if w.WorktreeDir == nil || w.Branch == nil {
+		return
+	}
+	subject, _, _ := strings.Cut(summary, "\n")
+	sha, err := git.AddAndCommit(*w.WorktreeDir, fmt.Sprintf("minuano: %s — %s", task.ID, subject))
+	if err != nil {
+		fmt.Printf("warning: %s: committing %s: %v\n", w.ID, task.ID, err)
+		return
+	}
+	if sha == "" {
+		return
+	}
+	base := baseBranch(proj, w.env)
+	if err := db.EnqueueMerge(w.pool, task.ID, w.ID, *w.Branch, *w.WorktreeDir, base, sha); err != nil {
+		fmt.Printf("warning: %s: %v\n", w.ID, err)
+		return
+	}
+	fmt.Printf("%s: committed %s on %s, enqueued for merge into %s\n", w.ID, sha, *w.Branch, base)
+}
+
+// fail records a failed attempt: the task goes back to ready, or to failed
+// once it is out of attempts.
+func (w *Worker) fail(task *db.Task, content string) {
+	if err := db.RecordFailure(w.pool, task.ID, w.ID, content); err != nil {
+		fmt.Printf("warning: %s: %v\n", w.ID, err)
+		return
+	}
+	w.reportFailure(task)
+}
+
+// reportFailure reports where a failed attempt left the task.
+func (w *Worker) reportFailure(task *db.Task) {
+	if task.Attempt >= task.MaxAttempts {
+		fmt.Printf("%s: %s failed after %d attempts\n", w.ID, task.ID, task.Attempt)
+	} else {
+		fmt.Printf("%s: %s failed (attempt %d/%d), reset to ready\n", w.ID, task.ID, task.Attempt, task.MaxAttempts)
+	}
+}
+
+// openLog starts a pane log recorder for the task at hand and returns the
+// writer feeding it. Output is discarded if logs can't be written.
+func (w *Worker) openLog() (io.Writer, func()) {
+	dir, err := panelog.Dir()
+	if err != nil {
+		fmt.Printf("warning: not archiving output of %s: %v\n", w.ID, err)
+		return io.Discard, func() {}
+	}
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- panelog.NewRecorder(w.pool, dir, w.ID).Run(context.Background(), pr)
+	}()
+	return pw, func() {
+		pw.Close()
+		if err := <-done; err != nil {
+			fmt.Printf("warning: archiving output of %s: %v\n", w.ID, err)
+		}
+	}
+}
+
+// writePrompt saves a task prompt to a temporary file, from which the
+// profile's command reads it.
+func writePrompt(prompt string) (string, error) {
+	f, err := os.CreateTemp("", "minuano-prompt-*.md")
+	if err != nil {
+		return "", fmt.Errorf("writing prompt: %w", err)
+	}
+	_, err = f.WriteString(prompt)
+	if err := errors.Join(err, f.Close()); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("writing prompt: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// prepare sets up a child process: it runs in the worker's directory and
+// environment, in its own process group, which is sent SIGTERM when the
+// worker stops.
+func (w *Worker) prepare(cmd *exec.Cmd) {
+	if w.WorktreeDir != nil {
+		cmd.Dir = *w.WorktreeDir
+	}
+	cmd.Env = workerEnv(os.Environ(), w.ID, w.scripts, w.env, w.WorktreeDir, w.Branch)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = gate.KillGrace
+}
+
+// killGroup kills whatever the child left running in its process group.
+func killGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// workerEnv returns base extended with what the bootstrap script exports to
+// spawned agents, so the minuano-* scripts work the same for the agent and the
+// completion gate.
+func workerEnv(base []string, agentID, scripts string, env map[string]string, worktreeDir, branch *string) []string {
+	path := ""
+	for _, kv := range base {
+		if v, ok := strings.CutPrefix(kv, "PATH="); ok {
+			path = v
+		}
+	}
+	out := slices.Clone(base)
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		out = append(out, k+"="+env[k])
+	}
+	out = append(out, "AGENT_ID="+agentID, "PATH="+path+":"+scripts)
+	if worktreeDir != nil {
+		out = append(out, "WORKTREE_DIR="+*worktreeDir)
+	}
+	if branch != nil {
+		out = append(out, "BRANCH="+*branch)
+	}
+	return out
+}
+
+// project returns the record of the task's project, or nil if it has none
+// or it can't be read.
+func (w *Worker) project(task *db.Task) *db.Project {
+	if task.ProjectID == nil {
+		return nil
+	}
+	p, err := db.GetProject(w.pool, *task.ProjectID)
+	if err != nil {
+		fmt.Printf("warning: %s: using default settings for %s: %v\n", w.ID, task.ID, err)
+		return nil
+	}
+	return p
+}
+
+// gatePlan is the list of gates a task's work must pass, in order: as in
+// minuano-done, a "test" gate running MINUANO_TEST_CMD from the agent
+// environment env if set, else the task's gates or test_cmd, else its
+// project's (proj may be nil), else the configured default test command,
+// else go test.
+func gatePlan(task *db.Task, proj *db.Project, env map[string]string) []gate.Def {
+	test := func(cmd string) []gate.Def {
+		return []gate.Def{{Name: "test", Run: cmd}}
+	}
+	if cmd := env["MINUANO_TEST_CMD"]; cmd != "" {
+		return test(cmd)
+	}
+	meta := task.Meta()
+	if len(meta.Gates) > 0 {
+		return meta.Gates
+	}
+	if meta.TestCmd != "" {
+		return test(meta.TestCmd)
+	}
+	if proj != nil && len(proj.Gates) > 0 {
+		return proj.Gates
+	}
+	if proj != nil && proj.TestCmd != nil {
+		return test(*proj.TestCmd)
+	}
+	if cmd := env["MINUANO_DEFAULT_TEST_CMD"]; cmd != "" {
+		return test(cmd)
+	}
+	return test("go test ./...")
+}
+
+// baseBranch is the branch a task's work is merged into: its project's base
+// branch (proj may be nil), else the configured one, else main.
+func baseBranch(proj *db.Project, env map[string]string) string {
+	if proj != nil && proj.BaseBranch != nil {
+		return *proj.BaseBranch
+	}
+	if b := env["MINUANO_BASE_BRANCH"]; b != "" {
+		return b
+	}
+	return "main"
+}
+
+// gateTimeout is how long the completion gate may run: MINUANO_GATE_TIMEOUT
+// from the agent environment env, as exported by minuano; none if unset or
+// invalid.
+func gateTimeout(env map[string]string) time.Duration {
+	d, err := time.ParseDuration(env["MINUANO_GATE_TIMEOUT"])
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// flakyReruns is how many times failed tests are re-run before a go test
+// gate fails: MINUANO_FLAKY_RERUNS from the agent environment env; none if
+// unset or invalid.
+func flakyReruns(env map[string]string) int {
+	n, err := strconv.Atoi(env["MINUANO_FLAKY_RERUNS"])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// summarize takes the task summary from the agent's reply: its last
+// paragraph, which the worker prompt asks for a summary in.
+func summarize(reply string) string {
+	paras := strings.Split(strings.TrimSpace(reply), "\n\n")
+	return strings.TrimSpace(paras[len(paras)-1])
+}
@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+)
+
+func TestWorkerEnv(t *testing.T) {
+	base := []string{"HOME=/home/me", "PATH=/usr/bin:/bin"}
+	env := map[string]string{"DATABASE_URL": "postgres://localhost/minuano"}
+	got := workerEnv(base, "worker-1", "/repo/scripts", env, nil, nil)
+
+	for _, kv := range []string{
+		"HOME=/home/me",
+		"DATABASE_URL=postgres://localhost/minuano",
+		"AGENT_ID=worker-1",
+		"PATH=/usr/bin:/bin:/repo/scripts",
+	} {
+		if !slices.Contains(got, kv) {
+			t.Errorf("missing %q in %q", kv, got)
+		}
+	}
+	if len(base) != 2 {
+		t.Error("workerEnv modified its base")
+	}
+	for _, kv := range got {
+		if strings.HasPrefix(kv, "WORKTREE_DIR=") || strings.HasPrefix(kv, "BRANCH=") {
+			t.Errorf("unexpected %q without a worktree", kv)
+		}
+	}
+
+	wt, branch := "/repo/.minuano/worktrees/worker-1", "minuano/worker-1"
+	got = workerEnv(base, "worker-1", "/repo/scripts", env, &wt, &branch)
+	if !slices.Contains(got, "WORKTREE_DIR="+wt) || !slices.Contains(got, "BRANCH="+branch) {
+		t.Errorf("expected worktree variables, got %q", got)
+	}
+}
+
+func TestGatePlan(t *testing.T) {
+	// run returns the plan's commands, with the names of gates not named test.
+	run := func(defs []gate.Def) string {
+		var runs []string
+		for _, d := range defs {
+			if d.Name != "test" {
+				runs = append(runs, d.Name+"="+d.Run)
+			} else {
+				runs = append(runs, d.Run)
+			}
+		}
+		return strings.Join(runs, "; ")
+	}
+
+	task := &db.Task{ID: "t"}
+	if got := run(gatePlan(task, nil, nil)); got != "go test ./..." {
+		t.Errorf("default gate = %q", got)
+	}
+
+	env := map[string]string{"MINUANO_DEFAULT_TEST_CMD": "make test"}
+	if got := run(gatePlan(task, nil, env)); got != "make test" {
+		t.Errorf("configured default gate = %q", got)
+	}
+
+	task.Metadata = json.RawMessage(`{"test_cmd":"make check"}`)
+	if got := run(gatePlan(task, nil, env)); got != "make check" {
+		t.Errorf("metadata gate = %q", got)
+	}
+
+	task.Metadata = nil
+	projCmd := "make ci"
+	proj := &db.Project{ID: "auth", TestCmd: &projCmd}
+	if got := run(gatePlan(task, proj, env)); got != "make ci" {
+		t.Errorf("project gate = %q", got)
+	}
+
+	proj.Gates = []gate.Def{{Name: "build", Run: "go build ./..."}, {Name: "vet", Run: "go vet ./..."}}
+	if got := run(gatePlan(task, proj, env)); got != "build=go build ./...; vet=go vet ./..." {
+		t.Errorf("project gates should win over its test command, got %q", got)
+	}
+
+	task.Metadata = json.RawMessage(`{"test_cmd":"make check"}`)
+	if got := run(gatePlan(task, proj, env)); got != "make check" {
+		t.Errorf("metadata should win over the project, got %q", got)
+	}
+
+	task.Metadata = json.RawMessage(`{"test_cmd":"make check","gates":[{"name":"lint","run":"golangci-lint run","advisory":true}]}`)
+	plan := gatePlan(task, proj, env)
+	if got := run(plan); got != "lint=golangci-lint run" || !plan[0].Advisory {
+		t.Errorf("task gates should win over its test command, got %q", got)
+	}
+
+	env["MINUANO_TEST_CMD"] = "true"
+	if got := run(gatePlan(task, proj, env)); got != "true" {
+		t.Errorf("MINUANO_TEST_CMD should win, got %q", got)
+	}
+}
+
+func TestBaseBranch(t *testing.T) {
+	if got := baseBranch(nil, nil); got != "main" {
+		t.Errorf("default base = %q", got)
+	}
+	env := map[string]string{"MINUANO_BASE_BRANCH": "develop"}
+	if got := baseBranch(&db.Project{ID: "auth"}, env); got != "develop" {
+		t.Errorf("configured base = %q", got)
+	}
+	release := "release"
+	if got := baseBranch(&db.Project{ID: "auth", BaseBranch: &release}, env); got != "release" {
+		t.Errorf("project base = %q", got)
+	}
+}
+
+func TestAgentBaseWithoutProject(t *testing.T) {
+	a := &db.Agent{ID: "worker-1"}
+	if got := agentBase(nil, a, ""); got != "main" {
+		t.Errorf("default base = %q", got)
+	}
+	if got := agentBase(nil, a, "develop"); got != "develop" {
+		t.Errorf("configured base = %q", got)
+	}
+}
+
+func TestGateTimeout(t *testing.T) {
+	tests := map[string]time.Duration{"": 0, "90s": 90 * time.Second, "0s": 0, "-1m": 0, "soon": 0}
+	for v, want := range tests {
+		if got := gateTimeout(map[string]string{"MINUANO_GATE_TIMEOUT": v}); got != want {
+			t.Errorf("gateTimeout(%q) = %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestFlakyReruns(t *testing.T) {
+	tests := map[string]int{"": 0, "2": 2, "0": 0, "-1": 0, "twice": 0}
+	for v, want := range tests {
+		if got := flakyReruns(map[string]string{"MINUANO_FLAKY_RERUNS": v}); got != want {
+			t.Errorf("flakyReruns(%q) = %d, want %d", v, got, want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		reply, want string
+	}{
+		{"", ""},
+		{"Added the handler.\n", "Added the handler."},
+		{"I looked around.\n\nAdded the OAuth handler\nand its tests.\n\n", "Added the OAuth handler\nand its tests."},
+	}
+	for _, tt := range tests {
+		if got := summarize(tt.reply); got != tt.want {
+			t.Errorf("summarize(%q) = %q, want %q", tt.reply, got, tt.want)
+		}
+	}
+}
+
+func TestScriptsDir(t *testing.T) {
+	if got := scriptsDir("/repo/claude/CLAUDE.md"); got != "/repo/scripts" {
+		t.Errorf("scriptsDir = %q", got)
+	}
+}
+
+func TestPrepareStopsGroupOnCancel(t *testing.T) {
+	w := &Worker{Agent: &Agent{ID: "worker-1"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, "bash", "-c", "sleep 30 & wait")
+	w.prepare(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected the cancelled command to fail")
+		}
+	case <-time.After(gate.KillGrace):
+		t.Fatal("command did not stop on cancel")
+	}
+	killGroup(cmd)
+	if !slices.Contains(cmd.Env, "AGENT_ID=worker-1") {
+		t.Error("expected the worker environment")
+	}
+}
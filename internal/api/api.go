@@ -0,0 +1,426 @@
+// Package api serves Minuano's operations as a versioned JSON HTTP API. Every
+// handler delegates to the service layer, the same code path the CLI uses.
+package api
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/service"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// Server routes /v1 requests to a service.
+type Server struct {
+	// EventRetention is how long Run keeps entries in the event log; zero
+	// disables pruning.
+	EventRetention time.Duration
+
+	svc    *service.Service
+	token  string
+	mux    *http.ServeMux
+	events *notifier
+}
+
+// New creates an API server. Every endpoint except the OpenAPI document
+// requires "Authorization: Bearer <token>"; token must not be empty. Call
+// Run alongside serving so /v1/events streams see new events.
+func New(svc *service.Service, token string) *Server {
+	s := &Server{
+		EventRetention: 7 * 24 * time.Hour,
+		svc:            svc,
+		token:          token,
+		mux:            http.NewServeMux(),
+		events:         newNotifier(),
+	}
+
+	s.mux.HandleFunc("GET /v1/openapi.json", s.openAPI)
+
+	s.handle("GET /v1/tasks", s.listTasks)
+	s.handle("POST /v1/tasks", s.createTask)
+	s.handle("GET /v1/tasks/{id}", s.getTask)
+	s.handle("PATCH /v1/tasks/{id}", s.updateTask)
+	s.handle("GET /v1/tasks/{id}/context", s.taskContext)
+	s.handle("GET /v1/tasks/{id}/dependencies", s.listDependencies)
+	s.handle("PUT /v1/tasks/{id}/dependencies/{dep}", s.addDependency)
+	s.handle("DELETE /v1/tasks/{id}/dependencies/{dep}", s.removeDependency)
+	s.handle("POST /v1/tasks/{id}/approve", s.approve)
+	s.handle("POST /v1/tasks/{id}/reject", s.reject)
+	s.handle("POST /v1/tasks/{id}/release", s.release)
+	s.handle("POST /v1/projects/{project}/release", s.releaseProject)
+
+	s.handle("GET /v1/agents", s.listAgents)
+	s.handle("GET /v1/agents/{id}", s.getAgent)
+	s.handle("GET /v1/agents/{id}/pane", s.agentPane)
+	s.handle("POST /v1/agents", s.spawnAgent)
+	s.handle("DELETE /v1/agents/{id}", s.killAgent)
+
+	s.handle("GET /v1/merge-queue", s.mergeQueue)
+	s.handle("GET /v1/search", s.search)
+	s.handle("GET /v1/graph", s.graph)
+	s.handle("GET /v1/events", s.streamEvents)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle registers an authenticated endpoint.
+func (s *Server) handle(pattern string, h http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="minuano"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		h(w, r)
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && r.URL.Path == "/v1/events" {
+		// Browser EventSource can't set headers.
+		got = r.URL.Query().Get("access_token")
+		ok = got != ""
+	}
+	if !ok || s.token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) == 1
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// --- tasks ---
+
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := s.svc.ListTasks(projectParam(r), r.URL.Query().Get("status"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+	var in service.CreateTaskInput
+	if !readJSON(w, r, &in) {
+		return
+	}
+	task, err := s.svc.CreateTask(in)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/tasks/"+task.ID)
+	writeTask(w, http.StatusCreated, task)
+}
+
+func (s *Server) getTask(w http.ResponseWriter, r *http.Request) {
+	task, err := s.svc.GetTask(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" && match == etag(task) {
+		w.Header().Set("ETag", etag(task))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *Server) updateTask(w http.ResponseWriter, r *http.Request) {
+	ifVersion, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		writeError(w, http.StatusPreconditionFailed, err)
+		return
+	}
+	var in service.UpdateTaskInput
+	if !readJSON(w, r, &in) {
+		return
+	}
+	task, err := s.svc.UpdateTask(r.PathValue("id"), in, ifVersion)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *Server) graph(w http.ResponseWriter, r *http.Request) {
+	g, err := s.svc.Graph(projectParam(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (s *Server) taskContext(w http.ResponseWriter, r *http.Request) {
+	ctxs, err := s.svc.GetTaskContext(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ctxs)
+}
+
+func (s *Server) listDependencies(w http.ResponseWriter, r *http.Request) {
+	deps, err := s.svc.Dependencies(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deps)
+}
+
+func (s *Server) addDependency(w http.ResponseWriter, r *http.Request) {
+	if err := s.svc.AddDependency(r.PathValue("id"), r.PathValue("dep")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) removeDependency(w http.ResponseWriter, r *http.Request) {
+	if err := s.svc.RemoveDependency(r.PathValue("id"), r.PathValue("dep")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) approve(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		By string `json:"by"`
+	}
+	if !readOptionalJSON(w, r, &in) {
+		return
+	}
+	if in.By == "" {
+		in.By = "api"
+	}
+	task, err := s.svc.Approve(r.PathValue("id"), in.By)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *Server) reject(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Reason string `json:"reason"`
+	}
+	if !readOptionalJSON(w, r, &in) {
+		return
+	}
+	task, err := s.svc.Reject(r.PathValue("id"), in.Reason)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *Server) release(w http.ResponseWriter, r *http.Request) {
+	task, err := s.svc.DraftRelease(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+func (s *Server) releaseProject(w http.ResponseWriter, r *http.Request) {
+	n, err := s.svc.DraftReleaseAll(r.PathValue("project"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"released": n})
+}
+
+// --- agents, merge queue, search ---
+
+func (s *Server) listAgents(w http.ResponseWriter, r *http.Request) {
+	agents, err := s.svc.ListAgents()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, agents)
+}
+
+func (s *Server) getAgent(w http.ResponseWriter, r *http.Request) {
+	a, err := s.svc.GetAgent(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (s *Server) agentPane(w http.ResponseWriter, r *http.Request) {
+	lines := 40
+	if v := r.URL.Query().Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid lines %q", v))
+			return
+		}
+		lines = n
+	}
+	text, err := s.svc.AgentPane(r.PathValue("id"), lines)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"agent_id": r.PathValue("id"), "text": text})
+}
+
+func (s *Server) spawnAgent(w http.ResponseWriter, r *http.Request) {
+	var in service.SpawnAgentInput
+	if !readJSON(w, r, &in) {
+		return
+	}
+	a, err := s.svc.SpawnAgent(in)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/agents/"+a.ID)
+	writeJSON(w, http.StatusCreated, a)
+}
+
+func (s *Server) killAgent(w http.ResponseWriter, r *http.Request) {
+	if err := s.svc.KillAgent(r.PathValue("id")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) mergeQueue(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.svc.MergeQueue()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	results, err := s.svc.Search(r.URL.Query().Get("q"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// --- helpers ---
+
+// projectParam returns the ?project= filter, nil when absent.
+func projectParam(r *http.Request) *string {
+	if p := r.URL.Query().Get("project"); p != "" {
+		return &p
+	}
+	return nil
+}
+
+// etag derives a strong validator from the task's row version.
+func etag(t *db.Task) string {
+	return fmt.Sprintf(`"v%d"`, t.Version)
+}
+
+// parseIfMatch returns the version an If-Match header requires, or nil when
+// the header is absent or "*".
+func parseIfMatch(h string) (*int, error) {
+	h = strings.TrimSpace(h)
+	if h == "" || h == "*" {
+		return nil, nil
+	}
+	v, ok := strings.CutPrefix(h, `"v`)
+	if ok {
+		v, ok = strings.CutSuffix(v, `"`)
+	}
+	n, err := strconv.Atoi(v)
+	if !ok || err != nil {
+		return nil, fmt.Errorf("If-Match %s does not name a task version", h)
+	}
+	return &n, nil
+}
+
+func writeTask(w http.ResponseWriter, status int, t *db.Task) {
+	w.Header().Set("ETag", etag(t))
+	writeJSON(w, status, t)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeServiceError maps service and db errors onto HTTP status codes.
+func writeServiceError(w http.ResponseWriter, err error) {
+	writeError(w, statusFor(err), err)
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, db.ErrWrongStatus), errors.Is(err, db.ErrExists), errors.Is(err, service.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrAmbiguous), errors.Is(err, service.ErrInvalid):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// readJSON decodes a required request body, writing a 400 on failure.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("decoding request body: %w", err))
+		return false
+	}
+	return true
+}
+
+// readOptionalJSON is readJSON for endpoints whose body may be empty.
+func readOptionalJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	return readJSON(w, r, v)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/service"
+)
+
+func do(t *testing.T, s *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthRequired(t *testing.T) {
+	s := New(&service.Service{}, "secret")
+	for _, token := range []string{"", "wrong"} {
+		rec := do(t, s, "GET", "/v1/tasks", token, "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: expected 401, got %d", token, rec.Code)
+		}
+		if rec.Header().Get("WWW-Authenticate") == "" {
+			t.Error("expected WWW-Authenticate header")
+		}
+	}
+}
+
+func TestEmptyTokenRejectsEverything(t *testing.T) {
+	s := New(&service.Service{}, "")
+	rec := do(t, s, "GET", "/v1/tasks", "", "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with no configured token, got %d", rec.Code)
+	}
+}
+
+func TestOpenAPIIsPublicAndValid(t *testing.T) {
+	s := New(&service.Service{}, "secret")
+	rec := do(t, s, "GET", "/v1/openapi.json", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	for _, p := range []string{"/v1/tasks", "/v1/tasks/{id}", "/v1/agents", "/v1/merge-queue", "/v1/search", "/v1/events", "/v1/graph", "/v1/agents/{id}/pane"} {
+		if _, ok := doc.Paths[p]; !ok {
+			t.Errorf("spec missing path %s", p)
+		}
+	}
+}
+
+func TestCreateTaskRejectsBadBody(t *testing.T) {
+	s := New(&service.Service{}, "secret")
+	rec := do(t, s, "POST", "/v1/tasks", "secret", `{"title":"x","bogus":1}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown field, got %d", rec.Code)
+	}
+}
+
+func TestCreateTaskValidation(t *testing.T) {
+	s := New(&service.Service{}, "secret")
+	rec := do(t, s, "POST", "/v1/tasks", "secret", `{"title":"  "}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for empty title, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUpdateTaskBadIfMatch(t *testing.T) {
+	s := New(&service.Service{}, "secret")
+	req := httptest.NewRequest("PATCH", "/v1/tasks/abc", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("If-Match", `"garbage"`)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412, got %d", rec.Code)
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int // -1 for nil
+		wantErr bool
+	}{
+		{"", -1, false},
+		{"*", -1, false},
+		{`"v3"`, 3, false},
+		{`W/"v3"`, 0, true},
+		{`"3"`, 0, true},
+		{`"vx"`, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseIfMatch(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIfMatch(%q) error = %v", tt.in, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if (got == nil) != (tt.want == -1) || (got != nil && *got != tt.want) {
+			t.Errorf("parseIfMatch(%q) = %v, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestETag(t *testing.T) {
+	if got := etag(&db.Task{Version: 7}); got != `"v7"` {
+		t.Errorf("etag = %s", got)
+	}
+}
+
+func TestStatusFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("%w: x", db.ErrNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: x", db.ErrVersionMismatch), http.StatusPreconditionFailed},
+		{fmt.Errorf("%w: x", db.ErrWrongStatus), http.StatusConflict},
+		{fmt.Errorf("%w: x", db.ErrExists), http.StatusConflict},
+		{fmt.Errorf("%w: x", service.ErrConflict), http.StatusConflict},
+		{fmt.Errorf("%w: x", db.ErrAmbiguous), http.StatusBadRequest},
+		{fmt.Errorf("%w: x", service.ErrInvalid), http.StatusBadRequest},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := statusFor(tt.err); got != tt.want {
+			t.Errorf("statusFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAgentPaneBadLines(t *testing.T) {
+	s := New(&service.Service{}, "secret")
+	for _, q := range []string{"abc", "0", "5000"} {
+		rec := do(t, s, "GET", "/v1/agents/agent-1/pane?lines="+q, "secret", "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("lines=%s: expected 400, got %d", q, rec.Code)
+		}
+	}
+}
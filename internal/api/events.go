@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/service"
+)
+
+const (
+	// eventBatch caps how many logged events are read per query while catching up.
+	eventBatch = 500
+	// keepAliveInterval keeps idle streams from being cut by proxies.
+	keepAliveInterval = 15 * time.Second
+)
+
+// notifier fans minuano_events notifications out to SSE subscribers. A wakeup
+// only means "the log may have grown"; subscribers re-read from their last ID.
+type notifier struct {
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+	done chan struct{}
+}
+
+func newNotifier() *notifier {
+	return &notifier{subs: map[chan struct{}]struct{}{}, done: make(chan struct{})}
+}
+
+func (n *notifier) subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	n.mu.Lock()
+	n.subs[ch] = struct{}{}
+	n.mu.Unlock()
+	return ch, func() {
+		n.mu.Lock()
+		delete(n.subs, ch)
+		n.mu.Unlock()
+	}
+}
+
+func (n *notifier) broadcast() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.subs {
+		select {
+		case ch <- struct{}{}:
+		default: // already pending
+		}
+	}
+}
+
+// Run relays event notifications to streaming clients and prunes the event
+// log until ctx is cancelled, then ends all open streams.
+func (s *Server) Run(ctx context.Context) {
+	defer close(s.events.done)
+
+	go func() {
+		for ctx.Err() == nil {
+			// Anything written while we weren't listening is picked up on the
+			// subscribers' next read, so just nudge them after (re)connecting.
+			s.events.broadcast()
+			if err := s.svc.ListenEvents(ctx, s.events.broadcast); err != nil {
+				log.Printf("api: %v (retrying)", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}
+	}()
+
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	for {
+		if s.EventRetention > 0 {
+			if n, err := s.svc.PruneEvents(s.EventRetention); err != nil {
+				log.Printf("api: %v", err)
+			} else if n > 0 {
+				log.Printf("api: pruned %d event(s) older than %s", n, s.EventRetention)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-prune.C:
+		}
+	}
+}
+
+// streamEvents serves the event log as Server-Sent Events. Without a
+// Last-Event-ID (header or last_event_id query parameter) the stream starts
+// at the newest event; with one, everything after it is replayed first.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	kinds, err := service.ParseEventKinds(r.URL.Query().Get("kinds"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	filter := service.EventFilter{ProjectID: projectParam(r), Kinds: kinds}
+
+	// Subscribe before the first read so nothing lands between the two.
+	wake, unsubscribe := s.events.subscribe()
+	defer unsubscribe()
+
+	cur, err := lastEventCursor(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if cur == nil {
+		cur = &service.EventCursor{}
+		if cur.After, err = s.svc.LatestEventID(); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		events, err := s.svc.Events(cur, filter, eventBatch)
+		if err != nil {
+			// The client reconnects with its Last-Event-ID and resumes.
+			log.Printf("api: event stream: %v", err)
+			return
+		}
+		sent := false
+		for _, e := range events {
+			cur.Advance(e.ID, time.Now())
+			if !e.Matched {
+				continue
+			}
+			if err := writeEvent(w, e, cur.String()); err != nil {
+				return
+			}
+			sent = true
+		}
+		if sent {
+			flusher.Flush()
+		}
+		if len(events) == eventBatch {
+			continue // still catching up
+		}
+
+		select {
+		case <-wake:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.events.done:
+			return
+		}
+	}
+}
+
+// lastEventCursor returns the cursor to resume from, or nil when the client
+// gave none.
+func lastEventCursor(r *http.Request) (*service.EventCursor, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v == "" {
+		return nil, nil
+	}
+	return service.ParseEventCursor(v)
+}
+
+// writeEvent writes one SSE frame, with the cursor to resume from after it as
+// its id. The data line is the whole event as JSON, which never contains a
+// raw newline.
+func writeEvent(w io.Writer, e *db.Event, id string) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, e.Kind, data)
+	return err
+}
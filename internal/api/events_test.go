@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/service"
+)
+
+func TestEventsAccessTokenQuery(t *testing.T) {
+	s := New(&service.Service{}, "secret")
+
+	// An unknown kind fails before touching the database, so a 400 proves
+	// the request got past authentication.
+	rec := do(t, s, "GET", "/v1/events?kinds=bogus&access_token=secret", "", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 with access_token, got %d", rec.Code)
+	}
+	rec = do(t, s, "GET", "/v1/events?kinds=bogus&access_token=wrong", "", "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong access_token, got %d", rec.Code)
+	}
+	rec = do(t, s, "GET", "/v1/events?kinds=bogus", "", "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+}
+
+func TestAccessTokenOnlyForEvents(t *testing.T) {
+	s := New(&service.Service{}, "secret")
+	rec := do(t, s, "GET", "/v1/tasks?access_token=secret", "", "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", rec.Code)
+	}
+}
+
+func TestLastEventCursor(t *testing.T) {
+	tests := []struct {
+		header, query string
+		want          string // "" for none
+		wantErr       bool
+	}{
+		{"", "", "", false},
+		{"42", "", "42", false},
+		{"", "7", "7", false},
+		{"42", "7", "42", false},
+		{"0", "", "0", false},
+		{"42.40.39", "", "42.39.40", false},
+		{"abc", "", "", true},
+		{"-3", "", "", true},
+		{"42.43", "", "", true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/v1/events", nil)
+		if tt.query != "" {
+			r.URL.RawQuery = "last_event_id=" + tt.query
+		}
+		if tt.header != "" {
+			r.Header.Set("Last-Event-ID", tt.header)
+		}
+		got, err := lastEventCursor(r)
+		if tt.wantErr {
+			if !errors.Is(err, service.ErrInvalid) {
+				t.Errorf("header %q query %q: expected ErrInvalid, got %v", tt.header, tt.query, err)
+			}
+			continue
+		}
+		if err != nil || (got == nil) != (tt.want == "") || (got != nil && got.String() != tt.want) {
+			t.Errorf("header %q query %q: got %v, %v; want %q", tt.header, tt.query, got, err, tt.want)
+		}
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	project := "web"
+	e := &db.Event{
+		ID:        12,
+		Kind:      "task",
+		ProjectID: &project,
+		Payload:   json.RawMessage(`{"task_id":"t-1","status":"done"}`),
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	var b strings.Builder
+	if err := writeEvent(&b, e, "12.10"); err != nil {
+		t.Fatal(err)
+	}
+	frame := b.String()
+	if !strings.HasPrefix(frame, "id: 12.10\nevent: task\ndata: {") || !strings.HasSuffix(frame, "}\n\n") {
+		t.Errorf("unexpected frame %q", frame)
+	}
+	if strings.Count(frame, "\n") != 4 {
+		t.Errorf("data must be a single line, got %q", frame)
+	}
+	data := strings.TrimSuffix(strings.SplitN(frame, "data: ", 2)[1], "\n\n")
+	var got db.Event
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("data is not JSON: %v", err)
+	}
+	if got.ID != 12 || *got.ProjectID != "web" {
+		t.Errorf("round trip mismatch: %+v", got)
+	}
+}
+
+func TestNotifierBroadcast(t *testing.T) {
+	n := newNotifier()
+	ch, cancel := n.subscribe()
+	n.broadcast()
+	n.broadcast() // coalesced, must not block
+	select {
+	case <-ch:
+	default:
+		t.Fatal("expected a wakeup")
+	}
+	cancel()
+	n.broadcast()
+	select {
+	case <-ch:
+		t.Error("unsubscribed channel woke up")
+	default:
+	}
+}
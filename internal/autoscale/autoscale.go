@@ -0,0 +1,110 @@
+// Package autoscale decides how many agents `minuano run --autoscale` keeps
+// running, from the number of ready tasks and of idle agents. It only
+// decides; spawning and draining agents is left to the caller.
+package autoscale
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions of a Decision.
+const (
+	ScaleUp   = "scale_up"
+	ScaleDown = "scale_down"
+)
+
+// Policy bounds the agent count and paces changes to it.
+type Policy struct {
+	Min, Max int
+	// UpCooldown is the least time between two scale-ups.
+	UpCooldown time.Duration
+	// DownCooldown is the least time between any scaling and a scale-down,
+	// so agents spawned for a burst are not drained before they claim work.
+	DownCooldown time.Duration
+}
+
+// ParseRange parses "min:max", as given to --autoscale.
+func ParseRange(s string) (min, max int, err error) {
+	lo, hi, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid autoscale range %q (want min:max)", s)
+	}
+	if min, err = strconv.Atoi(strings.TrimSpace(lo)); err != nil {
+		return 0, 0, fmt.Errorf("invalid autoscale minimum %q", lo)
+	}
+	if max, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil {
+		return 0, 0, fmt.Errorf("invalid autoscale maximum %q", hi)
+	}
+	if min < 0 || max < 1 || min > max {
+		return 0, 0, fmt.Errorf("invalid autoscale range %q (want 0 <= min <= max, max >= 1)", s)
+	}
+	return min, max, nil
+}
+
+// State is what the controller sees on one tick.
+type State struct {
+	// Ready is the number of ready tasks the agents could claim.
+	Ready int
+	// Idle and Working count the controller's agents.
+	Idle, Working int
+}
+
+// Agents is the number of agents in the state.
+func (s State) Agents() int { return s.Idle + s.Working }
+
+// Decision is a change to the agent count. Delta is positive to spawn that
+// many agents and negative to drain that many idle ones.
+type Decision struct {
+	Action string
+	Delta  int
+	Reason string
+}
+
+// Controller applies a Policy over successive states, remembering when it
+// last scaled.
+type Controller struct {
+	Policy
+	lastUp, lastScale time.Time
+}
+
+// NewController returns a controller for p that has never scaled.
+func NewController(p Policy) *Controller {
+	return &Controller{Policy: p}
+}
+
+// Decide returns the change to make in state s at time now, or nil to leave
+// the agents alone. A returned decision is assumed to be carried out.
+//
+// Agents are spawned while ready tasks outnumber idle agents, and idle agents
+// beyond the ready tasks are drained, within [Min, Max]. Falling below Min
+// (an agent exited) is corrected at once; everything else waits out the
+// cooldowns.
+func (c *Controller) Decide(s State, now time.Time) *Decision {
+	n := s.Agents()
+	var d *Decision
+	switch {
+	case n < c.Min:
+		d = &Decision{ScaleUp, c.Min - n, fmt.Sprintf("%d agent(s), below the minimum of %d", n, c.Min)}
+	case s.Ready > s.Idle && n < c.Max:
+		if !c.lastUp.IsZero() && now.Sub(c.lastUp) < c.UpCooldown {
+			return nil
+		}
+		d = &Decision{ScaleUp, min(s.Ready-s.Idle, c.Max-n), fmt.Sprintf("%d ready task(s), %d idle agent(s)", s.Ready, s.Idle)}
+	case s.Idle > s.Ready && n > c.Min:
+		if !c.lastScale.IsZero() && now.Sub(c.lastScale) < c.DownCooldown {
+			return nil
+		}
+		d = &Decision{ScaleDown, -min(s.Idle-s.Ready, n-c.Min), fmt.Sprintf("%d idle agent(s), %d ready task(s)", s.Idle, s.Ready)}
+	default:
+		return nil
+	}
+
+	c.lastScale = now
+	if d.Delta > 0 {
+		c.lastUp = now
+	}
+	return d
+}
@@ -0,0 +1,107 @@
+package autoscale
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		min, max int
+		wantErr  bool
+	}{
+		{"1:4", 1, 4, false},
+		{"0:2", 0, 2, false},
+		{" 2 : 2 ", 2, 2, false},
+		{"4", 0, 0, true},
+		{"4:1", 0, 0, true},
+		{"0:0", 0, 0, true},
+		{"-1:3", 0, 0, true},
+		{"a:3", 0, 0, true},
+		{"1:b", 0, 0, true},
+	}
+	for _, tt := range tests {
+		min, max, err := ParseRange(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRange(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (min != tt.min || max != tt.max) {
+			t.Errorf("ParseRange(%q) = %d:%d, want %d:%d", tt.in, min, max, tt.min, tt.max)
+		}
+	}
+}
+
+func TestDecide(t *testing.T) {
+	p := Policy{Min: 1, Max: 4, UpCooldown: 30 * time.Second, DownCooldown: 2 * time.Minute}
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		state State
+		want  int // delta, 0 for no decision
+	}{
+		{"below min", State{}, 1},
+		{"ready exceeds idle", State{Ready: 5, Idle: 1}, 3},
+		{"capped at max", State{Ready: 10, Working: 3}, 1},
+		{"at max", State{Ready: 10, Working: 4}, 0},
+		{"balanced", State{Ready: 1, Idle: 1, Working: 2}, 0},
+		{"drain idle", State{Idle: 3, Working: 1}, -3},
+		{"drain keeps min", State{Idle: 3}, -2},
+		{"drain keeps agents for ready work", State{Ready: 1, Idle: 3}, -2},
+		{"at min", State{Idle: 1}, 0},
+	}
+	for _, tt := range tests {
+		d := NewController(p).Decide(tt.state, t0)
+		got := 0
+		if d != nil {
+			got = d.Delta
+			if (got > 0) != (d.Action == ScaleUp) || d.Reason == "" {
+				t.Errorf("%s: inconsistent decision %+v", tt.name, d)
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: delta = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecideCooldowns(t *testing.T) {
+	c := NewController(Policy{Min: 0, Max: 8, UpCooldown: 30 * time.Second, DownCooldown: 2 * time.Minute})
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if d := c.Decide(State{Ready: 2}, t0); d == nil || d.Delta != 2 {
+		t.Fatalf("first scale-up = %+v", d)
+	}
+	if d := c.Decide(State{Ready: 4, Working: 2}, t0.Add(10*time.Second)); d != nil {
+		t.Errorf("scale-up within cooldown: %+v", d)
+	}
+	if d := c.Decide(State{Ready: 4, Working: 2}, t0.Add(30*time.Second)); d == nil || d.Delta != 4 {
+		t.Errorf("scale-up after cooldown = %+v", d)
+	}
+
+	// The queue drains; idle agents stay until the down cooldown has passed
+	// since the last scaling.
+	idle := State{Idle: 6}
+	if d := c.Decide(idle, t0.Add(time.Minute)); d != nil {
+		t.Errorf("scale-down within cooldown: %+v", d)
+	}
+	if d := c.Decide(idle, t0.Add(30*time.Second+2*time.Minute)); d == nil || d.Delta != -6 {
+		t.Errorf("scale-down after cooldown = %+v", d)
+	}
+
+	// A scale-down does not delay the next scale-up.
+	if d := c.Decide(State{Ready: 1}, t0.Add(30*time.Second+2*time.Minute+time.Second)); d == nil || d.Delta != 1 {
+		t.Errorf("scale-up after scale-down = %+v", d)
+	}
+}
+
+func TestDecideBelowMinIgnoresCooldown(t *testing.T) {
+	c := NewController(Policy{Min: 2, Max: 4, UpCooldown: time.Hour, DownCooldown: time.Hour})
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Decide(State{}, t0)
+	if d := c.Decide(State{Idle: 1}, t0.Add(time.Second)); d == nil || d.Delta != 1 {
+		t.Errorf("replacing an exited agent = %+v", d)
+	}
+}
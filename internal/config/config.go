@@ -0,0 +1,295 @@
+// Package config resolves Minuano's defaults: the project, tmux session,
+// runtime, base branch, test command and its timeout, attempts, agent count,
+// worktree mode, agent profile and prompts. Each setting comes from the first of its
+// environment variable, the repository's .minuano.yaml and a built-in value;
+// command-line flags override all of them.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otavio/minuano/internal/git"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the config file looked up at the repository root.
+const FileName = ".minuano.yaml"
+
+// SourceBuiltin is the source of a value nothing overrides. Other sources
+// are "env <VAR>", the config file's path and "flag --<name>".
+const SourceBuiltin = "built-in"
+
+// setting is one configurable default.
+type setting struct {
+	key     string // in the file; prompts.* are nested under prompts:
+	env     string
+	builtin string
+	kind    string // string, int, count, bool or duration
+}
+
+var settings = []setting{
+	{"project", "MINUANO_PROJECT", "", "string"},
+	{"session", "MINUANO_SESSION", "minuano", "string"},
+	{"runtime", "MINUANO_RUNTIME", "tmux", "string"},
+	{"base_branch", "MINUANO_BASE_BRANCH", "main", "string"},
+	{"test_cmd", "MINUANO_TEST_CMD", "go test ./...", "string"},
+	{"gate_timeout", "MINUANO_GATE_TIMEOUT", "10m", "duration"},
+	{"flaky_reruns", "MINUANO_FLAKY_RERUNS", "0", "count"},
+	{"max_attempts", "MINUANO_MAX_ATTEMPTS", "3", "int"},
+	{"agents", "MINUANO_AGENTS", "1", "int"},
+	{"worktrees", "MINUANO_WORKTREES", "false", "bool"},
+	{"profile", "MINUANO_PROFILE", "claude", "string"},
+	{"prompts.agent", "MINUANO_AGENT_PROMPT", "", "string"},
+	{"prompts.planner", "MINUANO_PLANNER_PROMPT", "", "string"},
+}
+
+// Value is a resolved setting and where it came from.
+type Value struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Env    string `json:"env"`
+}
+
+// Config is the resolved settings.
+type Config struct {
+	Project    string
+	Session    string
+	Runtime    string
+	BaseBranch string
+	TestCmd    string
+	// GateTimeout bounds a completion gate run; 0 for none.
+	GateTimeout time.Duration
+	// FlakyReruns is how many times a go test gate's failed tests are re-run
+	// before the gate fails; 0 for none.
+	FlakyReruns int
+	MaxAttempts int
+	Agents      int
+	Worktrees   bool
+	Profile     string
+	// AgentPrompt and PlannerPrompt are prompt files replacing
+	// claude/CLAUDE.md and claude/planner-system-prompt.md; empty to keep those.
+	AgentPrompt   string
+	PlannerPrompt string
+
+	// File is the config file that was read, if any.
+	File string
+
+	values []Value
+}
+
+// Path is where the config file is: $MINUANO_CONFIG, or .minuano.yaml at the
+// repository root. It is empty outside a repository.
+func Path() string {
+	if f := os.Getenv("MINUANO_CONFIG"); f != "" {
+		return f
+	}
+	root, err := git.RepoRoot()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(root, FileName)
+}
+
+// Load resolves the settings from the environment, the config file (which
+// may be missing) and the built-ins.
+func Load() (*Config, error) {
+	path, data, err := ReadFile()
+	if err != nil {
+		return nil, err
+	}
+	return Resolve(path, data, os.Getenv)
+}
+
+// ReadFile reads the config file at Path. A missing file gives an empty
+// path and no error.
+func ReadFile() (path string, data []byte, err error) {
+	path = Path()
+	if path == "" {
+		return "", nil, nil
+	}
+	data, err = os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return path, data, nil
+}
+
+// Resolve resolves the settings from getenv, the config file contents data
+// read from path (no file if path is empty) and the built-ins. Relative
+// prompt paths in the file are relative to the file.
+func Resolve(path string, data []byte, getenv func(string) string) (*Config, error) {
+	file := map[string]string{}
+	if path != "" {
+		var err error
+		if file, err = parse(path, data); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &Config{File: path}
+	for _, s := range settings {
+		v := Value{Key: s.key, Value: s.builtin, Source: SourceBuiltin, Env: s.env}
+		if fv, ok := file[s.key]; ok {
+			v.Value, v.Source = fv, path
+			if strings.HasPrefix(s.key, "prompts.") && fv != "" && !filepath.IsAbs(fv) {
+				v.Value = filepath.Join(filepath.Dir(path), fv)
+			}
+		}
+		if ev := getenv(s.env); ev != "" {
+			v.Value, v.Source = ev, "env "+s.env
+		}
+		if err := c.set(s, v); err != nil {
+			return nil, err
+		}
+		c.values = append(c.values, v)
+	}
+	return c, nil
+}
+
+// Override sets key to value from a higher-precedence source, such as a
+// command-line flag.
+func (c *Config) Override(key, value, source string) error {
+	for i, s := range settings {
+		if s.key == key {
+			v := Value{Key: key, Value: value, Source: source, Env: s.env}
+			if err := c.set(s, v); err != nil {
+				return err
+			}
+			c.values[i] = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown setting %q", key)
+}
+
+// Values returns every setting with its resolved value and source.
+func (c *Config) Values() []Value {
+	return c.values
+}
+
+// Source returns where key's value came from.
+func (c *Config) Source(key string) string {
+	for _, v := range c.values {
+		if v.Key == key {
+			return v.Source
+		}
+	}
+	return ""
+}
+
+func (c *Config) set(s setting, v Value) error {
+	var n int
+	var b bool
+	var d time.Duration
+	var err error
+	switch s.kind {
+	case "int":
+		n, err = strconv.Atoi(v.Value)
+		if err == nil && n < 1 {
+			err = errors.New("must be at least 1")
+		}
+	case "count":
+		n, err = strconv.Atoi(v.Value)
+		if err == nil && n < 0 {
+			err = errors.New("must not be negative")
+		}
+	case "bool":
+		b, err = strconv.ParseBool(v.Value)
+	case "duration":
+		d, err = time.ParseDuration(v.Value)
+		if err == nil && d < 0 {
+			err = errors.New("must not be negative")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s %q (from %s): %v", s.key, v.Value, v.Source, err)
+	}
+
+	switch s.key {
+	case "project":
+		c.Project = v.Value
+	case "session":
+		c.Session = v.Value
+	case "runtime":
+		c.Runtime = v.Value
+	case "base_branch":
+		c.BaseBranch = v.Value
+	case "test_cmd":
+		c.TestCmd = v.Value
+	case "gate_timeout":
+		c.GateTimeout = d
+	case "flaky_reruns":
+		c.FlakyReruns = n
+	case "max_attempts":
+		c.MaxAttempts = n
+	case "agents":
+		c.Agents = n
+	case "worktrees":
+		c.Worktrees = b
+	case "profile":
+		c.Profile = v.Value
+	case "prompts.agent":
+		c.AgentPrompt = v.Value
+	case "prompts.planner":
+		c.PlannerPrompt = v.Value
+	}
+	return nil
+}
+
+// parse reads the config file into flat keys, rejecting unknown ones so a
+// typo doesn't go unnoticed.
+func parse(path string, data []byte) (map[string]string, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	out := map[string]string{}
+	for k, v := range doc {
+		if k == "prompts" {
+			prompts, ok := v.(map[string]any)
+			if !ok && v != nil {
+				return nil, fmt.Errorf("%s: prompts must be a mapping", path)
+			}
+			for pk, pv := range prompts {
+				if err := flatten(out, "prompts."+pk, pv); err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+			}
+			continue
+		}
+		if err := flatten(out, k, v); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return out, nil
+}
+
+func flatten(out map[string]string, key string, v any) error {
+	known := false
+	for _, s := range settings {
+		known = known || s.key == key
+	}
+	if !known {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	switch v := v.(type) {
+	case nil:
+	case string:
+		out[key] = v
+	case int, bool, float64:
+		out[key] = fmt.Sprint(v)
+	default:
+		return fmt.Errorf("%s must be a scalar", key)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func noEnv(string) string { return "" }
+
+func TestResolveBuiltins(t *testing.T) {
+	c, err := Resolve("", nil, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Session != "minuano" || c.Runtime != "tmux" || c.BaseBranch != "main" || c.TestCmd != "go test ./..." ||
+		c.GateTimeout != 10*time.Minute || c.FlakyReruns != 0 || c.MaxAttempts != 3 || c.Agents != 1 || c.Worktrees || c.Profile != "claude" || c.Project != "" {
+		t.Errorf("unexpected built-ins: %+v", c)
+	}
+	for _, v := range c.Values() {
+		if v.Source != SourceBuiltin {
+			t.Errorf("%s from %q, want built-in", v.Key, v.Source)
+		}
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	data := []byte(`
+project: web
+session: work
+max_attempts: 5
+worktrees: true
+gate_timeout: 90s
+flaky_reruns: 2
+prompts:
+  agent: prompts/agent.md
+  planner: /abs/planner.md
+`)
+	env := map[string]string{"MINUANO_SESSION": "env-session", "MINUANO_AGENTS": "4"}
+	c, err := Resolve("/repo/.minuano.yaml", data, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, value, source string
+	}{
+		{"project", "web", "/repo/.minuano.yaml"},
+		{"session", "env-session", "env MINUANO_SESSION"},
+		{"agents", "4", "env MINUANO_AGENTS"},
+		{"max_attempts", "5", "/repo/.minuano.yaml"},
+		{"gate_timeout", "90s", "/repo/.minuano.yaml"},
+		{"flaky_reruns", "2", "/repo/.minuano.yaml"},
+		{"base_branch", "main", SourceBuiltin},
+		{"prompts.agent", "/repo/prompts/agent.md", "/repo/.minuano.yaml"},
+		{"prompts.planner", "/abs/planner.md", "/repo/.minuano.yaml"},
+	}
+	values := map[string]Value{}
+	for _, v := range c.Values() {
+		values[v.Key] = v
+	}
+	for _, tt := range tests {
+		v := values[tt.key]
+		if v.Value != tt.value || v.Source != tt.source {
+			t.Errorf("%s = %q from %q, want %q from %q", tt.key, v.Value, v.Source, tt.value, tt.source)
+		}
+	}
+	if c.Session != "env-session" || c.Agents != 4 || c.MaxAttempts != 5 || !c.Worktrees || c.GateTimeout != 90*time.Second || c.FlakyReruns != 2 || c.AgentPrompt != "/repo/prompts/agent.md" {
+		t.Errorf("typed fields not set: %+v", c)
+	}
+
+	if err := c.Override("session", "flag-session", "flag --session"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Session != "flag-session" || c.Source("session") != "flag --session" {
+		t.Errorf("override not applied: %q from %q", c.Session, c.Source("session"))
+	}
+	if err := c.Override("nope", "x", "flag"); err == nil {
+		t.Error("expected an error overriding an unknown setting")
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		env  map[string]string
+		want string
+	}{
+		{"projct: web\n", nil, `unknown setting "projct"`},
+		{"prompts:\n  agnt: x.md\n", nil, `unknown setting "prompts.agnt"`},
+		{"prompts: x.md\n", nil, "prompts must be a mapping"},
+		{"agents: [1, 2]\n", nil, "must be a scalar"},
+		{"agents: 0\n", nil, "must be at least 1"},
+		{"worktrees: maybe\n", nil, "worktrees"},
+		{"gate_timeout: soon\n", nil, "gate_timeout"},
+		{"gate_timeout: -1m\n", nil, "must not be negative"},
+		{"flaky_reruns: -1\n", nil, "must not be negative"},
+		{"project: [\n", nil, "parsing"},
+		{"", map[string]string{"MINUANO_MAX_ATTEMPTS": "many"}, "env MINUANO_MAX_ATTEMPTS"},
+	}
+	for _, tt := range tests {
+		_, err := Resolve("/repo/.minuano.yaml", []byte(tt.data), func(k string) string { return tt.env[k] })
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Resolve(%q) error = %v, want one containing %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestPathFromEnv(t *testing.T) {
+	t.Setenv("MINUANO_CONFIG", "/etc/minuano.yaml")
+	if got := Path(); got != "/etc/minuano.yaml" {
+		t.Errorf("Path() = %q", got)
+	}
+}
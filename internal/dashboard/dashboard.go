@@ -0,0 +1,22 @@
+// Package dashboard serves the embedded single-page web dashboard. The page
+// is static; everything it shows comes from the REST API (internal/api) on
+// the same origin, with live updates from /v1/events.
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the dashboard's files.
+func Handler() http.Handler {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err) // the embedded tree is fixed at build time
+	}
+	return http.FileServerFS(sub)
+}
@@ -0,0 +1,58 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec
+}
+
+func TestIndexServed(t *testing.T) {
+	rec := get(t, "/")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	body := rec.Body.String()
+	for _, ref := range []string{`src="app.js"`, `href="style.css"`} {
+		if !strings.Contains(body, ref) {
+			t.Errorf("index.html should reference %s", ref)
+		}
+	}
+}
+
+func TestAssetsServed(t *testing.T) {
+	for path, ct := range map[string]string{"/app.js": "javascript", "/style.css": "text/css"} {
+		rec := get(t, path)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.Contains(got, ct) {
+			t.Errorf("%s: content type %q, want %s", path, got, ct)
+		}
+	}
+}
+
+func TestAppUsesAPIEndpoints(t *testing.T) {
+	body := get(t, "/app.js").Body.String()
+	for _, endpoint := range []string{"/v1/graph", "/v1/agents", "/v1/merge-queue", "/v1/events", "/approve", "/reject", "/pane"} {
+		if !strings.Contains(body, endpoint) {
+			t.Errorf("app.js does not use %s", endpoint)
+		}
+	}
+}
+
+func TestUnknownPath(t *testing.T) {
+	if rec := get(t, "/nope.txt"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,443 @@
+// Minuano dashboard. Reads the REST API on the same origin and keeps itself
+// current from the /v1/events stream. No build step, no dependencies.
+"use strict";
+
+const STATUSES = ["draft", "pending", "ready", "claimed", "pending_approval", "done", "failed", "rejected"];
+const LIGHT = new Set(["draft"]);
+const PANE_LINES = 40;
+const PANE_REFRESH_MS = 3000;
+
+const state = {
+  token: localStorage.getItem("minuanoToken") || "",
+  project: new URLSearchParams(location.search).get("project") || "",
+  graph: { tasks: [], dependencies: {} },
+  agents: [],
+  merges: [],
+  openPanes: new Set(),
+  detailID: null,
+  stream: null,
+};
+
+// ---- helpers ---------------------------------------------------------------
+
+function $(id) { return document.getElementById(id); }
+
+// el builds DOM nodes without innerHTML, so task text can't inject markup.
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (v === undefined || v === null || v === false) continue;
+    if (k.startsWith("on")) node.addEventListener(k.slice(2), v);
+    else if (k === "class") node.className = v;
+    else node.setAttribute(k, v === true ? "" : v);
+  }
+  for (const c of children.flat()) {
+    if (c === undefined || c === null || c === false) continue;
+    node.append(c instanceof Node ? c : document.createTextNode(String(c)));
+  }
+  return node;
+}
+
+function svg(tag, attrs, ...children) {
+  const node = document.createElementNS("http://www.w3.org/2000/svg", tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (k.startsWith("on")) node.addEventListener(k.slice(2), v);
+    else node.setAttribute(k, v);
+  }
+  for (const c of children) node.append(c instanceof Node ? c : document.createTextNode(String(c)));
+  return node;
+}
+
+function badge(status) { return el("span", { class: "badge s-" + status }, status.replace("_", " ")); }
+
+function ago(ts) {
+  if (!ts) return "—";
+  const s = Math.max(0, Math.round((Date.now() - new Date(ts)) / 1000));
+  if (s < 60) return s + "s ago";
+  if (s < 3600) return Math.floor(s / 60) + "m ago";
+  if (s < 86400) return Math.floor(s / 3600) + "h ago";
+  return Math.floor(s / 86400) + "d ago";
+}
+
+function clip(s, n) { return s.length > n ? s.slice(0, n - 1) + "…" : s; }
+
+function fill(id, ...children) { $(id).replaceChildren(...children.flat()); }
+
+let errorTimer;
+function showError(err) {
+  const box = $("error");
+  box.textContent = err.message || String(err);
+  box.hidden = false;
+  clearTimeout(errorTimer);
+  errorTimer = setTimeout(() => { box.hidden = true; }, 6000);
+}
+
+async function api(method, path, body) {
+  const headers = { Authorization: "Bearer " + state.token };
+  if (body !== undefined) headers["Content-Type"] = "application/json";
+  const res = await fetch(path, { method, headers, body: body === undefined ? undefined : JSON.stringify(body) });
+  if (res.status === 401) {
+    signOut();
+    throw new Error("invalid API token");
+  }
+  if (!res.ok) {
+    const e = await res.json().catch(() => ({}));
+    throw new Error(e.error || res.status + " " + res.statusText);
+  }
+  return res.status === 204 ? null : res.json();
+}
+
+function projectQuery(extra) {
+  const q = new URLSearchParams(extra || {});
+  if (state.project) q.set("project", state.project);
+  const s = q.toString();
+  return s ? "?" + s : "";
+}
+
+function taskByID(id) { return state.graph.tasks.find((t) => t.id === id); }
+
+function taskLink(id) {
+  return el("span", { class: "link mono", onclick: (e) => { e.stopPropagation(); openTask(id); } }, id);
+}
+
+// ---- loading ---------------------------------------------------------------
+
+async function loadGraph() {
+  state.graph = await api("GET", "/v1/graph" + projectQuery());
+  renderGraph();
+  renderApprovals();
+  renderMerges(); // project filtering uses the task list
+}
+
+async function loadAgents() {
+  state.agents = await api("GET", "/v1/agents");
+  renderAgents();
+}
+
+async function loadMerges() {
+  state.merges = await api("GET", "/v1/merge-queue");
+  renderMerges();
+}
+
+function loadAll() {
+  return Promise.all([loadGraph(), loadAgents(), loadMerges()]).catch(showError);
+}
+
+// Coalesce bursts of events into one reload per resource.
+const pending = {};
+function reloadSoon(name, fn) {
+  if (pending[name]) return;
+  pending[name] = setTimeout(() => {
+    delete pending[name];
+    fn().catch(showError);
+  }, 250);
+}
+
+// ---- agents ----------------------------------------------------------------
+
+function renderAgents() {
+  $("agents-count").textContent = state.agents.length || "";
+  if (!state.agents.length) {
+    fill("agents", el("p", { class: "empty" }, "No agents."));
+    return;
+  }
+  const rows = [];
+  for (const a of state.agents) {
+    const open = state.openPanes.has(a.id);
+    rows.push(el("tr", { class: "clickable", title: "Show pane tail", onclick: () => togglePane(a.id) },
+      el("td", { class: "mono" }, (open ? "▾ " : "▸ ") + a.id),
+      el("td", {}, badge(a.status)),
+      el("td", {}, a.task_id ? taskLink(a.task_id) : "—"),
+      el("td", {}, ago(a.last_seen))));
+    if (open) {
+      rows.push(el("tr", {}, el("td", { colspan: 4 }, el("pre", { class: "pane", id: "pane-" + a.id }, "…"))));
+    }
+  }
+  fill("agents", el("table", {},
+    el("thead", {}, el("tr", {}, el("th", {}, "Agent"), el("th", {}, "Status"), el("th", {}, "Task"), el("th", {}, "Seen"))),
+    el("tbody", {}, rows)));
+  for (const id of state.openPanes) refreshPane(id);
+}
+
+function togglePane(id) {
+  if (state.openPanes.has(id)) state.openPanes.delete(id);
+  else state.openPanes.add(id);
+  renderAgents();
+}
+
+async function refreshPane(id) {
+  const pre = $("pane-" + id);
+  if (!pre) return;
+  try {
+    const res = await api("GET", "/v1/agents/" + encodeURIComponent(id) + "/pane?lines=" + PANE_LINES);
+    pre.textContent = res.text || "(empty)";
+    pre.scrollTop = pre.scrollHeight;
+  } catch (err) {
+    pre.textContent = "unavailable: " + err.message;
+  }
+}
+
+// Pane output doesn't raise events, so open panes are polled.
+setInterval(() => { for (const id of state.openPanes) refreshPane(id); }, PANE_REFRESH_MS);
+
+// ---- approvals -------------------------------------------------------------
+
+function renderApprovals() {
+  const waiting = state.graph.tasks.filter((t) => t.status === "pending_approval");
+  $("approvals-count").textContent = waiting.length || "";
+  if (!waiting.length) {
+    fill("approvals", el("p", { class: "empty" }, "Nothing awaiting approval."));
+    return;
+  }
+  fill("approvals", waiting.map((t) => el("div", { class: "item" },
+    el("div", { class: "title" }, t.title),
+    el("div", { class: "meta" }, taskLink(t.id), " · priority " + t.priority, t.project_id ? " · " + t.project_id : ""),
+    el("div", { class: "actions" },
+      el("button", { class: "approve", type: "button", onclick: () => approve(t.id) }, "Approve"),
+      el("button", { class: "reject", type: "button", onclick: () => reject(t.id) }, "Reject")))));
+}
+
+async function approve(id) {
+  try {
+    await api("POST", "/v1/tasks/" + encodeURIComponent(id) + "/approve", { by: "dashboard" });
+    await loadGraph();
+  } catch (err) { showError(err); }
+}
+
+async function reject(id) {
+  const reason = prompt("Reason for rejecting " + id + "?");
+  if (reason === null) return;
+  try {
+    await api("POST", "/v1/tasks/" + encodeURIComponent(id) + "/reject", { reason });
+    await loadGraph();
+  } catch (err) { showError(err); }
+}
+
+// ---- merge queue -----------------------------------------------------------
+
+function renderMerges() {
+  let entries = state.merges.filter((m) => m.status !== "merged");
+  if (state.project) entries = entries.filter((m) => taskByID(m.task_id));
+  $("merges-count").textContent = entries.length || "";
+  if (!entries.length) {
+    fill("merges", el("p", { class: "empty" }, "Queue is empty."));
+    return;
+  }
+  fill("merges", entries.map((m) => el("div", { class: "item" },
+    el("div", {}, badge(m.status), " ", taskLink(m.task_id), " ", el("span", { class: "mono" }, m.branch)),
+    el("div", { class: "meta" }, "by " + m.agent_id + " · queued " + ago(m.enqueued_at)),
+    m.conflict_files && m.conflict_files.length
+      ? el("ul", { class: "files mono" }, m.conflict_files.map((f) => el("li", {}, f)))
+      : null,
+    m.error_msg ? el("div", { class: "meta" }, m.error_msg) : null)));
+}
+
+// ---- task graph ------------------------------------------------------------
+
+const NODE_W = 200, NODE_H = 34, COL_GAP = 70, ROW_GAP = 12, PAD = 10;
+
+// layout places each task in the column after its deepest dependency.
+function layout(tasks, deps) {
+  const ids = new Set(tasks.map((t) => t.id));
+  const depth = new Map();
+  const visiting = new Set();
+  function depthOf(id) {
+    if (depth.has(id)) return depth.get(id);
+    if (visiting.has(id)) return 0; // cycles are rejected server-side; don't hang if one slips in
+    visiting.add(id);
+    let d = 0;
+    for (const dep of deps[id] || []) if (ids.has(dep)) d = Math.max(d, depthOf(dep) + 1);
+    visiting.delete(id);
+    depth.set(id, d);
+    return d;
+  }
+  const columns = [];
+  for (const t of tasks) {
+    const d = depthOf(t.id);
+    (columns[d] = columns[d] || []).push(t);
+  }
+  const pos = new Map();
+  columns.forEach((col, c) => {
+    col.sort((a, b) => STATUSES.indexOf(a.status) - STATUSES.indexOf(b.status) || b.priority - a.priority || a.id.localeCompare(b.id));
+    col.forEach((t, r) => pos.set(t.id, { x: PAD + c * (NODE_W + COL_GAP), y: PAD + r * (NODE_H + ROW_GAP) }));
+  });
+  const rows = Math.max(0, ...columns.map((c) => c.length));
+  return {
+    pos,
+    width: PAD * 2 + columns.length * NODE_W + Math.max(0, columns.length - 1) * COL_GAP,
+    height: PAD * 2 + rows * NODE_H + Math.max(0, rows - 1) * ROW_GAP,
+  };
+}
+
+function renderGraph() {
+  const { tasks, dependencies } = state.graph;
+  $("tasks-count").textContent = tasks.length || "";
+
+  const counts = {};
+  for (const t of tasks) counts[t.status] = (counts[t.status] || 0) + 1;
+  fill("legend", STATUSES.filter((s) => counts[s]).map((s) => el("span", {}, badge(s), " " + counts[s])));
+
+  if (!tasks.length) {
+    fill("graph", el("p", { class: "empty" }, "No tasks."));
+    return;
+  }
+
+  const { pos, width, height } = layout(tasks, dependencies);
+  const root = svg("svg", { width, height, viewBox: `0 0 ${width} ${height}` });
+
+  for (const [id, deps] of Object.entries(dependencies)) {
+    const to = pos.get(id);
+    if (!to) continue;
+    for (const dep of deps) {
+      const from = pos.get(dep);
+      if (!from) continue;
+      const x1 = from.x + NODE_W, y1 = from.y + NODE_H / 2, x2 = to.x, y2 = to.y + NODE_H / 2;
+      const mid = (x1 + x2) / 2;
+      root.append(svg("path", { class: "edge", d: `M${x1},${y1} C${mid},${y1} ${mid},${y2} ${x2},${y2}` }));
+    }
+  }
+
+  for (const t of tasks) {
+    const p = pos.get(t.id);
+    const label = clip(t.title, 28);
+    const g = svg("g", { class: "node" + (LIGHT.has(t.status) ? " light" : ""), transform: `translate(${p.x},${p.y})`, onclick: () => openTask(t.id) },
+      svg("title", {}, `${t.id} — ${t.status}\n${t.title}`),
+      svg("rect", { width: NODE_W, height: NODE_H, rx: 4, style: `fill: var(--${t.status}, var(--pending))` }),
+      svg("text", { x: 8, y: NODE_H / 2 + 4 }, label));
+    root.append(g);
+  }
+  fill("graph", root);
+}
+
+// ---- task detail -----------------------------------------------------------
+
+async function openTask(id) {
+  state.detailID = id;
+  $("detail").hidden = false;
+  await renderDetail().catch(showError);
+}
+
+function closeTask() {
+  state.detailID = null;
+  $("detail").hidden = true;
+}
+
+async function renderDetail() {
+  const id = state.detailID;
+  if (!id) return;
+  const path = "/v1/tasks/" + encodeURIComponent(id);
+  const [t, ctx] = await Promise.all([api("GET", path), api("GET", path + "/context")]);
+  if (state.detailID !== id) return; // closed or switched while loading
+
+  const deps = state.graph.dependencies[t.id] || [];
+  const dependents = Object.entries(state.graph.dependencies).filter(([, d]) => d.includes(t.id)).map(([k]) => k);
+  const row = (k, v) => (v === undefined || v === null || v === "" ? null : [el("dt", {}, k), el("dd", {}, v)]);
+
+  fill("detail-body",
+    el("h3", {}, t.title),
+    el("div", { class: "mono" }, t.id),
+    el("dl", {},
+      row("Status", badge(t.status)),
+      row("Priority", t.priority),
+      row("Project", t.project_id),
+      row("Attempt", `${t.attempt} / ${t.max_attempts}`),
+      row("Claimed by", t.claimed_by),
+      row("Created", ago(t.created_at)),
+      row("Done", t.done_at && ago(t.done_at)),
+      row("Approved by", t.approved_by),
+      row("Rejected", t.rejection_reason),
+      row("Depends on", deps.length ? deps.flatMap((d, i) => (i ? [", ", taskLink(d)] : [taskLink(d)])) : null),
+      row("Blocks", dependents.length ? dependents.flatMap((d, i) => (i ? [", ", taskLink(d)] : [taskLink(d)])) : null)),
+    t.body ? el("pre", { class: "body" }, t.body) : null,
+    el("h4", {}, `Context (${ctx.length})`),
+    ctx.length
+      ? ctx.map((c) => el("div", { class: "ctx" },
+        el("div", { class: "meta" }, c.kind, c.agent_id ? " · " + c.agent_id : "", c.source_task ? " · from " + c.source_task : "", " · " + ago(c.created_at)),
+        el("pre", {}, c.content)))
+      : el("p", { class: "empty" }, "No context yet."));
+}
+
+// ---- live updates ----------------------------------------------------------
+
+function connectStream() {
+  if (state.stream) state.stream.close();
+  const q = new URLSearchParams({ access_token: state.token });
+  if (state.project) q.set("project", state.project);
+  const es = new EventSource("/v1/events?" + q);
+  state.stream = es;
+
+  es.onopen = () => {
+    $("conn").textContent = "live";
+    $("conn").classList.add("live");
+    loadAll(); // catch up on anything missed while disconnected
+  };
+  es.onerror = () => {
+    $("conn").textContent = "reconnecting";
+    $("conn").classList.remove("live");
+  };
+
+  const onTask = (e) => {
+    reloadSoon("graph", loadGraph);
+    const data = JSON.parse(e.data).payload || {};
+    if (state.detailID && data.task_id === state.detailID) reloadSoon("detail", renderDetail);
+  };
+  es.addEventListener("task", onTask);
+  es.addEventListener("agent", () => reloadSoon("agents", loadAgents));
+  es.addEventListener("merge", () => reloadSoon("merges", loadMerges));
+}
+
+// ---- session ---------------------------------------------------------------
+
+function start() {
+  $("login").hidden = true;
+  $("app").hidden = false;
+  $("logout").hidden = false;
+  $("project").value = state.project;
+  connectStream();
+}
+
+function signOut() {
+  localStorage.removeItem("minuanoToken");
+  state.token = "";
+  if (state.stream) state.stream.close();
+  state.stream = null;
+  closeTask();
+  $("app").hidden = true;
+  $("logout").hidden = true;
+  $("login").hidden = false;
+  $("conn").textContent = "offline";
+  $("conn").classList.remove("live");
+}
+
+$("login-form").addEventListener("submit", (e) => {
+  e.preventDefault();
+  state.token = $("token").value.trim();
+  localStorage.setItem("minuanoToken", state.token);
+  $("token").value = "";
+  start();
+});
+
+$("logout").addEventListener("click", signOut);
+$("detail-close").addEventListener("click", closeTask);
+document.addEventListener("keydown", (e) => { if (e.key === "Escape") closeTask(); });
+
+$("project-form").addEventListener("submit", (e) => {
+  e.preventDefault();
+  state.project = $("project").value.trim();
+  const url = new URL(location.href);
+  if (state.project) url.searchParams.set("project", state.project);
+  else url.searchParams.delete("project");
+  history.replaceState(null, "", url);
+  if (state.token) connectStream();
+});
+
+// A link like /#token=... signs in directly; drop the token from the address bar.
+const hashToken = new URLSearchParams(location.hash.slice(1)).get("token");
+if (hashToken) {
+  state.token = hashToken;
+  localStorage.setItem("minuanoToken", hashToken);
+  history.replaceState(null, "", location.pathname + location.search);
+}
+
+if (state.token) start();
+else signOut();
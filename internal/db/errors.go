@@ -0,0 +1,20 @@
+package db
+
+import "errors"
+
+// Sentinel errors, wrapped with detail by the query functions. Callers that
+// need to tell failures apart (e.g. the HTTP API) match them with errors.Is.
+var (
+	// ErrNotFound means no row matched the given ID.
+	ErrNotFound = errors.New("not found")
+	// ErrAmbiguous means a partial task ID matched several tasks.
+	ErrAmbiguous = errors.New("ambiguous id")
+	// ErrExists means a row with the given ID already exists.
+	ErrExists = errors.New("already exists")
+	// ErrWrongStatus means a transition was attempted from a status that doesn't allow it.
+	ErrWrongStatus = errors.New("wrong status")
+	// ErrVersionMismatch means a conditional update lost a race with another writer.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrLocked means a lock is held by another process.
+	ErrLocked = errors.New("locked")
+)
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventKinds are the kinds written to the event log.
+var EventKinds = []string{"task", "planner", "merge", "agent", "autoscale"}
+
+// Event is one entry of the persisted event log.
+type Event struct {
+	ID        int64           `json:"id"`
+	Kind      string          `json:"kind"`
+	ProjectID *string         `json:"project_id,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	// Matched is false for an event ListEvents' filter leaves out.
+	Matched bool `json:"-"`
+}
+
+// ListEvents returns up to limit events with ID greater than after, or in
+// missing, oldest first. Events the filter leaves out are returned too, with
+// Matched false, so a reader can tell them from IDs that aren't visible.
+// Nil projectID or empty kinds mean no filter on that field.
+func ListEvents(pool *pgxpool.Pool, after int64, missing []int64, projectID *string, kinds []string, limit int) ([]*Event, error) {
+	if kinds == nil {
+		kinds = []string{} // NULL would fail the cardinality check
+	}
+	if missing == nil {
+		missing = []int64{}
+	}
+	rows, err := pool.Query(context.Background(), `
+		SELECT id, kind, project_id, payload, created_at,
+		       ($2::text IS NULL OR project_id = $2)
+		       AND (cardinality($3::text[]) = 0 OR kind = ANY($3))
+		FROM   events
+		WHERE  id > $1 OR id = ANY($5)
+		ORDER  BY id ASC
+		LIMIT  $4
+	`, after, projectID, kinds, limit, missing)
+	if err != nil {
+		return nil, fmt.Errorf("listing events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Kind, &e.ProjectID, &e.Payload, &e.CreatedAt, &e.Matched); err != nil {
+			return nil, fmt.Errorf("scanning event: %w", err)
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// LatestEventID returns the highest event ID, or 0 when the log is empty.
+func LatestEventID(pool *pgxpool.Pool) (int64, error) {
+	var id int64
+	err := pool.QueryRow(context.Background(), `SELECT COALESCE(MAX(id), 0) FROM events`).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("getting latest event id: %w", err)
+	}
+	return id, nil
+}
+
+// PruneEvents deletes events older than before and returns how many were removed.
+func PruneEvents(pool *pgxpool.Pool, before time.Time) (int64, error) {
+	tag, err := pool.Exec(context.Background(), `DELETE FROM events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("pruning events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// RecordEvent appends an event that no trigger writes, such as an autoscaling
+// decision, to the log.
+func RecordEvent(pool *pgxpool.Pool, kind string, projectID *string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding %s event: %w", kind, err)
+	}
+	var proj string
+	if projectID != nil {
+		proj = *projectID
+	}
+	if _, err := pool.Exec(context.Background(), `SELECT record_event($1, $2, $3)`, kind, proj, data); err != nil {
+		return fmt.Errorf("recording %s event: %w", kind, err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/gotest"
+)
+
+// GateRun is a run of one of a task's completion gates.
+type GateRun struct {
+	ID       int64   `json:"id"`
+	TaskID   string  `json:"task_id"`
+	AgentID  *string `json:"agent_id,omitempty"`
+	Attempt  int     `json:"attempt"`
+	Name     string  `json:"name"`
+	Advisory bool    `json:"advisory,omitempty"`
+	Command  string  `json:"command"`
+	Dir      string  `json:"dir"`
+	ExitCode int     `json:"exit_code"`
+	TimedOut bool    `json:"timed_out"`
+	Passed   bool    `json:"passed"`
+	// Flaky is set on a gate that failed, but whose failed tests all passed
+	// when re-run; it counts as passed.
+	Flaky       bool      `json:"flaky,omitempty"`
+	DurationMS  int64     `json:"duration_ms"`
+	OutputBytes int64     `json:"output_bytes"`
+	Artifact    *string   `json:"artifact,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	// FailedTests are the failing tests of a go test gate.
+	FailedTests []*FailedTest `json:"failed_tests,omitempty"`
+}
+
+// FailedTest is a package or test that failed in a gate run.
+type FailedTest struct {
+	ID        int64 `json:"id"`
+	GateRunID int64 `json:"gate_run_id"`
+	Attempt   int   `json:"attempt"`
+	gotest.Failure
+	// Repeat is set if it failed in an earlier attempt too.
+	Repeat bool `json:"repeat,omitempty"`
+	// Flaky is set if it passed when re-run.
+	Flaky bool `json:"flaky,omitempty"`
+}
+
+// FlakyTest is a test that has failed and then passed when re-run, counted
+// across tasks.
+type FlakyTest struct {
+	Package     string    `json:"package"`
+	Test        string    `json:"test"`
+	Occurrences int       `json:"occurrences"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	LastTaskID  *string   `json:"last_task_id,omitempty"`
+}
+
+// SettleGate records the runs of a task's gates and settles the task in the
+// same transaction: done with summary if passed, else the failure is recorded
+// as by RecordFailure, with content as its test_failure context. Failed tests
+// that passed on re-run are counted in flaky_tests.
+func SettleGate(pool *pgxpool.Pool, taskID, agentID string, runs []*GateRun, passed bool, summary, content string) error {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning gate tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, run := range runs {
+		err = tx.QueryRow(ctx, `
+			INSERT INTO gate_runs (task_id, agent_id, attempt, name, advisory, command, dir, exit_code,
+			                       timed_out, passed, flaky, duration_ms, output_bytes, artifact, started_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			RETURNING id
+		`, run.TaskID, run.AgentID, run.Attempt, run.Name, run.Advisory, run.Command, run.Dir, run.ExitCode,
+			run.TimedOut, run.Passed, run.Flaky, run.DurationMS, run.OutputBytes, run.Artifact, run.StartedAt).Scan(&run.ID)
+		if err != nil {
+			return fmt.Errorf("recording gate run: %w", err)
+		}
+		for _, f := range run.FailedTests {
+			f.GateRunID, f.Attempt = run.ID, run.Attempt
+			err = tx.QueryRow(ctx, `
+				INSERT INTO failed_tests (gate_run_id, task_id, attempt, package, test, output, repeat, flaky)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				RETURNING id
+			`, f.GateRunID, run.TaskID, f.Attempt, f.Package, f.Test, f.Output, f.Repeat, f.Flaky).Scan(&f.ID)
+			if err != nil {
+				return fmt.Errorf("recording failed test: %w", err)
+			}
+			if !f.Flaky {
+				continue
+			}
+			_, err = tx.Exec(ctx, `
+				INSERT INTO flaky_tests (package, test, last_task_id)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (package, test) DO UPDATE
+				SET    occurrences  = flaky_tests.occurrences + 1,
+				       last_seen    = NOW(),
+				       last_task_id = EXCLUDED.last_task_id
+			`, f.Package, f.Test, run.TaskID)
+			if err != nil {
+				return fmt.Errorf("counting flaky test: %w", err)
+			}
+		}
+	}
+
+	if passed {
+		err = markDone(ctx, tx, taskID, agentID, summary)
+	} else {
+		err = recordFailure(ctx, tx, taskID, agentID, content)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListGateRuns returns a task's gate runs, oldest first, with their failed
+// tests.
+func ListGateRuns(pool *pgxpool.Pool, taskID string) ([]*GateRun, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT id, task_id, agent_id, attempt, name, advisory, command, dir, exit_code, timed_out,
+		       passed, flaky, duration_ms, output_bytes, artifact, started_at
+		FROM   gate_runs
+		WHERE  task_id = $1
+		ORDER  BY started_at, id
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("listing gate runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*GateRun
+	for rows.Next() {
+		var r GateRun
+		if err := rows.Scan(&r.ID, &r.TaskID, &r.AgentID, &r.Attempt, &r.Name, &r.Advisory, &r.Command, &r.Dir,
+			&r.ExitCode, &r.TimedOut, &r.Passed, &r.Flaky, &r.DurationMS, &r.OutputBytes, &r.Artifact, &r.StartedAt); err != nil {
+			return nil, fmt.Errorf("scanning gate run: %w", err)
+		}
+		runs = append(runs, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	failed, err := ListFailedTests(pool, taskID)
+	if err != nil {
+		return nil, err
+	}
+	byRun := map[int64]*GateRun{}
+	for _, r := range runs {
+		byRun[r.ID] = r
+	}
+	for _, f := range failed {
+		if r := byRun[f.GateRunID]; r != nil {
+			r.FailedTests = append(r.FailedTests, f)
+		}
+	}
+	return runs, nil
+}
+
+// ListFailedTests returns the tests that failed in a task's gate runs, by
+// attempt.
+func ListFailedTests(pool *pgxpool.Pool, taskID string) ([]*FailedTest, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT id, gate_run_id, attempt, package, test, output, repeat, flaky
+		FROM   failed_tests
+		WHERE  task_id = $1
+		ORDER  BY attempt, package, test, id
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("listing failed tests: %w", err)
+	}
+	defer rows.Close()
+
+	var failed []*FailedTest
+	for rows.Next() {
+		var f FailedTest
+		if err := rows.Scan(&f.ID, &f.GateRunID, &f.Attempt, &f.Package, &f.Test, &f.Output, &f.Repeat, &f.Flaky); err != nil {
+			return nil, fmt.Errorf("scanning failed test: %w", err)
+		}
+		failed = append(failed, &f)
+	}
+	return failed, rows.Err()
+}
+
+// ListFlakyTests returns the tests found flaky most often first, at most
+// limit of them (0: all).
+func ListFlakyTests(pool *pgxpool.Pool, limit int) ([]*FlakyTest, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT package, test, occurrences, first_seen, last_seen, last_task_id
+		FROM   flaky_tests
+		ORDER  BY occurrences DESC, last_seen DESC, package, test
+		LIMIT  NULLIF($1, 0)
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing flaky tests: %w", err)
+	}
+	defer rows.Close()
+
+	var tests []*FlakyTest
+	for rows.Next() {
+		var t FlakyTest
+		if err := rows.Scan(&t.Package, &t.Test, &t.Occurrences, &t.FirstSeen, &t.LastSeen, &t.LastTaskID); err != nil {
+			return nil, fmt.Errorf("scanning flaky test: %w", err)
+		}
+		tests = append(tests, &t)
+	}
+	return tests, rows.Err()
+}
+
+// PruneGateArtifacts forgets the output files of gate runs that started
+// before the given time and returns their paths so the files can be removed.
+// The runs themselves are kept.
+func PruneGateArtifacts(pool *pgxpool.Pool, before time.Time) ([]string, error) {
+	rows, err := pool.Query(context.Background(), `
+		WITH old AS (
+			SELECT id, artifact
+			FROM   gate_runs
+			WHERE  artifact IS NOT NULL
+			  AND  started_at < $1
+			FOR UPDATE
+		)
+		UPDATE gate_runs g
+		SET    artifact = NULL
+		FROM   old
+		WHERE  g.id = old.id
+		RETURNING old.artifact
+	`, before)
+	if err != nil {
+		return nil, fmt.Errorf("pruning gate artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("scanning gate artifact path: %w", err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StatusCount is the number of rows in a status, optionally scoped to a project.
+type StatusCount struct {
+	ProjectID string // empty when not applicable or unset
+	Status    string
+	Count     int
+}
+
+// EventCount is an all-time total of one kind of task event for a project.
+type EventCount struct {
+	ProjectID string
+	Event     string // claims | completions | test_failures | reclaims
+	Count     int
+}
+
+// CountTasksByStatus returns task counts grouped by project and status.
+func CountTasksByStatus(pool *pgxpool.Pool) ([]StatusCount, error) {
+	return queryStatusCounts(pool, "counting tasks", `
+		SELECT COALESCE(project_id, ''), status, COUNT(*)
+		FROM   tasks
+		GROUP  BY 1, 2
+	`)
+}
+
+// CountAgentsByStatus returns agent counts grouped by status.
+func CountAgentsByStatus(pool *pgxpool.Pool) ([]StatusCount, error) {
+	return queryStatusCounts(pool, "counting agents", `
+		SELECT '', status, COUNT(*)
+		FROM   agents
+		GROUP  BY 2
+	`)
+}
+
+// CountMergeQueueByStatus returns merge queue entry counts grouped by project and status.
+func CountMergeQueueByStatus(pool *pgxpool.Pool) ([]StatusCount, error) {
+	return queryStatusCounts(pool, "counting merge queue", `
+		SELECT COALESCE(t.project_id, ''), mq.status, COUNT(*)
+		FROM   merge_queue mq
+		JOIN   tasks t ON t.id = mq.task_id
+		GROUP  BY 1, 2
+	`)
+}
+
+// CountClaimable returns, per project, the ready tasks an agent running
+// profile, and bound to projectID if not nil, could claim, up to the room
+// left under the project's max_agents cap.
+func CountClaimable(pool *pgxpool.Pool, profile string, projectID *string) (map[string]int, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT COALESCE(project_id, ''),
+		       LEAST(COUNT(*), COALESCE((
+		           SELECT p.max_agents - (SELECT COUNT(*) FROM tasks c
+		                                  WHERE  c.project_id = p.id AND c.status = 'claimed')
+		           FROM   projects p WHERE p.id = tasks.project_id), COUNT(*)))
+		FROM   tasks
+		WHERE  status = 'ready'
+		  AND  attempt < max_attempts
+		  AND  (metadata->>'profile' IS NULL OR metadata->>'profile' = $1)
+		  AND  ($2::text IS NULL OR project_id = $2)
+		  AND  `+projectOpen+`
+		GROUP  BY project_id
+	`, profile, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("counting claimable tasks: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var project string
+		var n int
+		if err := rows.Scan(&project, &n); err != nil {
+			return nil, fmt.Errorf("scanning claimable count: %w", err)
+		}
+		counts[project] = n
+	}
+	return counts, rows.Err()
+}
+
+func queryStatusCounts(pool *pgxpool.Pool, what, query string) ([]StatusCount, error) {
+	rows, err := pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	defer rows.Close()
+
+	var counts []StatusCount
+	for rows.Next() {
+		var c StatusCount
+		if err := rows.Scan(&c.ProjectID, &c.Status, &c.Count); err != nil {
+			return nil, fmt.Errorf("%s: %w", what, err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// CountTaskEvents returns all-time claim, completion, test failure and reclaim
+// totals per project.
+func CountTaskEvents(pool *pgxpool.Pool) ([]EventCount, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT COALESCE(project_id, ''), 'claims', COUNT(*)
+		FROM   task_transitions WHERE to_status = 'claimed' GROUP BY 1
+		UNION ALL
+		SELECT COALESCE(project_id, ''), 'completions', COUNT(*)
+		FROM   task_transitions WHERE to_status = 'done' GROUP BY 1
+		UNION ALL
+		SELECT COALESCE(t.project_id, ''),
+		       CASE tc.kind WHEN 'test_failure' THEN 'test_failures' ELSE 'reclaims' END,
+		       COUNT(*)
+		FROM   task_context tc
+		JOIN   tasks t ON t.id = tc.task_id
+		WHERE  tc.kind IN ('test_failure', 'reclaimed')
+		GROUP  BY 1, 2
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("counting task events: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []EventCount
+	for rows.Next() {
+		var c EventCount
+		if err := rows.Scan(&c.ProjectID, &c.Event, &c.Count); err != nil {
+			return nil, fmt.Errorf("scanning task event count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// OldestPendingApproval returns how long the oldest pending_approval task has
+// been waiting, or zero when none are.
+func OldestPendingApproval(pool *pgxpool.Pool) (time.Duration, error) {
+	var secs *float64
+	err := pool.QueryRow(context.Background(), `
+		SELECT EXTRACT(EPOCH FROM NOW() - MIN(COALESCE(
+		         (SELECT MAX(tt.created_at) FROM task_transitions tt
+		          WHERE  tt.task_id = t.id AND tt.to_status = 'pending_approval'),
+		         t.created_at)))::float8
+		FROM   tasks t
+		WHERE  t.status = 'pending_approval'
+	`).Scan(&secs)
+	if err != nil {
+		return 0, fmt.Errorf("querying oldest pending approval: %w", err)
+	}
+	if d := secondsToDuration(secs); d != nil {
+		return *d, nil
+	}
+	return 0, nil
+}
+
+// Listen subscribes to a NOTIFY channel and calls fn with each payload until
+// ctx is cancelled. It holds one pool connection for its lifetime.
+func Listen(ctx context.Context, pool *pgxpool.Pool, channel string, fn func(payload string)) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring listen connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("listening on %s: %w", channel, err)
+	}
+	// Don't hand a subscribed connection back to the pool.
+	defer conn.Exec(context.Background(), "UNLISTEN *")
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("waiting for %s notification: %w", channel, err)
+		}
+		fn(n.Payload)
+	}
+}
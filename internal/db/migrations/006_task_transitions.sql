@@ -0,0 +1,44 @@
+-- Status history for tasks, used by `minuano stats`.
+-- tasks.claimed_at/claimed_by are cleared on completion, so durations and
+-- per-agent outcomes have to be reconstructed from this log.
+
+CREATE TABLE task_transitions (
+  id          BIGSERIAL   PRIMARY KEY,
+  task_id     TEXT        NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  project_id  TEXT,
+  agent_id    TEXT,
+  from_status TEXT,
+  to_status   TEXT        NOT NULL,
+  attempt     INTEGER     NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_transitions_task   ON task_transitions(task_id, created_at);
+CREATE INDEX idx_transitions_status ON task_transitions(to_status, created_at);
+
+CREATE OR REPLACE FUNCTION record_task_transition()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    INSERT INTO task_transitions (task_id, project_id, agent_id, from_status, to_status, attempt)
+    VALUES (NEW.id, NEW.project_id, NEW.claimed_by, NULL, NEW.status, NEW.attempt);
+  ELSIF NEW.status != OLD.status THEN
+    -- Completion and release clear claimed_by, so fall back to the previous owner.
+    INSERT INTO task_transitions (task_id, project_id, agent_id, from_status, to_status, attempt)
+    VALUES (NEW.id, NEW.project_id, COALESCE(NEW.claimed_by, OLD.claimed_by),
+            OLD.status, NEW.status, NEW.attempt);
+  END IF;
+  RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER on_task_transition
+AFTER INSERT OR UPDATE OF status ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION record_task_transition();
+
+-- Backfill completions so throughput covers history from before this migration.
+INSERT INTO task_transitions (task_id, project_id, agent_id, from_status, to_status, attempt, created_at)
+SELECT id, project_id, NULL, NULL, 'done', attempt, done_at
+FROM   tasks
+WHERE  status = 'done' AND done_at IS NOT NULL;
@@ -0,0 +1,17 @@
+-- Row version for optimistic concurrency (HTTP ETag / If-Match on tasks).
+-- Bumped on every update, so any change — edit, claim, status — invalidates it.
+
+ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_task_version()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+BEGIN
+  NEW.version := OLD.version + 1;
+  RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER on_task_version
+BEFORE UPDATE ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION bump_task_version();
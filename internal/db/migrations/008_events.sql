@@ -0,0 +1,162 @@
+-- Persisted event log behind `minuano serve --api` /v1/events (SSE).
+-- The task_events / planner_events NOTIFY channels are fire-and-forget; this
+-- log lets a reconnecting client resume from its Last-Event-ID.
+
+CREATE TABLE events (
+  id          BIGSERIAL   PRIMARY KEY,
+  kind        TEXT        NOT NULL,   -- task | planner | merge | agent
+  project_id  TEXT,
+  payload     JSONB       NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_events_created ON events(created_at);
+
+-- record_event appends to the log and wakes listeners on minuano_events.
+-- The advisory lock serialises writers until commit, so event IDs become
+-- visible in order and a reader resuming after ID n can't skip a
+-- lower ID that committed late.
+CREATE OR REPLACE FUNCTION record_event(p_kind TEXT, p_project TEXT, p_payload JSONB)
+RETURNS VOID LANGUAGE plpgsql AS $$
+DECLARE
+  new_id BIGINT;
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('minuano_events'));
+  INSERT INTO events (kind, project_id, payload)
+  VALUES (p_kind, NULLIF(p_project, ''), p_payload)
+  RETURNING id INTO new_id;
+  PERFORM pg_notify('minuano_events', new_id::text);
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION log_task_event()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+DECLARE
+  old_status TEXT := 'none';
+  agent      TEXT := NEW.claimed_by;
+BEGIN
+  IF TG_OP = 'UPDATE' THEN
+    IF NEW.status = OLD.status THEN
+      RETURN NEW;
+    END IF;
+    old_status := OLD.status;
+    -- Completion and release clear claimed_by, so fall back to the previous owner.
+    agent := COALESCE(NEW.claimed_by, OLD.claimed_by);
+  END IF;
+
+  PERFORM record_event('task', NEW.project_id, jsonb_build_object(
+    'task_id',    NEW.id,
+    'title',      NEW.title,
+    'status',     NEW.status,
+    'old_status', old_status,
+    'agent_id',   agent,
+    'attempt',    NEW.attempt
+  ));
+  RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER on_task_event_log
+AFTER INSERT OR UPDATE OF status ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION log_task_event();
+
+CREATE OR REPLACE FUNCTION log_planner_event()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+DECLARE
+  old_status TEXT := 'none';
+BEGIN
+  IF TG_OP = 'UPDATE' THEN
+    IF NEW.status = OLD.status THEN
+      RETURN NEW;
+    END IF;
+    old_status := OLD.status;
+  END IF;
+
+  PERFORM record_event('planner', NEW.project_id, jsonb_build_object(
+    'session_id', NEW.id,
+    'topic_id',   NEW.topic_id,
+    'status',     NEW.status,
+    'old_status', old_status
+  ));
+  RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER on_planner_event_log
+AFTER INSERT OR UPDATE OF status ON planner_sessions
+FOR EACH ROW
+EXECUTE FUNCTION log_planner_event();
+
+CREATE OR REPLACE FUNCTION log_merge_event()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+DECLARE
+  old_status TEXT := 'none';
+BEGIN
+  IF TG_OP = 'UPDATE' THEN
+    IF NEW.status = OLD.status THEN
+      RETURN NEW;
+    END IF;
+    old_status := OLD.status;
+  END IF;
+
+  PERFORM record_event('merge',
+    (SELECT project_id FROM tasks WHERE id = NEW.task_id),
+    jsonb_build_object(
+      'entry_id',       NEW.id,
+      'task_id',        NEW.task_id,
+      'agent_id',       NEW.agent_id,
+      'branch',         NEW.branch,
+      'status',         NEW.status,
+      'old_status',     old_status,
+      'conflict_files', NEW.conflict_files
+    ));
+  RETURN NEW;
+END;
+$$;
+
+CREATE TRIGGER on_merge_event_log
+AFTER INSERT OR UPDATE OF status ON merge_queue
+FOR EACH ROW
+EXECUTE FUNCTION log_merge_event();
+
+-- Agents have no project; events carry the project of the agent's task, if any.
+-- Heartbeats only touch last_seen, so only status changes and (de)registration are logged.
+CREATE OR REPLACE FUNCTION log_agent_event()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+DECLARE
+  rec        agents%ROWTYPE;
+  new_status TEXT;
+  old_status TEXT := 'none';
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    rec := OLD;
+    new_status := 'gone';
+    old_status := OLD.status;
+  ELSE
+    rec := NEW;
+    new_status := NEW.status;
+    IF TG_OP = 'UPDATE' THEN
+      IF NEW.status = OLD.status THEN
+        RETURN NULL;
+      END IF;
+      old_status := OLD.status;
+    END IF;
+  END IF;
+
+  PERFORM record_event('agent',
+    (SELECT project_id FROM tasks WHERE id = rec.task_id),
+    jsonb_build_object(
+      'agent_id',   rec.id,
+      'status',     new_status,
+      'old_status', old_status,
+      'task_id',    rec.task_id
+    ));
+  RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER on_agent_event_log
+AFTER INSERT OR UPDATE OF status OR DELETE ON agents
+FOR EACH ROW
+EXECUTE FUNCTION log_agent_event();
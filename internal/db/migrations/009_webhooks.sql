@@ -0,0 +1,74 @@
+-- Outbound webhooks. Subscriptions are matched against the event log (008)
+-- inside the writing transaction and queued in webhook_deliveries, so an
+-- event is never lost just because no dispatcher was running at the time.
+
+CREATE TABLE webhooks (
+  id          SERIAL      PRIMARY KEY,
+  url         TEXT        NOT NULL,
+  secret      TEXT        NOT NULL,
+  project_id  TEXT,                   -- NULL: every project
+  events      TEXT[]      NOT NULL,   -- empty: every webhook event
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Outbox and delivery log in one: rows stay after delivery for inspection.
+CREATE TABLE webhook_deliveries (
+  id               BIGSERIAL   PRIMARY KEY,
+  webhook_id       INTEGER     NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+  event            TEXT        NOT NULL,
+  payload          JSONB       NOT NULL,
+  status           TEXT        NOT NULL DEFAULT 'pending',  -- pending | delivered | failed
+  attempts         INTEGER     NOT NULL DEFAULT 0,
+  next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_status_code INTEGER,
+  last_error       TEXT,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  delivered_at     TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_hook ON webhook_deliveries(webhook_id, created_at DESC);
+
+-- webhook_event maps a logged event to its webhook event name, or NULL when
+-- the change isn't one subscribers can ask for.
+CREATE OR REPLACE FUNCTION webhook_event(p_kind TEXT, p_payload JSONB)
+RETURNS TEXT LANGUAGE sql IMMUTABLE AS $$
+  SELECT CASE
+    WHEN p_kind = 'task' AND p_payload->>'status' IN ('ready', 'claimed', 'done', 'failed', 'pending_approval')
+      THEN 'task.' || (p_payload->>'status')
+    WHEN p_kind = 'merge' AND p_payload->>'status' = 'conflict'
+      THEN 'merge.conflict'
+  END;
+$$;
+
+CREATE OR REPLACE FUNCTION enqueue_webhook_deliveries()
+RETURNS TRIGGER LANGUAGE plpgsql AS $$
+DECLARE
+  name TEXT := webhook_event(NEW.kind, NEW.payload);
+BEGIN
+  IF name IS NULL THEN
+    RETURN NULL;
+  END IF;
+
+  INSERT INTO webhook_deliveries (webhook_id, event, payload)
+  SELECT w.id, name, jsonb_build_object(
+           'id',         NEW.id,
+           'event',      name,
+           'project_id', NEW.project_id,
+           'created_at', NEW.created_at,
+           'data',       NEW.payload)
+  FROM   webhooks w
+  WHERE  (w.project_id IS NULL OR w.project_id = NEW.project_id)
+    AND  (cardinality(w.events) = 0 OR name = ANY(w.events));
+
+  IF FOUND THEN
+    PERFORM pg_notify('webhook_deliveries', '');
+  END IF;
+  RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER on_event_enqueue_webhooks
+AFTER INSERT ON events
+FOR EACH ROW
+EXECUTE FUNCTION enqueue_webhook_deliveries();
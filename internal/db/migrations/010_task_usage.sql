@@ -0,0 +1,36 @@
+-- Token and cost accounting from Claude Code transcripts.
+--
+-- Each spawned agent runs Claude with a known --session-id, recorded in
+-- agent_sessions so the transcript can still be found after the agent row is
+-- gone. Syncing a session reads its JSONL transcript and upserts one
+-- task_usage row per assistant message, attributed to the task the agent had
+-- claimed when the message was written (NULL between tasks).
+
+CREATE TABLE agent_sessions (
+  session_id  TEXT        PRIMARY KEY,
+  agent_id    TEXT        NOT NULL,
+  started_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ended_at    TIMESTAMPTZ,
+  synced_at   TIMESTAMPTZ
+);
+
+CREATE INDEX idx_agent_sessions_agent ON agent_sessions(agent_id);
+
+CREATE TABLE task_usage (
+  message_id            TEXT          PRIMARY KEY,
+  session_id            TEXT          NOT NULL,
+  agent_id              TEXT          NOT NULL,
+  task_id               TEXT          REFERENCES tasks(id) ON DELETE SET NULL,
+  attempt               INTEGER,
+  project_id            TEXT,
+  model                 TEXT          NOT NULL,
+  input_tokens          BIGINT        NOT NULL DEFAULT 0,
+  output_tokens         BIGINT        NOT NULL DEFAULT 0,
+  cache_creation_tokens BIGINT        NOT NULL DEFAULT 0,
+  cache_read_tokens     BIGINT        NOT NULL DEFAULT 0,
+  cost_usd              NUMERIC(14,6) NOT NULL DEFAULT 0,
+  created_at            TIMESTAMPTZ   NOT NULL
+);
+
+CREATE INDEX idx_task_usage_task    ON task_usage(task_id, attempt);
+CREATE INDEX idx_task_usage_created ON task_usage(created_at);
@@ -0,0 +1,18 @@
+-- Archived agent pane output. Each agent window's output is streamed to
+-- gzip files on disk, one segment per claimed task attempt (task_id NULL for
+-- output between tasks); rows index the files so they can be found after the
+-- agent is gone, and pruned after the retention period.
+
+CREATE TABLE pane_logs (
+  id          BIGSERIAL   PRIMARY KEY,
+  agent_id    TEXT        NOT NULL,
+  task_id     TEXT        REFERENCES tasks(id) ON DELETE SET NULL,
+  attempt     INTEGER,
+  path        TEXT        NOT NULL,
+  bytes       BIGINT      NOT NULL DEFAULT 0,   -- uncompressed
+  started_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ended_at    TIMESTAMPTZ                       -- NULL while being written
+);
+
+CREATE INDEX idx_pane_logs_task  ON pane_logs(task_id, attempt);
+CREATE INDEX idx_pane_logs_agent ON pane_logs(agent_id, started_at);
@@ -0,0 +1,6 @@
+-- Agents can run under different runtimes (see internal/runtime). Each agent
+-- records the runtime that owns it; tmux_session/tmux_window are empty for
+-- non-tmux agents, and pid is set for agents running as a local process.
+
+ALTER TABLE agents ADD COLUMN runtime TEXT NOT NULL DEFAULT 'tmux';
+ALTER TABLE agents ADD COLUMN pid     INTEGER;
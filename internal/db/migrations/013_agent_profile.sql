@@ -0,0 +1,5 @@
+-- Agents run a coding CLI described by a profile (see internal/profile). A
+-- task may require one through metadata->>'profile'; only agents running
+-- that profile can claim it.
+
+ALTER TABLE agents ADD COLUMN profile TEXT NOT NULL DEFAULT 'claude';
@@ -0,0 +1,4 @@
+-- Agents may be bound to a project (run/spawn --project). A bound agent only
+-- claims that project's tasks, whichever claim path it takes.
+
+ALTER TABLE agents ADD COLUMN project_id TEXT;
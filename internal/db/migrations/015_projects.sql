@@ -0,0 +1,30 @@
+-- Projects as records. Tasks, schedules and planner sessions reference one by
+-- ID; NULL settings fall back to the configured defaults (.minuano.yaml).
+
+CREATE TABLE projects (
+  id            TEXT        PRIMARY KEY,
+  name          TEXT,
+  repo_path     TEXT,                  -- merges of its branches only run here
+  base_branch   TEXT,                  -- branches are merged into this one
+  test_cmd      TEXT,                  -- for tasks without their own
+  max_attempts  INTEGER CHECK (max_attempts >= 1),
+  max_agents    INTEGER CHECK (max_agents >= 1),  -- tasks claimed at once
+  paused        BOOLEAN     NOT NULL DEFAULT false,
+  archived_at   TIMESTAMPTZ,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Every project referenced so far becomes a record with default settings.
+INSERT INTO projects (id)
+SELECT project_id FROM tasks            WHERE project_id IS NOT NULL
+UNION
+SELECT project_id FROM schedules        WHERE project_id IS NOT NULL
+UNION
+SELECT project_id FROM planner_sessions WHERE project_id IS NOT NULL;
+
+ALTER TABLE tasks            ADD FOREIGN KEY (project_id) REFERENCES projects(id);
+ALTER TABLE schedules        ADD FOREIGN KEY (project_id) REFERENCES projects(id);
+ALTER TABLE planner_sessions ADD FOREIGN KEY (project_id) REFERENCES projects(id);
+
+-- The base branch is resolved when a merge is enqueued.
+ALTER TABLE merge_queue ALTER COLUMN base_branch DROP DEFAULT;
@@ -0,0 +1,21 @@
+-- Completion gate runs (`minuano verify`, and `minuano worker` on an agent's
+-- behalf): one row per run of a task's test command. The full output is kept
+-- in the artifact file; the failure context only gets an excerpt.
+
+CREATE TABLE gate_runs (
+  id           BIGSERIAL   PRIMARY KEY,
+  task_id      TEXT        NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  agent_id     TEXT,
+  attempt      INTEGER     NOT NULL,
+  command      TEXT        NOT NULL,
+  dir          TEXT        NOT NULL,
+  exit_code    INTEGER     NOT NULL,   -- -1: killed by a signal
+  timed_out    BOOLEAN     NOT NULL DEFAULT false,
+  passed       BOOLEAN     NOT NULL,
+  duration_ms  BIGINT      NOT NULL,
+  output_bytes BIGINT      NOT NULL DEFAULT 0,
+  artifact     TEXT,                   -- NULL if it couldn't be written
+  started_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_gate_runs_task ON gate_runs(task_id, attempt);
@@ -0,0 +1,11 @@
+-- Named, ordered completion gates (build, lint, test, ...). A task's gates
+-- come from its metadata (`gates`), else its project's; each gate run is a
+-- gate_runs row. Runs from before this are the single "test" gate.
+
+ALTER TABLE projects ADD COLUMN gates JSONB;  -- [{name, run, timeout, advisory}]
+
+ALTER TABLE gate_runs
+  ADD COLUMN name     TEXT    NOT NULL DEFAULT 'test',
+  ADD COLUMN advisory BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE gate_runs ALTER COLUMN name DROP DEFAULT;
@@ -0,0 +1,18 @@
+-- Failing packages and tests of gates running `go test`, which minuano runs
+-- with -json: one row per failure, with its own output. A test that failed in
+-- an earlier attempt of the same task is a repeat.
+
+CREATE TABLE failed_tests (
+  id          BIGSERIAL   PRIMARY KEY,
+  gate_run_id BIGINT      NOT NULL REFERENCES gate_runs(id) ON DELETE CASCADE,
+  task_id     TEXT        NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  attempt     INTEGER     NOT NULL,
+  package     TEXT        NOT NULL,
+  test        TEXT        NOT NULL DEFAULT '',  -- '': the package failed (to build, or in TestMain)
+  output      TEXT        NOT NULL DEFAULT '',
+  repeat      BOOLEAN     NOT NULL DEFAULT false,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_failed_tests_task ON failed_tests(task_id, package, test);
+CREATE INDEX idx_failed_tests_run ON failed_tests(gate_run_id);
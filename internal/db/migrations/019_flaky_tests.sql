@@ -0,0 +1,18 @@
+-- Flaky tests: failures of a go test gate that passed when re-run
+-- (flaky_reruns). A gate whose failures all passed on re-run passes, and the
+-- attempt isn't counted; each test is counted here so it can be fixed.
+
+ALTER TABLE gate_runs ADD COLUMN flaky BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE failed_tests ADD COLUMN flaky BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE flaky_tests (
+  package      TEXT        NOT NULL,
+  test         TEXT        NOT NULL,
+  occurrences  INTEGER     NOT NULL DEFAULT 1,
+  first_seen   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_seen    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_task_id TEXT        REFERENCES tasks(id) ON DELETE SET NULL,
+  PRIMARY KEY (package, test)
+);
+
+CREATE INDEX idx_flaky_tests_occurrences ON flaky_tests(occurrences DESC, last_seen DESC);
@@ -0,0 +1,5 @@
+-- Test-before-merge: `minuano merge` runs the task's gates on the merge result
+-- before moving the base branch. An entry whose merge fails them ends up
+-- test_failed, with the failure report in test_output.
+
+ALTER TABLE merge_queue ADD COLUMN test_output TEXT;
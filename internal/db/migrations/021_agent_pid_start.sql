@@ -0,0 +1,5 @@
+-- A process agent's pid alone doesn't identify it once the process is gone
+-- and the pid reused. pid_start is the process's start time, as the kernel
+-- reports it, checked before the agent's process is signalled.
+
+ALTER TABLE agents ADD COLUMN pid_start BIGINT;
@@ -0,0 +1,18 @@
+-- record_event no longer takes a database-wide advisory lock: holding it
+-- until commit serialised every transaction that changed a task, planner
+-- session, merge or agent status. Event IDs still come from the sequence, so
+-- an ID can become visible after higher ones were read, when its transaction
+-- commits late, or never, when it rolls back. Readers keep the IDs they
+-- skipped and look for them again for a while (see service.EventCursor).
+
+CREATE OR REPLACE FUNCTION record_event(p_kind TEXT, p_project TEXT, p_payload JSONB)
+RETURNS VOID LANGUAGE plpgsql AS $$
+DECLARE
+  new_id BIGINT;
+BEGIN
+  INSERT INTO events (kind, project_id, payload)
+  VALUES (p_kind, NULLIF(p_project, ''), p_payload)
+  RETURNING id INTO new_id;
+  PERFORM pg_notify('minuano_events', new_id::text);
+END;
+$$;
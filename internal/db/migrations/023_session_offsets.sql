@@ -0,0 +1,5 @@
+-- Usage syncs read transcripts incrementally: synced_bytes is how much of a
+-- session's transcript has been read, so a session left open doesn't have
+-- its whole transcript re-read on every sync.
+
+ALTER TABLE agent_sessions ADD COLUMN synced_bytes BIGINT NOT NULL DEFAULT 0;
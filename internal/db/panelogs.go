@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PaneLog is one archived segment of an agent's pane output.
+type PaneLog struct {
+	ID        int64      `json:"id"`
+	AgentID   string     `json:"agent_id"`
+	TaskID    *string    `json:"task_id,omitempty"`
+	Attempt   *int       `json:"attempt,omitempty"`
+	Path      string     `json:"path"`
+	Bytes     int64      `json:"bytes"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// CurrentClaim returns the task agentID holds and its attempt, or a nil
+// taskID when the agent is idle or gone.
+func CurrentClaim(pool *pgxpool.Pool, agentID string) (*string, int, error) {
+	var taskID string
+	var attempt int
+	err := pool.QueryRow(context.Background(), `
+		SELECT t.id, t.attempt
+		FROM   agents a
+		JOIN   tasks t ON t.id = a.task_id
+		WHERE  a.id = $1 AND t.status = 'claimed' AND t.claimed_by = a.id
+	`, agentID).Scan(&taskID, &attempt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("loading claim of %s: %w", agentID, err)
+	}
+	return &taskID, attempt, nil
+}
+
+// CreatePaneLog indexes a new segment file being written for agentID.
+func CreatePaneLog(pool *pgxpool.Pool, agentID string, taskID *string, attempt *int, path string) (int64, error) {
+	var id int64
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO pane_logs (agent_id, task_id, attempt, path)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, agentID, taskID, attempt, path).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("indexing pane log %s: %w", path, err)
+	}
+	return id, nil
+}
+
+// FinishPaneLog records that a segment is complete.
+func FinishPaneLog(pool *pgxpool.Pool, id, bytes int64) error {
+	_, err := pool.Exec(context.Background(),
+		`UPDATE pane_logs SET bytes = $2, ended_at = NOW() WHERE id = $1`, id, bytes)
+	if err != nil {
+		return fmt.Errorf("finishing pane log %d: %w", id, err)
+	}
+	return nil
+}
+
+const paneLogColumns = `id, agent_id, task_id, attempt, path, bytes, started_at, ended_at`
+
+func scanPaneLog(row pgx.Row) (*PaneLog, error) {
+	var l PaneLog
+	if err := row.Scan(&l.ID, &l.AgentID, &l.TaskID, &l.Attempt, &l.Path, &l.Bytes, &l.StartedAt, &l.EndedAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// ListPaneLogs returns a task's segments in the order they were written,
+// limited to one attempt when attempt > 0.
+func ListPaneLogs(pool *pgxpool.Pool, taskID string, attempt int) ([]*PaneLog, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT `+paneLogColumns+`
+		FROM   pane_logs
+		WHERE  task_id = $1 AND ($2 = 0 OR attempt = $2)
+		ORDER  BY started_at, id
+	`, taskID, attempt)
+	if err != nil {
+		return nil, fmt.Errorf("listing pane logs for %s: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	var logs []*PaneLog
+	for rows.Next() {
+		l, err := scanPaneLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning pane log: %w", err)
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// LatestPaneLog returns the segment agentID's recorder wrote last, or nil if
+// it has none.
+func LatestPaneLog(pool *pgxpool.Pool, agentID string) (*PaneLog, error) {
+	l, err := scanPaneLog(pool.QueryRow(context.Background(), `
+		SELECT `+paneLogColumns+`
+		FROM   pane_logs
+		WHERE  agent_id = $1
+		ORDER  BY id DESC
+		LIMIT  1
+	`, agentID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading latest pane log of %s: %w", agentID, err)
+	}
+	return l, nil
+}
+
+// PrunePaneLogs drops the index of segments that ended before the given time
+// and returns their paths so the files can be removed. A segment that never
+// ended is still being written unless its agent is gone, which leaves it
+// orphaned: it goes once it started before that time.
+func PrunePaneLogs(pool *pgxpool.Pool, before time.Time) ([]string, error) {
+	rows, err := pool.Query(context.Background(), `
+		DELETE FROM pane_logs l
+		WHERE  l.ended_at < $1
+		   OR  (l.ended_at IS NULL AND l.started_at < $1
+		        AND NOT EXISTS (SELECT 1 FROM agents a WHERE a.id = l.agent_id))
+		RETURNING l.path
+	`, before)
+	if err != nil {
+		return nil, fmt.Errorf("pruning pane logs: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("scanning pane log path: %w", err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/gate"
+)
+
+// Project is a project record. Nil settings fall back to the configured
+// defaults.
+type Project struct {
+	ID          string     `json:"id"`
+	Name        *string    `json:"name,omitempty"`
+	RepoPath    *string    `json:"repo_path,omitempty"`
+	BaseBranch  *string    `json:"base_branch,omitempty"`
+	TestCmd     *string    `json:"test_cmd,omitempty"`
+	Gates       []gate.Def `json:"gates,omitempty"` // run instead of test_cmd
+	MaxAttempts *int       `json:"max_attempts,omitempty"`
+	MaxAgents   *int       `json:"max_agents,omitempty"`
+	Paused      bool       `json:"paused"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ProjectSettings lists the settings to write; nil fields are left alone, and
+// empty strings or zero numbers clear a setting back to the default.
+type ProjectSettings struct {
+	Name        *string
+	RepoPath    *string
+	BaseBranch  *string
+	TestCmd     *string
+	Gates       *[]gate.Def
+	MaxAttempts *int
+	MaxAgents   *int
+	Paused      *bool
+}
+
+const projectColumns = `id, name, repo_path, base_branch, test_cmd, gates, max_attempts,
+		       max_agents, paused, archived_at, created_at`
+
+func scanProject(row pgx.Row) (*Project, error) {
+	var p Project
+	err := row.Scan(&p.ID, &p.Name, &p.RepoPath, &p.BaseBranch, &p.TestCmd, &p.Gates, &p.MaxAttempts,
+		&p.MaxAgents, &p.Paused, &p.ArchivedAt, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CreateProject adds a project record.
+func CreateProject(pool *pgxpool.Pool, id string, s ProjectSettings) (*Project, error) {
+	p, err := scanProject(pool.QueryRow(context.Background(), `
+		INSERT INTO projects (id, name, repo_path, base_branch, test_cmd, gates, max_attempts, max_agents, paused)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9, false))
+		RETURNING `+projectColumns,
+		id, textSetting(s.Name), textSetting(s.RepoPath), textSetting(s.BaseBranch), textSetting(s.TestCmd),
+		gatesSetting(s.Gates), intSetting(s.MaxAttempts), intSetting(s.MaxAgents), s.Paused))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, fmt.Errorf("%w: project %q", ErrExists, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating project: %w", err)
+	}
+	return p, nil
+}
+
+// UpdateProject writes the given settings of a project.
+func UpdateProject(pool *pgxpool.Pool, id string, s ProjectSettings) (*Project, error) {
+	var sets []string
+	args := []any{id}
+	set := func(column string, v any) {
+		args = append(args, v)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	text := func(column string, v *string) {
+		if v != nil {
+			set(column, textSetting(v))
+		}
+	}
+	number := func(column string, v *int) {
+		if v != nil {
+			set(column, intSetting(v))
+		}
+	}
+	text("name", s.Name)
+	text("repo_path", s.RepoPath)
+	text("base_branch", s.BaseBranch)
+	text("test_cmd", s.TestCmd)
+	if s.Gates != nil {
+		set("gates", gatesSetting(s.Gates))
+	}
+	number("max_attempts", s.MaxAttempts)
+	number("max_agents", s.MaxAgents)
+	if s.Paused != nil {
+		set("paused", *s.Paused)
+	}
+	if len(sets) == 0 {
+		return GetProject(pool, id)
+	}
+
+	p, err := scanProject(pool.QueryRow(context.Background(), `
+		UPDATE projects SET `+strings.Join(sets, ", ")+`
+		WHERE  id = $1
+		RETURNING `+projectColumns, args...))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: project %q", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("updating project: %w", err)
+	}
+	return p, nil
+}
+
+// textSetting and intSetting store unset and zero values as NULL, the "use
+// the default" setting.
+func textSetting(v *string) *string {
+	if v == nil || strings.TrimSpace(*v) == "" {
+		return nil
+	}
+	t := strings.TrimSpace(*v)
+	return &t
+}
+
+func intSetting(v *int) *int {
+	if v == nil || *v == 0 {
+		return nil
+	}
+	return v
+}
+
+// gatesSetting stores a gate list as JSON, and an empty one as NULL.
+func gatesSetting(v *[]gate.Def) []byte {
+	if v == nil || len(*v) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(*v)
+	return data
+}
+
+// GetProject returns a project by ID.
+func GetProject(pool *pgxpool.Pool, id string) (*Project, error) {
+	p, err := scanProject(pool.QueryRow(context.Background(),
+		`SELECT `+projectColumns+` FROM projects WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: project %q", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting project: %w", err)
+	}
+	return p, nil
+}
+
+// ListProjects returns the projects by ID, archived ones only if asked.
+func ListProjects(pool *pgxpool.Pool, archived bool) ([]*Project, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT `+projectColumns+`
+		FROM   projects
+		WHERE  $1 OR archived_at IS NULL
+		ORDER  BY id
+	`, archived)
+	if err != nil {
+		return nil, fmt.Errorf("listing projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []*Project
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning project: %w", err)
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// ArchiveProject archives a project: it keeps its tasks, but takes no new ones
+// and none of them are claimed.
+func ArchiveProject(pool *pgxpool.Pool, id string) error {
+	tag, err := pool.Exec(context.Background(), `
+		UPDATE projects SET archived_at = NOW()
+		WHERE  id = $1 AND archived_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("archiving project: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		p, err := GetProject(pool, id)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: project %q was archived %s", ErrWrongStatus, id, p.ArchivedAt.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// CountProjectTasks returns how many of the project's tasks are in each status.
+func CountProjectTasks(pool *pgxpool.Pool, id string) (map[string]int, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT status, COUNT(*) FROM tasks WHERE project_id = $1 GROUP BY status
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("counting project tasks: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("scanning project task count: %w", err)
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/gate"
 )
 
 // Task represents a work unit.
@@ -29,6 +30,7 @@ type Task struct {
 	ApprovedBy       *string         `json:"approved_by,omitempty"`
 	ApprovedAt       *time.Time      `json:"approved_at,omitempty"`
 	RejectionReason  *string         `json:"rejection_reason,omitempty"`
+	Version          int             `json:"version"`
 }
 
 // TaskContext represents a persistent context entry for a task.
@@ -53,6 +55,11 @@ type Agent struct {
 	LastSeen     *time.Time `json:"last_seen,omitempty"`
 	WorktreeDir  *string    `json:"worktree_dir,omitempty"`
 	Branch       *string    `json:"branch,omitempty"`
+	Runtime      string     `json:"runtime"`
+	PID          *int       `json:"pid,omitempty"`
+	PIDStart     *int64     `json:"pid_start,omitempty"`
+	Profile      string     `json:"profile"`
+	ProjectID    *string    `json:"project_id,omitempty"`
 }
 
 // MergeQueueEntry represents an entry in the merge queue.
@@ -68,6 +75,7 @@ type MergeQueueEntry struct {
 	MergeSHA      *string    `json:"merge_sha,omitempty"`
 	ConflictFiles []string   `json:"conflict_files,omitempty"`
 	ErrorMsg      *string    `json:"error_msg,omitempty"`
+	TestOutput    *string    `json:"test_output,omitempty"`
 	EnqueuedAt    time.Time  `json:"enqueued_at"`
 	StartedAt     *time.Time `json:"started_at,omitempty"`
 	CompletedAt   *time.Time `json:"completed_at,omitempty"`
@@ -76,7 +84,7 @@ type MergeQueueEntry struct {
 // taskColumns is the canonical SELECT column list for tasks.
 const taskColumns = `id, title, body, status, priority, claimed_by, claimed_at,
 		       done_at, created_at, attempt, max_attempts, project_id, metadata,
-		       requires_approval, approved_by, approved_at, rejection_reason`
+		       requires_approval, approved_by, approved_at, rejection_reason, version`
 
 // scanTask scans a single task row (must match taskColumns order).
 func scanTask(row pgx.Row) (Task, error) {
@@ -85,23 +93,45 @@ func scanTask(row pgx.Row) (Task, error) {
 		&t.ID, &t.Title, &t.Body, &t.Status, &t.Priority,
 		&t.ClaimedBy, &t.ClaimedAt, &t.DoneAt, &t.CreatedAt, &t.Attempt,
 		&t.MaxAttempts, &t.ProjectID, &t.Metadata,
-		&t.RequiresApproval, &t.ApprovedBy, &t.ApprovedAt, &t.RejectionReason,
+		&t.RequiresApproval, &t.ApprovedBy, &t.ApprovedAt, &t.RejectionReason, &t.Version,
 	)
 	return t, err
 }
 
+// TaskMeta is the decoded subset of tasks.metadata that Minuano itself reads.
+type TaskMeta struct {
+	TestCmd string     `json:"test_cmd,omitempty"`
+	Gates   []gate.Def `json:"gates,omitempty"` // run instead of test_cmd
+	Labels  []string   `json:"labels,omitempty"`
+	Spec    string     `json:"spec,omitempty"`    // source markdown file for import-md
+	Profile string     `json:"profile,omitempty"` // agent profile required to claim the task
+}
+
+// Meta decodes the task's metadata. Malformed or empty metadata yields a zero TaskMeta.
+func (t *Task) Meta() TaskMeta {
+	var m TaskMeta
+	if len(t.Metadata) > 0 {
+		json.Unmarshal(t.Metadata, &m)
+	}
+	return m
+}
+
 // TreeNode is a task with its children for tree rendering.
 type TreeNode struct {
 	Task     *Task
 	Children []*TreeNode
 }
 
-// CreateTask inserts a new task.
-func CreateTask(pool *pgxpool.Pool, id, title, body string, priority int, projectID *string, metadata json.RawMessage, requiresApproval bool) error {
-	_, err := pool.Exec(context.Background(), `
-		INSERT INTO tasks (id, title, body, priority, project_id, metadata, requires_approval)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, id, title, body, priority, projectID, metadata, requiresApproval)
+// DefaultMaxAttempts is how many times a task may be claimed unless
+// configured otherwise (the column default).
+const DefaultMaxAttempts = 3
+
+// CreateTask inserts a new task that may be claimed maxAttempts times.
+func CreateTask(q Querier, id, title, body string, priority, maxAttempts int, projectID *string, metadata json.RawMessage, requiresApproval bool) error {
+	_, err := q.Exec(context.Background(), `
+		INSERT INTO tasks (id, title, body, priority, max_attempts, project_id, metadata, requires_approval)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, id, title, body, priority, maxAttempts, projectID, metadata, requiresApproval)
 	if err != nil {
 		return fmt.Errorf("creating task: %w", err)
 	}
@@ -109,8 +139,8 @@ func CreateTask(pool *pgxpool.Pool, id, title, body string, priority int, projec
 }
 
 // SetTaskStatus sets a task's status directly.
-func SetTaskStatus(pool *pgxpool.Pool, id, status string) error {
-	_, err := pool.Exec(context.Background(), `
+func SetTaskStatus(q Querier, id, status string) error {
+	_, err := q.Exec(context.Background(), `
 		UPDATE tasks SET status = $2 WHERE id = $1
 	`, id, status)
 	if err != nil {
@@ -120,8 +150,8 @@ func SetTaskStatus(pool *pgxpool.Pool, id, status string) error {
 }
 
 // AddDependency creates a dependency edge.
-func AddDependency(pool *pgxpool.Pool, taskID, dependsOn string) error {
-	_, err := pool.Exec(context.Background(), `
+func AddDependency(q Querier, taskID, dependsOn string) error {
+	_, err := q.Exec(context.Background(), `
 		INSERT INTO task_deps (task_id, depends_on) VALUES ($1, $2)
 	`, taskID, dependsOn)
 	if err != nil {
@@ -132,8 +162,8 @@ func AddDependency(pool *pgxpool.Pool, taskID, dependsOn string) error {
 
 // ResolvePartialID finds a single task ID matching the given prefix.
 // Returns an error if zero or multiple tasks match.
-func ResolvePartialID(pool *pgxpool.Pool, prefix string) (string, error) {
-	rows, err := pool.Query(context.Background(), `
+func ResolvePartialID(q Querier, prefix string) (string, error) {
+	rows, err := q.Query(context.Background(), `
 		SELECT id FROM tasks WHERE id LIKE $1 || '%' LIMIT 2
 	`, prefix)
 	if err != nil {
@@ -155,22 +185,22 @@ func ResolvePartialID(pool *pgxpool.Pool, prefix string) (string, error) {
 
 	switch len(ids) {
 	case 0:
-		return "", fmt.Errorf("no task found matching %q", prefix)
+		return "", fmt.Errorf("%w: no task matches %q", ErrNotFound, prefix)
 	case 1:
 		return ids[0], nil
 	default:
-		return "", fmt.Errorf("ambiguous prefix %q matches multiple tasks", prefix)
+		return "", fmt.Errorf("%w: prefix %q matches multiple tasks", ErrAmbiguous, prefix)
 	}
 }
 
 // GetTask retrieves a task by exact or partial ID.
-func GetTask(pool *pgxpool.Pool, id string) (*Task, error) {
-	resolvedID, err := ResolvePartialID(pool, id)
+func GetTask(q Querier, id string) (*Task, error) {
+	resolvedID, err := ResolvePartialID(q, id)
 	if err != nil {
 		return nil, err
 	}
 
-	t, err := scanTask(pool.QueryRow(context.Background(),
+	t, err := scanTask(q.QueryRow(context.Background(),
 		`SELECT `+taskColumns+` FROM tasks WHERE id = $1`, resolvedID))
 	if err != nil {
 		return nil, fmt.Errorf("getting task %s: %w", resolvedID, err)
@@ -178,6 +208,20 @@ func GetTask(pool *pgxpool.Pool, id string) (*Task, error) {
 	return &t, nil
 }
 
+// GetTaskByID retrieves a task by its exact ID, failing with ErrNotFound if
+// there is none.
+func GetTaskByID(q Querier, id string) (*Task, error) {
+	t, err := scanTask(q.QueryRow(context.Background(),
+		`SELECT `+taskColumns+` FROM tasks WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: no task %q", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting task %s: %w", id, err)
+	}
+	return &t, nil
+}
+
 // ListTasks returns all tasks, optionally filtered by project.
 func ListTasks(pool *pgxpool.Pool, projectID *string) ([]*Task, error) {
 	var rows pgx.Rows
@@ -233,6 +277,27 @@ func GetTaskWithContext(pool *pgxpool.Pool, id string) (*Task, []*TaskContext, e
 	return task, ctxs, nil
 }
 
+// profileMatch is the claim condition on a task's required agent profile:
+// none, or the one the claiming agent ($1) runs.
+const profileMatch = `(metadata->>'profile' IS NULL
+			       OR metadata->>'profile' = (SELECT profile FROM agents WHERE id = $1))`
+
+// projectMatch is the claim condition on the project the claiming agent ($1)
+// is bound to, if any.
+const projectMatch = `(NOT EXISTS (SELECT 1 FROM agents WHERE id = $1 AND project_id IS NOT NULL)
+			       OR project_id = (SELECT project_id FROM agents WHERE id = $1))`
+
+// projectOpen is the claim condition on the task's project record: not paused
+// or archived, and with fewer claimed tasks than its max_agents cap. Claims of
+// different tasks running at the same time may overshoot the cap by the
+// number of racing claims.
+const projectOpen = `(project_id IS NULL OR EXISTS (
+			    SELECT 1 FROM projects p
+			    WHERE  p.id = tasks.project_id AND NOT p.paused AND p.archived_at IS NULL
+			      AND  (p.max_agents IS NULL OR p.max_agents > (
+			            SELECT COUNT(*) FROM tasks c
+			            WHERE  c.project_id = p.id AND c.status = 'claimed'))))`
+
 // AtomicClaim atomically claims one ready task, injects inherited context, and updates the agent.
 // Returns nil if no task is available. When projectID is non-nil, only claims from that project.
 func AtomicClaim(pool *pgxpool.Pool, agentID string, projectID *string) (*Task, error) {
@@ -244,6 +309,10 @@ func AtomicClaim(pool *pgxpool.Pool, agentID string, projectID *string) (*Task,
 	}
 	defer tx.Rollback(ctx)
 
+	if draining, err := lockAgent(ctx, tx, agentID); err != nil || draining {
+		return nil, err
+	}
+
 	var proj interface{}
 	if projectID != nil {
 		proj = *projectID
@@ -260,6 +329,9 @@ func AtomicClaim(pool *pgxpool.Pool, agentID string, projectID *string) (*Task,
 			WHERE  status = 'ready'
 			  AND  ($2::text IS NULL OR project_id = $2)
 			  AND  attempt < max_attempts
+			  AND  `+profileMatch+`
+			  AND  `+projectMatch+`
+			  AND  `+projectOpen+`
 			ORDER  BY priority DESC, created_at ASC
 			LIMIT  1
 			FOR UPDATE SKIP LOCKED
@@ -322,6 +394,12 @@ func ClaimByID(pool *pgxpool.Pool, taskID, agentID string) (*Task, error) {
 	}
 	defer tx.Rollback(ctx)
 
+	if draining, err := lockAgent(ctx, tx, agentID); err != nil {
+		return nil, err
+	} else if draining {
+		return nil, fmt.Errorf("agent %s is draining", agentID)
+	}
+
 	// Verify task is claimable and claim it.
 	t, err := scanTask(tx.QueryRow(ctx, `
 		UPDATE tasks
@@ -332,19 +410,51 @@ func ClaimByID(pool *pgxpool.Pool, taskID, agentID string) (*Task, error) {
 		WHERE  id         = $2
 		  AND  status     = 'ready'
 		  AND  attempt    < max_attempts
+		  AND  `+profileMatch+`
+		  AND  `+projectMatch+`
+		  AND  `+projectOpen+`
 		RETURNING `+taskColumns+`
 	`, agentID, resolvedID))
 	if err == pgx.ErrNoRows {
 		// Determine reason for failure.
 		var status string
 		var attempt, maxAttempts int
-		scanErr := pool.QueryRow(ctx, `SELECT status, attempt, max_attempts FROM tasks WHERE id = $1`, resolvedID).Scan(&status, &attempt, &maxAttempts)
+		var required, profile, project, bound *string
+		var paused, archived bool
+		var maxAgents *int
+		var claimed int
+		scanErr := pool.QueryRow(ctx, `
+			SELECT t.status, t.attempt, t.max_attempts, t.metadata->>'profile',
+			       (SELECT profile FROM agents WHERE id = $2),
+			       t.project_id, (SELECT project_id FROM agents WHERE id = $2),
+			       COALESCE(p.paused, false), p.archived_at IS NOT NULL, p.max_agents,
+			       (SELECT COUNT(*) FROM tasks c WHERE c.project_id = t.project_id AND c.status = 'claimed')
+			FROM   tasks t
+			LEFT   JOIN projects p ON p.id = t.project_id
+			WHERE  t.id = $1
+		`, resolvedID, agentID).Scan(&status, &attempt, &maxAttempts, &required, &profile, &project, &bound,
+			&paused, &archived, &maxAgents, &claimed)
 		if scanErr != nil {
 			return nil, fmt.Errorf("task %q not found", resolvedID)
 		}
 		if attempt >= maxAttempts {
 			return nil, fmt.Errorf("task %q has reached max attempts (%d/%d)", resolvedID, attempt, maxAttempts)
 		}
+		if required != nil && (profile == nil || *profile != *required) {
+			return nil, fmt.Errorf("task %q requires agent profile %q", resolvedID, *required)
+		}
+		if bound != nil && (project == nil || *project != *bound) {
+			return nil, fmt.Errorf("task %q is not in project %q, which agent %s is bound to", resolvedID, *bound, agentID)
+		}
+		if status == "ready" && archived {
+			return nil, fmt.Errorf("task %q is in archived project %q", resolvedID, *project)
+		}
+		if status == "ready" && paused {
+			return nil, fmt.Errorf("task %q is in paused project %q", resolvedID, *project)
+		}
+		if status == "ready" && maxAgents != nil && claimed >= *maxAgents {
+			return nil, fmt.Errorf("project %q already has %d claimed task(s), its max", *project, claimed)
+		}
 		return nil, fmt.Errorf("task %q is not ready (status: %s)", resolvedID, status)
 	}
 	if err != nil {
@@ -394,7 +504,14 @@ func MarkDone(pool *pgxpool.Pool, taskID, agentID, summary string) error {
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, `
+	if err := markDone(ctx, tx, taskID, agentID, summary); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func markDone(ctx context.Context, tx pgx.Tx, taskID, agentID, summary string) error {
+	_, err := tx.Exec(ctx, `
 		INSERT INTO task_context (task_id, agent_id, kind, content)
 		VALUES ($1, $2, 'result', $3)
 	`, taskID, agentID, summary)
@@ -422,8 +539,7 @@ func MarkDone(pool *pgxpool.Pool, taskID, agentID, summary string) error {
 	if err != nil {
 		return fmt.Errorf("releasing agent: %w", err)
 	}
-
-	return tx.Commit(ctx)
+	return nil
 }
 
 // RecordFailure records a test failure and resets the task to ready, or marks it failed
@@ -436,7 +552,14 @@ func RecordFailure(pool *pgxpool.Pool, taskID, agentID, output string) error {
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, `
+	if err := recordFailure(ctx, tx, taskID, agentID, output); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func recordFailure(ctx context.Context, tx pgx.Tx, taskID, agentID, output string) error {
+	_, err := tx.Exec(ctx, `
 		INSERT INTO task_context (task_id, agent_id, kind, content)
 		VALUES ($1, $2, 'test_failure', $3)
 	`, taskID, agentID, output)
@@ -474,20 +597,71 @@ func RecordFailure(pool *pgxpool.Pool, taskID, agentID, output string) error {
 	if err != nil {
 		return fmt.Errorf("releasing agent: %w", err)
 	}
+	return nil
+}
 
-	return tx.Commit(ctx)
+// ReopenTask sends a done task back for another attempt, with output as its
+// test_failure context: to ready, or to failed if it is out of attempts. It
+// returns the task's new status.
+func ReopenTask(pool *pgxpool.Pool, taskID, agentID, output string) (string, error) {
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("beginning reopen tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, `
+		UPDATE tasks
+		SET    status  = CASE WHEN attempt < max_attempts THEN 'ready' ELSE 'failed' END,
+		       done_at = NULL
+		WHERE  id     = $1
+		  AND  status = 'done'
+		RETURNING status
+	`, taskID).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return "", fmt.Errorf("%w: task %q is not done", ErrWrongStatus, taskID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reopening task: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO task_context (task_id, agent_id, kind, content)
+		VALUES ($1, $2, 'test_failure', $3)
+	`, taskID, agentID, output)
+	if err != nil {
+		return "", fmt.Errorf("recording failure: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return status, nil
 }
 
 // ReclaimStale resets tasks that have been claimed for longer than the given minutes.
+// Each reclaim is recorded as a 'reclaimed' context entry against the previous owner.
 // Returns the number of reclaimed tasks.
 func ReclaimStale(pool *pgxpool.Pool, minutes int) (int, error) {
 	tag, err := pool.Exec(context.Background(), `
-		UPDATE tasks
-		SET    status     = 'ready',
-		       claimed_by = NULL,
-		       claimed_at = NULL
-		WHERE  status     = 'claimed'
-		  AND  claimed_at < NOW() - make_interval(mins => $1)
+		WITH stale AS (
+		  SELECT id, claimed_by FROM tasks
+		  WHERE  status     = 'claimed'
+		    AND  claimed_at < NOW() - make_interval(mins => $1::int)
+		  FOR UPDATE
+		), reset AS (
+		  UPDATE tasks t
+		  SET    status     = 'ready',
+		         claimed_by = NULL,
+		         claimed_at = NULL
+		  FROM   stale
+		  WHERE  t.id = stale.id
+		)
+		INSERT INTO task_context (task_id, agent_id, kind, content)
+		SELECT id, claimed_by, 'reclaimed',
+		       format('Claim went stale after %s minutes; task reset to ready.', $1::int)
+		FROM   stale
 	`, minutes)
 	if err != nil {
 		return 0, fmt.Errorf("reclaiming stale tasks: %w", err)
@@ -519,59 +693,59 @@ func AddHandoff(pool *pgxpool.Pool, taskID, agentID, content string) error {
 	return nil
 }
 
-// GetDependencyTree builds a forest of TreeNodes for tree rendering.
+// GetDependencyTree builds a forest of TreeNodes for tree rendering. A task with
+// several dependencies is shared: the same *TreeNode appears under every parent.
+// Roots are tasks with no dependencies inside the selected set.
 func GetDependencyTree(pool *pgxpool.Pool, projectID *string) ([]*TreeNode, error) {
 	tasks, err := ListTasks(pool, projectID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Load all deps.
-	rows, err := pool.Query(context.Background(), `SELECT task_id, depends_on FROM task_deps`)
+	deps, err := ListDependencies(pool, projectID)
 	if err != nil {
-		return nil, fmt.Errorf("loading deps: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	// Map of taskID -> list of dependency IDs (parents).
-	parents := make(map[string][]string)
-	children := make(map[string][]string)
-	for rows.Next() {
-		var taskID, depID string
-		if err := rows.Scan(&taskID, &depID); err != nil {
-			return nil, fmt.Errorf("scanning dep: %w", err)
-		}
-		parents[taskID] = append(parents[taskID], depID)
-		children[depID] = append(children[depID], taskID)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating deps: %w", err)
-	}
+	return buildDependencyTree(tasks, deps), nil
+}
 
-	// Build node map.
-	nodeMap := make(map[string]*TreeNode)
+// buildDependencyTree wires tasks into a forest. Edges to tasks outside the set
+// are ignored, so a task whose only parents live in another project is a root.
+func buildDependencyTree(tasks []*Task, deps map[string][]string) []*TreeNode {
+	nodeMap := make(map[string]*TreeNode, len(tasks))
 	for _, t := range tasks {
 		nodeMap[t.ID] = &TreeNode{Task: t}
 	}
 
+	// Map of taskID -> list of dependent IDs (children), in task order.
+	hasParent := make(map[string]bool)
+	children := make(map[string][]string)
+	for _, t := range tasks {
+		for _, depID := range deps[t.ID] {
+			if _, ok := nodeMap[depID]; ok {
+				children[depID] = append(children[depID], t.ID)
+				hasParent[t.ID] = true
+			}
+		}
+	}
+
 	// Wire children.
 	for _, t := range tasks {
 		for _, childID := range children[t.ID] {
-			if child, ok := nodeMap[childID]; ok {
-				nodeMap[t.ID].Children = append(nodeMap[t.ID].Children, child)
-			}
+			nodeMap[t.ID].Children = append(nodeMap[t.ID].Children, nodeMap[childID])
 		}
 	}
 
 	// Roots are tasks with no parents.
 	var roots []*TreeNode
 	for _, t := range tasks {
-		if len(parents[t.ID]) == 0 {
+		if !hasParent[t.ID] {
 			roots = append(roots, nodeMap[t.ID])
 		}
 	}
 
-	return roots, nil
+	return roots
 }
 
 // SearchContext performs full-text search across task context.
@@ -598,23 +772,37 @@ func SearchContext(pool *pgxpool.Pool, query string) ([]*TaskContext, error) {
 	return results, rows.Err()
 }
 
-// RegisterAgent inserts a new agent record.
-func RegisterAgent(pool *pgxpool.Pool, id, tmuxSession, tmuxWindow string, worktreeDir, branch *string) error {
+// RegisterAgent inserts a new agent record owned by the named runtime and
+// running the named profile. A non-nil projectID binds the agent to that
+// project's tasks.
+func RegisterAgent(pool *pgxpool.Pool, id, runtime, profile string, projectID *string, tmuxSession, tmuxWindow string, worktreeDir, branch *string) error {
 	_, err := pool.Exec(context.Background(), `
-		INSERT INTO agents (id, tmux_session, tmux_window, last_seen, worktree_dir, branch)
-		VALUES ($1, $2, $3, NOW(), $4, $5)
-	`, id, tmuxSession, tmuxWindow, worktreeDir, branch)
+		INSERT INTO agents (id, runtime, profile, project_id, tmux_session, tmux_window, last_seen, worktree_dir, branch)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), $7, $8)
+	`, id, runtime, profile, projectID, tmuxSession, tmuxWindow, worktreeDir, branch)
 	if err != nil {
 		return fmt.Errorf("registering agent: %w", err)
 	}
 	return nil
 }
 
+// SetAgentPID records the process an agent runs as, and its start time, or 0
+// if that isn't known.
+func SetAgentPID(pool *pgxpool.Pool, id string, pid int, start int64) error {
+	_, err := pool.Exec(context.Background(), `
+		UPDATE agents SET pid = $2, pid_start = NULLIF($3, 0) WHERE id = $1
+	`, id, pid, start)
+	if err != nil {
+		return fmt.Errorf("recording pid of %s: %w", id, err)
+	}
+	return nil
+}
+
 // ListAgents returns all registered agents.
 func ListAgents(pool *pgxpool.Pool) ([]*Agent, error) {
 	rows, err := pool.Query(context.Background(), `
 		SELECT id, tmux_session, tmux_window, task_id, status, started_at, last_seen,
-		       worktree_dir, branch
+		       worktree_dir, branch, runtime, pid, pid_start, profile, project_id
 		FROM agents
 		ORDER BY started_at ASC
 	`)
@@ -627,7 +815,7 @@ func ListAgents(pool *pgxpool.Pool) ([]*Agent, error) {
 	for rows.Next() {
 		var a Agent
 		if err := rows.Scan(&a.ID, &a.TmuxSession, &a.TmuxWindow, &a.TaskID, &a.Status, &a.StartedAt, &a.LastSeen,
-			&a.WorktreeDir, &a.Branch); err != nil {
+			&a.WorktreeDir, &a.Branch, &a.Runtime, &a.PID, &a.PIDStart, &a.Profile, &a.ProjectID); err != nil {
 			return nil, fmt.Errorf("scanning agent: %w", err)
 		}
 		agents = append(agents, &a)
@@ -646,6 +834,35 @@ func UpdateAgentStatus(pool *pgxpool.Pool, id, status string) error {
 	return nil
 }
 
+// DrainAgent marks an idle agent 'draining', so it claims nothing more, and
+// reports whether it was idle. An agent holding a task, or gone, is left as
+// it is. Claims lock the agent's row first, so a claim and a drain can't both
+// succeed.
+func DrainAgent(pool *pgxpool.Pool, id string) (bool, error) {
+	tag, err := pool.Exec(context.Background(), `
+		UPDATE agents SET status = 'draining'
+		WHERE  id = $1 AND task_id IS NULL
+	`, id)
+	if err != nil {
+		return false, fmt.Errorf("draining agent %s: %w", id, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// lockAgent locks a claiming agent's row for the rest of tx, and reports
+// whether it is draining. An agent that isn't registered isn't draining.
+func lockAgent(ctx context.Context, tx pgx.Tx, id string) (bool, error) {
+	var status string
+	err := tx.QueryRow(ctx, `SELECT status FROM agents WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("locking agent %s: %w", id, err)
+	}
+	return status == "draining", nil
+}
+
 // DeleteAgent removes an agent and releases any claimed task.
 func DeleteAgent(pool *pgxpool.Pool, id string) error {
 	ctx := context.Background()
@@ -687,10 +904,159 @@ func UpdateTask(pool *pgxpool.Pool, id, title, body string) error {
 	return nil
 }
 
+// EditTask updates a task's title, body and priority. When version is non-nil the
+// update only applies if the task is still at that version; otherwise it fails
+// with ErrVersionMismatch.
+func EditTask(pool *pgxpool.Pool, id, title, body string, priority int, version *int) error {
+	tag, err := pool.Exec(context.Background(), `
+		UPDATE tasks SET title = $2, body = $3, priority = $4
+		WHERE  id = $1
+		  AND  ($5::int IS NULL OR version = $5)
+	`, id, title, body, priority, version)
+	if err != nil {
+		return fmt.Errorf("editing task: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if version != nil {
+			return fmt.Errorf("%w: task %q is no longer at version %d", ErrVersionMismatch, id, *version)
+		}
+		return fmt.Errorf("%w: task %q", ErrNotFound, id)
+	}
+	return nil
+}
+
+// UpdateTaskSpec overwrites the user-authored fields of a task (for import-md).
+// Status, claims and attempts are left untouched.
+func UpdateTaskSpec(q Querier, id, title, body string, priority int, metadata json.RawMessage, requiresApproval bool) error {
+	_, err := q.Exec(context.Background(), `
+		UPDATE tasks
+		SET    title             = $2,
+		       body              = $3,
+		       priority          = $4,
+		       metadata          = $5,
+		       requires_approval = $6
+		WHERE  id = $1
+	`, id, title, body, priority, metadata, requiresApproval)
+	if err != nil {
+		return fmt.Errorf("updating task spec: %w", err)
+	}
+	return nil
+}
+
+// GetTaskBySpec finds the task imported from the given spec file within a project.
+// Returns nil if no such task exists.
+func GetTaskBySpec(q Querier, projectID *string, spec string) (*Task, error) {
+	t, err := scanTask(q.QueryRow(context.Background(),
+		`SELECT `+taskColumns+` FROM tasks
+		WHERE metadata->>'spec' = $1
+		  AND project_id IS NOT DISTINCT FROM $2
+		ORDER BY created_at ASC
+		LIMIT 1`, spec, projectID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting task by spec %s: %w", spec, err)
+	}
+	return &t, nil
+}
+
+// ClearDependencies removes every dependency edge of a task.
+func ClearDependencies(q Querier, taskID string) error {
+	_, err := q.Exec(context.Background(), `
+		DELETE FROM task_deps WHERE task_id = $1
+	`, taskID)
+	if err != nil {
+		return fmt.Errorf("clearing dependencies: %w", err)
+	}
+	return nil
+}
+
+// RemoveDependency deletes a single dependency edge.
+func RemoveDependency(pool *pgxpool.Pool, taskID, dependsOn string) error {
+	tag, err := pool.Exec(context.Background(), `
+		DELETE FROM task_deps WHERE task_id = $1 AND depends_on = $2
+	`, taskID, dependsOn)
+	if err != nil {
+		return fmt.Errorf("removing dependency: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s does not depend on %s", ErrNotFound, taskID, dependsOn)
+	}
+	return nil
+}
+
+// GetTaskDependencies returns the IDs a task directly depends on.
+func GetTaskDependencies(pool *pgxpool.Pool, taskID string) ([]string, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT depends_on FROM task_deps WHERE task_id = $1 ORDER BY depends_on
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("getting dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	deps := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning dependency: %w", err)
+		}
+		deps = append(deps, id)
+	}
+	return deps, rows.Err()
+}
+
+// DependsOn reports whether taskID depends on target, directly or transitively.
+func DependsOn(pool *pgxpool.Pool, taskID, target string) (bool, error) {
+	var found bool
+	err := pool.QueryRow(context.Background(), `
+		WITH RECURSIVE up(id) AS (
+		  SELECT depends_on FROM task_deps WHERE task_id = $1
+		  UNION
+		  SELECT td.depends_on FROM task_deps td JOIN up ON td.task_id = up.id
+		)
+		SELECT EXISTS (SELECT 1 FROM up WHERE id = $2)
+	`, taskID, target).Scan(&found)
+	if err != nil {
+		return false, fmt.Errorf("checking dependency path: %w", err)
+	}
+	return found, nil
+}
+
+// ListDependencies returns a map of task ID to the IDs it depends on.
+// When projectID is non-nil, only edges whose dependent task is in that project are loaded.
+func ListDependencies(pool *pgxpool.Pool, projectID *string) (map[string][]string, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT td.task_id, td.depends_on
+		FROM   task_deps td
+		JOIN   tasks t ON t.id = td.task_id
+		WHERE  $1::text IS NULL OR t.project_id = $1
+		ORDER  BY td.task_id, td.depends_on
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("loading deps: %w", err)
+	}
+	defer rows.Close()
+
+	deps := make(map[string][]string)
+	for rows.Next() {
+		var taskID, depID string
+		if err := rows.Scan(&taskID, &depID); err != nil {
+			return nil, fmt.Errorf("scanning dep: %w", err)
+		}
+		deps[taskID] = append(deps[taskID], depID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating deps: %w", err)
+	}
+	return deps, nil
+}
+
 // HasUnmetDeps returns true if any of the task's dependencies are not yet done.
-func HasUnmetDeps(pool *pgxpool.Pool, taskID string) (bool, error) {
+func HasUnmetDeps(q Querier, taskID string) (bool, error) {
 	var count int
-	err := pool.QueryRow(context.Background(), `
+	err := q.QueryRow(context.Background(), `
 		SELECT COUNT(*)
 		FROM task_deps td
 		JOIN tasks t ON t.id = td.depends_on
@@ -707,10 +1073,10 @@ func GetAgentByTaskID(pool *pgxpool.Pool, taskID string) (*Agent, error) {
 	var a Agent
 	err := pool.QueryRow(context.Background(), `
 		SELECT id, tmux_session, tmux_window, task_id, status, started_at, last_seen,
-		       worktree_dir, branch
+		       worktree_dir, branch, runtime, pid, pid_start, profile, project_id
 		FROM agents WHERE task_id = $1
 	`, taskID).Scan(&a.ID, &a.TmuxSession, &a.TmuxWindow, &a.TaskID, &a.Status, &a.StartedAt, &a.LastSeen,
-		&a.WorktreeDir, &a.Branch)
+		&a.WorktreeDir, &a.Branch, &a.Runtime, &a.PID, &a.PIDStart, &a.Profile, &a.ProjectID)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -725,10 +1091,10 @@ func GetAgent(pool *pgxpool.Pool, id string) (*Agent, error) {
 	var a Agent
 	err := pool.QueryRow(context.Background(), `
 		SELECT id, tmux_session, tmux_window, task_id, status, started_at, last_seen,
-		       worktree_dir, branch
+		       worktree_dir, branch, runtime, pid, pid_start, profile, project_id
 		FROM agents WHERE id = $1
 	`, id).Scan(&a.ID, &a.TmuxSession, &a.TmuxWindow, &a.TaskID, &a.Status, &a.StartedAt, &a.LastSeen,
-		&a.WorktreeDir, &a.Branch)
+		&a.WorktreeDir, &a.Branch, &a.Runtime, &a.PID, &a.PIDStart, &a.Profile, &a.ProjectID)
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
@@ -750,9 +1116,10 @@ func EnqueueMerge(pool *pgxpool.Pool, taskID, agentID, branch, worktreeDir, base
 	return nil
 }
 
-// ClaimMergeEntry atomically claims the next pending merge queue entry.
-// Returns nil if no entry is available.
-func ClaimMergeEntry(pool *pgxpool.Pool) (*MergeQueueEntry, error) {
+// ClaimMergeEntry atomically claims the next pending merge queue entry that
+// can be merged in the repository at repoRoot: one whose project has no
+// repo_path, or that one. Returns nil if no entry is available.
+func ClaimMergeEntry(pool *pgxpool.Pool, repoRoot string) (*MergeQueueEntry, error) {
 	ctx := context.Background()
 	tx, err := pool.Begin(ctx)
 	if err != nil {
@@ -768,16 +1135,20 @@ func ClaimMergeEntry(pool *pgxpool.Pool) (*MergeQueueEntry, error) {
 		WHERE  id = (
 			SELECT id FROM merge_queue
 			WHERE  status = 'pending'
+			  AND  NOT EXISTS (
+			         SELECT 1 FROM tasks t JOIN projects p ON p.id = t.project_id
+			         WHERE  t.id = merge_queue.task_id
+			           AND  p.repo_path IS NOT NULL AND p.repo_path <> $1)
 			ORDER  BY enqueued_at ASC
 			LIMIT  1
 			FOR UPDATE SKIP LOCKED
 		)
 		RETURNING id, task_id, agent_id, branch, worktree_dir, base_branch, status,
-		          commit_sha, merge_sha, conflict_files, error_msg,
+		          commit_sha, merge_sha, conflict_files, error_msg, test_output,
 		          enqueued_at, started_at, completed_at
-	`).Scan(
+	`, repoRoot).Scan(
 		&e.ID, &e.TaskID, &e.AgentID, &e.Branch, &e.WorktreeDir, &e.BaseBranch, &e.Status,
-		&e.CommitSHA, &e.MergeSHA, &e.ConflictFiles, &e.ErrorMsg,
+		&e.CommitSHA, &e.MergeSHA, &e.ConflictFiles, &e.ErrorMsg, &e.TestOutput,
 		&e.EnqueuedAt, &e.StartedAt, &e.CompletedAt,
 	)
 	if err == pgx.ErrNoRows {
@@ -838,11 +1209,89 @@ func FailMerge(pool *pgxpool.Pool, id int64, errMsg string) error {
 	return nil
 }
 
+// RequeueMerge puts a merge queue entry that was interrupted while merging
+// back in the queue, to be processed again.
+func RequeueMerge(pool *pgxpool.Pool, id int64) error {
+	_, err := pool.Exec(context.Background(), `
+		UPDATE merge_queue
+		SET    status     = 'pending',
+		       started_at = NULL
+		WHERE  id = $1 AND status = 'merging'
+	`, id)
+	if err != nil {
+		return fmt.Errorf("requeuing merge: %w", err)
+	}
+	return nil
+}
+
+// RequeueStaleMerges puts the entries left merging in the repository at
+// repoRoot back in the queue, as RequeueMerge does, and returns how many there
+// were. Only call it holding LockMerges: then no processor is working on them.
+func RequeueStaleMerges(pool *pgxpool.Pool, repoRoot string) (int64, error) {
+	tag, err := pool.Exec(context.Background(), `
+		UPDATE merge_queue
+		SET    status     = 'pending',
+		       started_at = NULL
+		WHERE  status = 'merging'
+		  AND  NOT EXISTS (
+		         SELECT 1 FROM tasks t JOIN projects p ON p.id = t.project_id
+		         WHERE  t.id = merge_queue.task_id
+		           AND  p.repo_path IS NOT NULL AND p.repo_path <> $1)
+	`, repoRoot)
+	if err != nil {
+		return 0, fmt.Errorf("requeuing stale merges: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// TestFailMerge marks a merge queue entry as test_failed: the merge result
+// failed its gates, summarized by errMsg and reported in output.
+func TestFailMerge(pool *pgxpool.Pool, id int64, errMsg, output string) error {
+	_, err := pool.Exec(context.Background(), `
+		UPDATE merge_queue
+		SET    status       = 'test_failed',
+		       error_msg    = $2,
+		       test_output  = $3,
+		       completed_at = NOW()
+		WHERE  id = $1
+	`, id, errMsg, output)
+	if err != nil {
+		return fmt.Errorf("recording merge test failure: %w", err)
+	}
+	return nil
+}
+
+// LockMerges takes the merge lock of the repository at repoRoot, so that only
+// one merge processor runs there. It is a session advisory lock, held on a
+// connection of its own until unlock is called; if another processor holds
+// it, LockMerges fails with ErrLocked.
+func LockMerges(pool *pgxpool.Pool, repoRoot string) (unlock func(), err error) {
+	ctx := context.Background()
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquiring merge lock connection: %w", err)
+	}
+	var ok bool
+	err = conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtext('minuano merge'), hashtext($1))`, repoRoot).Scan(&ok)
+	if err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("taking merge lock: %w", err)
+	}
+	if !ok {
+		conn.Release()
+		return nil, fmt.Errorf("%w: another merge processor is running in %s", ErrLocked, repoRoot)
+	}
+	return func() {
+		conn.Exec(ctx, `SELECT pg_advisory_unlock(hashtext('minuano merge'), hashtext($1))`, repoRoot)
+		conn.Release()
+	}, nil
+}
+
 // ListMergeQueue returns all merge queue entries, ordered by enqueue time.
 func ListMergeQueue(pool *pgxpool.Pool) ([]*MergeQueueEntry, error) {
 	rows, err := pool.Query(context.Background(), `
 		SELECT id, task_id, agent_id, branch, worktree_dir, base_branch, status,
-		       commit_sha, merge_sha, conflict_files, error_msg,
+		       commit_sha, merge_sha, conflict_files, error_msg, test_output,
 		       enqueued_at, started_at, completed_at
 		FROM merge_queue
 		ORDER BY enqueued_at ASC
@@ -857,7 +1306,7 @@ func ListMergeQueue(pool *pgxpool.Pool) ([]*MergeQueueEntry, error) {
 		var e MergeQueueEntry
 		if err := rows.Scan(
 			&e.ID, &e.TaskID, &e.AgentID, &e.Branch, &e.WorktreeDir, &e.BaseBranch, &e.Status,
-			&e.CommitSHA, &e.MergeSHA, &e.ConflictFiles, &e.ErrorMsg,
+			&e.CommitSHA, &e.MergeSHA, &e.ConflictFiles, &e.ErrorMsg, &e.TestOutput,
 			&e.EnqueuedAt, &e.StartedAt, &e.CompletedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scanning merge entry: %w", err)
@@ -881,7 +1330,7 @@ func ApproveTask(pool *pgxpool.Pool, taskID, approvedBy string) error {
 		return fmt.Errorf("approving task: %w", err)
 	}
 	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("task %q is not pending_approval", taskID)
+		return fmt.Errorf("%w: task %q is not pending_approval", ErrWrongStatus, taskID)
 	}
 	return nil
 }
@@ -899,7 +1348,7 @@ func RejectTask(pool *pgxpool.Pool, taskID, reason string) error {
 		return fmt.Errorf("rejecting task: %w", err)
 	}
 	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("task %q is not pending_approval", taskID)
+		return fmt.Errorf("%w: task %q is not pending_approval", ErrWrongStatus, taskID)
 	}
 	return nil
 }
@@ -918,7 +1367,7 @@ func UnclaimTask(pool *pgxpool.Pool, taskID string) error {
 		return fmt.Errorf("unclaiming task: %w", err)
 	}
 	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("task %q is not claimed", taskID)
+		return fmt.Errorf("%w: task %q is not claimed", ErrWrongStatus, taskID)
 	}
 	return nil
 }
@@ -942,7 +1391,7 @@ func DraftRelease(pool *pgxpool.Pool, taskID string) error {
 		return fmt.Errorf("releasing draft task: %w", err)
 	}
 	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("task %q is not in draft status", taskID)
+		return fmt.Errorf("%w: task %q is not in draft status", ErrWrongStatus, taskID)
 	}
 	return nil
 }
@@ -120,3 +120,25 @@ func TestAgentJSONTags(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildDependencyTree(t *testing.T) {
+	tasks := []*Task{
+		{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "x"},
+	}
+	deps := map[string][]string{
+		"b": {"a"},
+		"c": {"a"},
+		"d": {"b", "c"},
+		"x": {"other-project-task"},
+	}
+
+	roots := buildDependencyTree(tasks, deps)
+	if len(roots) != 2 || roots[0].Task.ID != "a" || roots[1].Task.ID != "x" {
+		t.Fatalf("expected roots [a x], got %d roots", len(roots))
+	}
+
+	b, c := roots[0].Children[0], roots[0].Children[1]
+	if len(b.Children) != 1 || len(c.Children) != 1 || b.Children[0] != c.Children[0] {
+		t.Error("expected d to be a single node shared by b and c")
+	}
+}
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CompletionSample is one task reaching done, with the time since its last claim.
+type CompletionSample struct {
+	TaskID      string
+	ProjectID   string // empty when the task has no project
+	AgentID     string // empty when unknown (e.g. backfilled history)
+	Attempt     int
+	ClaimToDone *time.Duration // nil when no claim was recorded
+}
+
+// ClaimSample is one claim, with how long the task sat in ready beforehand.
+type ClaimSample struct {
+	TaskID    string
+	ProjectID string
+	AgentID   string
+	Wait      *time.Duration // nil when the ready transition wasn't recorded
+}
+
+// FailureSample is one test_failure context entry.
+type FailureSample struct {
+	TaskID    string
+	ProjectID string
+	AgentID   string
+}
+
+// MergeOutcome is one finished merge queue entry.
+type MergeOutcome struct {
+	ProjectID string
+	Status    string // merged | conflict | test_failed | failed
+}
+
+// GateSample is one run of a completion gate.
+type GateSample struct {
+	Name     string
+	Advisory bool
+	Passed   bool
+	TimedOut bool
+	Duration time.Duration
+}
+
+// ListCompletions returns done transitions since the given time.
+func ListCompletions(pool *pgxpool.Pool, projectID *string, since time.Time) ([]CompletionSample, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT d.task_id, COALESCE(d.project_id, ''), COALESCE(d.agent_id, ''), d.attempt,
+		       EXTRACT(EPOCH FROM d.created_at - (
+		         SELECT MAX(c.created_at) FROM task_transitions c
+		         WHERE  c.task_id = d.task_id
+		           AND  c.to_status = 'claimed'
+		           AND  c.created_at <= d.created_at
+		       ))::float8
+		FROM   task_transitions d
+		WHERE  d.to_status = 'done'
+		  AND  d.created_at >= $1
+		  AND  ($2::text IS NULL OR d.project_id = $2)
+		ORDER  BY d.created_at ASC
+	`, since, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("listing completions: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []CompletionSample
+	for rows.Next() {
+		var s CompletionSample
+		var secs *float64
+		if err := rows.Scan(&s.TaskID, &s.ProjectID, &s.AgentID, &s.Attempt, &secs); err != nil {
+			return nil, fmt.Errorf("scanning completion: %w", err)
+		}
+		s.ClaimToDone = secondsToDuration(secs)
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+// ListClaims returns claimed transitions since the given time.
+func ListClaims(pool *pgxpool.Pool, projectID *string, since time.Time) ([]ClaimSample, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT c.task_id, COALESCE(c.project_id, ''), COALESCE(c.agent_id, ''),
+		       EXTRACT(EPOCH FROM c.created_at - (
+		         SELECT MAX(r.created_at) FROM task_transitions r
+		         WHERE  r.task_id = c.task_id
+		           AND  r.to_status = 'ready'
+		           AND  r.created_at <= c.created_at
+		       ))::float8
+		FROM   task_transitions c
+		WHERE  c.to_status = 'claimed'
+		  AND  c.created_at >= $1
+		  AND  ($2::text IS NULL OR c.project_id = $2)
+		ORDER  BY c.created_at ASC
+	`, since, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("listing claims: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []ClaimSample
+	for rows.Next() {
+		var s ClaimSample
+		var secs *float64
+		if err := rows.Scan(&s.TaskID, &s.ProjectID, &s.AgentID, &secs); err != nil {
+			return nil, fmt.Errorf("scanning claim: %w", err)
+		}
+		s.Wait = secondsToDuration(secs)
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+// ListTestFailures returns test_failure context entries since the given time.
+func ListTestFailures(pool *pgxpool.Pool, projectID *string, since time.Time) ([]FailureSample, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT tc.task_id, COALESCE(t.project_id, ''), COALESCE(tc.agent_id, '')
+		FROM   task_context tc
+		JOIN   tasks t ON t.id = tc.task_id
+		WHERE  tc.kind = 'test_failure'
+		  AND  tc.created_at >= $1
+		  AND  ($2::text IS NULL OR t.project_id = $2)
+	`, since, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("listing test failures: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []FailureSample
+	for rows.Next() {
+		var s FailureSample
+		if err := rows.Scan(&s.TaskID, &s.ProjectID, &s.AgentID); err != nil {
+			return nil, fmt.Errorf("scanning test failure: %w", err)
+		}
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+// ListMergeOutcomes returns merge queue entries completed since the given time.
+func ListMergeOutcomes(pool *pgxpool.Pool, projectID *string, since time.Time) ([]MergeOutcome, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT COALESCE(t.project_id, ''), mq.status
+		FROM   merge_queue mq
+		JOIN   tasks t ON t.id = mq.task_id
+		WHERE  mq.completed_at >= $1
+		  AND  ($2::text IS NULL OR t.project_id = $2)
+	`, since, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("listing merge outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	var outcomes []MergeOutcome
+	for rows.Next() {
+		var o MergeOutcome
+		if err := rows.Scan(&o.ProjectID, &o.Status); err != nil {
+			return nil, fmt.Errorf("scanning merge outcome: %w", err)
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, rows.Err()
+}
+
+func secondsToDuration(secs *float64) *time.Duration {
+	if secs == nil {
+		return nil
+	}
+	d := time.Duration(*secs * float64(time.Second))
+	return &d
+}
+
+// ListGateSamples returns completion gate runs since the given time.
+func ListGateSamples(pool *pgxpool.Pool, projectID *string, since time.Time) ([]GateSample, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT g.name, g.advisory, g.passed, g.timed_out, g.duration_ms
+		FROM   gate_runs g
+		JOIN   tasks t ON t.id = g.task_id
+		WHERE  g.started_at >= $1
+		  AND  ($2::text IS NULL OR t.project_id = $2)
+	`, since, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("listing gate runs: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []GateSample
+	for rows.Next() {
+		var s GateSample
+		var ms int64
+		if err := rows.Scan(&s.Name, &s.Advisory, &s.Passed, &s.TimedOut, &ms); err != nil {
+			return nil, fmt.Errorf("scanning gate run: %w", err)
+		}
+		s.Duration = time.Duration(ms) * time.Millisecond
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
@@ -0,0 +1,300 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AgentSession is one Claude session started for an agent.
+type AgentSession struct {
+	SessionID string
+	AgentID   string
+	StartedAt time.Time
+	EndedAt   *time.Time
+	SyncedAt  *time.Time
+	// SyncedBytes is how much of the transcript has been read.
+	SyncedBytes int64
+}
+
+// ClaimWindow is the span during which an agent held a task: from the claim
+// until the task's next status change (nil while still claimed).
+type ClaimWindow struct {
+	TaskID  string
+	Attempt int
+	Start   time.Time
+	End     *time.Time
+}
+
+// Contains reports whether t falls inside the window.
+func (w ClaimWindow) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && (w.End == nil || t.Before(*w.End))
+}
+
+// UsageRecord is the token usage of one assistant message.
+type UsageRecord struct {
+	MessageID           string
+	SessionID           string
+	AgentID             string
+	TaskID              *string
+	Attempt             *int
+	Model               string
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+	CostUSD             float64
+	CreatedAt           time.Time
+}
+
+// UsageTotals sums token usage and cost.
+type UsageTotals struct {
+	InputTokens         int64   `json:"input_tokens"`
+	OutputTokens        int64   `json:"output_tokens"`
+	CacheCreationTokens int64   `json:"cache_creation_tokens"`
+	CacheReadTokens     int64   `json:"cache_read_tokens"`
+	CostUSD             float64 `json:"cost_usd"`
+}
+
+// Tokens is the total of all token kinds.
+func (u UsageTotals) Tokens() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationTokens + u.CacheReadTokens
+}
+
+// Add accumulates o into u.
+func (u *UsageTotals) Add(o UsageTotals) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheCreationTokens += o.CacheCreationTokens
+	u.CacheReadTokens += o.CacheReadTokens
+	u.CostUSD += o.CostUSD
+}
+
+// AttemptUsage is a task's usage for one attempt and model.
+type AttemptUsage struct {
+	Attempt int    `json:"attempt"`
+	AgentID string `json:"agent_id"`
+	Model   string `json:"model"`
+	UsageTotals
+}
+
+// UsageSample is usage summed per project, agent, task and attempt.
+type UsageSample struct {
+	ProjectID string
+	AgentID   string
+	TaskID    string
+	Attempt   int
+	UsageTotals
+}
+
+const usageSums = `
+	COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+	COALESCE(SUM(cache_creation_tokens), 0), COALESCE(SUM(cache_read_tokens), 0),
+	COALESCE(SUM(cost_usd), 0)::float8`
+
+func usageTargets(u *UsageTotals) []any {
+	return []any{&u.InputTokens, &u.OutputTokens, &u.CacheCreationTokens, &u.CacheReadTokens, &u.CostUSD}
+}
+
+// RegisterAgentSession records the Claude session an agent was started with.
+func RegisterAgentSession(pool *pgxpool.Pool, sessionID, agentID string) error {
+	_, err := pool.Exec(context.Background(),
+		`INSERT INTO agent_sessions (session_id, agent_id) VALUES ($1, $2)`, sessionID, agentID)
+	if err != nil {
+		return fmt.Errorf("registering session for %s: %w", agentID, err)
+	}
+	return nil
+}
+
+// EndAgentSessions marks an agent's open sessions as ended.
+func EndAgentSessions(pool *pgxpool.Pool, agentID string) error {
+	_, err := pool.Exec(context.Background(),
+		`UPDATE agent_sessions SET ended_at = NOW() WHERE agent_id = $1 AND ended_at IS NULL`, agentID)
+	if err != nil {
+		return fmt.Errorf("ending sessions for %s: %w", agentID, err)
+	}
+	return nil
+}
+
+// ListSessionsToSync returns sessions that may have unread usage: still
+// running, or ended after their last sync. A non-empty agentID limits the
+// result to that agent.
+func ListSessionsToSync(pool *pgxpool.Pool, agentID string) ([]*AgentSession, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT session_id, agent_id, started_at, ended_at, synced_at, synced_bytes
+		FROM   agent_sessions
+		WHERE  (ended_at IS NULL OR synced_at IS NULL OR synced_at < ended_at)
+		  AND  ($1 = '' OR agent_id = $1)
+		ORDER  BY started_at
+	`, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*AgentSession
+	for rows.Next() {
+		var s AgentSession
+		if err := rows.Scan(&s.SessionID, &s.AgentID, &s.StartedAt, &s.EndedAt, &s.SyncedAt, &s.SyncedBytes); err != nil {
+			return nil, fmt.Errorf("scanning session: %w", err)
+		}
+		sessions = append(sessions, &s)
+	}
+	return sessions, rows.Err()
+}
+
+// MarkSessionSynced records when a session's transcript was last read, and
+// how much of it.
+func MarkSessionSynced(pool *pgxpool.Pool, sessionID string, bytes int64) error {
+	_, err := pool.Exec(context.Background(),
+		`UPDATE agent_sessions SET synced_at = NOW(), synced_bytes = $2 WHERE session_id = $1`, sessionID, bytes)
+	if err != nil {
+		return fmt.Errorf("marking session %s synced: %w", sessionID, err)
+	}
+	return nil
+}
+
+// ListClaimWindows returns every span during which agentID held a task,
+// reconstructed from task_transitions.
+func ListClaimWindows(pool *pgxpool.Pool, agentID string) ([]ClaimWindow, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT task_id, attempt, created_at, ended_at
+		FROM (
+			SELECT task_id, attempt, agent_id, to_status, created_at,
+			       LEAD(created_at) OVER (PARTITION BY task_id ORDER BY created_at, id) AS ended_at
+			FROM   task_transitions
+			WHERE  task_id IN (SELECT task_id FROM task_transitions
+			                   WHERE agent_id = $1 AND to_status = 'claimed')
+		) t
+		WHERE  to_status = 'claimed' AND agent_id = $1
+		ORDER  BY created_at
+	`, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("listing claim windows for %s: %w", agentID, err)
+	}
+	defer rows.Close()
+
+	var windows []ClaimWindow
+	for rows.Next() {
+		var w ClaimWindow
+		if err := rows.Scan(&w.TaskID, &w.Attempt, &w.Start, &w.End); err != nil {
+			return nil, fmt.Errorf("scanning claim window: %w", err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// UpsertUsage stores usage records. A message seen again (its lines can be
+// read over several syncs) keeps the larger counts.
+func UpsertUsage(pool *pgxpool.Pool, records []UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, r := range records {
+		batch.Queue(`
+			INSERT INTO task_usage (message_id, session_id, agent_id, task_id, attempt, project_id, model,
+			                        input_tokens, output_tokens, cache_creation_tokens, cache_read_tokens,
+			                        cost_usd, created_at)
+			VALUES ($1, $2, $3, $4, $5, (SELECT project_id FROM tasks WHERE id = $4), $6,
+			        $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (message_id) DO UPDATE SET
+			  task_id               = EXCLUDED.task_id,
+			  attempt               = EXCLUDED.attempt,
+			  project_id            = EXCLUDED.project_id,
+			  input_tokens          = GREATEST(task_usage.input_tokens, EXCLUDED.input_tokens),
+			  output_tokens         = GREATEST(task_usage.output_tokens, EXCLUDED.output_tokens),
+			  cache_creation_tokens = GREATEST(task_usage.cache_creation_tokens, EXCLUDED.cache_creation_tokens),
+			  cache_read_tokens     = GREATEST(task_usage.cache_read_tokens, EXCLUDED.cache_read_tokens),
+			  cost_usd              = GREATEST(task_usage.cost_usd, EXCLUDED.cost_usd)
+		`, r.MessageID, r.SessionID, r.AgentID, r.TaskID, r.Attempt, r.Model,
+			r.InputTokens, r.OutputTokens, r.CacheCreationTokens, r.CacheReadTokens,
+			r.CostUSD, r.CreatedAt)
+	}
+	if err := pool.SendBatch(context.Background(), batch).Close(); err != nil {
+		return fmt.Errorf("storing usage: %w", err)
+	}
+	return nil
+}
+
+// GetTaskUsage returns a task's usage per attempt, agent and model.
+func GetTaskUsage(pool *pgxpool.Pool, taskID string) ([]*AttemptUsage, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT attempt, agent_id, model, `+usageSums+`
+		FROM   task_usage
+		WHERE  task_id = $1
+		GROUP  BY attempt, agent_id, model
+		ORDER  BY attempt, MIN(created_at)
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("loading usage for %s: %w", taskID, err)
+	}
+	defer rows.Close()
+
+	var out []*AttemptUsage
+	for rows.Next() {
+		var a AttemptUsage
+		if err := rows.Scan(append([]any{&a.Attempt, &a.AgentID, &a.Model}, usageTargets(&a.UsageTotals)...)...); err != nil {
+			return nil, fmt.Errorf("scanning usage: %w", err)
+		}
+		out = append(out, &a)
+	}
+	return out, rows.Err()
+}
+
+// UsageByTask returns total usage per task, optionally limited to a project.
+func UsageByTask(pool *pgxpool.Pool, projectID *string) (map[string]UsageTotals, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT task_id, `+usageSums+`
+		FROM   task_usage
+		WHERE  task_id IS NOT NULL
+		  AND  ($1::text IS NULL OR project_id = $1)
+		GROUP  BY task_id
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("loading usage by task: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]UsageTotals)
+	for rows.Next() {
+		var id string
+		var u UsageTotals
+		if err := rows.Scan(append([]any{&id}, usageTargets(&u)...)...); err != nil {
+			return nil, fmt.Errorf("scanning usage: %w", err)
+		}
+		out[id] = u
+	}
+	return out, rows.Err()
+}
+
+// ListUsage returns usage since the given time summed per project, agent,
+// task and attempt. Usage between tasks has an empty TaskID and ProjectID.
+func ListUsage(pool *pgxpool.Pool, projectID *string, since time.Time) ([]UsageSample, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT COALESCE(project_id, ''), agent_id, COALESCE(task_id, ''), COALESCE(attempt, 0), `+usageSums+`
+		FROM   task_usage
+		WHERE  created_at >= $2
+		  AND  ($1::text IS NULL OR project_id = $1)
+		GROUP  BY 1, 2, 3, 4
+		ORDER  BY 1, 2, 3, 4
+	`, projectID, since)
+	if err != nil {
+		return nil, fmt.Errorf("listing usage: %w", err)
+	}
+	defer rows.Close()
+
+	var out []UsageSample
+	for rows.Next() {
+		var s UsageSample
+		if err := rows.Scan(append([]any{&s.ProjectID, &s.AgentID, &s.TaskID, &s.Attempt}, usageTargets(&s.UsageTotals)...)...); err != nil {
+			return nil, fmt.Errorf("scanning usage: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimWindowContains(t *testing.T) {
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	closed := ClaimWindow{TaskID: "a", Start: start, End: &end}
+	open := ClaimWindow{TaskID: "b", Start: start}
+
+	tests := []struct {
+		w    ClaimWindow
+		t    time.Time
+		want bool
+	}{
+		{closed, start, true},
+		{closed, start.Add(30 * time.Minute), true},
+		{closed, end, false},
+		{closed, start.Add(-time.Second), false},
+		{open, start.Add(24 * time.Hour), true},
+		{open, start.Add(-time.Second), false},
+	}
+	for _, tt := range tests {
+		if got := tt.w.Contains(tt.t); got != tt.want {
+			t.Errorf("%s.Contains(%s) = %v, want %v", tt.w.TaskID, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestUsageTotals(t *testing.T) {
+	var u UsageTotals
+	u.Add(UsageTotals{InputTokens: 1, OutputTokens: 2, CacheCreationTokens: 3, CacheReadTokens: 4, CostUSD: 0.5})
+	u.Add(UsageTotals{InputTokens: 10, CostUSD: 0.25})
+	if u.Tokens() != 20 || u.CostUSD != 0.75 {
+		t.Errorf("unexpected totals %+v (tokens %d)", u, u.Tokens())
+	}
+}
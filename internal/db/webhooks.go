@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WebhookEvents are the event names a webhook can subscribe to. They are
+// derived from the event log by webhook_event() in 009_webhooks.sql.
+var WebhookEvents = []string{
+	"task.ready",
+	"task.claimed",
+	"task.done",
+	"task.failed",
+	"task.pending_approval",
+	"merge.conflict",
+}
+
+// Webhook is one subscription.
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	ProjectID *string   `json:"project_id,omitempty"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one queued or attempted POST of an event to a webhook.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// DueDelivery is a claimed delivery together with where and how to send it.
+type DueDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// CreateWebhook adds a subscription. Nil projectID or empty events subscribe
+// to everything on that axis.
+func CreateWebhook(pool *pgxpool.Pool, url, secret string, projectID *string, events []string) (*Webhook, error) {
+	if events == nil {
+		events = []string{}
+	}
+	w := &Webhook{URL: url, Secret: secret, ProjectID: projectID, Events: events}
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO webhooks (url, secret, project_id, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, url, secret, projectID, events).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("creating webhook: %w", err)
+	}
+	return w, nil
+}
+
+// ListWebhooks returns all subscriptions, oldest first.
+func ListWebhooks(pool *pgxpool.Pool) ([]*Webhook, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT id, url, secret, project_id, events, created_at
+		FROM   webhooks
+		ORDER  BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var hooks []*Webhook
+	for rows.Next() {
+		var w Webhook
+		if err := rows.Scan(&w.ID, &w.URL, &w.Secret, &w.ProjectID, &w.Events, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning webhook: %w", err)
+		}
+		hooks = append(hooks, &w)
+	}
+	return hooks, rows.Err()
+}
+
+// DeleteWebhook removes a subscription and its delivery history.
+func DeleteWebhook(pool *pgxpool.Pool, id int) error {
+	tag, err := pool.Exec(context.Background(), `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("deleting webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: webhook %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// ClaimDueDeliveries takes up to limit pending deliveries whose next attempt
+// is due, counts the attempt and pushes next_attempt_at out by lease so no
+// other dispatcher picks them up while they are in flight. A dispatcher that
+// dies mid-send simply lets the lease expire.
+func ClaimDueDeliveries(pool *pgxpool.Pool, limit int, lease time.Duration) ([]*DueDelivery, error) {
+	rows, err := pool.Query(context.Background(), `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE  status = 'pending' AND next_attempt_at <= NOW()
+			ORDER  BY next_attempt_at
+			LIMIT  $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET    attempts = d.attempts + 1,
+		       next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM   due, webhooks w
+		WHERE  d.id = due.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts,
+		          d.next_attempt_at, d.last_status_code, d.last_error, d.created_at,
+		          d.delivered_at, w.url, w.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claiming webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var due []*DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt,
+			&d.DeliveredAt, &d.URL, &d.Secret); err != nil {
+			return nil, fmt.Errorf("scanning webhook delivery: %w", err)
+		}
+		due = append(due, &d)
+	}
+	return due, rows.Err()
+}
+
+// MarkDeliveryDelivered records a successful attempt.
+func MarkDeliveryDelivered(pool *pgxpool.Pool, id int64, statusCode int) error {
+	_, err := pool.Exec(context.Background(), `
+		UPDATE webhook_deliveries
+		SET    status = 'delivered', last_status_code = $2, last_error = NULL, delivered_at = NOW()
+		WHERE  id = $1
+	`, id, statusCode)
+	if err != nil {
+		return fmt.Errorf("marking delivery %d delivered: %w", id, err)
+	}
+	return nil
+}
+
+// MarkDeliveryFailed records a failed attempt. With a nil retryAt the
+// delivery is given up on; otherwise it is retried at that time.
+func MarkDeliveryFailed(pool *pgxpool.Pool, id int64, statusCode *int, errMsg string, retryAt *time.Time) error {
+	_, err := pool.Exec(context.Background(), `
+		UPDATE webhook_deliveries
+		SET    status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+		       last_status_code = $2,
+		       last_error = $3,
+		       next_attempt_at = COALESCE($4, next_attempt_at)
+		WHERE  id = $1
+	`, id, statusCode, errMsg, retryAt)
+	if err != nil {
+		return fmt.Errorf("marking delivery %d failed: %w", id, err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the newest deliveries, optionally for one webhook.
+func ListWebhookDeliveries(pool *pgxpool.Pool, webhookID *int, limit int) ([]*WebhookDelivery, error) {
+	rows, err := pool.Query(context.Background(), `
+		SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at,
+		       last_status_code, last_error, created_at, delivered_at
+		FROM   webhook_deliveries
+		WHERE  $1::int IS NULL OR webhook_id = $1
+		ORDER  BY id DESC
+		LIMIT  $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("scanning webhook delivery: %w", err)
+		}
+		out = append(out, &d)
+	}
+	return out, rows.Err()
+}
+
+// PruneWebhookDeliveries deletes finished deliveries created before the given
+// time. Pending ones are kept regardless of age.
+func PruneWebhookDeliveries(pool *pgxpool.Pool, before time.Time) (int64, error) {
+	tag, err := pool.Exec(context.Background(), `
+		DELETE FROM webhook_deliveries
+		WHERE  status IN ('delivered', 'failed') AND created_at < $1
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("pruning webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
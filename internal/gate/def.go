@@ -0,0 +1,111 @@
+package gate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Def is a named gate, as configured for a task or project. A task's gates
+// run in order; the first one that fails, unless it is advisory, fails the
+// attempt and the rest are not run.
+type Def struct {
+	Name string `json:"name" yaml:"name"`
+	Run  string `json:"run" yaml:"run"`
+	// Timeout is a duration, e.g. "5m"; empty for the configured gate
+	// timeout, "0" for none.
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Advisory gates are recorded, but their failures don't fail the attempt.
+	Advisory bool `json:"advisory,omitempty" yaml:"advisory,omitempty"`
+}
+
+// ParseDef parses a gate given on the command line as
+// name[:timeout][:advisory]=command, e.g. "lint:5m:advisory=golangci-lint run".
+func ParseDef(s string) (Def, error) {
+	head, run, ok := strings.Cut(s, "=")
+	if !ok {
+		return Def{}, fmt.Errorf("gate %q: want name[:timeout][:advisory]=command", s)
+	}
+	parts := strings.Split(head, ":")
+	d := Def{Name: strings.TrimSpace(parts[0]), Run: strings.TrimSpace(run)}
+	for _, opt := range parts[1:] {
+		switch opt = strings.TrimSpace(opt); {
+		case opt == "advisory":
+			d.Advisory = true
+		case d.Timeout == "":
+			d.Timeout = opt
+		default:
+			return Def{}, fmt.Errorf("gate %q: unexpected option %q", s, opt)
+		}
+	}
+	return d, d.Validate()
+}
+
+// String formats d the way ParseDef reads it.
+func (d Def) String() string {
+	head := d.Name
+	if d.Timeout != "" {
+		head += ":" + d.Timeout
+	}
+	if d.Advisory {
+		head += ":advisory"
+	}
+	return head + "=" + d.Run
+}
+
+// Validate checks that d has a name, a command and a valid timeout.
+func (d Def) Validate() error {
+	if d.Name == "" || strings.ContainsAny(d.Name, ":= \t\n") {
+		return fmt.Errorf("gate name %q must be non-empty, without spaces, ':' or '='", d.Name)
+	}
+	if strings.TrimSpace(d.Run) == "" {
+		return fmt.Errorf("gate %q has no command", d.Name)
+	}
+	if d.Timeout != "" {
+		if _, err := d.timeout(); err != nil {
+			return fmt.Errorf("gate %q: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateDefs checks a gate list: each gate is valid, and names are unique.
+func ValidateDefs(defs []Def) error {
+	seen := map[string]bool{}
+	for _, d := range defs {
+		if err := d.Validate(); err != nil {
+			return err
+		}
+		if seen[d.Name] {
+			return fmt.Errorf("gate %q is listed twice", d.Name)
+		}
+		seen[d.Name] = true
+	}
+	return nil
+}
+
+// TimeoutOr returns d's timeout, or def if it has none of its own.
+func (d Def) TimeoutOr(def time.Duration) time.Duration {
+	if d.Timeout == "" {
+		return def
+	}
+	t, err := d.timeout()
+	if err != nil {
+		return def
+	}
+	return t
+}
+
+func (d Def) timeout() (time.Duration, error) {
+	if d.Timeout == "0" {
+		return 0, nil
+	}
+	t, err := time.ParseDuration(d.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q", d.Timeout)
+	}
+	if t < 0 {
+		return 0, fmt.Errorf("timeout %q must not be negative", d.Timeout)
+	}
+	return t, nil
+}
@@ -0,0 +1,60 @@
+package gate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDef(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Def
+	}{
+		{"build=go build ./...", Def{Name: "build", Run: "go build ./..."}},
+		{"lint:5m:advisory=golangci-lint run", Def{Name: "lint", Run: "golangci-lint run", Timeout: "5m", Advisory: true}},
+		{"lint:advisory = golangci-lint run", Def{Name: "lint", Run: "golangci-lint run", Advisory: true}},
+		{"test:0=go test -run 'A=B' ./...", Def{Name: "test", Run: "go test -run 'A=B' ./...", Timeout: "0"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseDef(tt.in)
+		if err != nil {
+			t.Errorf("ParseDef(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDef(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if again, _ := ParseDef(got.String()); again != got {
+			t.Errorf("%q does not round-trip: %+v", got.String(), again)
+		}
+	}
+
+	for _, in := range []string{"go test ./...", "=go test", "test=", "test:soon=go test", "test:1m:2m=go test", "my test=go test", "test:-1m=go test"} {
+		if _, err := ParseDef(in); err == nil {
+			t.Errorf("ParseDef(%q): expected an error", in)
+		}
+	}
+}
+
+func TestValidateDefs(t *testing.T) {
+	defs := []Def{{Name: "build", Run: "go build ./..."}, {Name: "test", Run: "go test ./..."}}
+	if err := ValidateDefs(defs); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateDefs(append(defs, Def{Name: "build", Run: "make"})); err == nil {
+		t.Error("expected an error for a repeated name")
+	}
+	if err := ValidateDefs([]Def{{Name: "lint"}}); err == nil {
+		t.Error("expected an error for a missing command")
+	}
+}
+
+func TestTimeoutOr(t *testing.T) {
+	def := 10 * time.Minute
+	tests := map[string]time.Duration{"": def, "0": 0, "90s": 90 * time.Second}
+	for timeout, want := range tests {
+		if got := (Def{Name: "test", Run: "true", Timeout: timeout}).TimeoutOr(def); got != want {
+			t.Errorf("TimeoutOr with %q = %s, want %s", timeout, got, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package gate runs completion gates: the checks a task's work must pass, such
+// as its build, linters and tests, each run through bash in the agent's
+// working directory under a timeout that kills the command's whole process
+// group. All of the output goes to an artifact; only a bounded
+// excerpt is kept in memory, for the task's failure context.
+package gate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// ExcerptLines and ExcerptBytes bound the excerpt of the output kept
+	// for the task's test_failure context.
+	ExcerptLines = 80
+	ExcerptBytes = 16 * 1024
+)
+
+// KillGrace is how long a stopped gate, or a worker's agent, gets to exit
+// after SIGTERM before its process group is killed. The subprocess runtime
+// gives the worker itself longer, so a worker stopped mid-gate still exits
+// before it is killed.
+const KillGrace = 4 * time.Second
+
+// killGrace is KillGrace, shortened by tests.
+var killGrace = KillGrace
+
+// Spec describes a gate run.
+type Spec struct {
+	Command string
+	Dir     string
+	Env     []string      // nil: the current environment
+	Timeout time.Duration // 0: none
+	// Output, if not nil, also receives the output as it is written.
+	Output io.Writer
+	// Filter, if not nil, wraps the writer the output goes to, rewriting it
+	// before it reaches the artifact, the excerpt and Output. It is closed
+	// once the command has exited.
+	Filter func(w io.Writer) io.WriteCloser
+}
+
+// Result is how a gate run went.
+type Result struct {
+	// ExitCode is the command's exit status, or -1 if it was killed by a
+	// signal (as when it timed out).
+	ExitCode int
+	TimedOut bool
+	Duration time.Duration
+	// Bytes is the size of the whole output.
+	Bytes   int64
+	Excerpt string
+}
+
+// Passed reports whether the gate passed.
+func (r *Result) Passed() bool {
+	return r.ExitCode == 0 && !r.TimedOut
+}
+
+// Describe summarizes the outcome, e.g. "exit 1 after 3.2s".
+func (r *Result) Describe() string {
+	d := r.Duration.Round(100 * time.Millisecond)
+	if r.TimedOut {
+		return fmt.Sprintf("timed out after %s", d)
+	}
+	return fmt.Sprintf("exit %d after %s", r.ExitCode, d)
+}
+
+// Run runs the gate, writing all of its output to artifact. It returns an
+// error only if the command could not be started, or ctx was cancelled
+// before it finished; a failing or timed-out command is a Result.
+func Run(ctx context.Context, s Spec, artifact io.Writer) (*Result, error) {
+	tail := NewTailBuffer(ExcerptBytes)
+	count := &counter{}
+	writers := []io.Writer{artifact, tail, count}
+	if s.Output != nil {
+		writers = append(writers, s.Output)
+	}
+	var out io.Writer = io.MultiWriter(writers...)
+	var filter io.WriteCloser
+	if s.Filter != nil {
+		filter = s.Filter(out)
+		out = filter
+	}
+
+	cmd := exec.Command("bash", "-c", s.Command)
+	cmd.Dir, cmd.Env = s.Dir, s.Env
+	cmd.Stdout, cmd.Stderr = out, out
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Children left running in the background may hold the output open;
+	// don't wait on them once the command itself has exited.
+	cmd.WaitDelay = killGrace
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %q: %w", s.Command, err)
+	}
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+
+	var timeout <-chan time.Time
+	if s.Timeout > 0 {
+		t := time.NewTimer(s.Timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	r := &Result{}
+	select {
+	case <-done:
+	case <-timeout:
+		r.TimedOut = true
+		stop(cmd.Process.Pid, done)
+	case <-ctx.Done():
+		stop(cmd.Process.Pid, done)
+	}
+	// Whatever the command left running goes with it.
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if filter != nil {
+		filter.Close()
+	}
+
+	if !r.TimedOut && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	r.Duration = time.Since(start)
+	r.ExitCode = cmd.ProcessState.ExitCode()
+	r.Bytes = count.n
+	r.Excerpt = LastLines(tail.String(), ExcerptLines)
+	return r, nil
+}
+
+// stop ends the process group led by pid: SIGTERM first, then SIGKILL if it
+// hasn't exited after the grace period.
+func stop(pid int, done <-chan struct{}) {
+	syscall.Kill(-pid, syscall.SIGTERM)
+	select {
+	case <-done:
+	case <-time.After(killGrace):
+		syscall.Kill(-pid, syscall.SIGKILL)
+		<-done
+	}
+}
+
+// LastLines returns the last n lines of s.
+func LastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TailBuffer keeps the last bytes written to it, up to a maximum.
+type TailBuffer struct {
+	max int
+	b   []byte
+}
+
+// NewTailBuffer returns a TailBuffer keeping the last max bytes.
+func NewTailBuffer(max int) *TailBuffer {
+	return &TailBuffer{max: max}
+}
+
+func (t *TailBuffer) Write(p []byte) (int, error) {
+	t.b = append(t.b, p...)
+	if len(t.b) > t.max {
+		t.b = t.b[len(t.b)-t.max:]
+	}
+	return len(p), nil
+}
+
+func (t *TailBuffer) String() string { return string(t.b) }
+
+// counter counts the bytes written to it.
+type counter struct{ n int64 }
+
+func (c *counter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,163 @@
+package gate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunPass(t *testing.T) {
+	dir := t.TempDir()
+	var artifact, live bytes.Buffer
+	r, err := Run(context.Background(), Spec{Command: "pwd; echo out; echo err >&2", Dir: dir, Output: &live}, &artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Passed() || r.ExitCode != 0 || r.TimedOut {
+		t.Errorf("expected a pass, got %+v", r)
+	}
+	want := dir + "\nout\nerr\n"
+	if artifact.String() != want || live.String() != want {
+		t.Errorf("artifact %q, live %q, want %q", artifact.String(), live.String(), want)
+	}
+	if r.Bytes != int64(len(want)) {
+		t.Errorf("bytes = %d, want %d", r.Bytes, len(want))
+	}
+}
+
+// upper upper-cases the output and records that it was closed.
+type upper struct {
+	w      io.Writer
+	closed bool
+}
+
+func (u *upper) Write(p []byte) (int, error) { return u.w.Write(bytes.ToUpper(p)) }
+func (u *upper) Close() error                { u.closed = true; return nil }
+
+func TestRunFilter(t *testing.T) {
+	var artifact, live bytes.Buffer
+	var u *upper
+	filter := func(w io.Writer) io.WriteCloser {
+		u = &upper{w: w}
+		return u
+	}
+	r, err := Run(context.Background(), Spec{Command: "echo hello", Output: &live, Filter: filter}, &artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifact.String() != "HELLO\n" || live.String() != "HELLO\n" || r.Excerpt != "HELLO" {
+		t.Errorf("artifact %q, live %q, excerpt %q", artifact.String(), live.String(), r.Excerpt)
+	}
+	if !u.closed {
+		t.Error("the filter should be closed")
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	var artifact bytes.Buffer
+	r, err := Run(context.Background(), Spec{Command: "for i in $(seq 1 200); do echo line $i; done; exit 3"}, &artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Passed() || r.ExitCode != 3 {
+		t.Errorf("expected exit 3, got %+v", r)
+	}
+	if !strings.Contains(artifact.String(), "line 1\n") {
+		t.Error("artifact should hold the whole output")
+	}
+	lines := strings.Split(r.Excerpt, "\n")
+	if len(lines) != ExcerptLines || lines[0] != "line 121" || lines[len(lines)-1] != "line 200" {
+		t.Errorf("excerpt should be the last %d lines, got %d from %q", ExcerptLines, len(lines), lines[0])
+	}
+	if !strings.HasPrefix(r.Describe(), "exit 3 after ") {
+		t.Errorf("Describe() = %q", r.Describe())
+	}
+}
+
+func TestRunTimeoutKillsGroup(t *testing.T) {
+	defer func(d time.Duration) { killGrace = d }(killGrace)
+	killGrace = 500 * time.Millisecond
+
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	// The background sleep ignores SIGTERM, so only killing the group stops it.
+	cmd := "(trap '' TERM; sleep 30) & echo $! > " + pidFile + "; wait"
+	start := time.Now()
+	r, err := Run(context.Background(), Spec{Command: cmd, Timeout: 200 * time.Millisecond}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.TimedOut || r.Passed() || r.ExitCode != -1 {
+		t.Errorf("expected a timeout, got %+v", r)
+	}
+	if !strings.HasPrefix(r.Describe(), "timed out after ") {
+		t.Errorf("Describe() = %q", r.Describe())
+	}
+	if time.Since(start) > killGrace+5*time.Second {
+		t.Errorf("took %s to time out", time.Since(start))
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	time.Sleep(100 * time.Millisecond)
+	if running(pid) {
+		t.Errorf("background process %d survived the timeout", pid)
+	}
+}
+
+// running reports whether pid is alive; a killed process nobody has reaped
+// yet is not.
+func running(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+	// The state follows the parenthesized command name.
+	_, rest, _ := strings.Cut(string(stat), ") ")
+	return !strings.HasPrefix(rest, "Z")
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err := Run(ctx, Spec{Command: "sleep 30"}, &bytes.Buffer{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRunCannotStart(t *testing.T) {
+	if _, err := Run(context.Background(), Spec{Command: "true", Dir: "/does/not/exist"}, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	if got := LastLines("a\nb\nc\n", 2); got != "b\nc" {
+		t.Errorf("got %q", got)
+	}
+	if got := LastLines("a\nb", 5); got != "a\nb" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := NewTailBuffer(5)
+	b.Write([]byte("abc"))
+	b.Write([]byte("defg"))
+	if got := b.String(); got != "cdefg" {
+		t.Errorf("got %q, want %q", got, "cdefg")
+	}
+}
@@ -1,8 +1,11 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -15,6 +18,10 @@ func (e *ConflictError) Error() string {
 	return fmt.Sprintf("merge conflict in %d file(s): %s", len(e.Files), strings.Join(e.Files, ", "))
 }
 
+// ErrBaseMoved is returned by FastForward when the base branch gained commits
+// after the merge was made, so the merge has to be redone on top of them.
+var ErrBaseMoved = errors.New("moved since the merge: not a fast-forward")
+
 // RepoRoot returns the root directory of the current git repository.
 func RepoRoot() (string, error) {
 	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
@@ -100,51 +107,122 @@ func AddAndCommit(worktreeDir, message string) (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
-// MergeNoFF performs a no-fast-forward merge of branch into baseBranch.
-// Returns the merge commit SHA on success, or a *ConflictError on conflict.
-func MergeNoFF(branch, baseBranch, message string) (string, error) {
-	// Checkout base branch.
-	if out, err := exec.Command("git", "checkout", baseBranch).CombinedOutput(); err != nil {
-		return "", fmt.Errorf("checkout %s: %s: %w", baseBranch, strings.TrimSpace(string(out)), err)
+// MergeWorktree returns the integration worktree merges are done in,
+// .minuano/merge under the repository root, adding it if it doesn't exist.
+// It is kept detached, so no branch is ever checked out there.
+func MergeWorktree(root string) (string, error) {
+	dir := filepath.Join(root, ".minuano", "merge")
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return dir, nil
+	}
+	// Forget the worktree if its directory was deleted, so it can be added again.
+	exec.Command("git", "-C", root, "worktree", "prune").Run()
+	if _, err := gitIn(root, "worktree", "add", "--detach", dir); err != nil {
+		return "", fmt.Errorf("adding merge worktree: %w", err)
 	}
+	return dir, nil
+}
 
-	// Attempt merge.
-	mergeCmd := exec.Command("git", "merge", "--no-ff", "-m", message, branch)
-	out, err := mergeCmd.CombinedOutput()
-	if err != nil {
-		// Check for conflict.
-		conflictFiles, conflictErr := getConflictFiles()
+// MergeNoFF performs a no-fast-forward merge of branch into baseBranch in the
+// worktree dir, which is first reset to a clean, detached checkout of
+// baseBranch; baseBranch itself isn't moved (see FastForward). Returns the
+// merge commit SHA on success, or a *ConflictError on conflict, after
+// aborting the merge.
+func MergeNoFF(dir, branch, baseBranch, message string) (string, error) {
+	if _, err := gitIn(dir, "checkout", "--detach", "--force", baseBranch); err != nil {
+		return "", fmt.Errorf("checkout %s: %w", baseBranch, err)
+	}
+	if _, err := gitIn(dir, "clean", "-ffd"); err != nil {
+		return "", fmt.Errorf("cleaning merge worktree: %w", err)
+	}
+
+	if _, err := gitIn(dir, "merge", "--no-ff", "-m", message, branch); err != nil {
+		conflictFiles, conflictErr := getConflictFiles(dir)
+		AbortMerge(dir)
 		if conflictErr == nil && len(conflictFiles) > 0 {
 			return "", &ConflictError{Files: conflictFiles}
 		}
-		return "", fmt.Errorf("merge failed: %s: %w", strings.TrimSpace(string(out)), err)
+		return "", fmt.Errorf("merge failed: %w", err)
 	}
 
-	// Get merge commit SHA.
-	shaOut, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	sha, err := gitIn(dir, "rev-parse", "HEAD")
 	if err != nil {
 		return "", fmt.Errorf("getting merge sha: %w", err)
 	}
-	return strings.TrimSpace(string(shaOut)), nil
+	return sha, nil
 }
 
-// AbortMerge aborts an in-progress merge.
-func AbortMerge() error {
-	out, err := exec.Command("git", "merge", "--abort").CombinedOutput()
+// AbortMerge aborts an in-progress merge in dir.
+func AbortMerge(dir string) error {
+	if _, err := gitIn(dir, "merge", "--abort"); err != nil {
+		return fmt.Errorf("aborting merge: %w", err)
+	}
+	return nil
+}
+
+// FastForward moves baseBranch to sha, which must descend from it. If
+// baseBranch is checked out in one of the repository's worktrees, that
+// checkout is fast-forwarded, which fails rather than overwrite local changes;
+// otherwise only the ref is updated, provided it hasn't moved meanwhile.
+func FastForward(root, baseBranch, sha string) error {
+	ref := "refs/heads/" + baseBranch
+	old, err := gitIn(root, "rev-parse", "--verify", ref)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", baseBranch, err)
+	}
+	if _, err := gitIn(root, "merge-base", "--is-ancestor", old, sha); err != nil {
+		return fmt.Errorf("%s has %w", baseBranch, ErrBaseMoved)
+	}
+
+	list, err := gitIn(root, "worktree", "list", "--porcelain")
 	if err != nil {
-		return fmt.Errorf("aborting merge: %s: %w", strings.TrimSpace(string(out)), err)
+		return fmt.Errorf("listing worktrees: %w", err)
+	}
+	if wt := worktreeOf(list, ref); wt != "" {
+		if _, err := gitIn(wt, "merge", "--ff-only", sha); err != nil {
+			return fmt.Errorf("fast-forwarding %s in %s: %w", baseBranch, wt, err)
+		}
+		return nil
+	}
+	if _, err := gitIn(root, "update-ref", "-m", "minuano merge", ref, sha, old); err != nil {
+		if now, rerr := gitIn(root, "rev-parse", "--verify", ref); rerr == nil && now != old {
+			return fmt.Errorf("%s has %w", baseBranch, ErrBaseMoved)
+		}
+		return fmt.Errorf("updating %s: %w", baseBranch, err)
 	}
 	return nil
 }
 
-func getConflictFiles() ([]string, error) {
-	out, err := exec.Command("git", "diff", "--name-only", "--diff-filter=U").Output()
+// worktreeOf returns the worktree ref is checked out in, from the output of
+// `git worktree list --porcelain`, or "" if it isn't checked out.
+func worktreeOf(porcelain, ref string) string {
+	var dir string
+	for _, line := range strings.Split(porcelain, "\n") {
+		if path, ok := strings.CutPrefix(line, "worktree "); ok {
+			dir = path
+		} else if line == "branch "+ref {
+			return dir
+		}
+	}
+	return ""
+}
+
+func getConflictFiles(dir string) ([]string, error) {
+	out, err := gitIn(dir, "diff", "--name-only", "--diff-filter=U")
 	if err != nil {
 		return nil, err
 	}
-	raw := strings.TrimSpace(string(out))
-	if raw == "" {
+	if out == "" {
 		return nil, nil
 	}
-	return strings.Split(raw, "\n"), nil
+	return strings.Split(out, "\n"), nil
+}
+
+// gitIn runs git in dir, returning its trimmed output.
+func gitIn(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return strings.TrimSpace(string(out)), nil
 }
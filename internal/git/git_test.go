@@ -0,0 +1,157 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// testRepo creates a repository with a commit on main, and returns its root.
+func testRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for k, v := range map[string]string{
+		"GIT_AUTHOR_NAME": "test", "GIT_AUTHOR_EMAIL": "test@example.com",
+		"GIT_COMMITTER_NAME": "test", "GIT_COMMITTER_EMAIL": "test@example.com",
+		"GIT_CONFIG_GLOBAL": os.DevNull, "GIT_CONFIG_NOSYSTEM": "1",
+	} {
+		t.Setenv(k, v)
+	}
+	root := t.TempDir()
+	mustGit(t, root, "init", "-q", "-b", "main")
+	commitFile(t, root, "a.txt", "a\n")
+	return root
+}
+
+func mustGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := gitIn(dir, args...)
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return out
+}
+
+func commitFile(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mustGit(t, dir, "add", name)
+	mustGit(t, dir, "commit", "-q", "-m", "edit "+name)
+}
+
+// branch commits data to name on a new branch off main.
+func branch(t *testing.T, root, name, file, data string) {
+	t.Helper()
+	cur := mustGit(t, root, "rev-parse", "--abbrev-ref", "HEAD")
+	mustGit(t, root, "checkout", "-q", "-b", name, "main")
+	commitFile(t, root, file, data)
+	mustGit(t, root, "checkout", "-q", cur)
+}
+
+func TestMergeInWorktree(t *testing.T) {
+	root := testRepo(t)
+	mustGit(t, root, "checkout", "-q", "-b", "mine")
+	if err := os.WriteFile(filepath.Join(root, "wip.txt"), []byte("uncommitted\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	branch(t, root, "feature", "b.txt", "b\n")
+	branch(t, root, "clash-1", "a.txt", "one\n")
+	branch(t, root, "clash-2", "a.txt", "two\n")
+
+	dir, err := MergeWorktree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := MergeWorktree(root); err != nil || again != dir {
+		t.Fatalf("the merge worktree should be reused: %q, %v", again, err)
+	}
+
+	base := mustGit(t, root, "rev-parse", "main")
+	sha, err := MergeNoFF(dir, "feature", "main", "Merge feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGit(t, root, "rev-parse", "main"); got != base {
+		t.Error("main should not move before FastForward")
+	}
+	if err := FastForward(root, "main", sha); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGit(t, root, "rev-parse", "main"); got != sha {
+		t.Errorf("main = %s, want the merge %s", got, sha)
+	}
+	if got := mustGit(t, root, "rev-parse", "--abbrev-ref", "HEAD"); got != "mine" {
+		t.Errorf("the repository checkout moved to %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "wip.txt")); err != nil {
+		t.Error("uncommitted work in the checkout should be left alone")
+	}
+
+	sha, err = MergeNoFF(dir, "clash-1", "main", "Merge clash-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := FastForward(root, "main", sha); err != nil {
+		t.Fatal(err)
+	}
+	_, err = MergeNoFF(dir, "clash-2", "main", "Merge clash-2")
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || len(conflict.Files) != 1 || conflict.Files[0] != "a.txt" {
+		t.Fatalf("expected a conflict in a.txt, got %v", err)
+	}
+	if got := mustGit(t, dir, "status", "--porcelain"); got != "" {
+		t.Errorf("the merge worktree should be clean after a conflict:\n%s", got)
+	}
+}
+
+func TestFastForwardCheckedOut(t *testing.T) {
+	root := testRepo(t)
+	branch(t, root, "feature", "b.txt", "b\n")
+	dir, err := MergeWorktree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha, err := MergeNoFF(dir, "feature", "main", "Merge feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := FastForward(root, "main", sha); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "b.txt")); err != nil {
+		t.Error("the checkout of main should be fast-forwarded with it")
+	}
+
+	// A base that moved since the merge isn't overwritten.
+	branch(t, root, "other", "c.txt", "c\n")
+	sha, err = MergeNoFF(dir, "other", "main", "Merge other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFile(t, root, "d.txt", "d\n")
+	if err := FastForward(root, "main", sha); !errors.Is(err, ErrBaseMoved) {
+		t.Errorf("fast-forwarding a base that has moved: got %v, want ErrBaseMoved", err)
+	}
+}
+
+func TestWorktreeOf(t *testing.T) {
+	list := "worktree /repo\nHEAD 1111\nbranch refs/heads/main\n\n" +
+		"worktree /repo/.minuano/merge\nHEAD 2222\ndetached\n\n" +
+		"worktree /repo/.minuano/worktrees/a1\nHEAD 3333\nbranch refs/heads/minuano/a1\n"
+	tests := map[string]string{
+		"refs/heads/main":       "/repo",
+		"refs/heads/minuano/a1": "/repo/.minuano/worktrees/a1",
+		"refs/heads/develop":    "",
+	}
+	for ref, want := range tests {
+		if got := worktreeOf(list, ref); got != want {
+			t.Errorf("worktreeOf(%s) = %q, want %q", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Package gotest reads the event stream of `go test -json`: it turns it back
+// into the text go test would have printed, and collects the failing
+// packages and tests with their own output.
+package gotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Command returns cmd with -json added if it is a plain go test invocation,
+// and whether it is one. Commands with shell syntax (pipes, lists,
+// substitutions) are left alone, as their output may not be go test's.
+func Command(cmd string) (string, bool) {
+	cmd = strings.TrimSpace(cmd)
+	rest, ok := strings.CutPrefix(cmd, "go test")
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return cmd, false
+	}
+	if strings.ContainsAny(cmd, "|;&<>`$()\n") {
+		return cmd, false
+	}
+	for _, f := range strings.Fields(rest) {
+		if f == "-json" || f == "--json" || f == "-json=true" {
+			return cmd, true
+		}
+	}
+	return "go test -json" + rest, true
+}
+
+// Failure is a failing test, or a package that failed outside of its tests
+// (to build, or in TestMain), with Test empty.
+type Failure struct {
+	Package string `json:"package"`
+	Test    string `json:"test,omitempty"`
+	Output  string `json:"output"`
+}
+
+// Name is "TestX in pkg/a", or "pkg/a" for a package failure.
+func (f Failure) Name() string {
+	if f.Test == "" {
+		return f.Package
+	}
+	return f.Test + " in " + f.Package
+}
+
+// maxReason bounds the length of a Reason.
+const maxReason = 200
+
+// Reason is the line of the failure's output most likely to say what went
+// wrong: the first one that isn't go test's own bookkeeping, cut short if it
+// is long.
+func (f Failure) Reason() string {
+	for _, line := range strings.Split(f.Output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "",
+			strings.HasPrefix(line, "=== "),
+			strings.HasPrefix(line, "--- FAIL"),
+			strings.HasPrefix(line, "# "),
+			strings.HasPrefix(line, "FAIL"),
+			line == "exit status 1":
+			continue
+		}
+		if len(line) > maxReason {
+			cut := maxReason
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			line = line[:cut] + "…"
+		}
+		return line
+	}
+	return ""
+}
+
+// event is a line of go test -json output (see `go doc test2json`).
+type event struct {
+	Action      string
+	Package     string
+	Test        string
+	Output      string
+	ImportPath  string // build-output and build-fail events
+	FailedBuild string // fail events of packages that didn't build
+}
+
+// Decoder is written go test -json output. It writes the text it encodes to
+// w, lines that aren't events as they are, and collects the failures.
+type Decoder struct {
+	w      io.Writer
+	line   []byte
+	output map[[2]string]*strings.Builder // by package and test
+	builds map[string]*strings.Builder    // build output by import path
+	passed map[[2]string]bool             // by package and test
+	failed []Failure
+}
+
+// NewDecoder returns a decoder writing text to w.
+func NewDecoder(w io.Writer) *Decoder {
+	return &Decoder{
+		w:      w,
+		output: map[[2]string]*strings.Builder{},
+		builds: map[string]*strings.Builder{},
+		passed: map[[2]string]bool{},
+	}
+}
+
+// Write decodes whole lines of p, keeping any partial line for the next write.
+func (d *Decoder) Write(p []byte) (int, error) {
+	d.line = append(d.line, p...)
+	for {
+		i := bytes.IndexByte(d.line, '\n')
+		if i < 0 {
+			break
+		}
+		if err := d.decode(d.line[:i+1]); err != nil {
+			return len(p), err
+		}
+		d.line = d.line[i+1:]
+	}
+	return len(p), nil
+}
+
+// Close decodes what is left of the last line.
+func (d *Decoder) Close() error {
+	if len(d.line) == 0 {
+		return nil
+	}
+	err := d.decode(d.line)
+	d.line = nil
+	return err
+}
+
+func (d *Decoder) decode(line []byte) error {
+	var e event
+	if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &e) != nil || e.Action == "" {
+		_, err := d.w.Write(line)
+		return err
+	}
+	switch e.Action {
+	case "output":
+		d.testOutput(e.Package, e.Test).WriteString(e.Output)
+		_, err := io.WriteString(d.w, e.Output)
+		return err
+	case "build-output":
+		d.buildOutput(e.ImportPath).WriteString(e.Output)
+		_, err := io.WriteString(d.w, e.Output)
+		return err
+	case "pass", "skip":
+		// Only failures' output is kept.
+		key := [2]string{e.Package, e.Test}
+		delete(d.output, key)
+		if e.Action == "pass" {
+			d.passed[key] = true
+		}
+	case "fail":
+		key := [2]string{e.Package, e.Test}
+		out := d.output[key]
+		if e.FailedBuild != "" {
+			out = d.builds[e.FailedBuild]
+		}
+		f := Failure{Package: e.Package, Test: e.Test}
+		if out != nil {
+			f.Output = out.String()
+		}
+		delete(d.output, key)
+		d.failed = append(d.failed, f)
+	}
+	return nil
+}
+
+func (d *Decoder) testOutput(pkg, test string) *strings.Builder {
+	k := [2]string{pkg, test}
+	if d.output[k] == nil {
+		d.output[k] = &strings.Builder{}
+	}
+	return d.output[k]
+}
+
+func (d *Decoder) buildOutput(importPath string) *strings.Builder {
+	if d.builds[importPath] == nil {
+		d.builds[importPath] = &strings.Builder{}
+	}
+	return d.builds[importPath]
+}
+
+// Passed reports whether the test passed; with an empty test, whether the
+// package did.
+func (d *Decoder) Passed(pkg, test string) bool {
+	return d.passed[[2]string{pkg, test}]
+}
+
+// Failures returns what failed, by package and test. A test failing only
+// because of its failing subtests is left out, as is a package failing only
+// because of its failing tests.
+func (d *Decoder) Failures() []Failure {
+	failing := map[string]bool{} // packages with failing tests
+	for _, f := range d.failed {
+		if f.Test != "" {
+			failing[f.Package] = true
+		}
+	}
+	var out []Failure
+	for _, f := range d.failed {
+		if f.Test == "" && failing[f.Package] {
+			continue
+		}
+		if f.Test != "" && d.hasFailingSubtest(f) {
+			continue
+		}
+		out = append(out, f)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Package != out[j].Package {
+			return out[i].Package < out[j].Package
+		}
+		return out[i].Test < out[j].Test
+	})
+	return out
+}
+
+func (d *Decoder) hasFailingSubtest(parent Failure) bool {
+	for _, f := range d.failed {
+		if f.Package == parent.Package && strings.HasPrefix(f.Test, parent.Test+"/") {
+			return true
+		}
+	}
+	return false
+}
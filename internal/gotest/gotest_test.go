@@ -0,0 +1,121 @@
+package gotest
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"go test ./...", "go test -json ./...", true},
+		{"go test", "go test -json", true},
+		{"  go test -race ./internal/...  ", "go test -json -race ./internal/...", true},
+		{"go test -json ./...", "go test -json ./...", true},
+		{"go testify", "go testify", false},
+		{"make test", "make test", false},
+		{"go test ./... | tee out.log", "go test ./... | tee out.log", false},
+		{"go test ./... && go vet ./...", "go test ./... && go vet ./...", false},
+		{"go test $(go list ./...)", "go test $(go list ./...)", false},
+	}
+	for _, tt := range tests {
+		got, ok := Command(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Command(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+const stream = `{"Action":"start","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/a","Test":"TestOK"}
+{"Action":"output","Package":"example.com/a","Test":"TestOK","Output":"=== RUN   TestOK\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestOK","Output":"--- PASS: TestOK (0.00s)\n"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOK"}
+{"Action":"run","Package":"example.com/a","Test":"TestLogin"}
+{"Action":"output","Package":"example.com/a","Test":"TestLogin","Output":"=== RUN   TestLogin\n"}
+{"Action":"run","Package":"example.com/a","Test":"TestLogin/bad_password"}
+{"Action":"output","Package":"example.com/a","Test":"TestLogin/bad_password","Output":"=== RUN   TestLogin/bad_password\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestLogin/bad_password","Output":"    login_test.go:12: expected 401, got 200\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestLogin/bad_password","Output":"--- FAIL: TestLogin/bad_password (0.00s)\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestLogin/bad_password"}
+{"Action":"output","Package":"example.com/a","Test":"TestLogin","Output":"--- FAIL: TestLogin (0.00s)\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestLogin"}
+{"Action":"output","Package":"example.com/a","Output":"FAIL\n"}
+{"Action":"fail","Package":"example.com/a"}
+{"ImportPath":"example.com/b","Action":"build-output","Output":"# example.com/b\n"}
+{"ImportPath":"example.com/b","Action":"build-output","Output":"b/b.go:3:2: undefined: x\n"}
+{"ImportPath":"example.com/b","Action":"build-fail"}
+{"Action":"output","Package":"example.com/b","Output":"FAIL\texample.com/b [build failed]\n"}
+{"Action":"fail","Package":"example.com/b","FailedBuild":"example.com/b"}
+`
+
+func TestDecoder(t *testing.T) {
+	var text strings.Builder
+	d := NewDecoder(&text)
+	// Write in uneven chunks, splitting lines, as a pipe would.
+	r := strings.NewReader("not json\n" + stream)
+	buf := make([]byte, 37)
+	for {
+		n, err := r.Read(buf)
+		d.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"not json\n", "=== RUN   TestOK\n", "--- FAIL: TestLogin (0.00s)\n", "b/b.go:3:2: undefined: x\n"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("decoded text is missing %q:\n%s", want, text.String())
+		}
+	}
+	if strings.Contains(text.String(), `"Action"`) {
+		t.Errorf("decoded text has raw events:\n%s", text.String())
+	}
+
+	if !d.Passed("example.com/a", "TestOK") || d.Passed("example.com/a", "TestLogin") || d.Passed("example.com/a", "") {
+		t.Error("only TestOK passed")
+	}
+	if len(d.output) != 0 {
+		t.Errorf("the output of finished tests should be dropped, %d kept", len(d.output))
+	}
+
+	got := d.Failures()
+	if len(got) != 2 {
+		t.Fatalf("got %d failures, want 2: %+v", len(got), got)
+	}
+	if got[0].Name() != "TestLogin/bad_password in example.com/a" || got[0].Reason() != "login_test.go:12: expected 401, got 200" {
+		t.Errorf("first failure = %q, %q", got[0].Name(), got[0].Reason())
+	}
+	if got[1].Name() != "example.com/b" || got[1].Reason() != "b/b.go:3:2: undefined: x" {
+		t.Errorf("second failure = %q, %q", got[1].Name(), got[1].Reason())
+	}
+}
+
+func TestDecoderPlainOutput(t *testing.T) {
+	var text strings.Builder
+	d := NewDecoder(&text)
+	io.WriteString(d, "flag provided but not defined: -json\nusage")
+	d.Close()
+	if len(d.Failures()) != 0 {
+		t.Errorf("expected no failures, got %+v", d.Failures())
+	}
+	if text.String() != "flag provided but not defined: -json\nusage" {
+		t.Errorf("got %q", text.String())
+	}
+}
+
+func TestReasonCut(t *testing.T) {
+	f := Failure{Output: "--- FAIL: TestX\n" + strings.Repeat("é", maxReason) + "\n"}
+	got := f.Reason()
+	if !strings.HasSuffix(got, "…") || len(got) > maxReason+len("…") || !utf8.ValidString(got) {
+		t.Errorf("got %q", got)
+	}
+}
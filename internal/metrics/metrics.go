@@ -0,0 +1,227 @@
+// Package metrics exports Minuano's database state in the Prometheus text format.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+)
+
+// refreshInterval bounds staleness for state that doesn't raise notifications
+// (agent heartbeats, merge queue, approval age).
+const refreshInterval = 15 * time.Second
+
+// debounce coalesces bursts of task_events into one refresh.
+const debounce = time.Second
+
+// agentStatuses are always exported, so "no working agents" is a zero rather
+// than a missing series.
+var agentStatuses = []string{"idle", "working", "dead"}
+
+// Snapshot is one read of everything the exporter reports.
+type Snapshot struct {
+	Tasks          []db.StatusCount
+	Agents         []db.StatusCount
+	MergeQueue     []db.StatusCount
+	Events         []db.EventCount
+	OldestApproval time.Duration
+	RefreshedAt    time.Time
+}
+
+// Collector keeps the latest rendered snapshot and refreshes it on task
+// notifications and on a timer, so scrapes never hit the database.
+type Collector struct {
+	pool *pgxpool.Pool
+
+	mu   sync.RWMutex
+	body []byte
+}
+
+// NewCollector creates a collector backed by pool.
+func NewCollector(pool *pgxpool.Pool) *Collector {
+	return &Collector{pool: pool}
+}
+
+// Refresh reads a new snapshot from the database.
+func (c *Collector) Refresh() error {
+	var s Snapshot
+	var err error
+	if s.Tasks, err = db.CountTasksByStatus(c.pool); err != nil {
+		return err
+	}
+	if s.Agents, err = db.CountAgentsByStatus(c.pool); err != nil {
+		return err
+	}
+	if s.MergeQueue, err = db.CountMergeQueueByStatus(c.pool); err != nil {
+		return err
+	}
+	if s.Events, err = db.CountTaskEvents(c.pool); err != nil {
+		return err
+	}
+	if s.OldestApproval, err = db.OldestPendingApproval(c.pool); err != nil {
+		return err
+	}
+	s.RefreshedAt = time.Now()
+
+	body := Render(s)
+	c.mu.Lock()
+	c.body = body
+	c.mu.Unlock()
+	return nil
+}
+
+// Run refreshes until ctx is cancelled. Task status changes trigger a refresh
+// via the task_events channel; everything else is picked up by the timer.
+// Call Refresh first so the endpoint has data before Run's first tick.
+func (c *Collector) Run(ctx context.Context) {
+	changed := make(chan struct{}, 1)
+	go func() {
+		for ctx.Err() == nil {
+			err := db.Listen(ctx, c.pool, "task_events", func(string) {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			})
+			if err != nil {
+				log.Printf("metrics: %v (retrying)", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(debounce):
+			}
+		case <-ticker.C:
+		}
+		if err := c.Refresh(); err != nil {
+			log.Printf("metrics: refresh failed: %v", err)
+		}
+	}
+}
+
+// ServeHTTP writes the latest snapshot.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	body := c.body
+	c.mu.RUnlock()
+
+	if body == nil {
+		http.Error(w, "metrics not collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(body)
+}
+
+// Render formats a snapshot in the Prometheus text exposition format.
+func Render(s Snapshot) []byte {
+	var b bytes.Buffer
+
+	family(&b, "minuano_tasks", "gauge", "Tasks by project and status.")
+	for _, c := range s.Tasks {
+		sample(&b, "minuano_tasks", float64(c.Count), "project", c.ProjectID, "status", c.Status)
+	}
+
+	family(&b, "minuano_agents", "gauge", "Registered agents by status.")
+	agents := map[string]int{}
+	for _, c := range s.Agents {
+		agents[c.Status] += c.Count
+	}
+	for _, st := range agentStatuses {
+		if _, ok := agents[st]; !ok {
+			agents[st] = 0
+		}
+	}
+	for _, st := range sortedKeys(agents) {
+		sample(&b, "minuano_agents", float64(agents[st]), "status", st)
+	}
+
+	// Counted from the queue as it stands, so even finished outcomes can go
+	// down: a gauge, not a counter.
+	family(&b, "minuano_merge_queue_entries", "gauge", "Merge queue entries by project and status.")
+	for _, c := range s.MergeQueue {
+		sample(&b, "minuano_merge_queue_entries", float64(c.Count), "project", c.ProjectID, "status", c.Status)
+	}
+
+	family(&b, "minuano_pending_approval_oldest_seconds", "gauge", "Age of the oldest task awaiting approval.")
+	sample(&b, "minuano_pending_approval_oldest_seconds", s.OldestApproval.Seconds())
+
+	events := []struct{ event, name, help string }{
+		{"claims", "minuano_task_claims_total", "Task claims."},
+		{"completions", "minuano_task_completions_total", "Tasks marked done."},
+		{"test_failures", "minuano_test_failures_total", "Failed test runs."},
+		{"reclaims", "minuano_task_reclaims_total", "Stale claims reset to ready."},
+	}
+	for _, e := range events {
+		family(&b, e.name, "counter", e.help)
+		for _, c := range s.Events {
+			if c.Event == e.event {
+				sample(&b, e.name, float64(c.Count), "project", c.ProjectID)
+			}
+		}
+	}
+
+	family(&b, "minuano_metrics_refreshed_timestamp_seconds", "gauge", "When the exporter last read the database.")
+	sample(&b, "minuano_metrics_refreshed_timestamp_seconds", float64(s.RefreshedAt.Unix()))
+
+	return b.Bytes()
+}
+
+func family(b *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes one line; labels are name/value pairs.
+func sample(b *bytes.Buffer, name string, value float64, labels ...string) {
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(b, " %g\n", value)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
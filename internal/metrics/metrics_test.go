@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+func TestRender(t *testing.T) {
+	s := Snapshot{
+		Tasks: []db.StatusCount{
+			{ProjectID: "web", Status: "ready", Count: 3},
+			{ProjectID: "", Status: "done", Count: 1},
+		},
+		Agents: []db.StatusCount{{Status: "idle", Count: 2}},
+		MergeQueue: []db.StatusCount{
+			{ProjectID: "web", Status: "pending", Count: 1},
+			{ProjectID: "web", Status: "conflict", Count: 4},
+		},
+		Events: []db.EventCount{
+			{ProjectID: "web", Event: "claims", Count: 7},
+			{ProjectID: "web", Event: "reclaims", Count: 1},
+		},
+		OldestApproval: 90 * time.Second,
+		RefreshedAt:    time.Unix(1700000000, 0),
+	}
+	out := string(Render(s))
+
+	checks := []string{
+		"# TYPE minuano_tasks gauge",
+		`minuano_tasks{project="web",status="ready"} 3`,
+		`minuano_tasks{project="",status="done"} 1`,
+		`minuano_agents{status="idle"} 2`,
+		`minuano_agents{status="working"} 0`,
+		"# TYPE minuano_merge_queue_entries gauge",
+		`minuano_merge_queue_entries{project="web",status="pending"} 1`,
+		`minuano_merge_queue_entries{project="web",status="conflict"} 4`,
+		"minuano_pending_approval_oldest_seconds 90",
+		`minuano_task_claims_total{project="web"} 7`,
+		`minuano_task_reclaims_total{project="web"} 1`,
+		"# TYPE minuano_test_failures_total counter",
+		"minuano_metrics_refreshed_timestamp_seconds 1.7e+09",
+	}
+	for _, c := range checks {
+		if !strings.Contains(out, c) {
+			t.Errorf("output missing %q:\n%s", c, out)
+		}
+	}
+	if strings.Contains(out, "minuano_merges_total") {
+		t.Error("merge outcomes should not be reported as a counter")
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	got := escapeLabel("a\"b\\c\nd")
+	want := `a\"b\\c\nd`
+	if got != want {
+		t.Errorf("escapeLabel = %q, want %q", got, want)
+	}
+}
+
+func TestServeHTTP_NotCollected(t *testing.T) {
+	c := NewCollector(nil)
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 503 {
+		t.Errorf("expected 503 before first refresh, got %d", rec.Code)
+	}
+}
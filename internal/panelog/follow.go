@@ -0,0 +1,192 @@
+package panelog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+)
+
+// pollInterval is how often a follower looks for more output or a new segment.
+const pollInterval = 500 * time.Millisecond
+
+// ansiRe matches terminal escape sequences: CSI (colors, cursor movement),
+// OSC (window titles, hyperlinks), charset selection and two-byte escapes.
+var ansiRe = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[()][0-9A-Za-z]|\x1b[@-Z\\-_]`)
+
+// StripANSI removes terminal escape sequences from s.
+func StripANSI(s string) string {
+	return ansiRe.ReplaceAllString(s, "")
+}
+
+// CleanLine turns a line of raw pane output into plain text: escape sequences
+// are removed and, where the line was redrawn with carriage returns (spinners,
+// progress bars), only the final text is kept.
+func CleanLine(s string) string {
+	s = strings.TrimRight(StripANSI(s), "\r")
+	if i := strings.LastIndexByte(s, '\r'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.Map(func(r rune) rune {
+		if r < ' ' && r != '\t' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// followReader reads a file that is still being written: at end of file it
+// waits for more rather than returning io.EOF, until done reports that the
+// file is complete or ctx is cancelled.
+type followReader struct {
+	ctx    context.Context
+	f      *os.File
+	done   func() bool
+	onWait func()
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		if n > 0 || (err != nil && err != io.EOF) {
+			return n, err
+		}
+		if r.onWait != nil {
+			r.onWait()
+		}
+		if r.done() {
+			// The last of it may have been written since the read above.
+			return r.f.Read(p)
+		}
+		select {
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Follow streams agentID's pane output line by line to emit, like tail -f:
+// first the last backlog lines of its current segment, then everything new,
+// moving on to the next segment whenever the agent's claim changes. It
+// returns when ctx is cancelled or the agent is gone and its output read.
+func Follow(ctx context.Context, pool *pgxpool.Pool, agentID string, backlog int, emit func(string)) error {
+	var last int64
+	for ctx.Err() == nil {
+		seg, err := db.LatestPaneLog(pool, agentID)
+		if err != nil {
+			return err
+		}
+		if seg != nil && seg.ID != last {
+			if err := FollowSegment(ctx, pool, seg, backlog, emit); err != nil {
+				return err
+			}
+			last = seg.ID
+			backlog = -1 // later segments are all new output
+			continue
+		}
+		if seg != nil && seg.EndedAt != nil {
+			if a, err := db.GetAgent(pool, agentID); err == nil && a == nil {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(pollInterval):
+		}
+	}
+	return nil
+}
+
+// FollowSegment streams one segment line by line to emit until it is
+// complete: its recorder finished it, or moved on to a newer segment. With
+// backlog >= 0, only the last backlog lines written before the call are
+// emitted; with backlog < 0, all of them are.
+func FollowSegment(ctx context.Context, pool *pgxpool.Pool, seg *db.PaneLog, backlog int, emit func(string)) error {
+	f, err := os.Open(seg.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var held []string
+	catchingUp := backlog >= 0
+	out := func(line string) {
+		if !catchingUp {
+			emit(line)
+			return
+		}
+		held = append(held, line)
+		if len(held) > backlog {
+			held = held[1:]
+		}
+	}
+
+	r := &followReader{
+		ctx: ctx,
+		f:   f,
+		done: func() bool {
+			latest, err := db.LatestPaneLog(pool, seg.AgentID)
+			return err == nil && latest != nil && (latest.ID != seg.ID || latest.EndedAt != nil)
+		},
+		// Reaching the end of the file means every complete line written so
+		// far has been read: release the backlog and stream from here on.
+		onWait: func() {
+			if catchingUp {
+				catchingUp = false
+				for _, l := range held {
+					emit(l)
+				}
+				held = nil
+			}
+		},
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err == io.EOF {
+		return nil // finished without output
+	}
+	if err != nil {
+		return ignoreCancel(ctx, err)
+	}
+	gz.Multistream(false)
+
+	err = readLines(gz, out)
+	r.onWait()
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil // recorder died mid-segment
+	}
+	return ignoreCancel(ctx, err)
+}
+
+// readLines calls fn for each line of r, including a final unterminated one.
+func readLines(r io.Reader, fn func(string)) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			fn(strings.TrimSuffix(line, "\n"))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func ignoreCancel(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,322 @@
+// Package panelog archives agent pane output per task attempt.
+//
+// The agent runtime pipes each agent's output (a tmux window via pipe-pane, a
+// subprocess via its stdout) into a recorder process, the hidden `minuano
+// pane-log` command, which writes it to gzip files on disk: a new segment
+// whenever the agent's claimed task or attempt changes. Segments are indexed
+// in pane_logs, so a task's output can be read long after the agent is gone.
+package panelog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/git"
+	"github.com/otavio/minuano/internal/shell"
+)
+
+const (
+	// claimCheckInterval bounds how often the recorder asks which task the
+	// agent holds; output written in between goes to the current segment.
+	claimCheckInterval = 2 * time.Second
+
+	// flushInterval is how stale a live segment can be on disk.
+	flushInterval = time.Second
+)
+
+// Dir is where pane logs are written: $MINUANO_LOG_DIR, or .minuano/logs at
+// the repository root (next to the agent worktrees).
+func Dir() (string, error) {
+	if dir := os.Getenv("MINUANO_LOG_DIR"); dir != "" {
+		return filepath.Abs(dir)
+	}
+	root, err := git.RepoRoot()
+	if err != nil {
+		if root, err = os.Getwd(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(root, ".minuano", "logs"), nil
+}
+
+// Command returns the shell command agentID's output should be piped into.
+func Command(agentID, dbURL string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("locating minuano binary: %w", err)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", fmt.Errorf("locating log directory: %w", err)
+	}
+	return command(exe, dir, dbURL, agentID), nil
+}
+
+func command(exe, dir, dbURL, agentID string) string {
+	return fmt.Sprintf("DATABASE_URL=%s exec %s pane-log --dir %s %s",
+		shell.Quote(dbURL), shell.Quote(exe), shell.Quote(dir), shell.Quote(agentID))
+}
+
+// segmentPath names the file for a segment started at t. Idle output (no
+// claimed task) goes to "idle" segments.
+func segmentPath(dir, agentID string, taskID *string, attempt int, t time.Time) string {
+	name := t.UTC().Format("20060102T150405.000Z")
+	if taskID != nil {
+		name += fmt.Sprintf("-%s-%d", strings.ReplaceAll(*taskID, string(filepath.Separator), "_"), attempt)
+	} else {
+		name += "-idle"
+	}
+	return filepath.Join(dir, agentID, name+".log.gz")
+}
+
+// segment is one gzip file being written.
+type segment struct {
+	id      int64 // pane_logs row; 0 if indexing failed
+	taskID  *string
+	attempt int
+	f       *os.File
+	gz      *gzip.Writer
+	bytes   int64
+}
+
+func createSegment(path string) (*segment, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &segment{f: f, gz: gzip.NewWriter(f)}, nil
+}
+
+func (s *segment) Write(p []byte) (int, error) {
+	n, err := s.gz.Write(p)
+	s.bytes += int64(n)
+	return n, err
+}
+
+func (s *segment) Flush() error {
+	return s.gz.Flush()
+}
+
+func (s *segment) Close() error {
+	return errors.Join(s.gz.Close(), s.f.Close())
+}
+
+func (s *segment) holds(taskID *string, attempt int) bool {
+	if s.taskID == nil || taskID == nil {
+		return s.taskID == nil && taskID == nil
+	}
+	return *s.taskID == *taskID && s.attempt == attempt
+}
+
+// Recorder writes one agent's pane output into segments.
+type Recorder struct {
+	pool    *pgxpool.Pool
+	dir     string
+	agentID string
+	seg     *segment
+}
+
+// NewRecorder returns a recorder writing agentID's segments under dir.
+func NewRecorder(pool *pgxpool.Pool, dir, agentID string) *Recorder {
+	return &Recorder{pool: pool, dir: dir, agentID: agentID}
+}
+
+// Run copies r into segments until r is exhausted (the pipe closes when the
+// agent goes away) or ctx is cancelled, then finishes the open segment.
+func (rec *Recorder) Run(ctx context.Context, r io.Reader) error {
+	chunks := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				select {
+				case chunks <- append([]byte(nil), buf[:n]...):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+
+	var checked time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return rec.finish()
+		case err := <-readErr:
+			return errors.Join(err, rec.finish())
+		case <-flush.C:
+			if rec.seg != nil {
+				if err := rec.seg.Flush(); err != nil {
+					log.Printf("pane-log %s: %v", rec.agentID, err)
+				}
+			}
+		case chunk := <-chunks:
+			if rec.seg == nil || time.Since(checked) >= claimCheckInterval {
+				checked = time.Now()
+				if err := rec.follow(); err != nil {
+					log.Printf("pane-log %s: %v", rec.agentID, err)
+				}
+			}
+			if rec.seg == nil {
+				continue
+			}
+			if _, err := rec.seg.Write(chunk); err != nil {
+				log.Printf("pane-log %s: %v", rec.agentID, err)
+			}
+		}
+	}
+}
+
+// follow starts a new segment if the agent's claim changed since the current
+// one began. A failed lookup keeps writing to the current segment.
+func (rec *Recorder) follow() error {
+	taskID, attempt, err := db.CurrentClaim(rec.pool, rec.agentID)
+	if err != nil {
+		if rec.seg != nil {
+			return err
+		}
+		taskID, attempt = nil, 0
+	}
+	if rec.seg != nil && rec.seg.holds(taskID, attempt) {
+		return nil
+	}
+	if err := rec.finish(); err != nil {
+		log.Printf("pane-log %s: %v", rec.agentID, err)
+	}
+
+	path := segmentPath(rec.dir, rec.agentID, taskID, attempt, time.Now())
+	seg, err := createSegment(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	seg.taskID, seg.attempt = taskID, attempt
+	rec.seg = seg
+
+	var attemptPtr *int
+	if taskID != nil {
+		attemptPtr = &attempt
+	}
+	// Unindexed output is still on disk; keep recording.
+	seg.id, err = db.CreatePaneLog(rec.pool, rec.agentID, taskID, attemptPtr, path)
+	return err
+}
+
+// finish closes the current segment and records its size.
+func (rec *Recorder) finish() error {
+	seg := rec.seg
+	if seg == nil {
+		return nil
+	}
+	rec.seg = nil
+	err := seg.Close()
+	if seg.id != 0 {
+		err = errors.Join(err, db.FinishPaneLog(rec.pool, seg.id, seg.bytes))
+	}
+	return err
+}
+
+// Copy writes a segment's uncompressed contents to w. A segment that is still
+// being written, or whose recorder died, ends at its last flush.
+func Copy(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err == io.EOF {
+		return nil // nothing flushed yet
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	defer gz.Close()
+
+	if _, err := io.Copy(w, gz); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
+
+// Tail returns the last lines of agentID's most recent segment as plain
+// text, the equivalent of capturing a pane for runtimes without one.
+func Tail(pool *pgxpool.Pool, agentID string, lines int) (string, error) {
+	seg, err := db.LatestPaneLog(pool, agentID)
+	if err != nil || seg == nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := Copy(&buf, seg.Path); err != nil {
+		return "", err
+	}
+	var out []string
+	readLines(&buf, func(line string) {
+		out = append(out, CleanLine(line))
+		if len(out) > lines {
+			out = out[1:]
+		}
+	})
+	return strings.Join(out, "\n"), nil
+}
+
+// Prune deletes segments that ended before the given time, or were left
+// unfinished by an agent that is gone, and returns how many were removed.
+func Prune(pool *pgxpool.Pool, before time.Time) (int, error) {
+	paths, err := db.PrunePaneLogs(pool, before)
+	if err != nil {
+		return 0, err
+	}
+	return len(paths), removeAll(paths)
+}
+
+// PruneGates deletes the kept output of gate runs that started before the
+// given time and returns how many files were removed.
+func PruneGates(pool *pgxpool.Pool, before time.Time) (int, error) {
+	paths, err := db.PruneGateArtifacts(pool, before)
+	if err != nil {
+		return 0, err
+	}
+	return len(paths), removeAll(paths)
+}
+
+// removeAll removes the files at paths, and their directories once empty.
+// Files already gone are not an error.
+func removeAll(paths []string) error {
+	var errs []error
+	for _, p := range paths {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		os.Remove(filepath.Dir(p)) // only succeeds once the directory is empty
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,180 @@
+package panelog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCommand(t *testing.T) {
+	got := command("/usr/bin/minuano", "/repo/.minuano/logs", "postgres://u:p'w@h/db", "agent-1")
+	want := `DATABASE_URL='postgres://u:p'\''w@h/db' exec '/usr/bin/minuano' pane-log --dir '/repo/.minuano/logs' 'agent-1'`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestSegmentPath(t *testing.T) {
+	at := time.Date(2026, 3, 2, 10, 4, 5, 0, time.FixedZone("BRT", -3*3600))
+	task := "impl-auth-3f2"
+
+	if got, want := segmentPath("/logs", "agent-1", &task, 2, at), "/logs/agent-1/20260302T130405.000Z-impl-auth-3f2-2.log.gz"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, want := segmentPath("/logs", "agent-1", nil, 0, at), "/logs/agent-1/20260302T130405.000Z-idle.log.gz"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSegmentHolds(t *testing.T) {
+	a, b := "a", "b"
+	idle := &segment{}
+	onA := &segment{taskID: &a, attempt: 1}
+
+	if !idle.holds(nil, 0) || idle.holds(&a, 1) {
+		t.Error("idle segment should only hold no claim")
+	}
+	if !onA.holds(&a, 1) || onA.holds(&a, 2) || onA.holds(&b, 1) || onA.holds(nil, 0) {
+		t.Error("task segment should only hold its own task and attempt")
+	}
+}
+
+func TestSegmentRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent-1", "seg.log.gz")
+	seg, err := createSegment(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg.Write([]byte("hello\n"))
+	seg.Write([]byte("world\n"))
+
+	// Flushed but unfinished: readable up to the flush.
+	if err := seg.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var live bytes.Buffer
+	if err := Copy(&live, path); err != nil {
+		t.Fatalf("reading live segment: %v", err)
+	}
+	if live.String() != "hello\nworld\n" {
+		t.Errorf("live segment = %q", live.String())
+	}
+
+	if err := seg.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if seg.bytes != 12 {
+		t.Errorf("bytes = %d, want 12", seg.bytes)
+	}
+	var done bytes.Buffer
+	if err := Copy(&done, path); err != nil {
+		t.Fatal(err)
+	}
+	if done.String() != "hello\nworld\n" {
+		t.Errorf("finished segment = %q", done.String())
+	}
+}
+
+func TestCopyEmptySegment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.log.gz")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Copy(&buf, path); err != nil || buf.Len() != 0 {
+		t.Errorf("got %q, %v", buf.String(), err)
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gates", "task-1")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "run.log")
+	if err := os.WriteFile(path, []byte("ok\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeAll([]string{path, filepath.Join(dir, "gone.log")}); err != nil {
+		t.Fatalf("removeAll: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("emptied directory still there: %v", err)
+	}
+}
+
+func TestCleanLine(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"\x1b[1;32mPASS\x1b[0m ok", "PASS ok"},
+		{"\x1b]0;claude\x07title set", "title set"},
+		{"\x1b[2K\x1b[1Gdone", "done"},
+		{"⠋ working\r⠙ working\rdone\r", "done"},
+		{"bell\x07 and\ttab", "bell and\ttab"},
+		{"\x1b(Bcharset", "charset"},
+	}
+	for _, tt := range tests {
+		if got := CleanLine(tt.in); got != tt.want {
+			t.Errorf("CleanLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	var got []string
+	if err := readLines(strings.NewReader("a\nb\n\nc"), func(l string) { got = append(got, l) }); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFollowReaderWaitsForMore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "live")
+	w, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.WriteString("first\n")
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var finished atomic.Bool
+	waits := 0
+	r := &followReader{
+		ctx:  context.Background(),
+		f:    f,
+		done: finished.Load,
+		onWait: func() {
+			waits++
+			if waits == 1 {
+				w.WriteString("second\n")
+			} else {
+				finished.Store(true)
+			}
+		},
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("got %q", data)
+	}
+}
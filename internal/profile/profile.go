@@ -0,0 +1,257 @@
+// Package profile describes how to run a coding CLI as a Minuano agent. A
+// profile gives the command template, extra environment, how the prompt is
+// delivered and how the agent signals that it finished its task, so Claude
+// Code, Codex CLI, Aider, Gemini CLI or a local stub can all work the same
+// task queue.
+//
+// Built-in profiles can be overridden, and new ones added, in a YAML file:
+// $MINUANO_PROFILES, or .minuano/profiles.yaml at the repository root.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/otavio/minuano/internal/git"
+	"github.com/otavio/minuano/internal/shell"
+	"gopkg.in/yaml.v3"
+)
+
+// Default is the profile agents run under when none is given.
+const Default = "claude"
+
+// Prompt delivery modes.
+const (
+	// PromptArg substitutes the prompt text into the command as {{.Prompt}}.
+	PromptArg = "arg"
+	// PromptStdin feeds the prompt to the command's standard input.
+	PromptStdin = "stdin"
+	// PromptFile passes the path of a file holding the prompt as {{.PromptFile}}.
+	PromptFile = "file"
+)
+
+// Completion modes.
+const (
+	// CompletionScript agents call minuano-done themselves, which runs the
+	// tests and settles the task. They can loop over the queue.
+	CompletionScript = "script"
+	// CompletionExit agents work one task and exit; Minuano then runs the
+	// completion gate in their place. Only `minuano worker` runs them.
+	CompletionExit = "exit"
+)
+
+// Profile is one way of running an agent.
+type Profile struct {
+	Name        string `yaml:"-" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	// Command is a shell command template. {{.Prompt}}, {{.PromptFile}},
+	// {{.AgentID}} and {{.SessionID}} expand to single, quoted shell words.
+	Command    string            `yaml:"command" json:"command"`
+	Env        map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	Prompt     string            `yaml:"prompt,omitempty" json:"prompt"`
+	Completion string            `yaml:"completion,omitempty" json:"completion"`
+	// Source is where the profile was defined: "built-in" or a file path.
+	Source string `yaml:"-" json:"source"`
+}
+
+// builtins are the profiles available without a profiles file.
+var builtins = map[string]Profile{
+	"claude": {
+		Description: "Claude Code",
+		Command:     "claude --dangerously-skip-permissions --session-id {{.SessionID}} -p {{.Prompt}}",
+		Prompt:      PromptArg,
+		Completion:  CompletionScript,
+	},
+	"codex": {
+		Description: "OpenAI Codex CLI",
+		Command:     "codex exec --full-auto {{.Prompt}}",
+		Prompt:      PromptArg,
+		Completion:  CompletionScript,
+	},
+	"gemini": {
+		Description: "Gemini CLI",
+		Command:     "gemini --yolo --prompt {{.Prompt}}",
+		Prompt:      PromptArg,
+		Completion:  CompletionScript,
+	},
+	"aider": {
+		Description: "Aider, one task per run",
+		Command:     "aider --yes-always --message-file {{.PromptFile}}",
+		Prompt:      PromptFile,
+		Completion:  CompletionExit,
+	},
+}
+
+// Vars are the values a command template is expanded with.
+type Vars struct {
+	AgentID   string
+	SessionID string
+	// PromptFile is the path of the file holding the prompt.
+	PromptFile string
+}
+
+// Script returns the shell command that runs the agent.
+func (p *Profile) Script(v Vars) (string, error) {
+	tmpl, err := template.New(p.Name).Option("missingkey=error").Parse(p.Command)
+	if err != nil {
+		return "", fmt.Errorf("profile %s: parsing command: %w", p.Name, err)
+	}
+	var b strings.Builder
+	err = tmpl.Execute(&b, map[string]string{
+		"AgentID":    shell.Quote(v.AgentID),
+		"SessionID":  shell.Quote(v.SessionID),
+		"Prompt":     `"$(cat ` + shell.Quote(v.PromptFile) + `)"`,
+		"PromptFile": shell.Quote(v.PromptFile),
+	})
+	if err != nil {
+		return "", fmt.Errorf("profile %s: expanding command: %w", p.Name, err)
+	}
+	script := b.String()
+	if p.Prompt == PromptStdin {
+		script += " < " + shell.Quote(v.PromptFile)
+	}
+	return script, nil
+}
+
+// Exports returns shell lines exporting the profile's environment, for
+// agents started by typing into a terminal.
+func (p *Profile) Exports() []string {
+	var lines []string
+	for _, k := range slices.Sorted(maps.Keys(p.Env)) {
+		lines = append(lines, "export "+k+"="+shell.Quote(p.Env[k]))
+	}
+	return lines
+}
+
+// UsesSession reports whether the command takes a session ID, and so leaves a
+// Claude transcript to read token usage from.
+func (p *Profile) UsesSession() bool {
+	return strings.Contains(p.Command, ".SessionID")
+}
+
+// Validate checks that the profile is complete and consistent.
+func (p *Profile) Validate() error {
+	if strings.TrimSpace(p.Command) == "" {
+		return fmt.Errorf("profile %s: command is required", p.Name)
+	}
+	if _, err := template.New(p.Name).Parse(p.Command); err != nil {
+		return fmt.Errorf("profile %s: parsing command: %w", p.Name, err)
+	}
+	switch p.Prompt {
+	case PromptArg:
+		if !strings.Contains(p.Command, ".Prompt}}") {
+			return fmt.Errorf("profile %s: prompt %q needs {{.Prompt}} in the command", p.Name, p.Prompt)
+		}
+	case PromptFile:
+		if !strings.Contains(p.Command, ".PromptFile") {
+			return fmt.Errorf("profile %s: prompt %q needs {{.PromptFile}} in the command", p.Name, p.Prompt)
+		}
+	case PromptStdin:
+	default:
+		return fmt.Errorf("profile %s: unknown prompt delivery %q (want arg, stdin or file)", p.Name, p.Prompt)
+	}
+	if p.Completion != CompletionScript && p.Completion != CompletionExit {
+		return fmt.Errorf("profile %s: unknown completion %q (want script or exit)", p.Name, p.Completion)
+	}
+	return nil
+}
+
+// Set is the profiles available to agents.
+type Set map[string]*Profile
+
+// Builtins returns the built-in profiles.
+func Builtins() Set {
+	s := Set{}
+	for name, p := range builtins {
+		p.Name, p.Source = name, "built-in"
+		p.Env = maps.Clone(p.Env)
+		s[name] = &p
+	}
+	return s
+}
+
+// File is where profiles are defined: $MINUANO_PROFILES, or
+// .minuano/profiles.yaml at the repository root.
+func File() string {
+	if f := os.Getenv("MINUANO_PROFILES"); f != "" {
+		return f
+	}
+	root, err := git.RepoRoot()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(root, ".minuano", "profiles.yaml")
+}
+
+// Load returns the built-in profiles merged with those in File. A missing
+// file is fine.
+func Load() (Set, error) {
+	s := Builtins()
+	path := File()
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.parse(path, data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parse adds the profiles defined in data, read from path. A profile named
+// after a built-in replaces it; omitted prompt and completion take the
+// defaults (arg and script).
+func (s Set) parse(path string, data []byte) error {
+	var file struct {
+		Profiles map[string]*Profile `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for name, p := range file.Profiles {
+		if p == nil {
+			return fmt.Errorf("%s: profile %s is empty", path, name)
+		}
+		p.Name, p.Source = name, path
+		if p.Prompt == "" {
+			p.Prompt = PromptArg
+		}
+		if p.Completion == "" {
+			p.Completion = CompletionScript
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		s[name] = p
+	}
+	return nil
+}
+
+// Get returns the named profile, or the default one for an empty name.
+func (s Set) Get(name string) (*Profile, error) {
+	if name == "" {
+		name = Default
+	}
+	p, ok := s[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown agent profile %q (have %s)", name, strings.Join(s.Names(), ", "))
+	}
+	return p, nil
+}
+
+// Names returns the profile names, sorted.
+func (s Set) Names() []string {
+	return slices.Sorted(maps.Keys(s))
+}
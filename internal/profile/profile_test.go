@@ -0,0 +1,155 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinsValidate(t *testing.T) {
+	for name, p := range Builtins() {
+		if p.Name != name || p.Source != "built-in" {
+			t.Errorf("%s: name %q, source %q", name, p.Name, p.Source)
+		}
+		if err := p.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	v := Vars{AgentID: "agent-1", SessionID: "sid", PromptFile: "/tmp/it's.md"}
+	tests := []struct {
+		name string
+		p    Profile
+		want string
+	}{
+		{
+			"arg",
+			Profile{Command: "claude --session-id {{.SessionID}} -p {{.Prompt}}", Prompt: PromptArg},
+			`claude --session-id 'sid' -p "$(cat '/tmp/it'\''s.md')"`,
+		},
+		{
+			"file",
+			Profile{Command: "aider --message-file {{.PromptFile}}", Prompt: PromptFile},
+			`aider --message-file '/tmp/it'\''s.md'`,
+		},
+		{
+			"stdin",
+			Profile{Command: "stub --agent {{.AgentID}}", Prompt: PromptStdin},
+			`stub --agent 'agent-1' < '/tmp/it'\''s.md'`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.p.Script(v)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	p := Profile{Name: "bad", Command: "run {{.Nope}}"}
+	if _, err := p.Script(v); err == nil {
+		t.Error("expected an error for an unknown placeholder")
+	}
+}
+
+func TestExports(t *testing.T) {
+	p := Profile{Env: map[string]string{"B": "it's", "A": "1"}}
+	got := strings.Join(p.Exports(), "\n")
+	want := "export A='1'\nexport B='it'\\''s'"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUsesSession(t *testing.T) {
+	s := Builtins()
+	if !s["claude"].UsesSession() {
+		t.Error("claude should use a session")
+	}
+	if s["aider"].UsesSession() {
+		t.Error("aider should not use a session")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		p    Profile
+		want string
+	}{
+		{Profile{Command: " ", Prompt: PromptArg, Completion: CompletionScript}, "command is required"},
+		{Profile{Command: "x {{", Prompt: PromptArg, Completion: CompletionScript}, "parsing command"},
+		{Profile{Command: "x", Prompt: PromptArg, Completion: CompletionScript}, "needs {{.Prompt}}"},
+		{Profile{Command: "x", Prompt: PromptFile, Completion: CompletionScript}, "needs {{.PromptFile}}"},
+		{Profile{Command: "x", Prompt: "pipe", Completion: CompletionScript}, "unknown prompt delivery"},
+		{Profile{Command: "x", Prompt: PromptStdin, Completion: "never"}, "unknown completion"},
+		{Profile{Command: "x", Prompt: PromptStdin, Completion: CompletionExit}, ""},
+	}
+	for _, tt := range tests {
+		err := tt.p.Validate()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected error %v", tt.p.Command, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: got %v, want error containing %q", tt.p.Command, err, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	s := Builtins()
+	data := []byte(`
+profiles:
+  claude:
+    command: my-claude -p {{.Prompt}}
+  stub:
+    command: ./stub.sh
+    prompt: stdin
+    completion: exit
+    env:
+      STUB_MODE: fast
+`)
+	if err := s.parse("profiles.yaml", data); err != nil {
+		t.Fatal(err)
+	}
+	if c := s["claude"]; c.Command != "my-claude -p {{.Prompt}}" || c.Source != "profiles.yaml" || c.Prompt != PromptArg || c.Completion != CompletionScript {
+		t.Errorf("claude not overridden with defaults: %+v", c)
+	}
+	if st := s["stub"]; st == nil || st.Prompt != PromptStdin || st.Completion != CompletionExit || st.Env["STUB_MODE"] != "fast" {
+		t.Errorf("stub not parsed: %+v", st)
+	}
+	if s["codex"] == nil {
+		t.Error("built-ins should be kept")
+	}
+
+	if err := Builtins().parse("p.yaml", []byte("profiles:\n  bad:\n    command: run\n")); err == nil {
+		t.Error("expected a validation error for a command without {{.Prompt}}")
+	}
+	if err := Builtins().parse("p.yaml", []byte("profiles: [")); err == nil {
+		t.Error("expected a YAML error")
+	}
+}
+
+func TestGet(t *testing.T) {
+	s := Builtins()
+	p, err := s.Get("")
+	if err != nil || p.Name != Default {
+		t.Errorf("Get(\"\") = %v, %v; want the default profile", p, err)
+	}
+	if _, err := s.Get("nope"); err == nil || !strings.Contains(err.Error(), "aider, claude, codex, gemini") {
+		t.Errorf("expected an unknown profile error listing the names, got %v", err)
+	}
+}
+
+func TestFileFromEnv(t *testing.T) {
+	t.Setenv("MINUANO_PROFILES", "/etc/minuano/profiles.yaml")
+	if got := File(); got != "/etc/minuano/profiles.yaml" {
+		t.Errorf("File() = %q", got)
+	}
+}
@@ -0,0 +1,80 @@
+// Package runtime abstracts where agent processes run. The tmux runtime puts
+// each agent in a window of one tmux session, for humans to watch and attach
+// to; the subprocess runtime runs agents as plain background processes, for
+// hosts without tmux such as CI runners. Each agent row records the runtime
+// that owns it, so later commands (kill, logs, attach) reach it the same way.
+package runtime
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+)
+
+// Runtime names, as recorded in agents.runtime.
+const (
+	Tmux       = "tmux"
+	Subprocess = "subprocess"
+)
+
+// Names lists the available runtimes.
+var Names = []string{Tmux, Subprocess}
+
+// Spec describes an agent to start.
+type Spec struct {
+	AgentID string
+	// Dir is the working directory; empty for the current one.
+	Dir string
+	// Env is added to the agent's environment.
+	Env map[string]string
+	// Script holds the shell lines that bootstrap and run the agent, in order.
+	Script []string
+	// LogCommand, when set, is a shell command the agent's output is piped into.
+	LogCommand string
+}
+
+// Runtime starts and controls agent processes. Methods other than Start take
+// the agent's row, which records where the runtime placed it.
+type Runtime interface {
+	// Name is recorded in agents.runtime.
+	Name() string
+	// Placement is the tmux session and window recorded for a new agent,
+	// empty for runtimes without one.
+	Placement(agentID string) (session, window string)
+	// Start launches an agent and returns its process ID when it runs as a
+	// local process, 0 otherwise.
+	Start(s Spec) (pid int, err error)
+	// Stop terminates an agent. Stopping an agent that is already gone is not
+	// an error.
+	Stop(a *db.Agent) error
+	// Alive reports whether an agent's process is still running.
+	Alive(a *db.Agent) bool
+	// Capture returns the last lines of an agent's output.
+	Capture(a *db.Agent, lines int) (string, error)
+	// Attach connects the terminal to an agent interactively.
+	Attach(a *db.Agent) error
+}
+
+// New returns the named runtime. session is the tmux session new tmux agents
+// are placed in; pool is where the subprocess runtime reads output from.
+func New(name string, pool *pgxpool.Pool, session string) (Runtime, error) {
+	switch name {
+	case Tmux, "":
+		return &tmuxRuntime{session: session}, nil
+	case Subprocess:
+		return NewSubprocess(pool), nil
+	}
+	return nil, fmt.Errorf("unknown runtime %q (want one of %v)", name, Names)
+}
+
+// For returns the runtime that owns a.
+func For(pool *pgxpool.Pool, a *db.Agent) (Runtime, error) {
+	return New(a.Runtime, pool, a.TmuxSession)
+}
+
+// Valid reports whether name is a known runtime.
+func Valid(name string) bool {
+	return slices.Contains(Names, name)
+}
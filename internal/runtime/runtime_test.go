@@ -0,0 +1,121 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+func TestNew(t *testing.T) {
+	for _, name := range []string{"", Tmux, Subprocess} {
+		rt, err := New(name, nil, "minuano")
+		if err != nil {
+			t.Fatalf("New(%q): %v", name, err)
+		}
+		want := name
+		if want == "" {
+			want = Tmux
+		}
+		if rt.Name() != want {
+			t.Errorf("New(%q).Name() = %q, want %q", name, rt.Name(), want)
+		}
+	}
+	if _, err := New("docker", nil, "minuano"); err == nil {
+		t.Error("expected error for unknown runtime")
+	}
+}
+
+func TestValid(t *testing.T) {
+	if !Valid(Tmux) || !Valid(Subprocess) || Valid("") || Valid("docker") {
+		t.Error("Valid should accept exactly the runtime names")
+	}
+}
+
+func TestPlacement(t *testing.T) {
+	tm, _ := New(Tmux, nil, "work")
+	if s, w := tm.Placement("agent-1"); s != "work" || w != "agent-1" {
+		t.Errorf("tmux placement = %q:%q", s, w)
+	}
+	sp, _ := New(Subprocess, nil, "work")
+	if s, w := sp.Placement("agent-1"); s != "" || w != "" {
+		t.Errorf("subprocess placement = %q:%q, want empty", s, w)
+	}
+}
+
+func TestSubprocessScript(t *testing.T) {
+	s := Spec{Script: []string{"export A=1", "run-agent"}}
+	if got := subprocessScript(s); got != "export A=1\nrun-agent" {
+		t.Errorf("without log command: %q", got)
+	}
+	s.LogCommand = "record"
+	if got, want := subprocessScript(s), "{\nexport A=1\nrun-agent\n} </dev/null 2>&1 | record"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSubprocessLifecycle(t *testing.T) {
+	rt := NewSubprocess(nil)
+	pid, err := rt.Start(Spec{AgentID: "agent-1", Script: []string{"sleep 30"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := ProcessStart(pid)
+	if start == 0 {
+		t.Fatal("expected the start time of a running process")
+	}
+
+	// A process that reused the pid isn't the agent, and isn't signalled.
+	other := start + 1
+	reused := &db.Agent{ID: "agent-1", Runtime: Subprocess, PID: &pid, PIDStart: &other}
+	if rt.Alive(reused) {
+		t.Error("expected a process with another start time not to be the agent")
+	}
+	if err := rt.Stop(reused); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &db.Agent{ID: "agent-1", Runtime: Subprocess, PID: &pid, PIDStart: &start}
+	if !rt.Alive(a) {
+		t.Fatal("expected started agent to be alive")
+	}
+	if err := rt.Stop(a); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-rt.Exited("agent-1"):
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent did not exit after Stop")
+	}
+	if rt.Alive(a) {
+		t.Error("expected stopped agent to be dead")
+	}
+	if err := rt.Stop(a); err != nil {
+		t.Errorf("stopping a stopped agent: %v", err)
+	}
+	if rt.Exited("agent-2") != nil {
+		t.Error("expected no exit channel for an agent this runtime didn't start")
+	}
+}
+
+func TestSubprocessAttach(t *testing.T) {
+	err := NewSubprocess(nil).Attach(&db.Agent{ID: "agent-1"})
+	if err == nil || !strings.Contains(err.Error(), "minuano logs agent-1") {
+		t.Errorf("expected a pointer to logs, got %v", err)
+	}
+}
+
+func TestParseStart(t *testing.T) {
+	tests := map[string]int64{
+		"4242 (sleep) S 1 4242 4242 0 -1 4194560 97 0 0 0 0 0 0 0 20 0 1 0 7310502 8400896 224 18446744073709551615": 7310502,
+		"4242 (a) b (c) S 1 4242 4242 0 -1 4194560 97 0 0 0 0 0 0 0 20 0 1 0 99 8400896":                             99,
+		"4242 (sleep) S 1": 0,
+		"":                 0,
+	}
+	for stat, want := range tests {
+		if got := parseStart(stat); got != want {
+			t.Errorf("parseStart(%q) = %d, want %d", stat, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+	"github.com/otavio/minuano/internal/panelog"
+)
+
+// stopGrace is how long a stopped subprocess agent gets to exit after
+// SIGTERM before it is killed: a second more than a worker gives its own
+// gates and agent, so a stopped worker drains first.
+const stopGrace = gate.KillGrace + time.Second
+
+// SubprocessRuntime runs each agent as a bash process in its own session, so
+// it outlives the command that spawned it and is stopped as a group. There is
+// no terminal: output goes to pane logs only, and is captured from there.
+type SubprocessRuntime struct {
+	pool *pgxpool.Pool
+
+	mu     sync.Mutex
+	exited map[string]chan struct{}
+}
+
+// NewSubprocess returns a subprocess runtime reading agent output through pool.
+func NewSubprocess(pool *pgxpool.Pool) *SubprocessRuntime {
+	return &SubprocessRuntime{pool: pool, exited: map[string]chan struct{}{}}
+}
+
+func (r *SubprocessRuntime) Name() string { return Subprocess }
+
+func (r *SubprocessRuntime) Placement(string) (string, string) { return "", "" }
+
+func (r *SubprocessRuntime) Start(s Spec) (int, error) {
+	cmd := exec.Command("bash", "-c", subprocessScript(s))
+	cmd.Dir = s.Dir
+	cmd.Env = os.Environ()
+	for k, v := range s.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("starting %s: %w", s.AgentID, err)
+	}
+
+	// Reap the process so it doesn't linger as a zombie that looks alive.
+	done := make(chan struct{})
+	r.mu.Lock()
+	r.exited[s.AgentID] = done
+	r.mu.Unlock()
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+	return cmd.Process.Pid, nil
+}
+
+// subprocessScript joins the agent's script, piping all of its output into
+// the log command when there is one.
+func subprocessScript(s Spec) string {
+	script := strings.Join(s.Script, "\n")
+	if s.LogCommand == "" {
+		return script
+	}
+	return fmt.Sprintf("{\n%s\n} </dev/null 2>&1 | %s", script, s.LogCommand)
+}
+
+// Exited returns a channel closed when agentID's process exits, or nil if
+// this runtime didn't start it.
+func (r *SubprocessRuntime) Exited(agentID string) <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exited[agentID]
+}
+
+func (r *SubprocessRuntime) Stop(a *db.Agent) error {
+	if a.PID == nil || !sameProcess(*a.PID, a.PIDStart) {
+		return nil // gone, and its pid may be another process's by now
+	}
+	pid := *a.PID
+	signal := func(sig syscall.Signal) error { return signalGroup(pid, sig) }
+	if pgid, err := syscall.Getpgid(pid); err == nil && pgid != pid {
+		// A `minuano worker` supervisor leads no process group of its own,
+		// so it is signalled alone, each time after checking it is still
+		// the process that registered.
+		if a.PIDStart == nil {
+			return fmt.Errorf("not stopping %s: process %d leads no process group, and can't be told from a process that reused its pid", a.ID, pid)
+		}
+		signal = func(sig syscall.Signal) error { return signalProcess(pid, *a.PIDStart, sig) }
+	}
+
+	if err := signal(syscall.SIGTERM); err != nil {
+		return err
+	}
+	for deadline := time.Now().Add(stopGrace); time.Now().Before(deadline); {
+		if !sameProcess(pid, a.PIDStart) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	// Kill whatever is left of it.
+	return signal(syscall.SIGKILL)
+}
+
+func (r *SubprocessRuntime) Alive(a *db.Agent) bool {
+	return a.PID != nil && sameProcess(*a.PID, a.PIDStart)
+}
+
+func (r *SubprocessRuntime) Capture(a *db.Agent, lines int) (string, error) {
+	return panelog.Tail(r.pool, a.ID, lines)
+}
+
+func (r *SubprocessRuntime) Attach(a *db.Agent) error {
+	return fmt.Errorf("%s runs as a subprocess and has no terminal to attach to; use `minuano logs %s --follow`", a.ID, a.ID)
+}
+
+// signalGroup signals the process group led by pid; a group that is already
+// gone is fine. A group's id isn't reused while any of its processes run, so
+// once its leader has been identified the group can be signalled even after
+// the leader exits.
+func signalGroup(pid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pid, sig)
+	if err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("signalling process group %d: %w", pid, err)
+	}
+	return nil
+}
+
+// signalProcess signals pid alone if it is still the process started at
+// start.
+func signalProcess(pid int, start int64, sig syscall.Signal) error {
+	if ProcessStart(pid) != start {
+		return nil
+	}
+	err := syscall.Kill(pid, sig)
+	if err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("signalling process %d: %w", pid, err)
+	}
+	return nil
+}
+
+// sameProcess reports whether pid is running and, when its start time was
+// recorded, is still the process started then rather than one that reused
+// its pid.
+func sameProcess(pid int, start *int64) bool {
+	if !processAlive(pid) {
+		return false
+	}
+	return start == nil || ProcessStart(pid) == *start
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// ProcessStart returns when process pid started, in clock ticks since boot,
+// from /proc/<pid>/stat; 0 if that can't be read.
+func ProcessStart(pid int) int64 {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0
+	}
+	return parseStart(string(b))
+}
+
+// parseStart takes the start time, the 22nd field, from the contents of a
+// /proc/<pid>/stat file. The command name, the 2nd, is in parentheses and
+// may itself hold spaces and parentheses.
+func parseStart(stat string) int64 {
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0
+	}
+	f := strings.Fields(stat[i+1:]) // from the 3rd field on
+	if len(f) < 20 {
+		return 0
+	}
+	n, _ := strconv.ParseInt(f[19], 10, 64)
+	return n
+}
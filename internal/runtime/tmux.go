@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/tmux"
+)
+
+// tmuxRuntime runs each agent in a window, named after the agent, of one
+// tmux session.
+type tmuxRuntime struct {
+	session string
+}
+
+func (r *tmuxRuntime) Name() string { return Tmux }
+
+func (r *tmuxRuntime) Placement(agentID string) (string, string) {
+	return r.session, agentID
+}
+
+func (r *tmuxRuntime) Start(s Spec) (int, error) {
+	if err := tmux.EnsureSession(r.session); err != nil {
+		return 0, err
+	}
+
+	var err error
+	if s.Dir != "" {
+		err = tmux.NewWindowWithDir(r.session, s.AgentID, s.Dir, s.Env)
+	} else {
+		err = tmux.NewWindow(r.session, s.AgentID, s.Env)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("creating tmux window: %w", err)
+	}
+
+	// The agent works without the archive, so failing to set it up is only
+	// a warning.
+	if s.LogCommand != "" {
+		if err := tmux.PipePane(r.session, s.AgentID, s.LogCommand); err != nil {
+			fmt.Printf("warning: not archiving pane output of %s: %v\n", s.AgentID, err)
+		}
+	}
+
+	for _, line := range s.Script {
+		tmux.SendKeys(r.session, s.AgentID, line)
+	}
+	return 0, nil
+}
+
+func (r *tmuxRuntime) Stop(a *db.Agent) error {
+	if !tmux.WindowExists(a.TmuxSession, a.TmuxWindow) {
+		return nil
+	}
+	return tmux.KillWindow(a.TmuxSession, a.TmuxWindow)
+}
+
+func (r *tmuxRuntime) Alive(a *db.Agent) bool {
+	return tmux.WindowExists(a.TmuxSession, a.TmuxWindow)
+}
+
+func (r *tmuxRuntime) Capture(a *db.Agent, lines int) (string, error) {
+	return tmux.CapturePane(a.TmuxSession, a.TmuxWindow, lines)
+}
+
+func (r *tmuxRuntime) Attach(a *db.Agent) error {
+	return tmux.AttachOrSwitch(a.TmuxSession, a.TmuxWindow)
+}
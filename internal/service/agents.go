@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/otavio/minuano/internal/agent"
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/git"
+	"github.com/otavio/minuano/internal/profile"
+	"github.com/otavio/minuano/internal/runtime"
+)
+
+// ListAgents returns all registered agents.
+func (s *Service) ListAgents() ([]*db.Agent, error) {
+	agents, err := db.ListAgents(s.Pool)
+	if err != nil {
+		return nil, err
+	}
+	if agents == nil {
+		agents = []*db.Agent{}
+	}
+	return agents, nil
+}
+
+// GetAgent returns a registered agent.
+func (s *Service) GetAgent(id string) (*db.Agent, error) {
+	a, err := db.GetAgent(s.Pool, id)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, fmt.Errorf("%w: no agent %q", db.ErrNotFound, id)
+	}
+	return a, nil
+}
+
+// SpawnAgentInput describes an agent to spawn.
+type SpawnAgentInput struct {
+	Name      string `json:"name"`
+	Profile   string `json:"profile,omitempty"`    // default: the service's
+	ProjectID string `json:"project_id,omitempty"` // bind the agent to this project
+	Worktrees bool   `json:"worktrees,omitempty"`  // isolate it in its own git worktree
+}
+
+// SpawnAgent starts a named agent under the service's runtime.
+func (s *Service) SpawnAgent(in SpawnAgentInput) (*db.Agent, error) {
+	name := in.Name
+	if name == "" {
+		return nil, fmt.Errorf("%w: agent name is required", ErrInvalid)
+	}
+	if s.ClaudeMD == "" {
+		return nil, fmt.Errorf("no CLAUDE.md configured for spawning agents")
+	}
+	existing, err := db.GetAgent(s.Pool, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: agent %q already exists", ErrConflict, name)
+	}
+
+	if in.Worktrees {
+		if _, err := git.RepoRoot(); err != nil {
+			return nil, fmt.Errorf("worktrees require a git repository: %w", err)
+		}
+	}
+
+	rt, err := s.AgentRuntime()
+	if err != nil {
+		return nil, err
+	}
+	prof, err := s.AgentProfile(in.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if prof.Completion == profile.CompletionExit {
+		return nil, fmt.Errorf("%w: profile %s finishes a task by exiting and only runs under `minuano worker`", ErrInvalid, prof.Name)
+	}
+
+	var projPtr *string
+	if in.ProjectID != "" {
+		if _, err := s.OpenProject(in.ProjectID); err != nil {
+			return nil, err
+		}
+		projPtr = &in.ProjectID
+	}
+
+	if in.Worktrees {
+		_, err = agent.SpawnWithWorktree(s.Pool, rt, prof, name, projPtr, s.ClaudeMD, s.AgentEnv)
+	} else {
+		_, err = agent.Spawn(s.Pool, rt, prof, name, projPtr, s.ClaudeMD, s.AgentEnv)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("spawning %s: %w", name, err)
+	}
+	return s.GetAgent(name)
+}
+
+// KillAgent stops an agent and releases its claimed task.
+func (s *Service) KillAgent(id string) error {
+	if _, err := s.GetAgent(id); err != nil {
+		return err
+	}
+	return agent.Kill(s.Pool, id, s.BaseBranch)
+}
+
+// AgentPane returns the last lines of an agent's output, from its tmux pane
+// or, for runtimes without one, its pane log.
+func (s *Service) AgentPane(id string, lines int) (string, error) {
+	if lines <= 0 || lines > 2000 {
+		return "", fmt.Errorf("%w: lines must be 1-2000", ErrInvalid)
+	}
+	a, err := s.GetAgent(id)
+	if err != nil {
+		return "", err
+	}
+	rt, err := runtime.For(s.Pool, a)
+	if err != nil {
+		return "", err
+	}
+	return rt.Capture(a, lines)
+}
+
+// KillAllAgents stops every registered agent.
+func (s *Service) KillAllAgents() error {
+	return agent.KillAll(s.Pool, s.BaseBranch)
+}
+
+// MergeQueue returns all merge queue entries, oldest first.
+func (s *Service) MergeQueue() ([]*db.MergeQueueEntry, error) {
+	entries, err := db.ListMergeQueue(s.Pool)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []*db.MergeQueueEntry{}
+	}
+	return entries, nil
+}
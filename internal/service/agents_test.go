@@ -0,0 +1,15 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAgentPaneValidatesLines(t *testing.T) {
+	s := &Service{}
+	for _, n := range []int{0, -1, 2001} {
+		if _, err := s.AgentPane("agent-1", n); !errors.Is(err, ErrInvalid) {
+			t.Errorf("lines %d: expected ErrInvalid, got %v", n, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+// EventFilter selects which logged events a subscriber receives.
+type EventFilter struct {
+	ProjectID *string  // nil for all projects
+	Kinds     []string // empty for all kinds
+}
+
+// ParseEventKinds splits a comma-separated kinds list, rejecting unknown kinds.
+func ParseEventKinds(csv string) ([]string, error) {
+	var kinds []string
+	for _, k := range strings.Split(csv, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if !slices.Contains(db.EventKinds, k) {
+			return nil, fmt.Errorf("%w: unknown event kind %q (want %s)", ErrInvalid, k, strings.Join(db.EventKinds, ", "))
+		}
+		kinds = append(kinds, k)
+	}
+	return kinds, nil
+}
+
+const (
+	// gapWait is how long a reader keeps looking for an event ID it skipped:
+	// past it, the transaction that took the ID is taken to have rolled back.
+	gapWait = time.Minute
+	// maxGaps bounds how many skipped IDs a reader looks for.
+	maxGaps = 100
+)
+
+// EventCursor is a reader's position in the event log. Event IDs are taken
+// from a sequence, but become visible when their transaction commits, which
+// needn't be in order; so besides the highest ID read, the cursor keeps the
+// lower IDs that weren't visible then, as gaps to read once they are.
+type EventCursor struct {
+	After int64
+	gaps  map[int64]time.Time // skipped ID → when it was first missed
+}
+
+// ParseEventCursor parses a cursor as String formats it: the highest ID read,
+// then any gaps, dot-separated, as in "42.39.40".
+func ParseEventCursor(s string) (*EventCursor, error) {
+	c := &EventCursor{}
+	now := time.Now()
+	for i, f := range strings.Split(s, ".") {
+		id, err := strconv.ParseInt(f, 10, 64)
+		if err != nil || id < 0 || (i > 0 && id >= c.After) {
+			return nil, fmt.Errorf("%w: bad event cursor %q", ErrInvalid, s)
+		}
+		if i == 0 {
+			c.After = id
+		} else {
+			c.skip(id, now)
+		}
+	}
+	return c, nil
+}
+
+// String formats the cursor for ParseEventCursor.
+func (c *EventCursor) String() string {
+	parts := []string{strconv.FormatInt(c.After, 10)}
+	for _, id := range slices.Sorted(maps.Keys(c.gaps)) {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ".")
+}
+
+// Missing returns the gaps still looked for at now, in order, dropping those
+// missed for longer than gapWait.
+func (c *EventCursor) Missing(now time.Time) []int64 {
+	for id, t := range c.gaps {
+		if now.Sub(t) > gapWait {
+			delete(c.gaps, id)
+		}
+	}
+	return slices.Sorted(maps.Keys(c.gaps))
+}
+
+// Advance moves the cursor past an event read at now: a gap it fills is
+// closed, and the IDs it skips over become gaps.
+func (c *EventCursor) Advance(id int64, now time.Time) {
+	if id <= c.After {
+		delete(c.gaps, id)
+		return
+	}
+	for g := max(c.After+1, id-maxGaps); g < id; g++ {
+		c.skip(g, now)
+	}
+	c.After = id
+}
+
+// skip adds a gap, dropping the lowest one when there are too many.
+func (c *EventCursor) skip(id int64, now time.Time) {
+	if c.gaps == nil {
+		c.gaps = map[int64]time.Time{}
+	}
+	if _, ok := c.gaps[id]; ok {
+		return
+	}
+	if len(c.gaps) >= maxGaps {
+		delete(c.gaps, slices.Min(slices.Collect(maps.Keys(c.gaps))))
+	}
+	c.gaps[id] = now
+}
+
+// Events returns up to limit logged events past the cursor, and those filling
+// its gaps, including the ones f leaves out (with Matched false) so that the
+// cursor can advance past them.
+func (s *Service) Events(c *EventCursor, f EventFilter, limit int) ([]*db.Event, error) {
+	return db.ListEvents(s.Pool, c.After, c.Missing(time.Now()), f.ProjectID, f.Kinds, limit)
+}
+
+// LatestEventID returns the ID of the newest logged event.
+func (s *Service) LatestEventID() (int64, error) {
+	return db.LatestEventID(s.Pool)
+}
+
+// PruneEvents drops logged events older than retention.
+func (s *Service) PruneEvents(retention time.Duration) (int64, error) {
+	return db.PruneEvents(s.Pool, time.Now().Add(-retention))
+}
+
+// ListenEvents calls wake for every event written to the log until ctx is
+// cancelled. It holds one database connection while running.
+func (s *Service) ListenEvents(ctx context.Context, wake func()) error {
+	return db.Listen(ctx, s.Pool, "minuano_events", func(string) { wake() })
+}
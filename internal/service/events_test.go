@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseEventKinds(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"task", []string{"task"}},
+		{"task, merge,", []string{"task", "merge"}},
+		{"agent,planner", []string{"agent", "planner"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseEventKinds(tt.in)
+		if err != nil {
+			t.Errorf("ParseEventKinds(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseEventKinds(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseEventKindsUnknown(t *testing.T) {
+	if _, err := ParseEventKinds("task,bogus"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestEventCursor(t *testing.T) {
+	now := time.Now()
+	c := &EventCursor{After: 10}
+	// 12 and 13 are visible before 11, whose transaction is still open.
+	c.Advance(12, now)
+	c.Advance(13, now)
+	if got := c.String(); got != "13.11" {
+		t.Fatalf("cursor = %q, want 13.11", got)
+	}
+	if got := c.Missing(now); !reflect.DeepEqual(got, []int64{11}) {
+		t.Errorf("missing = %v, want [11]", got)
+	}
+	c.Advance(11, now)
+	if got := c.String(); got != "13" {
+		t.Errorf("a filled gap should be closed, cursor = %q", got)
+	}
+
+	// 14 never turns up: its transaction rolled back.
+	c.Advance(15, now)
+	if got := c.Missing(now.Add(gapWait + time.Second)); len(got) != 0 {
+		t.Errorf("gaps should be given up on after gapWait, got %v", got)
+	}
+
+	c.Advance(15+3*maxGaps, now)
+	if got := c.Missing(now); len(got) != maxGaps || got[0] != 15+2*maxGaps {
+		t.Errorf("expected the last %d skipped IDs, got %v", maxGaps, got)
+	}
+
+	p, err := ParseEventCursor("20.17.18")
+	if err != nil || p.After != 20 || !reflect.DeepEqual(p.Missing(now), []int64{17, 18}) {
+		t.Errorf("ParseEventCursor = %v, %v", p, err)
+	}
+	for _, bad := range []string{"", "x", "-1", "20.20", "20..3"} {
+		if _, err := ParseEventCursor(bad); !errors.Is(err, ErrInvalid) {
+			t.Errorf("ParseEventCursor(%q): expected ErrInvalid, got %v", bad, err)
+		}
+	}
+}
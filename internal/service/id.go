@@ -0,0 +1,40 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// GenerateID creates a slug from the title plus a random suffix.
+func GenerateID(title string) string {
+	slug := slugify(title)
+	if len(slug) > 15 {
+		slug = slug[:15]
+	}
+	suffix := randomHex(3)
+	return slug + "-" + suffix
+}
+
+func slugify(s string) string {
+	s = strings.ToLower(s)
+	var b strings.Builder
+	prevDash := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			prevDash = false
+		} else if !prevDash && b.Len() > 0 {
+			b.WriteByte('-')
+			prevDash = true
+		}
+	}
+	return strings.TrimRight(b.String(), "-")
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)[:n+2]
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+// OpenProject returns the record of a project new tasks and agents can be
+// added to: one that exists and isn't archived.
+func (s *Service) OpenProject(id string) (*db.Project, error) {
+	p, err := db.GetProject(s.Pool, id)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown project %q (create it with `minuano project create %s`)", ErrInvalid, id, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.ArchivedAt != nil {
+		return nil, fmt.Errorf("%w: project %q is archived", ErrInvalid, id)
+	}
+	return p, nil
+}
+
+// TaskMaxAttempts returns how many attempts new tasks in projectID get: the
+// project's max_attempts, else the service's. The project must be open.
+func (s *Service) TaskMaxAttempts(projectID *string) (int, error) {
+	if projectID == nil {
+		return s.MaxAttempts, nil
+	}
+	p, err := s.OpenProject(*projectID)
+	if err != nil {
+		return 0, err
+	}
+	if p.MaxAttempts != nil {
+		return *p.MaxAttempts, nil
+	}
+	return s.MaxAttempts, nil
+}
@@ -0,0 +1,76 @@
+// Package service implements Minuano's task, approval and agent operations on
+// top of the db, agent and runtime packages. The CLI and the HTTP API both go
+// through it, so the two can't drift apart.
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/profile"
+	"github.com/otavio/minuano/internal/runtime"
+)
+
+var (
+	// ErrInvalid means the request itself is malformed (bad field values, cycles).
+	ErrInvalid = errors.New("invalid request")
+	// ErrConflict means the request clashes with existing state (e.g. duplicate agent).
+	ErrConflict = errors.New("conflict")
+)
+
+// Service holds what operations need beyond their arguments.
+type Service struct {
+	Pool *pgxpool.Pool
+
+	// Session is the tmux session tmux agents are spawned into.
+	Session string
+	// Runtime names the runtime new agents are started under (default tmux).
+	// Existing agents are always reached through the runtime that owns them.
+	Runtime string
+	// Profile names the agent profile new agents run when the request names
+	// none (default claude).
+	Profile string
+	// MaxAttempts is how many times new tasks may be claimed before they
+	// fail for good.
+	MaxAttempts int
+	// ClaudeMD is the bootstrap prompt for spawned agents. Spawning fails when empty.
+	ClaudeMD string
+	// AgentEnv is exported into every spawned agent's window.
+	AgentEnv map[string]string
+	// BaseBranch is the branch agents' work is merged into when their
+	// project sets none (default main).
+	BaseBranch string
+}
+
+// New creates a service over pool.
+func New(pool *pgxpool.Pool, session string) *Service {
+	return &Service{Pool: pool, Session: session, MaxAttempts: db.DefaultMaxAttempts}
+}
+
+// AgentRuntime returns the runtime new agents are started under.
+func (s *Service) AgentRuntime() (runtime.Runtime, error) {
+	rt, err := runtime.New(s.Runtime, s.Pool, s.Session)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return rt, nil
+}
+
+// AgentProfile returns the named agent profile, or the service's default one
+// for an empty name.
+func (s *Service) AgentProfile(name string) (*profile.Profile, error) {
+	if name == "" {
+		name = s.Profile
+	}
+	profiles, err := profile.Load()
+	if err != nil {
+		return nil, err
+	}
+	p, err := profiles.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/otavio/minuano/internal/db"
+	"github.com/otavio/minuano/internal/gate"
+)
+
+// CreateTaskInput describes a new task. Zero values take the CLI defaults.
+type CreateTaskInput struct {
+	Title            string     `json:"title"`
+	Body             string     `json:"body,omitempty"`
+	Priority         *int       `json:"priority,omitempty"` // default 5
+	TestCmd          string     `json:"test_cmd,omitempty"`
+	Gates            []gate.Def `json:"gates,omitempty"`   // run in order instead of test_cmd
+	Profile          string     `json:"profile,omitempty"` // agent profile required to claim it
+	ProjectID        string     `json:"project_id,omitempty"`
+	After            []string   `json:"after,omitempty"`  // dependency IDs, partial ok
+	Status           string     `json:"status,omitempty"` // ready (default) or draft
+	RequiresApproval bool       `json:"requires_approval,omitempty"`
+}
+
+// UpdateTaskInput lists the fields to change; nil fields are left alone.
+type UpdateTaskInput struct {
+	Title    *string `json:"title,omitempty"`
+	Body     *string `json:"body,omitempty"`
+	Priority *int    `json:"priority,omitempty"`
+}
+
+// CreateTask creates a task and its dependencies, then settles its status:
+// drafts stay draft, otherwise the task is ready unless a dependency is unfinished.
+func (s *Service) CreateTask(in CreateTaskInput) (*db.Task, error) {
+	in.Title = strings.TrimSpace(in.Title)
+	if in.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalid)
+	}
+	if in.Status == "" {
+		in.Status = "ready"
+	}
+	if in.Status != "ready" && in.Status != "draft" {
+		return nil, fmt.Errorf("%w: status %q must be 'ready' or 'draft'", ErrInvalid, in.Status)
+	}
+	priority := 5
+	if in.Priority != nil {
+		priority = *in.Priority
+	}
+	if err := checkPriority(priority); err != nil {
+		return nil, err
+	}
+	if in.Profile != "" {
+		if _, err := s.AgentProfile(in.Profile); err != nil {
+			return nil, err
+		}
+	}
+	if err := gate.ValidateDefs(in.Gates); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	// Resolve dependencies first so a bad reference doesn't leave a half-made task.
+	deps := make([]string, 0, len(in.After))
+	for _, dep := range in.After {
+		resolved, err := db.ResolvePartialID(s.Pool, dep)
+		if err != nil {
+			return nil, fmt.Errorf("resolving dependency %q: %w", dep, err)
+		}
+		deps = append(deps, resolved)
+	}
+
+	var projPtr *string
+	if in.ProjectID != "" {
+		projPtr = &in.ProjectID
+	}
+	maxAttempts, err := s.TaskMaxAttempts(projPtr)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata json.RawMessage
+	if in.TestCmd != "" || len(in.Gates) > 0 || in.Profile != "" {
+		metadata, _ = json.Marshal(db.TaskMeta{TestCmd: in.TestCmd, Gates: in.Gates, Profile: in.Profile})
+	}
+
+	// One transaction, so a failure part way doesn't leave a task without its
+	// dependencies or stuck in the wrong status.
+	id := GenerateID(in.Title)
+	err = db.InTx(s.Pool, func(tx db.Querier) error {
+		if err := db.CreateTask(tx, id, in.Title, in.Body, priority, maxAttempts, projPtr, metadata, in.RequiresApproval); err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if err := db.AddDependency(tx, id, dep); err != nil {
+				return err
+			}
+		}
+
+		status := in.Status
+		if status == "ready" && len(deps) > 0 {
+			hasUnmet, err := db.HasUnmetDeps(tx, id)
+			if err != nil {
+				return err
+			}
+			if hasUnmet {
+				status = "pending" // the default; the done trigger promotes it later
+			}
+		}
+		if status != "pending" {
+			return db.SetTaskStatus(tx, id, status)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetTask(s.Pool, id)
+}
+
+// ListTasks returns tasks, optionally filtered by project and status.
+func (s *Service) ListTasks(projectID *string, status string) ([]*db.Task, error) {
+	tasks, err := db.ListTasks(s.Pool, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if status == "" {
+		return tasks, nil
+	}
+	filtered := []*db.Task{}
+	for _, t := range tasks {
+		if t.Status == status {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, nil
+}
+
+// GetTask returns a task by exact or partial ID.
+func (s *Service) GetTask(id string) (*db.Task, error) {
+	return db.GetTask(s.Pool, id)
+}
+
+// GetTaskContext returns a task's context log.
+func (s *Service) GetTaskContext(id string) ([]*db.TaskContext, error) {
+	_, ctxs, err := db.GetTaskWithContext(s.Pool, id)
+	if err != nil {
+		return nil, err
+	}
+	if ctxs == nil {
+		ctxs = []*db.TaskContext{}
+	}
+	return ctxs, nil
+}
+
+// UpdateTask applies in to a task. When ifVersion is non-nil the task must
+// still be at that version; either way the write is conditional on the version
+// read here, so concurrent edits fail with db.ErrVersionMismatch instead of
+// silently overwriting each other.
+func (s *Service) UpdateTask(id string, in UpdateTaskInput, ifVersion *int) (*db.Task, error) {
+	task, err := db.GetTask(s.Pool, id)
+	if err != nil {
+		return nil, err
+	}
+	if ifVersion != nil && task.Version != *ifVersion {
+		return nil, fmt.Errorf("%w: task %q is at version %d, not %d", db.ErrVersionMismatch, task.ID, task.Version, *ifVersion)
+	}
+
+	title, body, priority := task.Title, task.Body, task.Priority
+	if in.Title != nil {
+		title = strings.TrimSpace(*in.Title)
+		if title == "" {
+			return nil, fmt.Errorf("%w: title cannot be empty", ErrInvalid)
+		}
+	}
+	if in.Body != nil {
+		body = *in.Body
+	}
+	if in.Priority != nil {
+		if err := checkPriority(*in.Priority); err != nil {
+			return nil, err
+		}
+		priority = *in.Priority
+	}
+
+	if err := db.EditTask(s.Pool, task.ID, title, body, priority, &task.Version); err != nil {
+		return nil, err
+	}
+	return db.GetTask(s.Pool, task.ID)
+}
+
+// Dependencies returns the IDs a task directly depends on.
+func (s *Service) Dependencies(id string) ([]string, error) {
+	resolved, err := db.ResolvePartialID(s.Pool, id)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetTaskDependencies(s.Pool, resolved)
+}
+
+// TaskGraph is every task in scope plus its dependency edges.
+type TaskGraph struct {
+	Tasks        []*db.Task          `json:"tasks"`
+	Dependencies map[string][]string `json:"dependencies"` // task ID → IDs it depends on
+}
+
+// Graph returns the task DAG, optionally limited to one project.
+func (s *Service) Graph(projectID *string) (*TaskGraph, error) {
+	tasks, err := db.ListTasks(s.Pool, projectID)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := db.ListDependencies(s.Pool, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []*db.Task{}
+	}
+	return &TaskGraph{Tasks: tasks, Dependencies: deps}, nil
+}
+
+// AddDependency makes taskID wait for dependsOn. Self-dependencies and
+// cycles are rejected. A ready task with a new unfinished dependency goes
+// back to pending.
+func (s *Service) AddDependency(taskID, dependsOn string) error {
+	task, err := db.GetTask(s.Pool, taskID)
+	if err != nil {
+		return err
+	}
+	dep, err := db.ResolvePartialID(s.Pool, dependsOn)
+	if err != nil {
+		return fmt.Errorf("resolving dependency %q: %w", dependsOn, err)
+	}
+	if dep == task.ID {
+		return fmt.Errorf("%w: task %s cannot depend on itself", ErrInvalid, task.ID)
+	}
+	cycle, err := db.DependsOn(s.Pool, dep, task.ID)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return fmt.Errorf("%w: %s already depends on %s; adding this edge would create a cycle", ErrInvalid, dep, task.ID)
+	}
+
+	if err := db.AddDependency(s.Pool, task.ID, dep); err != nil {
+		return err
+	}
+	if task.Status == "ready" {
+		return s.settleReadiness(task.ID, "pending")
+	}
+	return nil
+}
+
+// RemoveDependency drops one dependency edge. A pending task whose remaining
+// dependencies are all done becomes ready.
+func (s *Service) RemoveDependency(taskID, dependsOn string) error {
+	task, err := db.GetTask(s.Pool, taskID)
+	if err != nil {
+		return err
+	}
+	dep, err := db.ResolvePartialID(s.Pool, dependsOn)
+	if err != nil {
+		return fmt.Errorf("resolving dependency %q: %w", dependsOn, err)
+	}
+	if err := db.RemoveDependency(s.Pool, task.ID, dep); err != nil {
+		return err
+	}
+	if task.Status == "pending" {
+		return s.settleReadiness(task.ID, "ready")
+	}
+	return nil
+}
+
+// settleReadiness moves a task to want if that matches its dependency state.
+func (s *Service) settleReadiness(id, want string) error {
+	hasUnmet, err := db.HasUnmetDeps(s.Pool, id)
+	if err != nil {
+		return err
+	}
+	if hasUnmet == (want == "pending") {
+		return db.SetTaskStatus(s.Pool, id, want)
+	}
+	return nil
+}
+
+// Approve moves a pending_approval task to ready.
+func (s *Service) Approve(id, by string) (*db.Task, error) {
+	resolved, err := db.ResolvePartialID(s.Pool, id)
+	if err != nil {
+		return nil, err
+	}
+	if by == "" {
+		by = "cli"
+	}
+	if err := db.ApproveTask(s.Pool, resolved, by); err != nil {
+		return nil, err
+	}
+	return db.GetTask(s.Pool, resolved)
+}
+
+// Reject moves a pending_approval task to rejected.
+func (s *Service) Reject(id, reason string) (*db.Task, error) {
+	resolved, err := db.ResolvePartialID(s.Pool, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.RejectTask(s.Pool, resolved, reason); err != nil {
+		return nil, err
+	}
+	return db.GetTask(s.Pool, resolved)
+}
+
+// DraftRelease moves a draft task to ready, or pending if it has unmet dependencies.
+func (s *Service) DraftRelease(id string) (*db.Task, error) {
+	resolved, err := db.ResolvePartialID(s.Pool, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.DraftRelease(s.Pool, resolved); err != nil {
+		return nil, err
+	}
+	return db.GetTask(s.Pool, resolved)
+}
+
+// DraftReleaseAll releases every draft task in a project.
+func (s *Service) DraftReleaseAll(projectID string) (int, error) {
+	if projectID == "" {
+		return 0, fmt.Errorf("%w: project is required", ErrInvalid)
+	}
+	return db.DraftReleaseAll(s.Pool, projectID)
+}
+
+// Search runs a full-text search across task context.
+func (s *Service) Search(query string) ([]*db.TaskContext, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("%w: query is required", ErrInvalid)
+	}
+	results, err := db.SearchContext(s.Pool, query)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []*db.TaskContext{}
+	}
+	return results, nil
+}
+
+func checkPriority(p int) error {
+	if p < 0 || p > 10 {
+		return fmt.Errorf("%w: priority %d must be between 0 and 10", ErrInvalid, p)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreateTaskValidation(t *testing.T) {
+	s := &Service{}
+	bad := []CreateTaskInput{
+		{Title: ""},
+		{Title: "x", Status: "claimed"},
+		{Title: "x", Priority: intPtr(11)},
+		{Title: "x", Priority: intPtr(-1)},
+	}
+	for _, in := range bad {
+		if _, err := s.CreateTask(in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("CreateTask(%+v) error = %v, want ErrInvalid", in, err)
+		}
+	}
+}
+
+func TestSearchRequiresQuery(t *testing.T) {
+	s := &Service{}
+	if _, err := s.Search("  "); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Search(blank) error = %v, want ErrInvalid", err)
+	}
+}
+
+func TestDraftReleaseAllRequiresProject(t *testing.T) {
+	s := &Service{}
+	if _, err := s.DraftReleaseAll(""); !errors.Is(err, ErrInvalid) {
+		t.Errorf("DraftReleaseAll(\"\") error = %v, want ErrInvalid", err)
+	}
+}
+
+func intPtr(n int) *int { return &n }
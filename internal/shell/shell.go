@@ -0,0 +1,9 @@
+// Package shell builds command lines for bash.
+package shell
+
+import "strings"
+
+// Quote quotes s as a single bash word.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,31 @@
+package shell
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"":           "''",
+		"plain":      "'plain'",
+		"it's $HOME": `'it'\''s $HOME'`,
+	}
+	for in, want := range tests {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %s, want %s", in, got, want)
+		}
+	}
+
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	in := "a 'b' \"c\" $d `e` \\f\n"
+	out, err := exec.Command("bash", "-c", "printf %s "+Quote(in)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Errorf("bash read %q back as %q", in, out)
+	}
+}
@@ -25,10 +25,19 @@ func EnsureSession(name string) error {
 	return nil
 }
 
-// WindowExists checks if a window exists in the given session.
+// WindowExists checks if a window exists in the given session, without
+// selecting it.
 func WindowExists(session, window string) bool {
-	target := session + ":" + window
-	return exec.Command("tmux", "select-window", "-t", target).Run() == nil
+	out, err := exec.Command("tmux", "list-windows", "-t", session, "-F", "#{window_name}").Output()
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Split(string(out), "\n") {
+		if name == window {
+			return true
+		}
+	}
+	return false
 }
 
 // NewWindow creates a new window in the given session with environment variables.
@@ -101,6 +110,17 @@ func CapturePane(session, window string, lines int) (string, error) {
 	return strings.TrimRight(string(out), "\n"), nil
 }
 
+// PipePane streams everything a window prints to a shell command's stdin.
+// The pipe closes when the window does.
+func PipePane(session, window, command string) error {
+	target := session + ":" + window
+	cmd := exec.Command("tmux", "pipe-pane", "-t", target, command)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("piping pane %s: %s: %w", target, string(out), err)
+	}
+	return nil
+}
+
 // KillWindow kills a tmux window.
 func KillWindow(session, window string) error {
 	target := session + ":" + window
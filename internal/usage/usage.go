@@ -0,0 +1,281 @@
+// Package usage attributes Claude token usage to tasks.
+//
+// Agents run Claude with a session ID recorded in agent_sessions. Syncing a
+// session reads its JSONL transcript, prices each assistant message and
+// stores it against the task the agent had claimed at the time.
+package usage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+)
+
+// Message is the usage of one assistant message in a transcript.
+type Message struct {
+	ID                  string
+	SessionID           string
+	Model               string
+	Timestamp           time.Time
+	InputTokens         int64
+	OutputTokens        int64
+	CacheCreationTokens int64
+	CacheReadTokens     int64
+}
+
+// transcriptLine is the subset of a Claude Code transcript entry we read.
+type transcriptLine struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"sessionId"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   struct {
+		ID    string `json:"id"`
+		Model string `json:"model"`
+		Usage *struct {
+			InputTokens              int64 `json:"input_tokens"`
+			OutputTokens             int64 `json:"output_tokens"`
+			CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+}
+
+// ParseTranscript reads assistant-message usage from a JSONL transcript.
+// Claude Code writes one line per content block, repeating the message's
+// usage, so lines are merged by message ID keeping the largest counts.
+// Unparseable lines (e.g. a partially written last line) are skipped.
+func ParseTranscript(r io.Reader) ([]Message, error) {
+	br := bufio.NewReader(r)
+	index := make(map[string]int)
+	var msgs []Message
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var tl transcriptLine
+			if json.Unmarshal(line, &tl) == nil && tl.Type == "assistant" &&
+				tl.Message.ID != "" && tl.Message.Usage != nil && tl.Message.Model != "<synthetic>" {
+				u := tl.Message.Usage
+				m := Message{
+					ID:                  tl.Message.ID,
+					SessionID:           tl.SessionID,
+					Model:               tl.Message.Model,
+					Timestamp:           tl.Timestamp,
+					InputTokens:         u.InputTokens,
+					OutputTokens:        u.OutputTokens,
+					CacheCreationTokens: u.CacheCreationInputTokens,
+					CacheReadTokens:     u.CacheReadInputTokens,
+				}
+				if i, ok := index[m.ID]; ok {
+					merge(&msgs[i], m)
+				} else {
+					index[m.ID] = len(msgs)
+					msgs = append(msgs, m)
+				}
+			}
+		}
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading transcript: %w", err)
+		}
+	}
+}
+
+func merge(dst *Message, m Message) {
+	dst.InputTokens = max(dst.InputTokens, m.InputTokens)
+	dst.OutputTokens = max(dst.OutputTokens, m.OutputTokens)
+	dst.CacheCreationTokens = max(dst.CacheCreationTokens, m.CacheCreationTokens)
+	dst.CacheReadTokens = max(dst.CacheReadTokens, m.CacheReadTokens)
+	if !m.Timestamp.IsZero() && (dst.Timestamp.IsZero() || m.Timestamp.Before(dst.Timestamp)) {
+		dst.Timestamp = m.Timestamp
+	}
+}
+
+// Price is a model's list price in USD per million tokens.
+type Price struct {
+	Input, Output, CacheWrite, CacheRead float64
+}
+
+// prices are matched by model-name prefix, most specific first.
+var prices = []struct {
+	prefix string
+	price  Price
+}{
+	{"claude-opus-4-5", Price{5, 25, 6.25, 0.50}},
+	{"claude-opus-4", Price{15, 75, 18.75, 1.50}},
+	{"claude-3-opus", Price{15, 75, 18.75, 1.50}},
+	{"claude-sonnet-4", Price{3, 15, 3.75, 0.30}},
+	{"claude-3-7-sonnet", Price{3, 15, 3.75, 0.30}},
+	{"claude-3-5-sonnet", Price{3, 15, 3.75, 0.30}},
+	{"claude-haiku-4-5", Price{1, 5, 1.25, 0.10}},
+	{"claude-3-5-haiku", Price{0.80, 4, 1, 0.08}},
+	{"claude-3-haiku", Price{0.25, 1.25, 0.30, 0.03}},
+}
+
+// PriceFor returns the price of a model, and false for unknown models.
+func PriceFor(model string) (Price, bool) {
+	for _, p := range prices {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.price, true
+		}
+	}
+	return Price{}, false
+}
+
+// Cost returns a message's cost in USD; unknown models cost 0.
+func Cost(m Message) float64 {
+	p, _ := PriceFor(m.Model)
+	return (float64(m.InputTokens)*p.Input +
+		float64(m.OutputTokens)*p.Output +
+		float64(m.CacheCreationTokens)*p.CacheWrite +
+		float64(m.CacheReadTokens)*p.CacheRead) / 1e6
+}
+
+// ClaudeDir is where Claude Code keeps its state: $CLAUDE_CONFIG_DIR, or ~/.claude.
+func ClaudeDir() string {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".claude"
+	}
+	return filepath.Join(home, ".claude")
+}
+
+// FindTranscript returns the transcript path for a session under claudeDir,
+// or "" when Claude hasn't written one (yet). Transcripts live in a
+// per-working-directory folder, so every project folder is searched.
+func FindTranscript(claudeDir, sessionID string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(claudeDir, "projects", "*", sessionID+".jsonl"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+	return matches[0], nil
+}
+
+// Attribute builds usage records for a session's messages, assigning each to
+// the claim window it falls in.
+func Attribute(s *db.AgentSession, msgs []Message, windows []db.ClaimWindow) []db.UsageRecord {
+	records := make([]db.UsageRecord, 0, len(msgs))
+	for _, m := range msgs {
+		r := db.UsageRecord{
+			MessageID:           m.ID,
+			SessionID:           s.SessionID,
+			AgentID:             s.AgentID,
+			Model:               m.Model,
+			InputTokens:         m.InputTokens,
+			OutputTokens:        m.OutputTokens,
+			CacheCreationTokens: m.CacheCreationTokens,
+			CacheReadTokens:     m.CacheReadTokens,
+			CostUSD:             Cost(m),
+			CreatedAt:           m.Timestamp,
+		}
+		for _, w := range windows {
+			if w.Contains(m.Timestamp) {
+				r.TaskID = &w.TaskID
+				r.Attempt = &w.Attempt
+				break
+			}
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// SyncSession reads what was added to one session's transcript since it was
+// last synced and stores its usage. It returns the number of messages read;
+// a session without a transcript yet reads zero.
+func SyncSession(pool *pgxpool.Pool, claudeDir string, s *db.AgentSession) (int, error) {
+	path, err := FindTranscript(claudeDir, s.SessionID)
+	if err != nil || path == "" {
+		return 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	data, offset, err := readNew(f, s.SyncedBytes, s.EndedAt != nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	// A message read before whose later lines are read now is merged by
+	// UpsertUsage, which keeps the larger counts.
+	msgs, err := ParseTranscript(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	windows, err := db.ListClaimWindows(pool, s.AgentID)
+	if err != nil {
+		return 0, err
+	}
+	if err := db.UpsertUsage(pool, Attribute(s, msgs, windows)); err != nil {
+		return 0, err
+	}
+	if err := db.MarkSessionSynced(pool, s.SessionID, offset); err != nil {
+		return 0, err
+	}
+	return len(msgs), nil
+}
+
+// readNew reads a transcript from offset, up to its last complete line, and
+// returns that and the offset to read from next. A partly written last line
+// is left for the next read, unless the session has ended. A transcript
+// shorter than offset was rewritten, and is read from the start.
+func readNew(f *os.File, offset int64, ended bool) ([]byte, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ended {
+		data = data[:bytes.LastIndexByte(data, '\n')+1]
+	}
+	return data, offset + int64(len(data)), nil
+}
+
+// Sync reads every session with possibly unread usage, or only agentID's
+// when it is non-empty. It keeps going past failing sessions and returns
+// their errors joined.
+func Sync(pool *pgxpool.Pool, agentID string) (int, error) {
+	sessions, err := db.ListSessionsToSync(pool, agentID)
+	if err != nil {
+		return 0, err
+	}
+	dir := ClaudeDir()
+	total := 0
+	var errs []error
+	for _, s := range sessions {
+		n, err := SyncSession(pool, dir, s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("session %s (%s): %w", s.SessionID, s.AgentID, err))
+		}
+		total += n
+	}
+	return total, errors.Join(errs...)
+}
@@ -0,0 +1,187 @@
+package usage
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+const fixtureSession = "0b3e7a52-8c1d-4f7e-9a64-2f0d5c9b1e10"
+
+func parseFixture(t *testing.T) []Message {
+	t.Helper()
+	f, err := os.Open("testdata/transcript.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	msgs, err := ParseTranscript(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+func TestParseTranscript(t *testing.T) {
+	msgs := parseFixture(t)
+
+	// msg_01 appears twice, msg_03 is synthetic, msg_05 is cut off mid-line.
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d: %+v", len(msgs), msgs)
+	}
+	m := msgs[0]
+	if m.ID != "msg_01" || m.SessionID != fixtureSession || m.Model != "claude-sonnet-4-5-20250929" {
+		t.Errorf("unexpected first message %+v", m)
+	}
+	if m.OutputTokens != 85 {
+		t.Errorf("repeated lines should keep the larger output count, got %d", m.OutputTokens)
+	}
+	if want := time.Date(2026, 3, 2, 10, 0, 5, 0, time.UTC); !m.Timestamp.Equal(want) {
+		t.Errorf("timestamp = %s, want the first line's %s", m.Timestamp, want)
+	}
+	if msgs[1].CacheReadTokens != 4000 || msgs[1].CacheCreationTokens != 500 {
+		t.Errorf("unexpected cache counts %+v", msgs[1])
+	}
+	if msgs[2].ID != "msg_04" {
+		t.Errorf("expected msg_04 last, got %s", msgs[2].ID)
+	}
+}
+
+func TestParseTranscriptEmpty(t *testing.T) {
+	msgs, err := ParseTranscript(strings.NewReader(""))
+	if err != nil || len(msgs) != 0 {
+		t.Errorf("got %v, %v", msgs, err)
+	}
+}
+
+func TestPriceFor(t *testing.T) {
+	tests := []struct {
+		model string
+		input float64
+		ok    bool
+	}{
+		{"claude-opus-4-5-20251101", 5, true},
+		{"claude-opus-4-1-20250805", 15, true},
+		{"claude-sonnet-4-5-20250929", 3, true},
+		{"claude-3-5-haiku-20241022", 0.80, true},
+		{"gpt-4o", 0, false},
+	}
+	for _, tt := range tests {
+		p, ok := PriceFor(tt.model)
+		if ok != tt.ok || p.Input != tt.input {
+			t.Errorf("PriceFor(%q) = %+v, %v; want input %v, %v", tt.model, p, ok, tt.input, tt.ok)
+		}
+	}
+}
+
+func TestCost(t *testing.T) {
+	m := Message{
+		Model:               "claude-sonnet-4-5-20250929",
+		InputTokens:         1_000_000,
+		OutputTokens:        100_000,
+		CacheCreationTokens: 200_000,
+		CacheReadTokens:     1_000_000,
+	}
+	// 3 + 1.5 + 0.75 + 0.30
+	if got := Cost(m); math.Abs(got-5.55) > 1e-9 {
+		t.Errorf("Cost = %v, want 5.55", got)
+	}
+	m.Model = "unknown"
+	if got := Cost(m); got != 0 {
+		t.Errorf("unknown model should cost 0, got %v", got)
+	}
+}
+
+func TestFindTranscript(t *testing.T) {
+	dir := t.TempDir()
+	if path, err := FindTranscript(dir, fixtureSession); err != nil || path != "" {
+		t.Errorf("missing transcript: got %q, %v", path, err)
+	}
+
+	want := filepath.Join(dir, "projects", "-home-dev-repo", fixtureSession+".jsonl")
+	if err := os.MkdirAll(filepath.Dir(want), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(want, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := FindTranscript(dir, fixtureSession); err != nil || path != want {
+		t.Errorf("got %q, %v; want %q", path, err, want)
+	}
+}
+
+func TestClaudeDir(t *testing.T) {
+	t.Setenv("CLAUDE_CONFIG_DIR", "/srv/claude")
+	if got := ClaudeDir(); got != "/srv/claude" {
+		t.Errorf("ClaudeDir() = %q", got)
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	msgs := parseFixture(t)
+	at := func(min int) time.Time { return time.Date(2026, 3, 2, 10, min, 0, 0, time.UTC) }
+	end := at(10)
+	windows := []db.ClaimWindow{
+		{TaskID: "fix-login-a1b2", Attempt: 1, Start: at(0).Add(7 * time.Second), End: &end},
+		{TaskID: "add-logout-c3d4", Attempt: 2, Start: at(15)},
+	}
+	s := &db.AgentSession{SessionID: fixtureSession, AgentID: "agent-1"}
+
+	records := Attribute(s, msgs, windows)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if records[0].TaskID != nil {
+		t.Errorf("message before the claim should be unattributed, got %s", *records[0].TaskID)
+	}
+	if records[1].TaskID == nil || *records[1].TaskID != "fix-login-a1b2" || *records[1].Attempt != 1 {
+		t.Errorf("msg_02 should belong to fix-login attempt 1, got %+v", records[1])
+	}
+	if records[2].TaskID == nil || *records[2].TaskID != "add-logout-c3d4" || *records[2].Attempt != 2 {
+		t.Errorf("msg_04 should belong to the open add-logout claim, got %+v", records[2])
+	}
+	for _, r := range records {
+		if r.AgentID != "agent-1" || r.SessionID != fixtureSession {
+			t.Errorf("record not tagged with session: %+v", r)
+		}
+		if r.CostUSD <= 0 {
+			t.Errorf("record %s should have a cost", r.MessageID)
+		}
+	}
+}
+
+func TestReadNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthr"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		offset     int64
+		ended      bool
+		want       string
+		wantOffset int64
+	}{
+		{0, false, "one\ntwo\n", 8},
+		{4, false, "two\n", 8},
+		{8, false, "", 8},
+		{8, true, "thr", 11},
+		{100, false, "one\ntwo\n", 8}, // rewritten since
+	}
+	for _, tt := range tests {
+		data, offset, err := readNew(f, tt.offset, tt.ended)
+		if err != nil || string(data) != tt.want || offset != tt.wantOffset {
+			t.Errorf("readNew(%d, %v) = %q, %d, %v; want %q, %d", tt.offset, tt.ended, data, offset, err, tt.want, tt.wantOffset)
+		}
+	}
+}
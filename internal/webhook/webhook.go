@@ -0,0 +1,241 @@
+// Package webhook delivers queued lifecycle events to subscribed URLs.
+//
+// Deliveries are queued by a database trigger as events are logged (see
+// 009_webhooks.sql); a Dispatcher drains that outbox, signing each POST and
+// retrying failures with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/otavio/minuano/internal/db"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is marked failed.
+	MaxAttempts = 8
+
+	baseBackoff  = 10 * time.Second
+	maxBackoff   = time.Hour
+	sendTimeout  = 10 * time.Second
+	pollInterval = 5 * time.Second
+	batchSize    = 20
+	// lease must outlast a whole batch, sent one delivery after another each
+	// for up to sendTimeout, so none of it is claimed again while in flight.
+	lease = batchSize*sendTimeout + time.Minute
+)
+
+// Signature headers. The signature is the hex HMAC-SHA256, keyed with the
+// webhook's secret, of "<timestamp>.<body>".
+const (
+	HeaderEvent     = "X-Minuano-Event"
+	HeaderDelivery  = "X-Minuano-Delivery"
+	HeaderTimestamp = "X-Minuano-Timestamp"
+	HeaderSignature = "X-Minuano-Signature"
+)
+
+// ParseEvents splits a comma-separated event list, rejecting unknown names.
+func ParseEvents(csv string) ([]string, error) {
+	var events []string
+	for _, e := range strings.Split(csv, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !slices.Contains(db.WebhookEvents, e) {
+			return nil, fmt.Errorf("unknown webhook event %q (want %s)", e, strings.Join(db.WebhookEvents, ", "))
+		}
+		if !slices.Contains(events, e) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Sign returns the X-Minuano-Signature value for body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature as a receiver would.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff is the delay before retrying after the given (1-based) attempt:
+// 10s, 20s, 40s, ... capped at an hour.
+func Backoff(attempt int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// Dispatcher drains the webhook outbox.
+type Dispatcher struct {
+	// Retention is how long finished deliveries stay in the log; zero keeps them.
+	Retention time.Duration
+
+	pool   *pgxpool.Pool
+	client *http.Client
+}
+
+// NewDispatcher creates a dispatcher backed by pool.
+func NewDispatcher(pool *pgxpool.Pool) *Dispatcher {
+	return &Dispatcher{
+		Retention: 7 * 24 * time.Hour,
+		pool:      pool,
+		client:    &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Run delivers until ctx is cancelled. New deliveries wake it through the
+// webhook_deliveries channel; retries are picked up by polling.
+func (d *Dispatcher) Run(ctx context.Context) {
+	wake := make(chan struct{}, 1)
+	go func() {
+		for ctx.Err() == nil {
+			err := db.Listen(ctx, d.pool, "webhook_deliveries", func(string) {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			})
+			if err != nil {
+				log.Printf("webhook: %v (retrying)", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}
+	}()
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	d.prune()
+
+	for {
+		for {
+			n, err := d.DeliverDue(ctx)
+			if err != nil {
+				log.Printf("webhook: %v", err)
+			}
+			if n < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-poll.C:
+		case <-prune.C:
+			d.prune()
+		}
+	}
+}
+
+func (d *Dispatcher) prune() {
+	if d.Retention <= 0 {
+		return
+	}
+	if n, err := db.PruneWebhookDeliveries(d.pool, time.Now().Add(-d.Retention)); err != nil {
+		log.Printf("webhook: %v", err)
+	} else if n > 0 {
+		log.Printf("webhook: pruned %d delivery record(s) older than %s", n, d.Retention)
+	}
+}
+
+// DeliverDue sends one batch of due deliveries and returns how many it tried.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	due, err := db.ClaimDueDeliveries(d.pool, batchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, del := range due {
+		code, err := send(ctx, d.client, del)
+		if err == nil {
+			if err := db.MarkDeliveryDelivered(d.pool, del.ID, code); err != nil {
+				log.Printf("webhook: %v", err)
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return len(due), nil // shutting down; the lease hands these to the next run
+		}
+
+		var codePtr *int
+		if code != 0 {
+			codePtr = &code
+		}
+		var retryAt *time.Time
+		if del.Attempts < MaxAttempts {
+			t := time.Now().Add(Backoff(del.Attempts))
+			retryAt = &t
+			log.Printf("webhook: delivery %d to %s failed (attempt %d/%d, retrying in %s): %v",
+				del.ID, del.URL, del.Attempts, MaxAttempts, Backoff(del.Attempts), err)
+		} else {
+			log.Printf("webhook: delivery %d to %s failed after %d attempts: %v", del.ID, del.URL, del.Attempts, err)
+		}
+		if err := db.MarkDeliveryFailed(d.pool, del.ID, codePtr, err.Error(), retryAt); err != nil {
+			log.Printf("webhook: %v", err)
+		}
+	}
+	return len(due), nil
+}
+
+// send POSTs one delivery. It returns the response status (0 if there was
+// none) and an error unless the receiver answered 2xx.
+func send(ctx context.Context, client *http.Client, d *db.DueDelivery) (int, error) {
+	ts := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "minuano-webhook")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(d.Secret, ts, d.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/otavio/minuano/internal/db"
+)
+
+func TestParseEvents(t *testing.T) {
+	got, err := ParseEvents(" task.done, merge.conflict,task.done,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"task.done", "merge.conflict"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, err := ParseEvents(""); err != nil || got != nil {
+		t.Errorf("empty list: got %v, %v", got, err)
+	}
+	if _, err := ParseEvents("task.done,task.exploded"); err == nil {
+		t.Error("expected error for unknown event")
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"event":"task.done"}`)
+	sig := Sign("s3cret", 1700000000, body)
+	if len(sig) != len("sha256=")+64 || sig[:7] != "sha256=" {
+		t.Fatalf("unexpected signature format %q", sig)
+	}
+	if !Verify("s3cret", 1700000000, body, sig) {
+		t.Error("signature should verify")
+	}
+	if Verify("other", 1700000000, body, sig) {
+		t.Error("wrong secret must not verify")
+	}
+	if Verify("s3cret", 1700000001, body, sig) {
+		t.Error("wrong timestamp must not verify")
+	}
+	if Verify("s3cret", 1700000000, []byte(`{"event":"task.failed"}`), sig) {
+		t.Error("tampered body must not verify")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{8, 1280 * time.Second},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, b := NewSecret(), NewSecret()
+	if len(a) != 64 || a == b {
+		t.Errorf("unexpected secrets %q, %q", a, b)
+	}
+}
+
+func TestSendSignsRequest(t *testing.T) {
+	payload := json.RawMessage(`{"id":7,"event":"task.done"}`)
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := &db.DueDelivery{URL: srv.URL, Secret: "s3cret"}
+	d.ID = 42
+	d.Event = "task.done"
+	d.Payload = payload
+
+	code, err := send(context.Background(), srv.Client(), d)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("send = %d, %v", code, err)
+	}
+	if got.Method != http.MethodPost || got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected request %s %s", got.Method, got.Header.Get("Content-Type"))
+	}
+	if got.Header.Get(HeaderEvent) != "task.done" || got.Header.Get(HeaderDelivery) != "42" {
+		t.Errorf("unexpected headers %v", got.Header)
+	}
+	ts, err := strconv.ParseInt(got.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("bad timestamp header: %v", err)
+	}
+	if !Verify("s3cret", ts, gotBody, got.Header.Get(HeaderSignature)) {
+		t.Error("receiver could not verify the signature")
+	}
+	if string(gotBody) != string(payload) {
+		t.Errorf("body = %s, want %s", gotBody, payload)
+	}
+}
+
+func TestSendNon2xxIsFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	d := &db.DueDelivery{URL: srv.URL}
+	d.Payload = json.RawMessage(`{}`)
+	code, err := send(context.Background(), srv.Client(), d)
+	if err == nil || code != http.StatusBadGateway {
+		t.Errorf("send = %d, %v; want 502 and an error", code, err)
+	}
+}
+
+func TestSendConnectionRefused(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	d := &db.DueDelivery{URL: url}
+	d.Payload = json.RawMessage(`{}`)
+	code, err := send(context.Background(), http.DefaultClient, d)
+	if err == nil || code != 0 {
+		t.Errorf("send = %d, %v; want 0 and an error", code, err)
+	}
+}
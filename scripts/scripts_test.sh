@@ -61,6 +61,16 @@ else
     fail "claim missing FOR UPDATE SKIP LOCKED"
 fi
 
+# Test: claim and pick lock the agent and skip draining agents
+for script in minuano-claim minuano-pick; do
+    if grep -q "FROM agents WHERE id='\$AGENT_ID' FOR UPDATE" "$SCRIPTS_DIR/$script" \
+        && grep -q "status = 'draining'" "$SCRIPTS_DIR/$script"; then
+        pass "$script refuses draining agents"
+    else
+        fail "$script does not check for draining agents"
+    fi
+done
+
 # Test: done script requires task ID and summary
 if grep -q 'TASK_ID.*Usage:' "$SCRIPTS_DIR/minuano-done"; then
     pass "done requires task ID"
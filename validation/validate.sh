@@ -291,6 +291,7 @@ expect_contains "prompt auto requires --project" "project" \
   run_minuano prompt auto 2>&1 || true
 
 # Create a project-scoped task for auto test.
+run_minuano project create "val-project" >/dev/null 2>&1 || true
 run_minuano add "Auto mode test" --project "val-project" --priority 5 >/dev/null 2>&1
 AUTO_PROMPT=$(run_minuano prompt auto --project val-project)
 if echo "$AUTO_PROMPT" | grep -q "Auto Mode"; then
@@ -328,6 +329,8 @@ section "Phase 3: Agent Scripts"
 
 export AGENT_ID="validate-agent-$$"
 export DATABASE_URL="$DB_URL"
+# minuano-done runs the gate with `minuano verify`.
+export MINUANO_BIN="$BINARY"
 
 # Register a test agent.
 psql "$DB_URL" -c "INSERT INTO agents (id, tmux_session, tmux_window) VALUES ('$AGENT_ID', '$SESSION_NAME', 'test')" >/dev/null 2>&1